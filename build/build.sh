@@ -69,13 +69,21 @@ EXPORTED_FUNCTIONS=(
   _TIFFSetFieldExtraSamples
   _TIFFSetFieldTwoUint16
   _TIFFGetFieldTwoUint16
+  _TIFFSetFieldThreeArrays
+  _TIFFGetFieldThreeArrays
   _TIFFGetFieldUint64_t
   _TIFFSetFieldUint64_t
+  _TIFFSetFieldCountedArray
+  _TIFFGetFieldCountedArray
+  _TIFFSetFieldArray
+  _TIFFGetFieldArray
+  _TIFFMergeAnonymousField
 
   # Directory navigation
   _TIFFReadDirectory
   _TIFFSetDirectory
   _TIFFCurrentDirectory
+  _TIFFCurrentDirOffset
   _TIFFLastDirectory
   _TIFFNumberOfDirectories
   _TIFFSetSubDirectory
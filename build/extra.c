@@ -12,6 +12,10 @@ extern void TIFFUnmapFileProcGoCB(thandle_t, void *base, toff_t size);
 extern int TIFFOpenOptionsSetErrorHandlerExtRGoCB(TIFF *tif, void *user_data, const char *module, const char *fmt, va_list ap);
 extern int TIFFOpenOptionsSetWarningHandlerExtRGoCB(TIFF *tif, void *user_data, const char *module, const char *fmt, va_list ap);
 
+// Internal libtiff functions (tif_dir.h), used to register unknown tags.
+extern TIFFField *_TIFFCreateAnonField(TIFF *tif, uint32_t tag, TIFFDataType field_type);
+extern int _TIFFMergeFields(TIFF *tif, const TIFFField *fields, uint32_t n);
+
 EMSCRIPTEN_KEEPALIVE
 int TIFFGetFieldUint16_t(TIFF *tif, uint32_t tag, uint16_t *val) {
   return TIFFGetField(tif, tag, val);
@@ -107,6 +111,17 @@ int TIFFGetFieldTwoUint16(TIFF *tif, uint32_t tag, uint16_t *val1, uint16_t *val
   return TIFFGetField(tif, tag, val1, val2);
 }
 
+// Used for tags that are passed as three arrays, like TIFFTAG_COLORMAP.
+EMSCRIPTEN_KEEPALIVE
+int TIFFSetFieldThreeArrays(TIFF *tif, uint32_t tag, const void *val1, const void *val2, const void *val3) {
+  return TIFFSetField(tif, tag, val1, val2, val3);
+}
+
+EMSCRIPTEN_KEEPALIVE
+int TIFFGetFieldThreeArrays(TIFF *tif, uint32_t tag, void **val1, void **val2, void **val3) {
+  return TIFFGetField(tif, tag, val1, val2, val3);
+}
+
 EMSCRIPTEN_KEEPALIVE
 int TIFFGetFieldUint64_t(TIFF *tif, uint32_t tag, uint64_t *val) {
   return TIFFGetField(tif, tag, val);
@@ -116,3 +131,54 @@ EMSCRIPTEN_KEEPALIVE
 int TIFFSetFieldUint64_t(TIFF *tif, uint32_t tag, uint64_t val) {
   return TIFFSetField(tif, tag, val);
 }
+
+EMSCRIPTEN_KEEPALIVE
+int TIFFSetFieldCountedArray(TIFF *tif, uint32_t tag, uint32_t count, const void *val) {
+  return TIFFSetField(tif, tag, count, val);
+}
+
+EMSCRIPTEN_KEEPALIVE
+int TIFFGetFieldCountedArray(TIFF *tif, uint32_t tag, uint32_t *count, void **val) {
+  *count = 0;
+  const TIFFField *fip = TIFFFindField(tif, tag, TIFF_ANY);
+  if (fip == NULL || !TIFFFieldPassCount(fip)) {
+    return 0;
+  }
+
+  // Tags with a TIFF_VARIABLE2 read count use a 32-bit count, all others a 16-bit one.
+  if (TIFFFieldReadCount(fip) == TIFF_VARIABLE2) {
+    return TIFFGetField(tif, tag, count, val);
+  }
+
+  uint16_t count16 = 0;
+  int ret = TIFFGetField(tif, tag, &count16, val);
+  *count = count16;
+  return ret;
+}
+
+EMSCRIPTEN_KEEPALIVE
+int TIFFSetFieldArray(TIFF *tif, uint32_t tag, const void *val) {
+  return TIFFSetField(tif, tag, val);
+}
+
+EMSCRIPTEN_KEEPALIVE
+int TIFFGetFieldArray(TIFF *tif, uint32_t tag, void **val) {
+  return TIFFGetField(tif, tag, val);
+}
+
+// Registers an unknown tag the same way libtiff does when it reads one, so it
+// can be set with TIFFSetFieldCountedArray. The registration is reset when a
+// new directory is started.
+EMSCRIPTEN_KEEPALIVE
+int TIFFMergeAnonymousField(TIFF *tif, uint32_t tag, TIFFDataType field_type) {
+  if (TIFFFindField(tif, tag, TIFF_ANY) != NULL) {
+    return 1;
+  }
+
+  TIFFField *fip = _TIFFCreateAnonField(tif, tag, field_type);
+  if (fip == NULL) {
+    return 0;
+  }
+
+  return _TIFFMergeFields(tif, fip, 1);
+}
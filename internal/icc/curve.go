@@ -0,0 +1,116 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// curve maps a value in [0, 1] to a value in [0, 1].
+type curve func(float64) float64
+
+func identity(v float64) float64 {
+	return v
+}
+
+// parseCurve parses a curveType or parametricCurveType element and returns
+// the curve and the size of the element.
+func parseCurve(data []byte) (curve, int, error) {
+	if len(data) < 12 {
+		return nil, 0, errors.New("invalid ICC curve")
+	}
+
+	switch string(data[:4]) {
+	case "curv":
+		count := int(binary.BigEndian.Uint32(data[8:]))
+		size := 12 + count*2
+		if len(data) < size {
+			return nil, 0, errors.New("invalid ICC curve")
+		}
+		switch count {
+		case 0:
+			return identity, size, nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(data[12:])) / 256
+			return func(v float64) float64 {
+				return math.Pow(clamp01(v), gamma)
+			}, size, nil
+		}
+		table := make([]float64, count)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(data[12+i*2:])) / 65535
+		}
+		return func(v float64) float64 {
+			return interpolateTable(table, v)
+		}, size, nil
+	case "para":
+		parameterCounts := []int{1, 3, 4, 5, 7}
+		function := int(binary.BigEndian.Uint16(data[8:]))
+		if function >= len(parameterCounts) {
+			return nil, 0, errors.New("unsupported ICC parametric curve")
+		}
+		size := 12 + parameterCounts[function]*4
+		if len(data) < size {
+			return nil, 0, errors.New("invalid ICC parametric curve")
+		}
+		var p [7]float64
+		for i := 0; i < parameterCounts[function]; i++ {
+			p[i] = s15Fixed16(data[12+i*4:])
+		}
+		return parametricCurve(function, p), size, nil
+	}
+
+	return nil, 0, errors.New("unsupported ICC curve type")
+}
+
+// parametricCurve returns the curve of a parametricCurveType, the parameters
+// are g, a, b, c, d, e and f.
+func parametricCurve(function int, p [7]float64) curve {
+	g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+	pow := func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		return math.Pow(x, g)
+	}
+
+	return func(x float64) float64 {
+		x = clamp01(x)
+		var y float64
+		switch function {
+		case 0:
+			y = pow(x)
+		case 1:
+			if x >= -b/a {
+				y = pow(a*x + b)
+			}
+		case 2:
+			y = c
+			if x >= -b/a {
+				y = pow(a*x+b) + c
+			}
+		case 3:
+			y = c * x
+			if x >= d {
+				y = pow(a*x + b)
+			}
+		case 4:
+			y = c*x + f
+			if x >= d {
+				y = pow(a*x+b) + e
+			}
+		}
+		return clamp01(y)
+	}
+}
+
+// interpolateTable linearly interpolates an evenly spaced table over [0, 1].
+func interpolateTable(table []float64, v float64) float64 {
+	if len(table) == 1 {
+		return table[0]
+	}
+	x := clamp01(v) * float64(len(table)-1)
+	i := min(int(x), len(table)-2)
+	frac := x - float64(i)
+	return table[i] + (table[i+1]-table[i])*frac
+}
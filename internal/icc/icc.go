@@ -0,0 +1,159 @@
+// Package icc implements a small colour management module that converts
+// colours described by an ICC profile to sRGB.
+//
+// It supports matrix/TRC RGB profiles, TRC gray profiles and LUT based
+// (lut8, lut16 and lutAtoB) profiles, which covers the RGB, gray and CMYK
+// profiles that are commonly embedded in scanned and prepress TIFFs.
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ColorSpace is the data colour space signature of a profile.
+type ColorSpace string
+
+const (
+	ColorSpaceRGB  ColorSpace = "RGB "
+	ColorSpaceCMYK ColorSpace = "CMYK"
+	ColorSpaceGray ColorSpace = "GRAY"
+)
+
+// Channels returns the number of channels of the colour space.
+func (c ColorSpace) Channels() int {
+	switch c {
+	case ColorSpaceRGB:
+		return 3
+	case ColorSpaceCMYK:
+		return 4
+	case ColorSpaceGray:
+		return 1
+	}
+	return 0
+}
+
+// pcsXYZ and pcsLab are the profile connection space signatures.
+const (
+	pcsXYZ = "XYZ "
+	pcsLab = "Lab "
+)
+
+// Profile is a parsed ICC profile.
+type Profile struct {
+	ColorSpace ColorSpace
+	// Intent is the rendering intent from the profile header.
+	Intent uint32
+
+	// toXYZ converts colour space values in [0, 1] to D50 XYZ.
+	toXYZ func(in []float64) [3]float64
+}
+
+// Parse parses an ICC profile.
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, errors.New("not an ICC profile")
+	}
+
+	profile := &Profile{
+		ColorSpace: ColorSpace(data[16:20]),
+		Intent:     binary.BigEndian.Uint32(data[64:]),
+	}
+	if profile.ColorSpace.Channels() == 0 {
+		return nil, fmt.Errorf("unsupported ICC profile colour space %q", string(profile.ColorSpace))
+	}
+
+	pcs := string(data[20:24])
+	if pcs != pcsXYZ && pcs != pcsLab {
+		return nil, fmt.Errorf("unsupported ICC profile connection space %q", pcs)
+	}
+
+	tagCount := int(binary.BigEndian.Uint32(data[128:]))
+	if 132+tagCount*12 > len(data) {
+		return nil, errors.New("invalid ICC profile tag table")
+	}
+	tags := map[string][]byte{}
+	for i := 0; i < tagCount; i++ {
+		entry := data[132+i*12:]
+		offset := int(binary.BigEndian.Uint32(entry[4:]))
+		size := int(binary.BigEndian.Uint32(entry[8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("invalid ICC profile tag %q", string(entry[:4]))
+		}
+		tags[string(entry[:4])] = data[offset : offset+size]
+	}
+
+	var err error
+	switch {
+	case profile.ColorSpace == ColorSpaceRGB && hasTags(tags, "rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"):
+		profile.toXYZ, err = parseMatrixTRC(tags)
+	case profile.ColorSpace == ColorSpaceGray && hasTags(tags, "kTRC"):
+		profile.toXYZ, err = parseGrayTRC(tags)
+	default:
+		lut := tags[fmt.Sprintf("A2B%d", min(profile.Intent, 2))]
+		if lut == nil {
+			lut = tags["A2B0"]
+		}
+		if lut == nil {
+			return nil, errors.New("ICC profile has no supported transform to the connection space")
+		}
+		profile.toXYZ, err = parseLut(lut, profile.ColorSpace.Channels(), pcs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func hasTags(tags map[string][]byte, signatures ...string) bool {
+	for _, signature := range signatures {
+		if _, ok := tags[signature]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// s15Fixed16 decodes a signed 15.16 fixed point number.
+func s15Fixed16(data []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(data))) / 65536
+}
+
+func parseXYZ(data []byte) ([3]float64, error) {
+	if len(data) < 20 || string(data[:4]) != "XYZ " {
+		return [3]float64{}, errors.New("invalid ICC XYZ tag")
+	}
+	return [3]float64{s15Fixed16(data[8:]), s15Fixed16(data[12:]), s15Fixed16(data[16:])}, nil
+}
+
+// d50 is the white point of the profile connection space.
+var d50 = [3]float64{0.9642, 1.0, 0.8249}
+
+// labToXYZ converts a D50 Lab colour to XYZ.
+func labToXYZ(l, a, b float64) [3]float64 {
+	f := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return 3 * (6.0 / 29) * (6.0 / 29) * (t - 4.0/29)
+	}
+	fy := (l + 16) / 116
+	return [3]float64{
+		d50[0] * f(fy+a/500),
+		d50[1] * f(fy),
+		d50[2] * f(fy-b/200),
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
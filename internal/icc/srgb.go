@@ -0,0 +1,100 @@
+package icc
+
+import (
+	"math"
+)
+
+// xyzD50ToLinearSRGB converts D50 XYZ to linear sRGB, with Bradford
+// adaptation from D50 to D65.
+var xyzD50ToLinearSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// srgbEncodeTable maps linear values to gamma encoded 8-bit sRGB values.
+var srgbEncodeTable = func() [4097]uint8 {
+	var table [4097]uint8
+	for i := range table {
+		v := float64(i) / 4096
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		table[i] = uint8(math.Round(v * 255))
+	}
+	return table
+}()
+
+// ToSRGB converts colour space values in [0, 1] to 8-bit sRGB.
+func (p *Profile) ToSRGB(in []float64) [3]uint8 {
+	xyz := p.toXYZ(in)
+	var rgb [3]uint8
+	for i, row := range xyzD50ToLinearSRGB {
+		linear := row[0]*xyz[0] + row[1]*xyz[1] + row[2]*xyz[2]
+		rgb[i] = srgbEncodeTable[int(math.Round(clamp01(linear)*4096))]
+	}
+	return rgb
+}
+
+// maxCacheSize is the number of colours a Transform remembers before it
+// starts over.
+const maxCacheSize = 1 << 16
+
+// Transform converts integer samples to sRGB and caches the results, since
+// images often reuse colours. A Transform is not safe for concurrent use.
+type Transform struct {
+	profile *Profile
+	cache   map[uint64][3]uint8
+}
+
+// NewTransform creates a Transform for the profile.
+func NewTransform(profile *Profile) *Transform {
+	return &Transform{
+		profile: profile,
+		cache:   map[uint64][3]uint8{},
+	}
+}
+
+// Convert8 converts 8-bit samples to sRGB.
+func (t *Transform) Convert8(samples []uint8) [3]uint8 {
+	var key uint64
+	for _, s := range samples {
+		key = key<<8 | uint64(s)
+	}
+	if rgb, ok := t.cache[key]; ok {
+		return rgb
+	}
+
+	var in [maxChannels]float64
+	for i, s := range samples {
+		in[i] = float64(s) / 255
+	}
+	return t.store(key, t.profile.ToSRGB(in[:len(samples)]))
+}
+
+// Convert16 converts 16-bit samples to sRGB.
+func (t *Transform) Convert16(samples []uint16) [3]uint8 {
+	var key uint64
+	for _, s := range samples {
+		key = key<<16 | uint64(s)
+	}
+	if rgb, ok := t.cache[key]; ok {
+		return rgb
+	}
+
+	var in [maxChannels]float64
+	for i, s := range samples {
+		in[i] = float64(s) / 65535
+	}
+	return t.store(key, t.profile.ToSRGB(in[:len(samples)]))
+}
+
+func (t *Transform) store(key uint64, rgb [3]uint8) [3]uint8 {
+	if len(t.cache) >= maxCacheSize {
+		clear(t.cache)
+	}
+	t.cache[key] = rgb
+	return rgb
+}
@@ -0,0 +1,336 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// maxChannels is the maximum number of channels of a lut.
+const maxChannels = 15
+
+func parseMatrixTRC(tags map[string][]byte) (func(in []float64) [3]float64, error) {
+	var columns [3][3]float64
+	var curves [3]curve
+	for i, channel := range []string{"r", "g", "b"} {
+		var err error
+		columns[i], err = parseXYZ(tags[channel+"XYZ"])
+		if err != nil {
+			return nil, err
+		}
+		curves[i], _, err = parseCurve(tags[channel+"TRC"])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(in []float64) [3]float64 {
+		r, g, b := curves[0](in[0]), curves[1](in[1]), curves[2](in[2])
+		return [3]float64{
+			columns[0][0]*r + columns[1][0]*g + columns[2][0]*b,
+			columns[0][1]*r + columns[1][1]*g + columns[2][1]*b,
+			columns[0][2]*r + columns[1][2]*g + columns[2][2]*b,
+		}
+	}, nil
+}
+
+func parseGrayTRC(tags map[string][]byte) (func(in []float64) [3]float64, error) {
+	trc, _, err := parseCurve(tags["kTRC"])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(in []float64) [3]float64 {
+		y := trc(in[0])
+		return [3]float64{d50[0] * y, d50[1] * y, d50[2] * y}
+	}, nil
+}
+
+// parseLut parses a lut8Type, lut16Type or lutAtoBType tag and returns a
+// function that converts to XYZ.
+func parseLut(data []byte, channels int, pcs string) (func(in []float64) [3]float64, error) {
+	if len(data) < 12 {
+		return nil, errors.New("invalid ICC lut")
+	}
+	if int(data[8]) != channels || data[9] != 3 {
+		return nil, errors.New("ICC lut channels do not match the profile")
+	}
+
+	var lut func(in []float64) [3]float64
+	var err error
+	legacyLab := false
+	switch string(data[:4]) {
+	case "mft1":
+		lut, err = parseLutN(data, channels, false)
+	case "mft2":
+		lut, err = parseLutN(data, channels, true)
+		legacyLab = true
+	case "mAB ":
+		lut, err = parseLutAToB(data, channels)
+	default:
+		return nil, errors.New("unsupported ICC lut type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if pcs == pcsXYZ {
+		return func(in []float64) [3]float64 {
+			v := lut(in)
+			// XYZ is encoded as u1Fixed15, 1.0 is 0x8000.
+			const scale = 65535.0 / 32768
+			return [3]float64{v[0] * scale, v[1] * scale, v[2] * scale}
+		}, nil
+	}
+
+	return func(in []float64) [3]float64 {
+		v := lut(in)
+		if legacyLab {
+			// The legacy 16-bit Lab encoding maps L=100 to 0xFF00.
+			return labToXYZ(v[0]*65535/65280*100, v[1]*65535/256-128, v[2]*65535/256-128)
+		}
+		return labToXYZ(v[0]*100, v[1]*255-128, v[2]*255-128)
+	}, nil
+}
+
+// parseLutN parses a lut8Type or lut16Type.
+func parseLutN(data []byte, channels int, sixteenBit bool) (func(in []float64) [3]float64, error) {
+	points := int(data[10])
+	inputEntries, outputEntries, offset, entrySize := 256, 256, 48, 1
+	if sixteenBit {
+		if len(data) < 52 {
+			return nil, errors.New("invalid ICC lut")
+		}
+		inputEntries = int(binary.BigEndian.Uint16(data[48:]))
+		outputEntries = int(binary.BigEndian.Uint16(data[50:]))
+		offset, entrySize = 52, 2
+	}
+	if points < 2 || inputEntries < 2 || outputEntries < 2 {
+		return nil, errors.New("invalid ICC lut")
+	}
+
+	clutSize := 3
+	for i := 0; i < channels; i++ {
+		clutSize *= points
+	}
+	if len(data) < offset+(channels*inputEntries+clutSize+3*outputEntries)*entrySize {
+		return nil, errors.New("invalid ICC lut")
+	}
+
+	read := func(n int) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			if sixteenBit {
+				values[i] = float64(binary.BigEndian.Uint16(data[offset+i*2:])) / 65535
+			} else {
+				values[i] = float64(data[offset+i]) / 255
+			}
+		}
+		offset += n * entrySize
+		return values
+	}
+
+	inputTables := make([][]float64, channels)
+	for i := range inputTables {
+		inputTables[i] = read(inputEntries)
+	}
+	grid := make([]int, channels)
+	for i := range grid {
+		grid[i] = points
+	}
+	table := newCLUT(grid, 3, read(clutSize))
+	var outputTables [3][]float64
+	for i := range outputTables {
+		outputTables[i] = read(outputEntries)
+	}
+
+	return func(in []float64) [3]float64 {
+		var values [maxChannels]float64
+		for i := 0; i < channels; i++ {
+			values[i] = interpolateTable(inputTables[i], in[i])
+		}
+		var out [3]float64
+		table.lookup(values[:channels], out[:])
+		for i := range out {
+			out[i] = interpolateTable(outputTables[i], out[i])
+		}
+		return out
+	}, nil
+}
+
+// parseLutAToB parses a lutAtoBType, which applies the A curves, the CLUT,
+// the M curves, the matrix and the B curves in that order.
+func parseLutAToB(data []byte, channels int) (func(in []float64) [3]float64, error) {
+	if len(data) < 32 {
+		return nil, errors.New("invalid ICC lut")
+	}
+	offsetB := int(binary.BigEndian.Uint32(data[12:]))
+	offsetMatrix := int(binary.BigEndian.Uint32(data[16:]))
+	offsetM := int(binary.BigEndian.Uint32(data[20:]))
+	offsetCLUT := int(binary.BigEndian.Uint32(data[24:]))
+	offsetA := int(binary.BigEndian.Uint32(data[28:]))
+
+	readCurves := func(offset, n int) ([]curve, error) {
+		curves := make([]curve, n)
+		for i := range curves {
+			if offset <= 0 || offset >= len(data) {
+				return nil, errors.New("invalid ICC lut curve offset")
+			}
+			c, size, err := parseCurve(data[offset:])
+			if err != nil {
+				return nil, err
+			}
+			curves[i] = c
+			// Curves are padded to a 4-byte boundary.
+			offset = (offset + size + 3) &^ 3
+		}
+		return curves, nil
+	}
+
+	if offsetB == 0 {
+		return nil, errors.New("ICC lut has no B curves")
+	}
+	curvesB, err := readCurves(offsetB, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	var curvesA, curvesM []curve
+	var matrix []float64
+	var table *clut
+	if offsetA != 0 {
+		if curvesA, err = readCurves(offsetA, channels); err != nil {
+			return nil, err
+		}
+	}
+	if offsetCLUT != 0 {
+		if offsetCLUT+20 > len(data) {
+			return nil, errors.New("invalid ICC lut CLUT")
+		}
+		grid := make([]int, channels)
+		size := 3
+		for i := range grid {
+			grid[i] = int(data[offsetCLUT+i])
+			if grid[i] == 0 {
+				return nil, errors.New("invalid ICC lut CLUT")
+			}
+			size *= grid[i]
+		}
+		precision := int(data[offsetCLUT+16])
+		if (precision != 1 && precision != 2) || offsetCLUT+20+size*precision > len(data) {
+			return nil, errors.New("invalid ICC lut CLUT")
+		}
+		values := make([]float64, size)
+		for i := range values {
+			if precision == 2 {
+				values[i] = float64(binary.BigEndian.Uint16(data[offsetCLUT+20+i*2:])) / 65535
+			} else {
+				values[i] = float64(data[offsetCLUT+20+i]) / 255
+			}
+		}
+		table = newCLUT(grid, 3, values)
+	} else if channels != 3 {
+		return nil, errors.New("ICC lut without CLUT must have 3 input channels")
+	}
+	if offsetM != 0 && offsetMatrix != 0 {
+		if curvesM, err = readCurves(offsetM, 3); err != nil {
+			return nil, err
+		}
+		if offsetMatrix+48 > len(data) {
+			return nil, errors.New("invalid ICC lut matrix")
+		}
+		matrix = make([]float64, 12)
+		for i := range matrix {
+			matrix[i] = s15Fixed16(data[offsetMatrix+i*4:])
+		}
+	}
+
+	return func(in []float64) [3]float64 {
+		var values [maxChannels]float64
+		copy(values[:channels], in)
+		for i, c := range curvesA {
+			values[i] = c(values[i])
+		}
+
+		var out [3]float64
+		if table != nil {
+			table.lookup(values[:channels], out[:])
+		} else {
+			copy(out[:], values[:3])
+		}
+
+		if matrix != nil {
+			for i, c := range curvesM {
+				out[i] = c(out[i])
+			}
+			x, y, z := out[0], out[1], out[2]
+			for i := range out {
+				out[i] = matrix[i*3]*x + matrix[i*3+1]*y + matrix[i*3+2]*z + matrix[9+i]
+			}
+		}
+
+		for i, c := range curvesB {
+			out[i] = c(out[i])
+		}
+		return out
+	}, nil
+}
+
+// clut is a multidimensional colour lookup table.
+type clut struct {
+	grid    []int
+	strides []int
+	outputs int
+	values  []float64
+}
+
+func newCLUT(grid []int, outputs int, values []float64) *clut {
+	strides := make([]int, len(grid))
+	stride := outputs
+	for i := len(grid) - 1; i >= 0; i-- {
+		strides[i] = stride
+		stride *= grid[i]
+	}
+	return &clut{grid: grid, strides: strides, outputs: outputs, values: values}
+}
+
+// lookup interpolates the table multilinearly, the first input channel
+// varies slowest in the table.
+func (c *clut) lookup(in []float64, out []float64) {
+	var fractions [maxChannels]float64
+	base := 0
+	for i, v := range in {
+		if c.grid[i] == 1 {
+			continue
+		}
+		x := clamp01(v) * float64(c.grid[i]-1)
+		index := min(int(x), c.grid[i]-2)
+		fractions[i] = x - float64(index)
+		base += index * c.strides[i]
+	}
+
+	for i := range out {
+		out[i] = 0
+	}
+	for corner := 0; corner < 1<<len(in); corner++ {
+		weight := 1.0
+		offset := base
+		for i := range in {
+			if corner&(1<<i) != 0 {
+				if c.grid[i] == 1 {
+					weight = 0
+					break
+				}
+				weight *= fractions[i]
+				offset += c.strides[i]
+			} else {
+				weight *= 1 - fractions[i]
+			}
+		}
+		if weight == 0 {
+			continue
+		}
+		for o := range out {
+			out[o] += weight * c.values[offset+o]
+		}
+	}
+}
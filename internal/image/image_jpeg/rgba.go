@@ -0,0 +1,18 @@
+package image_jpeg
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ToRGBA returns the image as *image.RGBA for Encode, converting it when it
+// is of another type, like the *image.Paletted of palette images.
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
@@ -150,7 +150,7 @@ type TIFFSeekProcGoCB struct {
 
 func (cb TIFFSeekProcGoCB) Call(ctx context.Context, mod api.Module, stack []uint64) {
 	paramPointer := uint32(stack[0])
-	offset := uint32(stack[1])
+	offset := int64(stack[1])
 	whence := api.DecodeI32(stack[2])
 
 	mem := mod.Memory()
@@ -169,7 +169,7 @@ func (cb TIFFSeekProcGoCB) Call(ctx context.Context, mod api.Module, stack []uin
 		return
 	}
 
-	newOffset, err := openFile.ReadWriteSeeker.Seek(int64(offset), int(whence))
+	newOffset, err := openFile.ReadWriteSeeker.Seek(offset, int(whence))
 	if err != nil {
 		if openFile.WarnHandler != nil {
 			openFile.WarnHandler("TIFFSeekProcGoCB", fmt.Sprintf("Could not seek to %d with whence %d: %v", offset, whence, err))
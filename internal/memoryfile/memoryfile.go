@@ -0,0 +1,61 @@
+// Package memoryfile implements an io.ReadWriteSeeker in memory, for TIFF
+// files that are built before they are written to a stream.
+package memoryfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// File is an io.ReadWriteSeeker over a growing byte slice.
+type File struct {
+	data     []byte
+	position int64
+}
+
+// Bytes returns the contents of the file.
+func (m *File) Bytes() []byte {
+	return m.data
+}
+
+func (m *File) Read(p []byte) (int, error) {
+	if m.position >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.position:])
+	m.position += int64(n)
+	return n, nil
+}
+
+func (m *File) Write(p []byte) (int, error) {
+	end := m.position + int64(len(p))
+	if end > int64(len(m.data)) {
+		if end > int64(cap(m.data)) {
+			data := make([]byte, end, max(end, 2*int64(cap(m.data))))
+			copy(data, m.data)
+			m.data = data
+		}
+		m.data = m.data[:end]
+	}
+	copy(m.data[m.position:], p)
+	m.position = end
+	return len(p), nil
+}
+
+func (m *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += m.position
+	case io.SeekEnd:
+		offset += int64(len(m.data))
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	m.position = offset
+	return offset, nil
+}
@@ -0,0 +1,80 @@
+package libtiff
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// BigTIFFMode selects the format of a file that is opened for writing.
+type BigTIFFMode int
+
+const (
+	// BigTIFFNo writes a classic TIFF, which is limited to 4 GiB.
+	BigTIFFNo BigTIFFMode = iota
+	// BigTIFFYes writes a BigTIFF, like file mode "w8".
+	BigTIFFYes
+	// BigTIFFAuto writes a BigTIFF when OpenMode.EstimatedSize does not
+	// fit in a classic TIFF.
+	BigTIFFAuto
+)
+
+// bigTIFFAutoThreshold is the estimated size from which BigTIFFAuto writes a
+// BigTIFF. It leaves room below the 4 GiB limit for the directories and
+// metadata, which are not part of the estimate.
+const bigTIFFAutoThreshold = 4<<30 - 256<<20
+
+var bigTIFFModeNames = map[BigTIFFMode]string{
+	BigTIFFNo:   "no",
+	BigTIFFYes:  "yes",
+	BigTIFFAuto: "auto",
+}
+
+// String returns the name of the mode, like "auto".
+func (m BigTIFFMode) String() string {
+	if name, ok := bigTIFFModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("BigTIFFMode(%d)", int(m))
+}
+
+// ParseBigTIFFMode parses "no", "yes" or "auto", case-insensitively. "false"
+// and "true" are accepted as well.
+func ParseBigTIFFMode(value string) (BigTIFFMode, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "no", "false":
+		return BigTIFFNo, nil
+	case "yes", "true":
+		return BigTIFFYes, nil
+	case "auto":
+		return BigTIFFAuto, nil
+	}
+	return BigTIFFNo, fmt.Errorf("unknown BigTIFF mode %q", value)
+}
+
+// EstimateImageSize returns the size in bytes of the uncompressed image data
+// FromGoImage writes for an image with the given size and color model. It
+// can be summed over the pages of a file for OpenMode.EstimatedSize.
+func EstimateImageSize(width, height int, colorModel color.Model) uint64 {
+	bytesPerPixel := uint64(4)
+	switch colorModel {
+	case color.GrayModel:
+		bytesPerPixel = 1
+	case color.Gray16Model:
+		bytesPerPixel = 2
+	case color.RGBA64Model, color.NRGBA64Model:
+		bytesPerPixel = 8
+	default:
+		if _, ok := colorModel.(color.Palette); ok {
+			bytesPerPixel = 1
+		}
+	}
+	return uint64(width) * uint64(height) * bytesPerPixel
+}
+
+// EstimateGoImageSize returns EstimateImageSize for an image.
+func EstimateGoImageSize(img image.Image) uint64 {
+	bounds := img.Bounds()
+	return EstimateImageSize(bounds.Dx(), bounds.Dy(), img.ColorModel())
+}
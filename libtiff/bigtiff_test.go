@@ -0,0 +1,157 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BigTIFF", func() {
+	ctx := context.Background()
+
+	// openForWriting creates a temp file and opens it for writing with the
+	// given mode.
+	openForWriting := func(mode libtiff.OpenMode) (*libtiff.File, *os.File, error) {
+		tmpFile, err := os.CreateTemp("", "libtiff-bigtiff-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, tmpFile.Name())
+
+		mode.Access = libtiff.OpenWrite
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			Mode: &mode,
+		})
+		return tiffFile, tmpFile, err
+	}
+
+	DescribeTable("selects the file format",
+		func(mode libtiff.OpenMode, expectBigTIFF bool) {
+			tiffFile, tmpFile, err := openForWriting(mode)
+			Expect(err).To(BeNil())
+			Expect(tiffFile.FromGoImage(ctx, createTestRGBA(8, 8), nil)).To(Succeed())
+
+			readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+			isBigTIFF, err := readTiff.TIFFIsBigTIFF(ctx)
+			Expect(err).To(BeNil())
+			Expect(isBigTIFF).To(Equal(expectBigTIFF))
+
+			goImage, imgCleanup, err := readTiff.ToGoImage(ctx)
+			Expect(err).To(BeNil())
+			defer imgCleanup(ctx)
+			Expect(goImage.Bounds()).To(Equal(image.Rect(0, 0, 8, 8)))
+		},
+		Entry("classic by default", libtiff.OpenMode{}, false),
+		Entry("BigTIFF when requested", libtiff.OpenMode{BigTIFF: libtiff.BigTIFFYes}, true),
+		Entry("classic for a small estimate", libtiff.OpenMode{
+			BigTIFF:       libtiff.BigTIFFAuto,
+			EstimatedSize: 64,
+		}, false),
+		Entry("BigTIFF for a large estimate", libtiff.OpenMode{
+			BigTIFF:       libtiff.BigTIFFAuto,
+			EstimatedSize: libtiff.EstimateImageSize(40000, 30000, color.RGBAModel),
+		}, true),
+	)
+
+	It("estimates the uncompressed image size", func() {
+		Expect(libtiff.EstimateImageSize(100, 10, color.RGBAModel)).To(Equal(uint64(4000)))
+		Expect(libtiff.EstimateImageSize(100, 10, color.GrayModel)).To(Equal(uint64(1000)))
+		Expect(libtiff.EstimateImageSize(100, 10, color.RGBA64Model)).To(Equal(uint64(8000)))
+		Expect(libtiff.EstimateImageSize(100, 10, color.Palette{color.Black, color.White})).To(Equal(uint64(1000)))
+		Expect(libtiff.EstimateGoImageSize(image.NewGray16(image.Rect(0, 0, 300, 200)))).To(Equal(uint64(120000)))
+	})
+
+	It("parses modes", func() {
+		for _, mode := range []libtiff.BigTIFFMode{libtiff.BigTIFFNo, libtiff.BigTIFFYes, libtiff.BigTIFFAuto} {
+			parsed, err := libtiff.ParseBigTIFFMode(mode.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(mode))
+		}
+
+		_, err := libtiff.ParseBigTIFFMode("maybe")
+		Expect(err).To(MatchError(`unknown BigTIFF mode "maybe"`))
+	})
+
+	Context("a sparse file over 4 GiB", func() {
+		const (
+			size      = 65536
+			tileSize  = 256
+			sparseEnd = 5 << 30
+		)
+
+		firstTile := bytes.Repeat([]byte{0x11}, tileSize*tileSize)
+		lastTile := bytes.Repeat([]byte{0xEE}, tileSize*tileSize)
+
+		// writeSparse writes the first tile of a 64k x 64k gray image, extends
+		// the file past 5 GiB without writing data and writes the last tile
+		// after it, so its data and the directory are past the classic limit.
+		writeSparse := func(tiffFile *libtiff.File, tmpFile *os.File) error {
+			Expect(tiffFile.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGEWIDTH, size)).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGELENGTH, size)).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE, 8)).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_SAMPLESPERPIXEL, 1)).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_PHOTOMETRIC, uint16(libtiff.PHOTOMETRIC_MINISBLACK))).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_TILEWIDTH, tileSize)).To(Succeed())
+			Expect(tiffFile.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_TILELENGTH, tileSize)).To(Succeed())
+
+			lastTileIndex, err := tiffFile.TIFFComputeTile(ctx, size-1, size-1, 0, 0)
+			Expect(err).To(BeNil())
+
+			Expect(tiffFile.TIFFWriteEncodedTile(ctx, 0, firstTile)).To(Succeed())
+			Expect(tmpFile.Truncate(sparseEnd)).To(Succeed())
+			if err := tiffFile.TIFFWriteEncodedTile(ctx, lastTileIndex, lastTile); err != nil {
+				return err
+			}
+			return tiffFile.TIFFWriteDirectory(ctx)
+		}
+
+		It("writes and reopens a BigTIFF", func() {
+			tiffFile, tmpFile, err := openForWriting(libtiff.OpenMode{
+				BigTIFF:       libtiff.BigTIFFAuto,
+				EstimatedSize: libtiff.EstimateImageSize(size, size, color.GrayModel),
+			})
+			Expect(err).To(BeNil())
+			Expect(writeSparse(tiffFile, tmpFile)).To(Succeed())
+
+			readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+			stat, err := os.Stat(tmpFile.Name())
+			Expect(err).To(BeNil())
+			Expect(stat.Size()).To(BeNumerically(">", int64(sparseEnd)))
+
+			isBigTIFF, err := readTiff.TIFFIsBigTIFF(ctx)
+			Expect(err).To(BeNil())
+			Expect(isBigTIFF).To(BeTrue())
+
+			width, height, err := readTiff.GetDimensions(ctx)
+			Expect(err).To(BeNil())
+			Expect(width).To(Equal(size))
+			Expect(height).To(Equal(size))
+
+			lastTileIndex, err := readTiff.TIFFComputeTile(ctx, size-1, size-1, 0, 0)
+			Expect(err).To(BeNil())
+
+			data, err := readTiff.TIFFReadEncodedTile(ctx, 0)
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(firstTile))
+
+			data, err = readTiff.TIFFReadEncodedTile(ctx, lastTileIndex)
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(lastTile))
+		})
+
+		It("can't be written as a classic TIFF", func() {
+			tiffFile, tmpFile, err := openForWriting(libtiff.OpenMode{})
+			Expect(err).To(BeNil())
+			defer tmpFile.Close()
+			defer tiffFile.Close(ctx)
+
+			Expect(writeSparse(tiffFile, tmpFile)).ToNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,340 @@
+package libtiff
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Binarization selects how images are converted to black and white for
+// CCITT compression.
+type Binarization int
+
+const (
+	// BinarizeThreshold makes pixels with a luminance below
+	// BilevelThreshold black.
+	BinarizeThreshold Binarization = iota
+	// BinarizeFloydSteinberg diffuses the error of every pixel to its
+	// neighbours with Floyd–Steinberg dithering, which keeps the shades of
+	// photos.
+	BinarizeFloydSteinberg
+	// BinarizeAtkinson diffuses 3/4 of the error with Atkinson dithering,
+	// which gives more contrast than Floyd–Steinberg.
+	BinarizeAtkinson
+	// BinarizeOrdered dithers with an 8x8 Bayer matrix.
+	BinarizeOrdered
+	// BinarizeOtsu picks the global threshold that best separates the
+	// dark and light pixels of the image.
+	BinarizeOtsu
+	// BinarizeSauvola thresholds every pixel against the mean m and the
+	// standard deviation s of the window around it, with
+	// m * (1 + k * (s/128 - 1)). It handles uneven lighting and keeps
+	// backgrounds clean, which suits scanned documents.
+	BinarizeSauvola
+	// BinarizeNiblack thresholds every pixel against m + k * s of the window
+	// around it.
+	BinarizeNiblack
+)
+
+var binarizationNames = map[Binarization]string{
+	BinarizeThreshold:      "threshold",
+	BinarizeFloydSteinberg: "floyd-steinberg",
+	BinarizeAtkinson:       "atkinson",
+	BinarizeOrdered:        "ordered",
+	BinarizeOtsu:           "otsu",
+	BinarizeSauvola:        "sauvola",
+	BinarizeNiblack:        "niblack",
+}
+
+// String returns the name of the binarization, like "floyd-steinberg".
+func (b Binarization) String() string {
+	if name, ok := binarizationNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Binarization(%d)", int(b))
+}
+
+// ParseBinarization parses "threshold", "floyd-steinberg", "atkinson",
+// "ordered", "otsu", "sauvola" or "niblack", case-insensitively.
+func ParseBinarization(value string) (Binarization, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for binarization, name := range binarizationNames {
+		if name == value {
+			return binarization, nil
+		}
+	}
+	return BinarizeThreshold, fmt.Errorf("unknown binarization %q", value)
+}
+
+// wholeImage returns whether the binarization needs the whole image,
+// instead of only the pixel that is converted.
+func (b Binarization) wholeImage() bool {
+	return b != BinarizeThreshold && b != BinarizeOrdered
+}
+
+const (
+	// defaultBinarizationWindow is the window size of the adaptive
+	// thresholds when none is set.
+	defaultBinarizationWindow = 15
+	defaultSauvolaK           = 0.2
+	defaultNiblackK           = -0.2
+)
+
+// bayerMatrix is the 8x8 ordered dithering matrix.
+var bayerMatrix = [8][8]uint8{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// pixelLuminance returns the luminance of a color as 0-255.
+func pixelLuminance(img image.Image, x, y int) uint8 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
+
+// validateBinarization checks the binarization options.
+func validateBinarization(options *FromGoImageOptions) error {
+	if options == nil {
+		return nil
+	}
+	if _, ok := binarizationNames[options.Binarization]; !ok {
+		return fmt.Errorf("unknown binarization %d", int(options.Binarization))
+	}
+	if options.BinarizationWindow < 0 {
+		return fmt.Errorf("invalid binarization window %d", options.BinarizationWindow)
+	}
+	return nil
+}
+
+// newBilevel returns the function that tells whether the pixel at x, y of
+// img is black for CCITT output.
+func newBilevel(img image.Image, options *FromGoImageOptions) func(x, y int) bool {
+	threshold := bilevelThreshold(options)
+	binarization := BinarizeThreshold
+	if options != nil {
+		binarization = options.Binarization
+	}
+
+	switch binarization {
+	case BinarizeThreshold:
+		return func(x, y int) bool {
+			return pixelLuminance(img, x, y) < threshold
+		}
+	case BinarizeOrdered:
+		return func(x, y int) bool {
+			// Spread the thresholds of the matrix evenly over 0-255.
+			return int(pixelLuminance(img, x, y))*64 < int(bayerMatrix[y&7][x&7])*256+128
+		}
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	lum := imageLuminance(img)
+	black := make([]bool, len(lum))
+
+	switch binarization {
+	case BinarizeFloydSteinberg:
+		diffuseError(lum, black, width, height, threshold, []diffusion{
+			{1, 0, 7}, {-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+		}, 16)
+	case BinarizeAtkinson:
+		diffuseError(lum, black, width, height, threshold, []diffusion{
+			{1, 0, 1}, {2, 0, 1}, {-1, 1, 1}, {0, 1, 1}, {1, 1, 1}, {0, 2, 1},
+		}, 8)
+	case BinarizeOtsu:
+		otsu := otsuThreshold(lum)
+		for i, value := range lum {
+			black[i] = value <= otsu
+		}
+	case BinarizeSauvola, BinarizeNiblack:
+		window, k := defaultBinarizationWindow, defaultSauvolaK
+		if binarization == BinarizeNiblack {
+			k = defaultNiblackK
+		}
+		if options.BinarizationWindow > 0 {
+			window = options.BinarizationWindow
+		}
+		if options.BinarizationK != 0 {
+			k = options.BinarizationK
+		}
+		adaptiveThreshold(lum, black, width, height, window/2, func(mean, deviation float64) float64 {
+			if binarization == BinarizeSauvola {
+				return mean * (1 + k*(deviation/128-1))
+			}
+			return mean + k*deviation
+		})
+	}
+
+	return func(x, y int) bool {
+		return black[(y-bounds.Min.Y)*width+x-bounds.Min.X]
+	}
+}
+
+// imageLuminance returns the luminance of all pixels of img, row by row.
+func imageLuminance(img image.Image) []uint8 {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	lum := make([]uint8, width*bounds.Dy())
+	if gray, ok := img.(*image.Gray); ok {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			start := gray.PixOffset(bounds.Min.X, y)
+			copy(lum[(y-bounds.Min.Y)*width:], gray.Pix[start:start+width])
+		}
+		return lum
+	}
+
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			lum[i] = pixelLuminance(img, x, y)
+			i++
+		}
+	}
+	return lum
+}
+
+// diffusion is the share of the error of a pixel that goes to the pixel at
+// dx, dy from it.
+type diffusion struct {
+	dx, dy, weight int
+}
+
+// diffuseError dithers lum into black, passing weight/divisor of the error
+// of every pixel on to its neighbours.
+func diffuseError(lum []uint8, black []bool, width, height int, threshold uint8, diffusions []diffusion, divisor int) {
+	rows := 1
+	for _, d := range diffusions {
+		rows = max(rows, d.dy+1)
+	}
+	// errors holds the error of the current row and the rows below it,
+	// with room for diffusion past the left and right edges.
+	errors := make([][]int, rows)
+	for i := range errors {
+		errors[i] = make([]int, width+4)
+	}
+
+	for y := 0; y < height; y++ {
+		current := errors[0]
+		for x := 0; x < width; x++ {
+			value := int(lum[y*width+x]) + current[x+2]/divisor
+			output := 255
+			if value < int(threshold) {
+				output = 0
+				black[y*width+x] = true
+			}
+			quantError := value - output
+			for _, d := range diffusions {
+				errors[d.dy][x+2+d.dx] += quantError * d.weight
+			}
+		}
+
+		// Move to the next row.
+		copy(errors, errors[1:])
+		clear(current)
+		errors[rows-1] = current
+	}
+}
+
+// otsuThreshold returns the threshold that maximizes the variance between
+// the pixels up to and above it.
+func otsuThreshold(lum []uint8) uint8 {
+	var histogram [256]int
+	for _, value := range lum {
+		histogram[value]++
+	}
+
+	total := len(lum)
+	var sum float64
+	for value, count := range histogram {
+		sum += float64(value * count)
+	}
+
+	var best uint8
+	var bestVariance, sumBelow float64
+	below := 0
+	for value, count := range histogram {
+		below += count
+		if below == 0 {
+			continue
+		}
+		above := total - below
+		if above == 0 {
+			break
+		}
+		sumBelow += float64(value * count)
+		meanBelow := sumBelow / float64(below)
+		meanAbove := (sum - sumBelow) / float64(above)
+		variance := float64(below) * float64(above) * (meanBelow - meanAbove) * (meanBelow - meanAbove)
+		if variance > bestVariance {
+			bestVariance = variance
+			best = uint8(value)
+		}
+	}
+	return best
+}
+
+// adaptiveThreshold makes the pixels of lum that are below the threshold of
+// the window of radius around them black. The window is clipped at the
+// edges of the image. Only the column sums of the rows in the window are
+// kept, so it needs little memory for large images.
+func adaptiveThreshold(lum []uint8, black []bool, width, height, radius int, threshold func(mean, deviation float64) float64) {
+	columnSums := make([]uint64, width)
+	columnSquares := make([]uint64, width)
+	addRow := func(y int, sign int) {
+		row := lum[y*width : (y+1)*width]
+		for x, value := range row {
+			v := uint64(value)
+			if sign > 0 {
+				columnSums[x] += v
+				columnSquares[x] += v * v
+			} else {
+				columnSums[x] -= v
+				columnSquares[x] -= v * v
+			}
+		}
+	}
+
+	for y := 0; y < min(radius, height-1)+1; y++ {
+		addRow(y, 1)
+	}
+	for y := 0; y < height; y++ {
+		if y > 0 {
+			if bottom := y + radius; bottom < height {
+				addRow(bottom, 1)
+			}
+			if top := y - radius - 1; top >= 0 {
+				addRow(top, -1)
+			}
+		}
+		rows := min(y+radius, height-1) - max(y-radius, 0) + 1
+
+		var sum, squares uint64
+		for x := 0; x < min(radius, width-1)+1; x++ {
+			sum += columnSums[x]
+			squares += columnSquares[x]
+		}
+		for x := 0; x < width; x++ {
+			if x > 0 {
+				if right := x + radius; right < width {
+					sum += columnSums[right]
+					squares += columnSquares[right]
+				}
+				if left := x - radius - 1; left >= 0 {
+					sum -= columnSums[left]
+					squares -= columnSquares[left]
+				}
+			}
+			count := float64(rows * (min(x+radius, width-1) - max(x-radius, 0) + 1))
+			mean := float64(sum) / count
+			deviation := math.Sqrt(max(float64(squares)/count-mean*mean, 0))
+			black[y*width+x] = float64(lum[y*width+x]) < threshold(mean, deviation)
+		}
+	}
+}
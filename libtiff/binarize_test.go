@@ -0,0 +1,196 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// createUniformGray returns an image of a single gray level.
+func createUniformGray(width, height int, level uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	return img
+}
+
+// createUnevenScan returns a page that gets lighter from left to right, with
+// a 2 pixel wide stroke every 8 pixels that is 60 levels darker than the
+// page around it.
+func createUnevenScan(width, height int) (*image.Gray, func(x int) bool) {
+	isStroke := func(x int) bool {
+		return x%8 < 2
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			level := 80 + 160*x/(width-1)
+			if isStroke(x) {
+				level -= 60
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(level)})
+		}
+	}
+	return img, isStroke
+}
+
+var _ = Describe("Binarization", func() {
+	ctx := context.Background()
+
+	// writeBilevel writes img with CCITT group 4 compression and returns
+	// which pixels were written black.
+	writeBilevel := func(img image.Image, options *libtiff.FromGoImageOptions) [][]bool {
+		options.Compression = libtiff.COMPRESSION_CCITTFAX4
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+		goImage, imgCleanup, err := readTiff.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		bounds := goImage.Bounds()
+		black := make([][]bool, bounds.Dy())
+		for y := range black {
+			black[y] = make([]bool, bounds.Dx())
+			for x := range black[y] {
+				black[y][x] = color.GrayModel.Convert(goImage.At(x, y)).(color.Gray).Y < 128
+			}
+		}
+		return black
+	}
+
+	blackFraction := func(black [][]bool) float64 {
+		count, total := 0, 0
+		for _, row := range black {
+			for _, isBlack := range row {
+				if isBlack {
+					count++
+				}
+				total++
+			}
+		}
+		return float64(count) / float64(total)
+	}
+
+	DescribeTable("keeps the shade of gray levels",
+		func(binarization libtiff.Binarization, expected, tolerance float64) {
+			black := writeBilevel(createUniformGray(64, 64, 96), &libtiff.FromGoImageOptions{
+				Binarization: binarization,
+			})
+			Expect(blackFraction(black)).To(BeNumerically("~", expected, tolerance))
+		},
+		Entry("not with a threshold", libtiff.BinarizeThreshold, 1.0, 0.0),
+		Entry("with Floyd–Steinberg dithering", libtiff.BinarizeFloydSteinberg, 1-96.0/255, 0.02),
+		Entry("with Atkinson dithering", libtiff.BinarizeAtkinson, 1-96.0/255, 0.1),
+		Entry("with ordered dithering", libtiff.BinarizeOrdered, 1-96.0/255, 0.02),
+	)
+
+	It("dithers in tiles like in strips", func() {
+		img, _ := createUnevenScan(50, 40)
+		options := &libtiff.FromGoImageOptions{Binarization: libtiff.BinarizeFloydSteinberg}
+		strips := writeBilevel(img, options)
+		options.TileWidth = 16
+		options.TileHeight = 16
+		Expect(writeBilevel(img, options)).To(Equal(strips))
+	})
+
+	It("picks the threshold with Otsu's method", func() {
+		img := createUniformGray(40, 10, 200)
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 15; x++ {
+				img.SetGray(x, y, color.Gray{Y: 60})
+			}
+		}
+
+		// The threshold is ignored.
+		black := writeBilevel(img, &libtiff.FromGoImageOptions{
+			Binarization:     libtiff.BinarizeOtsu,
+			BilevelThreshold: 50,
+		})
+		for _, row := range black {
+			for x, isBlack := range row {
+				Expect(isBlack).To(Equal(x < 15), "x %d", x)
+			}
+		}
+	})
+
+	DescribeTable("separates the strokes from an unevenly lit page",
+		func(binarization libtiff.Binarization) {
+			img, isStroke := createUnevenScan(96, 32)
+
+			// A global threshold turns the dark side of the page black.
+			black := writeBilevel(img, &libtiff.FromGoImageOptions{})
+			Expect(black[16][2*8+4]).To(BeTrue())
+			Expect(black[16][11*8+4]).To(BeFalse())
+
+			black = writeBilevel(img, &libtiff.FromGoImageOptions{
+				Binarization: binarization,
+			})
+			for y, row := range black {
+				// The edges of the page have no strokes on one side.
+				for x := 8; x < 88; x++ {
+					Expect(row[x]).To(Equal(isStroke(x)), "pixel %d, %d", x, y)
+				}
+			}
+		},
+		Entry("with Sauvola", libtiff.BinarizeSauvola),
+		Entry("with Niblack", libtiff.BinarizeNiblack),
+	)
+
+	It("uses the options of the adaptive thresholds", func() {
+		img, _ := createUnevenScan(64, 16)
+		black := writeBilevel(img, &libtiff.FromGoImageOptions{
+			Binarization:       libtiff.BinarizeSauvola,
+			BinarizationWindow: 31,
+			BinarizationK:      0.9,
+		})
+		// A large k pushes the threshold below the strokes.
+		Expect(blackFraction(black)).To(BeNumerically("<", 0.1))
+	})
+
+	It("returns an error for invalid options", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		err := tiffFile.FromGoImage(ctx, createTestGray(8, 8), &libtiff.FromGoImageOptions{
+			Compression:  libtiff.COMPRESSION_CCITTFAX4,
+			Binarization: libtiff.Binarization(42),
+		})
+		Expect(err).To(MatchError("unknown binarization 42"))
+
+		err = tiffFile.FromGoImage(ctx, createTestGray(8, 8), &libtiff.FromGoImageOptions{
+			Compression:        libtiff.COMPRESSION_CCITTFAX4,
+			Binarization:       libtiff.BinarizeSauvola,
+			BinarizationWindow: -1,
+		})
+		Expect(err).To(MatchError("invalid binarization window -1"))
+
+		_, err = tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 8, Height: 8, Options: &libtiff.FromGoImageOptions{
+			Compression:  libtiff.COMPRESSION_CCITTFAX4,
+			Binarization: libtiff.BinarizeAtkinson,
+		}})
+		Expect(err).To(MatchError("binarization atkinson needs the whole image and is not supported by the strip writer"))
+	})
+
+	It("parses binarization names", func() {
+		for _, binarization := range []libtiff.Binarization{
+			libtiff.BinarizeThreshold, libtiff.BinarizeFloydSteinberg, libtiff.BinarizeAtkinson,
+			libtiff.BinarizeOrdered, libtiff.BinarizeOtsu, libtiff.BinarizeSauvola, libtiff.BinarizeNiblack,
+		} {
+			parsed, err := libtiff.ParseBinarization(binarization.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(binarization))
+		}
+
+		_, err := libtiff.ParseBinarization("bradley")
+		Expect(err).To(MatchError(`unknown binarization "bradley"`))
+	})
+})
@@ -0,0 +1,483 @@
+package libtiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/klippa-app/go-libtiff/internal/memoryfile"
+)
+
+// cogTileSize is the tile size of COG output when no tile size is set.
+const cogTileSize = 512
+
+// writeCOG writes img with its overviews to a file in memory, and writes
+// that file to f in the Cloud Optimized GeoTIFF layout.
+func (f *File) writeCOG(ctx context.Context, img image.Image, options *FromGoImageOptions) error {
+	if f.readerFile == nil || f.readerFile.ReadWriteSeeker == nil {
+		return errors.New("COG output requires a file opened with TIFFOpenFileFromReadWriteSeeker")
+	}
+	directories, err := f.TIFFNumberOfDirectories(ctx)
+	if err != nil {
+		return err
+	}
+	if directories != 0 {
+		return errors.New("COG output must be the only image in a new file")
+	}
+
+	cogOptions := *options
+	cogOptions.COG = false
+	if cogOptions.TileWidth == 0 && cogOptions.TileHeight == 0 {
+		cogOptions.TileWidth = cogTileSize
+		cogOptions.TileHeight = cogTileSize
+	}
+	overviews := Overviews{}
+	if options.Overviews != nil {
+		overviews = *options.Overviews
+	}
+	// COG readers find the overviews in the main IFD chain.
+	overviews.SubIFDs = false
+	cogOptions.Overviews = &overviews
+
+	bigEndian, err := f.TIFFIsBigEndian(ctx)
+	if err != nil {
+		return err
+	}
+	bigTIFF, err := f.TIFFIsBigTIFF(ctx)
+	if err != nil {
+		return err
+	}
+	mode := &OpenMode{Access: OpenWrite, ByteOrder: ByteOrderLittleEndian}
+	if bigEndian {
+		mode.ByteOrder = ByteOrderBigEndian
+	}
+	if bigTIFF {
+		mode.BigTIFF = BigTIFFYes
+	}
+
+	// libtiff writes every directory after its image data, so the image is
+	// written to memory first and then reordered.
+	memory := &memoryfile.File{}
+	memoryTiff, err := f.instance.TIFFOpenFileFromReadWriteSeeker(ctx, "cog.tif", memory, 0, &OpenOptions{Mode: mode})
+	if err != nil {
+		return err
+	}
+	if err := memoryTiff.writeGoImageWithOverviews(ctx, img, &cogOptions); err != nil {
+		memoryTiff.Close(ctx)
+		return err
+	}
+	if err := memoryTiff.Close(ctx); err != nil {
+		return err
+	}
+
+	data, err := cogLayout(memory.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write COG layout: %w", err)
+	}
+
+	writer := f.readerFile.ReadWriteSeeker
+	if _, err := writer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("could not write COG: %w", err)
+	}
+	return nil
+}
+
+// cogGhostArea is the structural metadata GDAL writes after the header of
+// a COG, so readers know the layout without reading the file. The size is
+// filled in by cogLayout.
+const cogGhostArea = "LAYOUT=IFDS_BEFORE_DATA\nBLOCK_ORDER=ROW_MAJOR\nKNOWN_INCOMPATIBLE_EDITION=NO\n"
+
+// cogEntry is a directory entry with its value, which is stored inline or
+// out of line depending on its size.
+type cogEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint64
+	value []byte
+	// known is false for unknown data types, their value field is copied
+	// as-is.
+	known bool
+}
+
+// cogDirectory is a directory that is moved to a new offset.
+type cogDirectory struct {
+	entries []cogEntry
+	// subDirectories are the EXIF, GPS and Interoperability directories,
+	// by the index of the entry that points to them.
+	subDirectories map[int]*cogDirectory
+	offset         uint64
+	// valueOffsets are the new offsets of the out of line values, by entry
+	// index.
+	valueOffsets map[int]uint64
+}
+
+// cogLayouter moves the directories and image data of a TIFF file into
+// the COG layout.
+type cogLayouter struct {
+	d        *describer
+	bigTIFF  bool
+	order    binary.ByteOrder
+	position uint64
+}
+
+// cogLayout returns the TIFF file in data in the COG layout: the header,
+// the ghost area, all directories with their values in chain order, and
+// then the image data of the directories in reverse chain order, so the
+// smallest overview comes first.
+func cogLayout(data []byte) ([]byte, error) {
+	d, err := newDescriber(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	offset, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	l := &cogLayouter{d: d, bigTIFF: d.bigTIFF, order: d.order}
+
+	var chain []*cogDirectory
+	for offset != 0 {
+		directory, next, err := l.readDirectory(offset, "image")
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, directory)
+		offset = next
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("file has no directories")
+	}
+
+	headerSize := uint64(8)
+	if l.bigTIFF {
+		headerSize = 16
+	}
+	ghost := fmt.Sprintf("GDAL_STRUCTURAL_METADATA_SIZE=%06d bytes\n%s", len(cogGhostArea), cogGhostArea)
+
+	l.position = headerSize + uint64(len(ghost))
+	for _, directory := range chain {
+		l.placeDirectory(directory)
+	}
+
+	// The image data goes after the directories, smallest overview first.
+	dataOffset := l.position
+	var blocks [][]byte
+	for i := len(chain) - 1; i >= 0; i-- {
+		directory := chain[i]
+		offsetsIndex, byteCountsIndex := -1, -1
+		for j, entry := range directory.entries {
+			switch TIFFTAG(entry.tag) {
+			case TIFFTAG_TILEOFFSETS, TIFFTAG_STRIPOFFSETS:
+				offsetsIndex = j
+			case TIFFTAG_TILEBYTECOUNTS, TIFFTAG_STRIPBYTECOUNTS:
+				byteCountsIndex = j
+			case TIFFTAG_SUBIFD:
+				return nil, errors.New("SubIFDs are not supported in the COG layout")
+			}
+		}
+		if offsetsIndex < 0 || byteCountsIndex < 0 {
+			return nil, fmt.Errorf("directory %d has no image data", i)
+		}
+
+		offsets := l.decodeUints(directory.entries[offsetsIndex])
+		byteCounts := l.decodeUints(directory.entries[byteCountsIndex])
+		if len(offsets) != len(byteCounts) {
+			return nil, fmt.Errorf("directory %d has %d offsets and %d byte counts", i, len(offsets), len(byteCounts))
+		}
+		newOffsets := make([]uint64, len(offsets))
+		for j := range offsets {
+			// Blocks that were never written keep offset 0.
+			if byteCounts[j] == 0 {
+				continue
+			}
+			if offsets[j] > uint64(len(data)) || byteCounts[j] > uint64(len(data))-offsets[j] {
+				return nil, fmt.Errorf("block %d of directory %d is outside of the file", j, i)
+			}
+			newOffsets[j] = l.position
+			blocks = append(blocks, data[offsets[j]:offsets[j]+byteCounts[j]])
+			l.position += byteCounts[j]
+		}
+		if err := l.encodeUints(&directory.entries[offsetsIndex], newOffsets); err != nil {
+			return nil, err
+		}
+	}
+	if !l.bigTIFF && l.position > math.MaxUint32 {
+		return nil, errors.New("the file is too large for classic TIFF, use BigTIFF")
+	}
+
+	out := make([]byte, l.position)
+	copy(out, data[:4])
+	if l.bigTIFF {
+		copy(out[4:], data[4:8])
+		l.order.PutUint64(out[8:], chain[0].offset)
+	} else {
+		l.order.PutUint32(out[4:], uint32(chain[0].offset))
+	}
+	copy(out[headerSize:], ghost)
+
+	for i, directory := range chain {
+		var next uint64
+		if i+1 < len(chain) {
+			next = chain[i+1].offset
+		}
+		if err := l.writeDirectory(out, directory, next); err != nil {
+			return nil, err
+		}
+	}
+
+	position := dataOffset
+	for _, block := range blocks {
+		copy(out[position:], block)
+		position += uint64(len(block))
+	}
+	return out, nil
+}
+
+// readDirectory reads the directory at the given offset with its EXIF, GPS
+// and Interoperability directories, and returns the offset of the next
+// directory.
+func (l *cogLayouter) readDirectory(offset uint64, kind string) (*cogDirectory, uint64, error) {
+	d := l.d
+	if d.visited[offset] {
+		return nil, 0, fmt.Errorf("directory loop at offset %d", offset)
+	}
+	if len(d.visited) >= maxDescribeDirectories {
+		return nil, 0, errors.New("too many directories")
+	}
+	d.visited[offset] = true
+
+	countSize, entrySize, offsetSize := l.sizes()
+	data, err := d.readAt(offset, countSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := uint64(l.order.Uint16(data))
+	if l.bigTIFF {
+		count = l.order.Uint64(data)
+	}
+	if count > math.MaxUint16 {
+		return nil, 0, fmt.Errorf("invalid directory entry count %d at offset %d", count, offset)
+	}
+	data, err = d.readAt(offset+countSize, count*entrySize+offsetSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	directory := &cogDirectory{
+		subDirectories: map[int]*cogDirectory{},
+		valueOffsets:   map[int]uint64{},
+	}
+	for i := uint64(0); i < count; i++ {
+		raw := data[i*entrySize : (i+1)*entrySize]
+		entry := cogEntry{
+			tag: l.order.Uint16(raw),
+			typ: l.order.Uint16(raw[2:]),
+		}
+		valueField := raw[8:12]
+		if l.bigTIFF {
+			entry.count = l.order.Uint64(raw[4:])
+			valueField = raw[12:20]
+		} else {
+			entry.count = uint64(l.order.Uint32(raw[4:]))
+		}
+
+		dataType, ok := describeTypes[entry.typ]
+		if !ok {
+			entry.value = append([]byte(nil), valueField...)
+		} else {
+			entry.known = true
+			if entry.count > d.size/dataType.size {
+				return nil, 0, fmt.Errorf("invalid count %d of tag %d", entry.count, entry.tag)
+			}
+			size := dataType.size * entry.count
+			if size <= uint64(len(valueField)) {
+				entry.value = append([]byte(nil), valueField[:size]...)
+			} else {
+				entry.value, err = d.readAt(l.readOffset(valueField), size)
+				if err != nil {
+					return nil, 0, fmt.Errorf("could not read value of tag %d: %w", entry.tag, err)
+				}
+			}
+		}
+		directory.entries = append(directory.entries, entry)
+	}
+
+	for i, entry := range directory.entries {
+		var subKind string
+		switch {
+		case kind == "image" && TIFFTAG(entry.tag) == TIFFTAG_EXIFIFD:
+			subKind = "exif"
+		case kind == "image" && TIFFTAG(entry.tag) == TIFFTAG_GPSIFD:
+			subKind = "gps"
+		case kind == "exif" && TIFFTAG(entry.tag) == TIFFTAG_INTEROPERABILITYIFD:
+			subKind = "interoperability"
+		}
+		if subKind == "" || !entry.known || entry.count != 1 {
+			continue
+		}
+		subOffset := l.decodeUints(entry)[0]
+		if subOffset == 0 {
+			continue
+		}
+		subDirectory, _, err := l.readDirectory(subOffset, subKind)
+		if err != nil {
+			return nil, 0, err
+		}
+		directory.subDirectories[i] = subDirectory
+	}
+
+	return directory, l.readOffset(data[count*entrySize:]), nil
+}
+
+// sizes returns the size of the entry count, an entry and an offset.
+func (l *cogLayouter) sizes() (uint64, uint64, uint64) {
+	if l.bigTIFF {
+		return 8, 20, 8
+	}
+	return 2, 12, 4
+}
+
+func (l *cogLayouter) readOffset(data []byte) uint64 {
+	if l.bigTIFF {
+		return l.order.Uint64(data)
+	}
+	return uint64(l.order.Uint32(data))
+}
+
+func (l *cogLayouter) putOffset(data []byte, offset uint64) {
+	if l.bigTIFF {
+		l.order.PutUint64(data, offset)
+		return
+	}
+	l.order.PutUint32(data, uint32(offset))
+}
+
+// placeDirectory sets the new offsets of the directory, its out of line
+// values and its sub directories, starting at the current position.
+func (l *cogLayouter) placeDirectory(directory *cogDirectory) {
+	countSize, entrySize, offsetSize := l.sizes()
+	// Directories and values start on a word boundary.
+	l.position += l.position % 2
+	directory.offset = l.position
+	l.position += countSize + uint64(len(directory.entries))*entrySize + offsetSize
+
+	for i, entry := range directory.entries {
+		if entry.known && uint64(len(entry.value)) > offsetSize {
+			l.position += l.position % 2
+			directory.valueOffsets[i] = l.position
+			l.position += uint64(len(entry.value))
+		}
+	}
+	for i := range directory.entries {
+		if subDirectory, ok := directory.subDirectories[i]; ok {
+			l.placeDirectory(subDirectory)
+		}
+	}
+}
+
+// writeDirectory writes the directory, its values and its sub directories
+// to out at their new offsets.
+func (l *cogLayouter) writeDirectory(out []byte, directory *cogDirectory, next uint64) error {
+	countSize, entrySize, offsetSize := l.sizes()
+	position := directory.offset
+	if l.bigTIFF {
+		l.order.PutUint64(out[position:], uint64(len(directory.entries)))
+	} else {
+		l.order.PutUint16(out[position:], uint16(len(directory.entries)))
+	}
+	position += countSize
+
+	for i, entry := range directory.entries {
+		if subDirectory, ok := directory.subDirectories[i]; ok {
+			if err := l.encodeUints(&entry, []uint64{subDirectory.offset}); err != nil {
+				return err
+			}
+		}
+
+		raw := out[position : position+entrySize]
+		l.order.PutUint16(raw, entry.tag)
+		l.order.PutUint16(raw[2:], entry.typ)
+		valueField := raw[8:12]
+		if l.bigTIFF {
+			l.order.PutUint64(raw[4:], entry.count)
+			valueField = raw[12:20]
+		} else {
+			l.order.PutUint32(raw[4:], uint32(entry.count))
+		}
+
+		if valueOffset, ok := directory.valueOffsets[i]; ok {
+			l.putOffset(valueField, valueOffset)
+			copy(out[valueOffset:], entry.value)
+		} else {
+			copy(valueField, entry.value)
+		}
+		position += entrySize
+	}
+	l.putOffset(out[position:position+offsetSize], next)
+
+	for i := range directory.entries {
+		if subDirectory, ok := directory.subDirectories[i]; ok {
+			if err := l.writeDirectory(out, subDirectory, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeUints returns the values of an entry of an unsigned integer type.
+func (l *cogLayouter) decodeUints(entry cogEntry) []uint64 {
+	values := make([]uint64, 0, entry.count)
+	switch entry.typ {
+	case 3:
+		for i := 0; i+2 <= len(entry.value); i += 2 {
+			values = append(values, uint64(l.order.Uint16(entry.value[i:])))
+		}
+	case 4, 13:
+		for i := 0; i+4 <= len(entry.value); i += 4 {
+			values = append(values, uint64(l.order.Uint32(entry.value[i:])))
+		}
+	case 16, 18:
+		for i := 0; i+8 <= len(entry.value); i += 8 {
+			values = append(values, l.order.Uint64(entry.value[i:]))
+		}
+	}
+	return values
+}
+
+// encodeUints replaces the values of an entry of an unsigned integer type,
+// keeping its type.
+func (l *cogLayouter) encodeUints(entry *cogEntry, values []uint64) error {
+	if uint64(len(values)) != entry.count {
+		return fmt.Errorf("tag %d has %d values, not %d", entry.tag, entry.count, len(values))
+	}
+	value := make([]byte, len(entry.value))
+	for i, v := range values {
+		switch entry.typ {
+		case 3:
+			if v > math.MaxUint16 {
+				return fmt.Errorf("offset %d does not fit in tag %d", v, entry.tag)
+			}
+			l.order.PutUint16(value[i*2:], uint16(v))
+		case 4, 13:
+			if v > math.MaxUint32 {
+				return fmt.Errorf("offset %d does not fit in tag %d", v, entry.tag)
+			}
+			l.order.PutUint32(value[i*4:], uint32(v))
+		case 16, 18:
+			l.order.PutUint64(value[i*8:], v)
+		default:
+			return fmt.Errorf("tag %d has type %d, which can't hold an offset", entry.tag, entry.typ)
+		}
+	}
+	entry.value = value
+	return nil
+}
@@ -0,0 +1,144 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("COG", func() {
+	ctx := context.Background()
+
+	// writeCOG writes img as a COG in a file opened with the mode, and
+	// returns the reopened file and its contents.
+	writeCOG := func(img image.Image, mode libtiff.OpenMode, options *libtiff.FromGoImageOptions) (*libtiff.File, []byte) {
+		tmpFile, err := os.CreateTemp("", "libtiff-cog-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, tmpFile.Name())
+
+		mode.Access = libtiff.OpenWrite
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			Mode: &mode,
+		})
+		Expect(err).To(BeNil())
+
+		options.COG = true
+		Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+		data, err := os.ReadFile(tmpFile.Name())
+		Expect(err).To(BeNil())
+		return readTiff, data
+	}
+
+	// expectCOGLayout checks that all directories come before the tile data
+	// and that the tile data of the smallest level comes first.
+	expectCOGLayout := func(tiffFile *libtiff.File, levels int) {
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories).To(HaveLen(levels))
+
+		var lastDirectory uint64
+		firstTile := make([]uint64, levels)
+		for i, directory := range info.Directories {
+			Expect(directory.Offset).To(BeNumerically(">", lastDirectory))
+			lastDirectory = directory.Offset
+			if directory.Exif != nil {
+				lastDirectory = max(lastDirectory, directory.Exif.Offset)
+			}
+
+			Expect(directory.Image.Tiled).To(BeTrue())
+			firstTile[i] = directory.Image.Offsets[0]
+		}
+		for i := range firstTile {
+			Expect(firstTile[i]).To(BeNumerically(">", lastDirectory))
+			if i > 0 {
+				Expect(firstTile[i]).To(BeNumerically("<", firstTile[i-1]))
+			}
+		}
+	}
+
+	It("writes the directories before the tile data", func() {
+		img := createTestRGBA(1000, 700)
+		tiffFile, data := writeCOG(img, libtiff.OpenMode{}, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			TileWidth:   256,
+			TileHeight:  256,
+			Exif:        &libtiff.Exif{FNumber: 2.8},
+		})
+
+		Expect(string(data[:4])).To(Equal("II*\x00"))
+		Expect(string(data[8 : 8+119])).To(Equal("GDAL_STRUCTURAL_METADATA_SIZE=000076 bytes\n" +
+			"LAYOUT=IFDS_BEFORE_DATA\nBLOCK_ORDER=ROW_MAJOR\nKNOWN_INCOMPATIBLE_EDITION=NO\n"))
+		expectCOGLayout(tiffFile, 3)
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		Expect(goImage.Bounds()).To(Equal(img.Bounds()))
+		for _, point := range []image.Point{{0, 0}, {300, 200}, {999, 699}} {
+			Expect(color.RGBAModel.Convert(goImage.At(point.X, point.Y))).To(Equal(img.At(point.X, point.Y)))
+		}
+		imgCleanup(ctx)
+
+		offset, err := tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD)
+		Expect(err).To(BeNil())
+		Expect(tiffFile.TIFFReadEXIFDirectory(ctx, offset)).To(Succeed())
+		fNumber, err := tiffFile.TIFFGetFieldFloat(ctx, libtiff.EXIFTAG_FNUMBER)
+		Expect(err).To(BeNil())
+		Expect(fNumber).To(Equal(float32(2.8)))
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 2)).To(Succeed())
+		width, height, err := tiffFile.GetDimensions(ctx)
+		Expect(err).To(BeNil())
+		Expect(image.Pt(width, height)).To(Equal(image.Pt(250, 175)))
+		subfileType, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_SUBFILETYPE)
+		Expect(err).To(BeNil())
+		Expect(libtiff.SubfileType(subfileType)).To(Equal(libtiff.FILETYPE_REDUCEDIMAGE))
+	})
+
+	It("writes big-endian BigTIFF", func() {
+		img := createTestGray(300, 100)
+		tiffFile, data := writeCOG(img, libtiff.OpenMode{
+			ByteOrder: libtiff.ByteOrderBigEndian,
+			BigTIFF:   libtiff.BigTIFFYes,
+		}, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Overviews:   &libtiff.Overviews{Factors: []int{2, 4}, SubIFDs: true},
+		})
+
+		Expect(string(data[:4])).To(Equal("MM\x00+"))
+		Expect(strings.HasPrefix(string(data[16:]), "GDAL_STRUCTURAL_METADATA_SIZE=")).To(BeTrue())
+		expectCOGLayout(tiffFile, 3)
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		width, height, err := tiffFile.GetDimensions(ctx)
+		Expect(err).To(BeNil())
+		Expect(image.Pt(width, height)).To(Equal(image.Pt(300, 100)))
+		tileWidth, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_TILEWIDTH)
+		Expect(err).To(BeNil())
+		Expect(tileWidth).To(Equal(uint32(512)))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(color.GrayModel.Convert(goImage.At(123, 45))).To(Equal(img.At(123, 45)))
+	})
+
+	It("returns an error when the file already has images", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		Expect(tiffFile.FromGoImage(ctx, createTestGray(8, 8), nil)).To(Succeed())
+		err := tiffFile.FromGoImage(ctx, createTestGray(8, 8), &libtiff.FromGoImageOptions{COG: true})
+		Expect(err).To(MatchError("COG output must be the only image in a new file"))
+	})
+})
@@ -0,0 +1,245 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/klippa-app/go-libtiff/internal/icc"
+)
+
+// ColorManagementOptions configures the conversion to sRGB.
+type ColorManagementOptions struct {
+	// ICCProfile is used instead of the embedded ICC profile of the
+	// directory, for files without a profile or with a wrong one.
+	ICCProfile []byte
+}
+
+// ToSRGBGoImage converts the current directory to RGBA like ToGoImage, but
+// converts the colours from the ICC profile of the directory to sRGB.
+// Matrix/TRC RGB profiles, TRC gray profiles and LUT-based RGB, gray and
+// CMYK profiles are supported. CMYK images are read from their raw samples,
+// which must be contiguous 8 or 16-bit CMYK, and are returned opaque.
+// When there is no profile the image is returned as-is from ToGoImage. The
+// caller is responsible for calling the returned cleanup function.
+func (f *File) ToSRGBGoImage(ctx context.Context, options *ColorManagementOptions) (image.Image, func(context.Context) error, error) {
+	var profileData []byte
+	if options != nil {
+		profileData = options.ICCProfile
+	}
+	if len(profileData) == 0 {
+		var err error
+		profileData, err = f.ICCProfile(ctx)
+		if err != nil {
+			if _, ok := err.(*TagNotDefinedError); ok {
+				return f.ToGoImage(ctx)
+			}
+			return nil, nil, err
+		}
+	}
+
+	profile, err := icc.Parse(profileData)
+	if err != nil {
+		return nil, nil, err
+	}
+	transform := icc.NewTransform(profile)
+
+	photometric, err := f.GetPhotometric(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matches := false
+	switch profile.ColorSpace {
+	case icc.ColorSpaceCMYK:
+		matches = photometric == PHOTOMETRIC_SEPARATED
+	case icc.ColorSpaceRGB:
+		matches = photometric == PHOTOMETRIC_RGB || photometric == PHOTOMETRIC_YCBCR || photometric == PHOTOMETRIC_PALETTE
+	case icc.ColorSpaceGray:
+		matches = photometric == PHOTOMETRIC_MINISBLACK || photometric == PHOTOMETRIC_MINISWHITE
+	}
+	if !matches {
+		return nil, nil, fmt.Errorf("ICC profile colour space %q does not match photometric interpretation %s", string(profile.ColorSpace), photometric)
+	}
+
+	if profile.ColorSpace == icc.ColorSpaceCMYK {
+		img, err := f.readCMYKToSRGB(ctx, transform)
+		if err != nil {
+			return nil, nil, err
+		}
+		return img, func(context.Context) error { return nil }, nil
+	}
+
+	rgba, cleanup, err := f.toRGBAGoImage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		pixel := rgba.Pix[i : i+4 : i+4]
+		alpha := uint32(pixel[3])
+		if alpha == 0 {
+			continue
+		}
+
+		// The colour transform works on straight, not premultiplied, colours.
+		var samples [3]uint8
+		for c := range samples {
+			samples[c] = uint8(min(uint32(pixel[c])*255/alpha, 255))
+		}
+
+		var rgb [3]uint8
+		if profile.ColorSpace == icc.ColorSpaceGray {
+			rgb = transform.Convert8(samples[:1])
+		} else {
+			rgb = transform.Convert8(samples[:])
+		}
+		for c := range rgb {
+			pixel[c] = uint8(uint32(rgb[c]) * alpha / 255)
+		}
+	}
+
+	return rgba, cleanup, nil
+}
+
+// readCMYKToSRGB reads the raw CMYK samples of the current directory and
+// converts them to sRGB, flipping the image like TIFFReadRGBAImageOriented.
+func (f *File) readCMYKToSRGB(ctx context.Context, transform *icc.Transform) (*image.RGBA, error) {
+	width, height, err := f.GetDimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bitsPerSample, err := f.getUint16WithDefault(ctx, TIFFTAG_BITSPERSAMPLE, 1)
+	if err != nil {
+		return nil, err
+	}
+	samplesPerPixel, err := f.getUint16WithDefault(ctx, TIFFTAG_SAMPLESPERPIXEL, 1)
+	if err != nil {
+		return nil, err
+	}
+	planarConfig, err := f.getUint16WithDefault(ctx, TIFFTAG_PLANARCONFIG, uint16(PLANARCONFIG_CONTIG))
+	if err != nil {
+		return nil, err
+	}
+	inkSet, err := f.getUint16WithDefault(ctx, TIFFTAG_INKSET, uint16(INKSET_CMYK))
+	if err != nil {
+		return nil, err
+	}
+	orientation, err := f.GetOrientation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if TIFFTAG(inkSet) != INKSET_CMYK || samplesPerPixel < 4 || (bitsPerSample != 8 && bitsPerSample != 16) || TIFFTAG(planarConfig) != PLANARCONFIG_CONTIG {
+		return nil, errors.New("only contiguous 8 and 16-bit CMYK images can be colour managed")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	bytesPerPixel := int(samplesPerPixel) * int(bitsPerSample) / 8
+
+	// convertBlock converts a block of samples with the given row length to
+	// the image at the given position.
+	convertBlock := func(data []byte, blockX, blockY, blockWidth, rowLength int) {
+		rows := len(data) / (rowLength * bytesPerPixel)
+		for y := 0; y < rows && blockY+y < height; y++ {
+			for x := 0; x < blockWidth && blockX+x < width; x++ {
+				sample := data[(y*rowLength+x)*bytesPerPixel:]
+				var rgb [3]uint8
+				if bitsPerSample == 8 {
+					rgb = transform.Convert8(sample[:4])
+				} else {
+					var samples [4]uint16
+					for c := range samples {
+						samples[c] = binary.LittleEndian.Uint16(sample[c*2:])
+					}
+					rgb = transform.Convert16(samples[:])
+				}
+				offset := img.PixOffset(blockX+x, blockY+y)
+				img.Pix[offset], img.Pix[offset+1], img.Pix[offset+2], img.Pix[offset+3] = rgb[0], rgb[1], rgb[2], 255
+			}
+		}
+	}
+
+	isTiled, err := f.TIFFIsTiled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTiled {
+		tileWidth, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH)
+		if err != nil {
+			return nil, err
+		}
+		tileHeight, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILELENGTH)
+		if err != nil {
+			return nil, err
+		}
+		for y := 0; y < height; y += int(tileHeight) {
+			for x := 0; x < width; x += int(tileWidth) {
+				tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, 0)
+				if err != nil {
+					return nil, err
+				}
+				data, err := f.TIFFReadEncodedTile(ctx, tile)
+				if err != nil {
+					return nil, err
+				}
+				convertBlock(data, x, y, int(tileWidth), int(tileWidth))
+			}
+		}
+	} else {
+		strips, err := f.TIFFNumberOfStrips(ctx)
+		if err != nil {
+			return nil, err
+		}
+		y := 0
+		for strip := uint32(0); strip < strips && y < height; strip++ {
+			data, err := f.TIFFReadEncodedStrip(ctx, strip)
+			if err != nil {
+				return nil, err
+			}
+			convertBlock(data, 0, y, width, width)
+			y += len(data) / (width * bytesPerPixel)
+		}
+	}
+
+	// TIFFReadRGBAImageOriented only flips, it does not transpose.
+	switch orientation {
+	case ORIENTATION_TOPRIGHT, ORIENTATION_RIGHTTOP:
+		flipRGBA(img, true, false)
+	case ORIENTATION_BOTRIGHT, ORIENTATION_RIGHTBOT:
+		flipRGBA(img, true, true)
+	case ORIENTATION_BOTLEFT, ORIENTATION_LEFTBOT:
+		flipRGBA(img, false, true)
+	}
+
+	return img, nil
+}
+
+// flipRGBA flips an image in place.
+func flipRGBA(img *image.RGBA, horizontal, vertical bool) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if horizontal {
+		for y := 0; y < height; y++ {
+			row := img.Pix[y*img.Stride : y*img.Stride+width*4]
+			for left, right := 0, width-1; left < right; left, right = left+1, right-1 {
+				for c := 0; c < 4; c++ {
+					row[left*4+c], row[right*4+c] = row[right*4+c], row[left*4+c]
+				}
+			}
+		}
+	}
+	if vertical {
+		rowBuffer := make([]byte, width*4)
+		for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+			topRow := img.Pix[top*img.Stride : top*img.Stride+width*4]
+			bottomRow := img.Pix[bottom*img.Stride : bottom*img.Stride+width*4]
+			copy(rowBuffer, topRow)
+			copy(topRow, bottomRow)
+			copy(bottomRow, rowBuffer)
+		}
+	}
+}
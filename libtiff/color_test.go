@@ -0,0 +1,370 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type testICCTag struct {
+	signature string
+	data      []byte
+}
+
+// buildTestICCProfile builds an ICC profile with the given tags.
+func buildTestICCProfile(colorSpace, pcs string, tags []testICCTag) []byte {
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[8:], 0x04300000)
+	copy(header[12:], "mntr")
+	copy(header[16:], colorSpace)
+	copy(header[20:], pcs)
+	copy(header[36:], "acsp")
+
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	dataOffset := 128 + 4 + len(tags)*12
+	var data []byte
+	for _, tag := range tags {
+		table = append(table, tag.signature...)
+		table = binary.BigEndian.AppendUint32(table, uint32(dataOffset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+		data = append(data, tag.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func s15Fixed16(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+}
+
+func testICCXYZ(x, y, z float64) []byte {
+	data := append([]byte("XYZ "), 0, 0, 0, 0)
+	data = append(data, s15Fixed16(x)...)
+	data = append(data, s15Fixed16(y)...)
+	return append(data, s15Fixed16(z)...)
+}
+
+// testICCGamma returns a curveType with a single gamma value.
+func testICCGamma(gamma float64) []byte {
+	data := append([]byte("curv"), 0, 0, 0, 0, 0, 0, 0, 1)
+	return binary.BigEndian.AppendUint16(data, uint16(gamma*256))
+}
+
+// testICCSRGBCurve returns the sRGB transfer function as parametricCurveType.
+func testICCSRGBCurve() []byte {
+	data := append([]byte("para"), 0, 0, 0, 0, 0, 3, 0, 0)
+	for _, p := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		data = append(data, s15Fixed16(p)...)
+	}
+	return data
+}
+
+// testRGBProfile returns a matrix/TRC profile with the sRGB primaries.
+func testRGBProfile(trc []byte) []byte {
+	return buildTestICCProfile("RGB ", "XYZ ", []testICCTag{
+		{"rXYZ", testICCXYZ(0.4360747, 0.2225045, 0.0139322)},
+		{"gXYZ", testICCXYZ(0.3850649, 0.7168786, 0.0971045)},
+		{"bXYZ", testICCXYZ(0.1430804, 0.0606169, 0.7141733)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	})
+}
+
+// testCMYKLab is the colour of the test CMYK profiles: the lightness only
+// depends on cyan and black, and cyan shifts to green-blue.
+func testCMYKLab(c, m, y, k float64) (float64, float64, float64) {
+	return 100 * (1 - k) * (1 - 0.3*c), -40 * c, -20 * c
+}
+
+// testCMYKLut16Profile returns a CMYK profile with a lut16Type A2B0.
+func testCMYKLut16Profile() []byte {
+	data := append([]byte("mft2"), 0, 0, 0, 0, 4, 3, 2, 0)
+	for i := 0; i < 9; i++ {
+		identity := 0.0
+		if i%4 == 0 {
+			identity = 1
+		}
+		data = append(data, s15Fixed16(identity)...)
+	}
+	data = binary.BigEndian.AppendUint16(data, 2)
+	data = binary.BigEndian.AppendUint16(data, 2)
+	for i := 0; i < 4; i++ {
+		data = binary.BigEndian.AppendUint16(data, 0)
+		data = binary.BigEndian.AppendUint16(data, 0xFFFF)
+	}
+	for i := 0; i < 16; i++ {
+		l, a, b := testCMYKLab(float64(i>>3&1), float64(i>>2&1), float64(i>>1&1), float64(i&1))
+		// Legacy 16-bit Lab encoding.
+		data = binary.BigEndian.AppendUint16(data, uint16(math.Round(l/100*0xFF00)))
+		data = binary.BigEndian.AppendUint16(data, uint16(math.Round((a+128)*256)))
+		data = binary.BigEndian.AppendUint16(data, uint16(math.Round((b+128)*256)))
+	}
+	for i := 0; i < 3; i++ {
+		data = binary.BigEndian.AppendUint16(data, 0)
+		data = binary.BigEndian.AppendUint16(data, 0xFFFF)
+	}
+	return buildTestICCProfile("CMYK", "Lab ", []testICCTag{{"A2B0", data}})
+}
+
+// testCMYKLutAToBProfile returns a CMYK profile with a lutAtoBType A2B0.
+func testCMYKLutAToBProfile() []byte {
+	identity := append([]byte("curv"), 0, 0, 0, 0, 0, 0, 0, 0)
+
+	var curvesA, curvesB []byte
+	for i := 0; i < 4; i++ {
+		curvesA = append(curvesA, identity...)
+	}
+	for i := 0; i < 3; i++ {
+		curvesB = append(curvesB, identity...)
+	}
+
+	clut := make([]byte, 16)
+	for i := 0; i < 4; i++ {
+		clut[i] = 2
+	}
+	clut = append(clut, 1, 0, 0, 0)
+	for i := 0; i < 16; i++ {
+		l, a, b := testCMYKLab(float64(i>>3&1), float64(i>>2&1), float64(i>>1&1), float64(i&1))
+		clut = append(clut, uint8(math.Round(l/100*255)), uint8(math.Round(a+128)), uint8(math.Round(b+128)))
+	}
+
+	offsetB := 32
+	offsetCLUT := offsetB + len(curvesB)
+	offsetA := offsetCLUT + len(clut)
+	data := append([]byte("mAB "), 0, 0, 0, 0, 4, 3, 0, 0)
+	data = binary.BigEndian.AppendUint32(data, uint32(offsetB))
+	data = binary.BigEndian.AppendUint32(data, 0)
+	data = binary.BigEndian.AppendUint32(data, 0)
+	data = binary.BigEndian.AppendUint32(data, uint32(offsetCLUT))
+	data = binary.BigEndian.AppendUint32(data, uint32(offsetA))
+	data = append(data, curvesB...)
+	data = append(data, clut...)
+	data = append(data, curvesA...)
+	return buildTestICCProfile("CMYK", "Lab ", []testICCTag{{"A2B0", data}})
+}
+
+// writeCMYKTestTIFF writes a single row 8-bit CMYK TIFF and opens it.
+func writeCMYKTestTIFF(ctx context.Context, pixels [][4]byte, profile []byte, orientation libtiff.Orientation) (*libtiff.File, func()) {
+	tmpFile, err := os.CreateTemp("", "libtiff-cmyk-test-*.tif")
+	Expect(err).To(BeNil())
+	tmpPath := tmpFile.Name()
+
+	fileMode := "w"
+	writeTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+		FileMode: &fileMode,
+	})
+	Expect(err).To(BeNil())
+
+	var strip []byte
+	for _, pixel := range pixels {
+		strip = append(strip, pixel[:]...)
+	}
+
+	Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGEWIDTH, uint32(len(pixels)))).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGELENGTH, 1)).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE, 8)).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_SAMPLESPERPIXEL, 4)).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_PHOTOMETRIC, uint16(libtiff.PHOTOMETRIC_SEPARATED))).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_PLANARCONFIG, uint16(libtiff.PLANARCONFIG_CONTIG))).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_ORIENTATION, uint16(orientation))).To(Succeed())
+	Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_ROWSPERSTRIP, 1)).To(Succeed())
+	if profile != nil {
+		Expect(writeTiff.TIFFSetFieldByteArray(ctx, libtiff.TIFFTAG_ICCPROFILE, profile)).To(Succeed())
+	}
+	Expect(writeTiff.TIFFWriteEncodedStrip(ctx, 0, strip)).To(Succeed())
+	Expect(writeTiff.TIFFWriteDirectory(ctx)).To(Succeed())
+	writeTiff.Close(ctx)
+	tmpFile.Close()
+
+	readFile, err := os.Open(tmpPath)
+	Expect(err).To(BeNil())
+	stat, err := readFile.Stat()
+	Expect(err).To(BeNil())
+	readTiff, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", readFile, uint64(stat.Size()), nil)
+	Expect(err).To(BeNil())
+
+	return readTiff, func() {
+		readTiff.Close(ctx)
+		readFile.Close()
+		os.Remove(tmpPath)
+	}
+}
+
+func expectRGBA(img image.Image, x, y int, r, g, b uint8) {
+	pixel := img.(*image.RGBA).RGBAAt(x, y)
+	Expect(pixel.R).To(BeNumerically("~", r, 2), "red of pixel %d,%d", x, y)
+	Expect(pixel.G).To(BeNumerically("~", g, 2), "green of pixel %d,%d", x, y)
+	Expect(pixel.B).To(BeNumerically("~", b, 2), "blue of pixel %d,%d", x, y)
+	Expect(pixel.A).To(Equal(uint8(255)))
+}
+
+var _ = Describe("ToSRGBGoImage", func() {
+	ctx := context.Background()
+
+	grayRGBA := func(values ...uint8) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, len(values), 1))
+		for x, v := range values {
+			img.SetRGBA(x, 0, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+		return img
+	}
+
+	It("converts a linear RGB profile to sRGB", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, grayRGBA(0, 64, 128, 255), &libtiff.FromGoImageOptions{
+			ICCProfile: testRGBProfile(testICCGamma(1)),
+		})
+		defer cleanup()
+
+		img, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		expectRGBA(img, 0, 0, 0, 0, 0)
+		expectRGBA(img, 1, 0, 137, 137, 137)
+		expectRGBA(img, 2, 0, 188, 188, 188)
+		expectRGBA(img, 3, 0, 255, 255, 255)
+	})
+
+	It("keeps colours of an sRGB profile", func() {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		img.SetRGBA(0, 0, color.RGBA{R: 200, G: 30, B: 90, A: 255})
+		img.SetRGBA(1, 0, color.RGBA{R: 10, G: 120, B: 240, A: 255})
+		tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+			ICCProfile: testRGBProfile(testICCSRGBCurve()),
+		})
+		defer cleanup()
+
+		converted, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		expectRGBA(converted, 0, 0, 200, 30, 90)
+		expectRGBA(converted, 1, 0, 10, 120, 240)
+	})
+
+	It("uses a caller provided profile", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, grayRGBA(128), nil)
+		defer cleanup()
+
+		img, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(BeNil())
+		expectRGBA(img, 0, 0, 128, 128, 128)
+		Expect(imgCleanup(ctx)).To(Succeed())
+
+		img, imgCleanup, err = tiffFile.ToSRGBGoImage(ctx, &libtiff.ColorManagementOptions{
+			ICCProfile: testRGBProfile(testICCGamma(1)),
+		})
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		expectRGBA(img, 0, 0, 188, 188, 188)
+	})
+
+	It("converts a gray profile", func() {
+		grayProfile := buildTestICCProfile("GRAY", "XYZ ", []testICCTag{{"kTRC", testICCGamma(1)}})
+		tiffFile := openTestTIFF(ctx, buildTestTIFF([]testIFDEntry{
+			{libtiff.TIFFTAG_ICCPROFILE, 7, uint32(len(grayProfile)), grayProfile},
+		}))
+		defer tiffFile.Close(ctx)
+
+		img, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		expectRGBA(img, 0, 0, 0, 0, 0)
+		expectRGBA(img, 1, 0, 137, 137, 137)
+		expectRGBA(img, 0, 1, 188, 188, 188)
+		expectRGBA(img, 1, 1, 255, 255, 255)
+	})
+
+	for name, profile := range map[string][]byte{
+		"lut16":   testCMYKLut16Profile(),
+		"lutAtoB": testCMYKLutAToBProfile(),
+	} {
+		It("converts CMYK with a "+name+" profile", func() {
+			tiffFile, cleanup := writeCMYKTestTIFF(ctx, [][4]byte{
+				{0, 0, 0, 0},
+				{0, 0, 0, 255},
+				{0, 0, 0, 128},
+				{255, 0, 0, 0},
+				{0, 255, 255, 0},
+			}, profile, libtiff.ORIENTATION_TOPLEFT)
+			defer cleanup()
+
+			img, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+			Expect(err).To(BeNil())
+			defer imgCleanup(ctx)
+
+			expectRGBA(img, 0, 0, 255, 255, 255)
+			expectRGBA(img, 1, 0, 0, 0, 0)
+			// L=49.8
+			expectRGBA(img, 2, 0, 118, 118, 118)
+			// L=70, a=-40, b=-20
+			expectRGBA(img, 3, 0, 0, 192, 206)
+			// Magenta and yellow don't change the colour of this profile,
+			// unlike the naive conversion of libtiff.
+			expectRGBA(img, 4, 0, 255, 255, 255)
+		})
+	}
+
+	It("flips CMYK images like ToGoImage", func() {
+		tiffFile, cleanup := writeCMYKTestTIFF(ctx, [][4]byte{
+			{0, 0, 0, 0},
+			{0, 0, 0, 255},
+		}, testCMYKLutAToBProfile(), libtiff.ORIENTATION_TOPRIGHT)
+		defer cleanup()
+
+		img, imgCleanup, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		expectRGBA(img, 0, 0, 0, 0, 0)
+		expectRGBA(img, 1, 0, 255, 255, 255)
+	})
+
+	It("returns an error when the profile does not match the image", func() {
+		tiffFile := writeWithForeignICCProfile(ctx, grayRGBA(128), nil, testCMYKLut16Profile())
+
+		_, _, err := tiffFile.ToSRGBGoImage(ctx, nil)
+		Expect(err).To(MatchError(`ICC profile colour space "CMYK" does not match photometric interpretation PHOTOMETRIC_RGB`))
+
+		_, _, err = tiffFile.ToSRGBGoImage(ctx, &libtiff.ColorManagementOptions{
+			ICCProfile: []byte("not a profile"),
+		})
+		Expect(err).To(MatchError("not an ICC profile"))
+	})
+
+	It("renders colour managed images in ToImage", func() {
+		tiffFile, cleanup := writeCMYKTestTIFF(ctx, [][4]byte{{0, 255, 255, 0}}, testCMYKLut16Profile(), libtiff.ORIENTATION_TOPLEFT)
+		defer cleanup()
+
+		pngBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat:    libtiff.ImageOptionsOutputFormatPNG,
+			OutputTarget:    libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:      true,
+			ColorManagement: &libtiff.ColorManagementOptions{},
+		})
+		Expect(err).To(BeNil())
+		Expect(readPNGICCProfile(pngBytes)).To(BeNil())
+
+		img, err := png.Decode(bytes.NewReader(pngBytes))
+		Expect(err).To(BeNil())
+		r, g, b, _ := img.At(0, 0).RGBA()
+		Expect([]uint32{r >> 8, g >> 8, b >> 8}).To(Equal([]uint32{255, 255, 255}))
+	})
+})
@@ -4,185 +4,207 @@ type TIFFTAG uint32
 
 // https://gitlab.com/libtiff/libtiff/-/blob/master/libtiff/tiff.h
 
+type TIFFDataType uint32
+
+var (
+	TIFF_NOTYPE    = TIFFDataType(0)  /* placeholder */
+	TIFF_BYTE      = TIFFDataType(1)  /* 8-bit unsigned integer */
+	TIFF_ASCII     = TIFFDataType(2)  /* 8-bit bytes w/ last byte null */
+	TIFF_SHORT     = TIFFDataType(3)  /* 16-bit unsigned integer */
+	TIFF_LONG      = TIFFDataType(4)  /* 32-bit unsigned integer */
+	TIFF_RATIONAL  = TIFFDataType(5)  /* 64-bit unsigned fraction */
+	TIFF_SBYTE     = TIFFDataType(6)  /* !8-bit signed integer */
+	TIFF_UNDEFINED = TIFFDataType(7)  /* !8-bit untyped data */
+	TIFF_SSHORT    = TIFFDataType(8)  /* !16-bit signed integer */
+	TIFF_SLONG     = TIFFDataType(9)  /* !32-bit signed integer */
+	TIFF_SRATIONAL = TIFFDataType(10) /* !64-bit signed fraction */
+	TIFF_FLOAT     = TIFFDataType(11) /* !32-bit IEEE floating point */
+	TIFF_DOUBLE    = TIFFDataType(12) /* !64-bit IEEE floating point */
+	TIFF_IFD       = TIFFDataType(13) /* %32-bit unsigned integer (offset) */
+	TIFF_LONG8     = TIFFDataType(16) /* BigTIFF 64-bit unsigned integer */
+	TIFF_SLONG8    = TIFFDataType(17) /* BigTIFF 64-bit signed integer */
+	TIFF_IFD8      = TIFFDataType(18) /* BigTIFF 64-bit unsigned integer (offset) */
+)
+
 var (
-	TIFFTAG_SUBFILETYPE     = TIFFTAG(254)   /* subfile data descriptor */
-	FILETYPE_REDUCEDIMAGE   = TIFFTAG(0x1)   /* reduced resolution version */
-	FILETYPE_PAGE           = TIFFTAG(0x2)   /* one page of many */
-	FILETYPE_MASK           = TIFFTAG(0x4)   /* transparency mask */
-	TIFFTAG_OSUBFILETYPE    = TIFFTAG(255)   /* +kind of data in subfile */
-	OFILETYPE_IMAGE         = TIFFTAG(1)     /* full resolution image data */
-	OFILETYPE_REDUCEDIMAGE  = TIFFTAG(2)     /* reduced size image data */
-	OFILETYPE_PAGE          = TIFFTAG(3)     /* one page of many */
-	TIFFTAG_IMAGEWIDTH      = TIFFTAG(256)   /* image width in pixels */
-	TIFFTAG_IMAGELENGTH     = TIFFTAG(257)   /* image height in pixels */
-	TIFFTAG_BITSPERSAMPLE   = TIFFTAG(258)   /* bits per channel (sample) */
-	TIFFTAG_COMPRESSION     = TIFFTAG(259)   /* data compression technique */
-	COMPRESSION_NONE        = TIFFTAG(1)     /* dump mode */
-	COMPRESSION_CCITTRLE    = TIFFTAG(2)     /* CCITT modified Huffman RLE */
-	COMPRESSION_CCITTFAX3   = TIFFTAG(3)     /* CCITT Group 3 fax encoding */
-	COMPRESSION_CCITT_T4    = TIFFTAG(3)     /* CCITT T.4 (TIFF 6 name) */
-	COMPRESSION_CCITTFAX4   = TIFFTAG(4)     /* CCITT Group 4 fax encoding */
-	COMPRESSION_CCITT_T6    = TIFFTAG(4)     /* CCITT T.6 (TIFF 6 name) */
-	COMPRESSION_LZW         = TIFFTAG(5)     /* Lempel-Ziv  & Welch */
-	COMPRESSION_OJPEG       = TIFFTAG(6)     /* !6.0 JPEG */
-	COMPRESSION_JPEG        = TIFFTAG(7)     /* %JPEG DCT compression */
-	COMPRESSION_T85         = TIFFTAG(9)     /* !TIFF/FX T.85 JBIG compression */
-	COMPRESSION_T43         = TIFFTAG(10)    /* !TIFF/FX T.43 colour by layered JBIG compression */
-	COMPRESSION_NEXT        = TIFFTAG(32766) /* NeXT 2-bit RLE */
-	COMPRESSION_CCITTRLEW   = TIFFTAG(32771) /* #1 w/ word alignment */
-	COMPRESSION_PACKBITS    = TIFFTAG(32773) /* Macintosh RLE */
-	COMPRESSION_THUNDERSCAN = TIFFTAG(32809) /* ThunderScan RLE */
+	TIFFTAG_SUBFILETYPE     = TIFFTAG(254)       /* subfile data descriptor */
+	FILETYPE_REDUCEDIMAGE   = SubfileType(0x1)   /* reduced resolution version */
+	FILETYPE_PAGE           = SubfileType(0x2)   /* one page of many */
+	FILETYPE_MASK           = SubfileType(0x4)   /* transparency mask */
+	TIFFTAG_OSUBFILETYPE    = TIFFTAG(255)       /* +kind of data in subfile */
+	OFILETYPE_IMAGE         = TIFFTAG(1)         /* full resolution image data */
+	OFILETYPE_REDUCEDIMAGE  = TIFFTAG(2)         /* reduced size image data */
+	OFILETYPE_PAGE          = TIFFTAG(3)         /* one page of many */
+	TIFFTAG_IMAGEWIDTH      = TIFFTAG(256)       /* image width in pixels */
+	TIFFTAG_IMAGELENGTH     = TIFFTAG(257)       /* image height in pixels */
+	TIFFTAG_BITSPERSAMPLE   = TIFFTAG(258)       /* bits per channel (sample) */
+	TIFFTAG_COMPRESSION     = TIFFTAG(259)       /* data compression technique */
+	COMPRESSION_NONE        = Compression(1)     /* dump mode */
+	COMPRESSION_CCITTRLE    = Compression(2)     /* CCITT modified Huffman RLE */
+	COMPRESSION_CCITTFAX3   = Compression(3)     /* CCITT Group 3 fax encoding */
+	COMPRESSION_CCITT_T4    = Compression(3)     /* CCITT T.4 (TIFF 6 name) */
+	COMPRESSION_CCITTFAX4   = Compression(4)     /* CCITT Group 4 fax encoding */
+	COMPRESSION_CCITT_T6    = Compression(4)     /* CCITT T.6 (TIFF 6 name) */
+	COMPRESSION_LZW         = Compression(5)     /* Lempel-Ziv  & Welch */
+	COMPRESSION_OJPEG       = Compression(6)     /* !6.0 JPEG */
+	COMPRESSION_JPEG        = Compression(7)     /* %JPEG DCT compression */
+	COMPRESSION_T85         = Compression(9)     /* !TIFF/FX T.85 JBIG compression */
+	COMPRESSION_T43         = Compression(10)    /* !TIFF/FX T.43 colour by layered JBIG compression */
+	COMPRESSION_NEXT        = Compression(32766) /* NeXT 2-bit RLE */
+	COMPRESSION_CCITTRLEW   = Compression(32771) /* #1 w/ word alignment */
+	COMPRESSION_PACKBITS    = Compression(32773) /* Macintosh RLE */
+	COMPRESSION_THUNDERSCAN = Compression(32809) /* ThunderScan RLE */
 	/* codes 32895-32898 are reserved for ANSI IT8 TIFF/IT <dkelly@apago.com) */
-	COMPRESSION_IT8CTPAD = TIFFTAG(32895) /* IT8 CT w/padding */
-	COMPRESSION_IT8LW    = TIFFTAG(32896) /* IT8 Linework RLE */
-	COMPRESSION_IT8MP    = TIFFTAG(32897) /* IT8 Monochrome picture */
-	COMPRESSION_IT8BL    = TIFFTAG(32898) /* IT8 Binary line art */
+	COMPRESSION_IT8CTPAD = Compression(32895) /* IT8 CT w/padding */
+	COMPRESSION_IT8LW    = Compression(32896) /* IT8 Linework RLE */
+	COMPRESSION_IT8MP    = Compression(32897) /* IT8 Monochrome picture */
+	COMPRESSION_IT8BL    = Compression(32898) /* IT8 Binary line art */
 	/* compression codes 32908-32911 are reserved for Pixar */
-	COMPRESSION_PIXARFILM     = TIFFTAG(32908) /* Pixar companded 10bit LZW */
-	COMPRESSION_PIXARLOG      = TIFFTAG(32909) /* Pixar companded 11bit ZIP */
-	COMPRESSION_DEFLATE       = TIFFTAG(32946) /* Deflate compression, legacy tag */
-	COMPRESSION_ADOBE_DEFLATE = TIFFTAG(8)     /* Deflate compression, as recognized by Adobe */
+	COMPRESSION_PIXARFILM     = Compression(32908) /* Pixar companded 10bit LZW */
+	COMPRESSION_PIXARLOG      = Compression(32909) /* Pixar companded 11bit ZIP */
+	COMPRESSION_DEFLATE       = Compression(32946) /* Deflate compression, legacy tag */
+	COMPRESSION_ADOBE_DEFLATE = Compression(8)     /* Deflate compression, as recognized by Adobe */
 	/* compression code 32947 is reserved for Oceana Matrix <dev@oceana.com> */
-	COMPRESSION_DCS      = TIFFTAG(32947) /* Kodak DCS encoding */
-	COMPRESSION_JBIG     = TIFFTAG(34661) /* ISO JBIG */
-	COMPRESSION_SGILOG   = TIFFTAG(34676) /* SGI Log Luminance RLE */
-	COMPRESSION_SGILOG24 = TIFFTAG(34677) /* SGI Log 24-bit packed */
-	COMPRESSION_JP2000   = TIFFTAG(34712) /* Leadtools JPEG2000 */
-	COMPRESSION_LERC     = TIFFTAG(34887) /* ESRI Lerc codec: https://github.com/Esri/lerc */
+	COMPRESSION_DCS      = Compression(32947) /* Kodak DCS encoding */
+	COMPRESSION_JBIG     = Compression(34661) /* ISO JBIG */
+	COMPRESSION_SGILOG   = Compression(34676) /* SGI Log Luminance RLE */
+	COMPRESSION_SGILOG24 = Compression(34677) /* SGI Log 24-bit packed */
+	COMPRESSION_JP2000   = Compression(34712) /* Leadtools JPEG2000 */
+	COMPRESSION_LERC     = Compression(34887) /* ESRI Lerc codec: https://github.com/Esri/lerc */
 	/* compression codes 34887-34889 are reserved for ESRI */
-	COMPRESSION_LZMA               = TIFFTAG(34925) /* LZMA2 */
-	COMPRESSION_ZSTD               = TIFFTAG(50000) /* ZSTD: WARNING not registered in Adobe-maintained registry */
-	COMPRESSION_WEBP               = TIFFTAG(50001) /* WEBP: WARNING not registered in Adobe-maintained registry */
-	COMPRESSION_JXL                = TIFFTAG(50002) /* JPEGXL: WARNING not registered in Adobe-maintained registry */
-	COMPRESSION_JXL_DNG_1_7        = TIFFTAG(52546) /* JPEGXL from DNG 1.7 specification */
-	TIFFTAG_PHOTOMETRIC            = TIFFTAG(262)   /* photometric interpretation */
-	PHOTOMETRIC_MINISWHITE         = TIFFTAG(0)     /* min value is white */
-	PHOTOMETRIC_MINISBLACK         = TIFFTAG(1)     /* min value is black */
-	PHOTOMETRIC_RGB                = TIFFTAG(2)     /* RGB color model */
-	PHOTOMETRIC_PALETTE            = TIFFTAG(3)     /* color map indexed */
-	PHOTOMETRIC_MASK               = TIFFTAG(4)     /* $holdout mask */
-	PHOTOMETRIC_SEPARATED          = TIFFTAG(5)     /* !color separations */
-	PHOTOMETRIC_YCBCR              = TIFFTAG(6)     /* !CCIR 601 */
-	PHOTOMETRIC_CIELAB             = TIFFTAG(8)     /* !1976 CIE L*a*b* */
-	PHOTOMETRIC_ICCLAB             = TIFFTAG(9)     /* ICC L*a*b* [Adobe TIFF Technote 4] */
-	PHOTOMETRIC_ITULAB             = TIFFTAG(10)    /* ITU L*a*b* */
-	PHOTOMETRIC_CFA                = TIFFTAG(32803) /* color filter array */
-	PHOTOMETRIC_LOGL               = TIFFTAG(32844) /* CIE Log2(L) */
-	PHOTOMETRIC_LOGLUV             = TIFFTAG(32845) /* CIE Log2(L) (u',v') */
-	TIFFTAG_THRESHHOLDING          = TIFFTAG(263)   /* +thresholding used on data */
-	THRESHHOLD_BILEVEL             = TIFFTAG(1)     /* b&w art scan */
-	THRESHHOLD_HALFTONE            = TIFFTAG(2)     /* or dithered scan */
-	THRESHHOLD_ERRORDIFFUSE        = TIFFTAG(3)     /* usually floyd-steinberg */
-	TIFFTAG_CELLWIDTH              = TIFFTAG(264)   /* +dithering matrix width */
-	TIFFTAG_CELLLENGTH             = TIFFTAG(265)   /* +dithering matrix height */
-	TIFFTAG_FILLORDER              = TIFFTAG(266)   /* data order within a byte */
-	FILLORDER_MSB2LSB              = TIFFTAG(1)     /* most significant -> least */
-	FILLORDER_LSB2MSB              = TIFFTAG(2)     /* least significant -> most */
-	TIFFTAG_DOCUMENTNAME           = TIFFTAG(269)   /* name of doc. image is from */
-	TIFFTAG_IMAGEDESCRIPTION       = TIFFTAG(270)   /* info about image */
-	TIFFTAG_MAKE                   = TIFFTAG(271)   /* scanner manufacturer name */
-	TIFFTAG_MODEL                  = TIFFTAG(272)   /* scanner model name/number */
-	TIFFTAG_STRIPOFFSETS           = TIFFTAG(273)   /* offsets to data strips */
-	TIFFTAG_ORIENTATION            = TIFFTAG(274)   /* +image orientation */
-	ORIENTATION_TOPLEFT            = TIFFTAG(1)     /* row 0 top, col 0 lhs */
-	ORIENTATION_TOPRIGHT           = TIFFTAG(2)     /* row 0 top, col 0 rhs */
-	ORIENTATION_BOTRIGHT           = TIFFTAG(3)     /* row 0 bottom, col 0 rhs */
-	ORIENTATION_BOTLEFT            = TIFFTAG(4)     /* row 0 bottom, col 0 lhs */
-	ORIENTATION_LEFTTOP            = TIFFTAG(5)     /* row 0 lhs, col 0 top */
-	ORIENTATION_RIGHTTOP           = TIFFTAG(6)     /* row 0 rhs, col 0 top */
-	ORIENTATION_RIGHTBOT           = TIFFTAG(7)     /* row 0 rhs, col 0 bottom */
-	ORIENTATION_LEFTBOT            = TIFFTAG(8)     /* row 0 lhs, col 0 bottom */
-	TIFFTAG_SAMPLESPERPIXEL        = TIFFTAG(277)   /* samples per pixel */
-	TIFFTAG_ROWSPERSTRIP           = TIFFTAG(278)   /* rows per strip of data */
-	TIFFTAG_STRIPBYTECOUNTS        = TIFFTAG(279)   /* bytes counts for strips */
-	TIFFTAG_MINSAMPLEVALUE         = TIFFTAG(280)   /* +minimum sample value */
-	TIFFTAG_MAXSAMPLEVALUE         = TIFFTAG(281)   /* +maximum sample value */
-	TIFFTAG_XRESOLUTION            = TIFFTAG(282)   /* pixels/resolution in x */
-	TIFFTAG_YRESOLUTION            = TIFFTAG(283)   /* pixels/resolution in y */
-	TIFFTAG_PLANARCONFIG           = TIFFTAG(284)   /* storage organization */
-	PLANARCONFIG_CONTIG            = TIFFTAG(1)     /* single image plane */
-	PLANARCONFIG_SEPARATE          = TIFFTAG(2)     /* separate planes of data */
-	TIFFTAG_PAGENAME               = TIFFTAG(285)   /* page name image is from */
-	TIFFTAG_XPOSITION              = TIFFTAG(286)   /* x page offset of image lhs */
-	TIFFTAG_YPOSITION              = TIFFTAG(287)   /* y page offset of image lhs */
-	TIFFTAG_FREEOFFSETS            = TIFFTAG(288)   /* +byte offset to free block */
-	TIFFTAG_FREEBYTECOUNTS         = TIFFTAG(289)   /* +sizes of free blocks */
-	TIFFTAG_GRAYRESPONSEUNIT       = TIFFTAG(290)   /* $gray scale curve accuracy */
-	GRAYRESPONSEUNIT_10S           = TIFFTAG(1)     /* tenths of a unit */
-	GRAYRESPONSEUNIT_100S          = TIFFTAG(2)     /* hundredths of a unit */
-	GRAYRESPONSEUNIT_1000S         = TIFFTAG(3)     /* thousandths of a unit */
-	GRAYRESPONSEUNIT_10000S        = TIFFTAG(4)     /* ten-thousandths of a unit */
-	GRAYRESPONSEUNIT_100000S       = TIFFTAG(5)     /* hundred-thousandths */
-	TIFFTAG_GRAYRESPONSECURVE      = TIFFTAG(291)   /* $gray scale response curve */
-	TIFFTAG_GROUP3OPTIONS          = TIFFTAG(292)   /* 32 flag bits */
-	TIFFTAG_T4OPTIONS              = TIFFTAG(292)   /* TIFF 6.0 proper name alias */
-	GROUP3OPT_2DENCODING           = TIFFTAG(0x1)   /* 2-dimensional coding */
-	GROUP3OPT_UNCOMPRESSED         = TIFFTAG(0x2)   /* data not compressed */
-	GROUP3OPT_FILLBITS             = TIFFTAG(0x4)   /* fill to byte boundary */
-	TIFFTAG_GROUP4OPTIONS          = TIFFTAG(293)   /* 32 flag bits */
-	TIFFTAG_T6OPTIONS              = TIFFTAG(293)   /* TIFF 6.0 proper name */
-	GROUP4OPT_UNCOMPRESSED         = TIFFTAG(0x2)   /* data not compressed */
-	TIFFTAG_RESOLUTIONUNIT         = TIFFTAG(296)   /* units of resolutions */
-	RESUNIT_NONE                   = TIFFTAG(1)     /* no meaningful units */
-	RESUNIT_INCH                   = TIFFTAG(2)     /* english */
-	RESUNIT_CENTIMETER             = TIFFTAG(3)     /* metric */
-	TIFFTAG_PAGENUMBER             = TIFFTAG(297)   /* page numbers of multi-page */
-	TIFFTAG_COLORRESPONSEUNIT      = TIFFTAG(300)   /* $color curve accuracy */
-	COLORRESPONSEUNIT_10S          = TIFFTAG(1)     /* tenths of a unit */
-	COLORRESPONSEUNIT_100S         = TIFFTAG(2)     /* hundredths of a unit */
-	COLORRESPONSEUNIT_1000S        = TIFFTAG(3)     /* thousandths of a unit */
-	COLORRESPONSEUNIT_10000S       = TIFFTAG(4)     /* ten-thousandths of a unit */
-	COLORRESPONSEUNIT_100000S      = TIFFTAG(5)     /* hundred-thousandths */
-	TIFFTAG_TRANSFERFUNCTION       = TIFFTAG(301)   /* !colorimetry info */
-	TIFFTAG_SOFTWARE               = TIFFTAG(305)   /* name & release */
-	TIFFTAG_DATETIME               = TIFFTAG(306)   /* creation date and time */
-	TIFFTAG_ARTIST                 = TIFFTAG(315)   /* creator of image */
-	TIFFTAG_HOSTCOMPUTER           = TIFFTAG(316)   /* machine where created */
-	TIFFTAG_PREDICTOR              = TIFFTAG(317)   /* prediction scheme w/ LZW */
-	PREDICTOR_NONE                 = TIFFTAG(1)     /* no prediction scheme used */
-	PREDICTOR_HORIZONTAL           = TIFFTAG(2)     /* horizontal differencing */
-	PREDICTOR_FLOATINGPOINT        = TIFFTAG(3)     /* floating point predictor */
-	TIFFTAG_WHITEPOINT             = TIFFTAG(318)   /* image white point */
-	TIFFTAG_PRIMARYCHROMATICITIES  = TIFFTAG(319)   /* !primary chromaticities */
-	TIFFTAG_COLORMAP               = TIFFTAG(320)   /* RGB map for palette image */
-	TIFFTAG_HALFTONEHINTS          = TIFFTAG(321)   /* !highlight+shadow info */
-	TIFFTAG_TILEWIDTH              = TIFFTAG(322)   /* !tile width in pixels */
-	TIFFTAG_TILELENGTH             = TIFFTAG(323)   /* !tile height in pixels */
-	TIFFTAG_TILEOFFSETS            = TIFFTAG(324)   /* !offsets to data tiles */
-	TIFFTAG_TILEBYTECOUNTS         = TIFFTAG(325)   /* !byte counts for tiles */
-	TIFFTAG_BADFAXLINES            = TIFFTAG(326)   /* lines w/ wrong pixel count */
-	TIFFTAG_CLEANFAXDATA           = TIFFTAG(327)   /* regenerated line info */
-	CLEANFAXDATA_CLEAN             = TIFFTAG(0)     /* no errors detected */
-	CLEANFAXDATA_REGENERATED       = TIFFTAG(1)     /* receiver regenerated lines */
-	CLEANFAXDATA_UNCLEAN           = TIFFTAG(2)     /* uncorrected errors exist */
-	TIFFTAG_CONSECUTIVEBADFAXLINES = TIFFTAG(328)   /* max consecutive bad lines */
-	TIFFTAG_SUBIFD                 = TIFFTAG(330)   /* subimage descriptors */
-	TIFFTAG_INKSET                 = TIFFTAG(332)   /* !inks in separated image */
-	INKSET_CMYK                    = TIFFTAG(1)     /* !cyan-magenta-yellow-black color */
-	INKSET_MULTIINK                = TIFFTAG(2)     /* !multi-ink or hi-fi color */
-	TIFFTAG_INKNAMES               = TIFFTAG(333)   /* !ascii names of inks */
-	TIFFTAG_NUMBEROFINKS           = TIFFTAG(334)   /* !number of inks */
-	TIFFTAG_DOTRANGE               = TIFFTAG(336)   /* !0% and 100% dot codes */
-	TIFFTAG_TARGETPRINTER          = TIFFTAG(337)   /* !separation target */
-	TIFFTAG_EXTRASAMPLES           = TIFFTAG(338)   /* !info about extra samples */
-	EXTRASAMPLE_UNSPECIFIED        = TIFFTAG(0)     /* !unspecified data */
-	EXTRASAMPLE_ASSOCALPHA         = TIFFTAG(1)     /* !associated alpha data */
-	EXTRASAMPLE_UNASSALPHA         = TIFFTAG(2)     /* !unassociated alpha data */
-	TIFFTAG_SAMPLEFORMAT           = TIFFTAG(339)   /* !data sample format */
-	SAMPLEFORMAT_UINT              = TIFFTAG(1)     /* !unsigned integer data */
-	SAMPLEFORMAT_INT               = TIFFTAG(2)     /* !signed integer data */
-	SAMPLEFORMAT_IEEEFP            = TIFFTAG(3)     /* !IEEE floating point data */
-	SAMPLEFORMAT_VOID              = TIFFTAG(4)     /* !untyped data */
-	SAMPLEFORMAT_COMPLEXINT        = TIFFTAG(5)     /* !complex signed int */
-	SAMPLEFORMAT_COMPLEXIEEEFP     = TIFFTAG(6)     /* !complex ieee floating */
-	TIFFTAG_SMINSAMPLEVALUE        = TIFFTAG(340)   /* !variable MinSampleValue */
-	TIFFTAG_SMAXSAMPLEVALUE        = TIFFTAG(341)   /* !variable MaxSampleValue */
-	TIFFTAG_CLIPPATH               = TIFFTAG(343)   /* %ClipPath [Adobe TIFF technote 2] */
-	TIFFTAG_XCLIPPATHUNITS         = TIFFTAG(344)   /* %XClipPathUnits [Adobe TIFF technote 2] */
-	TIFFTAG_YCLIPPATHUNITS         = TIFFTAG(345)   /* %YClipPathUnits [Adobe TIFF technote 2] */
-	TIFFTAG_INDEXED                = TIFFTAG(346)   /* %Indexed [Adobe TIFF Technote 3] */
-	TIFFTAG_JPEGTABLES             = TIFFTAG(347)   /* %JPEG table stream */
-	TIFFTAG_OPIPROXY               = TIFFTAG(351)   /* %OPI Proxy [Adobe TIFF technote] */
+	COMPRESSION_LZMA               = Compression(34925) /* LZMA2 */
+	COMPRESSION_ZSTD               = Compression(50000) /* ZSTD: WARNING not registered in Adobe-maintained registry */
+	COMPRESSION_WEBP               = Compression(50001) /* WEBP: WARNING not registered in Adobe-maintained registry */
+	COMPRESSION_JXL                = Compression(50002) /* JPEGXL: WARNING not registered in Adobe-maintained registry */
+	COMPRESSION_JXL_DNG_1_7        = Compression(52546) /* JPEGXL from DNG 1.7 specification */
+	TIFFTAG_PHOTOMETRIC            = TIFFTAG(262)       /* photometric interpretation */
+	PHOTOMETRIC_MINISWHITE         = Photometric(0)     /* min value is white */
+	PHOTOMETRIC_MINISBLACK         = Photometric(1)     /* min value is black */
+	PHOTOMETRIC_RGB                = Photometric(2)     /* RGB color model */
+	PHOTOMETRIC_PALETTE            = Photometric(3)     /* color map indexed */
+	PHOTOMETRIC_MASK               = Photometric(4)     /* $holdout mask */
+	PHOTOMETRIC_SEPARATED          = Photometric(5)     /* !color separations */
+	PHOTOMETRIC_YCBCR              = Photometric(6)     /* !CCIR 601 */
+	PHOTOMETRIC_CIELAB             = Photometric(8)     /* !1976 CIE L*a*b* */
+	PHOTOMETRIC_ICCLAB             = Photometric(9)     /* ICC L*a*b* [Adobe TIFF Technote 4] */
+	PHOTOMETRIC_ITULAB             = Photometric(10)    /* ITU L*a*b* */
+	PHOTOMETRIC_CFA                = Photometric(32803) /* color filter array */
+	PHOTOMETRIC_LOGL               = Photometric(32844) /* CIE Log2(L) */
+	PHOTOMETRIC_LOGLUV             = Photometric(32845) /* CIE Log2(L) (u',v') */
+	TIFFTAG_THRESHHOLDING          = TIFFTAG(263)       /* +thresholding used on data */
+	THRESHHOLD_BILEVEL             = TIFFTAG(1)         /* b&w art scan */
+	THRESHHOLD_HALFTONE            = TIFFTAG(2)         /* or dithered scan */
+	THRESHHOLD_ERRORDIFFUSE        = TIFFTAG(3)         /* usually floyd-steinberg */
+	TIFFTAG_CELLWIDTH              = TIFFTAG(264)       /* +dithering matrix width */
+	TIFFTAG_CELLLENGTH             = TIFFTAG(265)       /* +dithering matrix height */
+	TIFFTAG_FILLORDER              = TIFFTAG(266)       /* data order within a byte */
+	FILLORDER_MSB2LSB              = TIFFTAG(1)         /* most significant -> least */
+	FILLORDER_LSB2MSB              = TIFFTAG(2)         /* least significant -> most */
+	TIFFTAG_DOCUMENTNAME           = TIFFTAG(269)       /* name of doc. image is from */
+	TIFFTAG_IMAGEDESCRIPTION       = TIFFTAG(270)       /* info about image */
+	TIFFTAG_MAKE                   = TIFFTAG(271)       /* scanner manufacturer name */
+	TIFFTAG_MODEL                  = TIFFTAG(272)       /* scanner model name/number */
+	TIFFTAG_STRIPOFFSETS           = TIFFTAG(273)       /* offsets to data strips */
+	TIFFTAG_ORIENTATION            = TIFFTAG(274)       /* +image orientation */
+	ORIENTATION_TOPLEFT            = Orientation(1)     /* row 0 top, col 0 lhs */
+	ORIENTATION_TOPRIGHT           = Orientation(2)     /* row 0 top, col 0 rhs */
+	ORIENTATION_BOTRIGHT           = Orientation(3)     /* row 0 bottom, col 0 rhs */
+	ORIENTATION_BOTLEFT            = Orientation(4)     /* row 0 bottom, col 0 lhs */
+	ORIENTATION_LEFTTOP            = Orientation(5)     /* row 0 lhs, col 0 top */
+	ORIENTATION_RIGHTTOP           = Orientation(6)     /* row 0 rhs, col 0 top */
+	ORIENTATION_RIGHTBOT           = Orientation(7)     /* row 0 rhs, col 0 bottom */
+	ORIENTATION_LEFTBOT            = Orientation(8)     /* row 0 lhs, col 0 bottom */
+	TIFFTAG_SAMPLESPERPIXEL        = TIFFTAG(277)       /* samples per pixel */
+	TIFFTAG_ROWSPERSTRIP           = TIFFTAG(278)       /* rows per strip of data */
+	TIFFTAG_STRIPBYTECOUNTS        = TIFFTAG(279)       /* bytes counts for strips */
+	TIFFTAG_MINSAMPLEVALUE         = TIFFTAG(280)       /* +minimum sample value */
+	TIFFTAG_MAXSAMPLEVALUE         = TIFFTAG(281)       /* +maximum sample value */
+	TIFFTAG_XRESOLUTION            = TIFFTAG(282)       /* pixels/resolution in x */
+	TIFFTAG_YRESOLUTION            = TIFFTAG(283)       /* pixels/resolution in y */
+	TIFFTAG_PLANARCONFIG           = TIFFTAG(284)       /* storage organization */
+	PLANARCONFIG_CONTIG            = TIFFTAG(1)         /* single image plane */
+	PLANARCONFIG_SEPARATE          = TIFFTAG(2)         /* separate planes of data */
+	TIFFTAG_PAGENAME               = TIFFTAG(285)       /* page name image is from */
+	TIFFTAG_XPOSITION              = TIFFTAG(286)       /* x page offset of image lhs */
+	TIFFTAG_YPOSITION              = TIFFTAG(287)       /* y page offset of image lhs */
+	TIFFTAG_FREEOFFSETS            = TIFFTAG(288)       /* +byte offset to free block */
+	TIFFTAG_FREEBYTECOUNTS         = TIFFTAG(289)       /* +sizes of free blocks */
+	TIFFTAG_GRAYRESPONSEUNIT       = TIFFTAG(290)       /* $gray scale curve accuracy */
+	GRAYRESPONSEUNIT_10S           = TIFFTAG(1)         /* tenths of a unit */
+	GRAYRESPONSEUNIT_100S          = TIFFTAG(2)         /* hundredths of a unit */
+	GRAYRESPONSEUNIT_1000S         = TIFFTAG(3)         /* thousandths of a unit */
+	GRAYRESPONSEUNIT_10000S        = TIFFTAG(4)         /* ten-thousandths of a unit */
+	GRAYRESPONSEUNIT_100000S       = TIFFTAG(5)         /* hundred-thousandths */
+	TIFFTAG_GRAYRESPONSECURVE      = TIFFTAG(291)       /* $gray scale response curve */
+	TIFFTAG_GROUP3OPTIONS          = TIFFTAG(292)       /* 32 flag bits */
+	TIFFTAG_T4OPTIONS              = TIFFTAG(292)       /* TIFF 6.0 proper name alias */
+	GROUP3OPT_2DENCODING           = TIFFTAG(0x1)       /* 2-dimensional coding */
+	GROUP3OPT_UNCOMPRESSED         = TIFFTAG(0x2)       /* data not compressed */
+	GROUP3OPT_FILLBITS             = TIFFTAG(0x4)       /* fill to byte boundary */
+	TIFFTAG_GROUP4OPTIONS          = TIFFTAG(293)       /* 32 flag bits */
+	TIFFTAG_T6OPTIONS              = TIFFTAG(293)       /* TIFF 6.0 proper name */
+	GROUP4OPT_UNCOMPRESSED         = TIFFTAG(0x2)       /* data not compressed */
+	TIFFTAG_RESOLUTIONUNIT         = TIFFTAG(296)       /* units of resolutions */
+	RESUNIT_NONE                   = ResolutionUnit(1)  /* no meaningful units */
+	RESUNIT_INCH                   = ResolutionUnit(2)  /* english */
+	RESUNIT_CENTIMETER             = ResolutionUnit(3)  /* metric */
+	TIFFTAG_PAGENUMBER             = TIFFTAG(297)       /* page numbers of multi-page */
+	TIFFTAG_COLORRESPONSEUNIT      = TIFFTAG(300)       /* $color curve accuracy */
+	COLORRESPONSEUNIT_10S          = TIFFTAG(1)         /* tenths of a unit */
+	COLORRESPONSEUNIT_100S         = TIFFTAG(2)         /* hundredths of a unit */
+	COLORRESPONSEUNIT_1000S        = TIFFTAG(3)         /* thousandths of a unit */
+	COLORRESPONSEUNIT_10000S       = TIFFTAG(4)         /* ten-thousandths of a unit */
+	COLORRESPONSEUNIT_100000S      = TIFFTAG(5)         /* hundred-thousandths */
+	TIFFTAG_TRANSFERFUNCTION       = TIFFTAG(301)       /* !colorimetry info */
+	TIFFTAG_SOFTWARE               = TIFFTAG(305)       /* name & release */
+	TIFFTAG_DATETIME               = TIFFTAG(306)       /* creation date and time */
+	TIFFTAG_ARTIST                 = TIFFTAG(315)       /* creator of image */
+	TIFFTAG_HOSTCOMPUTER           = TIFFTAG(316)       /* machine where created */
+	TIFFTAG_PREDICTOR              = TIFFTAG(317)       /* prediction scheme w/ LZW */
+	PREDICTOR_NONE                 = Predictor(1)       /* no prediction scheme used */
+	PREDICTOR_HORIZONTAL           = Predictor(2)       /* horizontal differencing */
+	PREDICTOR_FLOATINGPOINT        = Predictor(3)       /* floating point predictor */
+	TIFFTAG_WHITEPOINT             = TIFFTAG(318)       /* image white point */
+	TIFFTAG_PRIMARYCHROMATICITIES  = TIFFTAG(319)       /* !primary chromaticities */
+	TIFFTAG_COLORMAP               = TIFFTAG(320)       /* RGB map for palette image */
+	TIFFTAG_HALFTONEHINTS          = TIFFTAG(321)       /* !highlight+shadow info */
+	TIFFTAG_TILEWIDTH              = TIFFTAG(322)       /* !tile width in pixels */
+	TIFFTAG_TILELENGTH             = TIFFTAG(323)       /* !tile height in pixels */
+	TIFFTAG_TILEOFFSETS            = TIFFTAG(324)       /* !offsets to data tiles */
+	TIFFTAG_TILEBYTECOUNTS         = TIFFTAG(325)       /* !byte counts for tiles */
+	TIFFTAG_BADFAXLINES            = TIFFTAG(326)       /* lines w/ wrong pixel count */
+	TIFFTAG_CLEANFAXDATA           = TIFFTAG(327)       /* regenerated line info */
+	CLEANFAXDATA_CLEAN             = TIFFTAG(0)         /* no errors detected */
+	CLEANFAXDATA_REGENERATED       = TIFFTAG(1)         /* receiver regenerated lines */
+	CLEANFAXDATA_UNCLEAN           = TIFFTAG(2)         /* uncorrected errors exist */
+	TIFFTAG_CONSECUTIVEBADFAXLINES = TIFFTAG(328)       /* max consecutive bad lines */
+	TIFFTAG_SUBIFD                 = TIFFTAG(330)       /* subimage descriptors */
+	TIFFTAG_INKSET                 = TIFFTAG(332)       /* !inks in separated image */
+	INKSET_CMYK                    = TIFFTAG(1)         /* !cyan-magenta-yellow-black color */
+	INKSET_MULTIINK                = TIFFTAG(2)         /* !multi-ink or hi-fi color */
+	TIFFTAG_INKNAMES               = TIFFTAG(333)       /* !ascii names of inks */
+	TIFFTAG_NUMBEROFINKS           = TIFFTAG(334)       /* !number of inks */
+	TIFFTAG_DOTRANGE               = TIFFTAG(336)       /* !0% and 100% dot codes */
+	TIFFTAG_TARGETPRINTER          = TIFFTAG(337)       /* !separation target */
+	TIFFTAG_EXTRASAMPLES           = TIFFTAG(338)       /* !info about extra samples */
+	EXTRASAMPLE_UNSPECIFIED        = TIFFTAG(0)         /* !unspecified data */
+	EXTRASAMPLE_ASSOCALPHA         = TIFFTAG(1)         /* !associated alpha data */
+	EXTRASAMPLE_UNASSALPHA         = TIFFTAG(2)         /* !unassociated alpha data */
+	TIFFTAG_SAMPLEFORMAT           = TIFFTAG(339)       /* !data sample format */
+	SAMPLEFORMAT_UINT              = SampleFormat(1)    /* !unsigned integer data */
+	SAMPLEFORMAT_INT               = SampleFormat(2)    /* !signed integer data */
+	SAMPLEFORMAT_IEEEFP            = SampleFormat(3)    /* !IEEE floating point data */
+	SAMPLEFORMAT_VOID              = SampleFormat(4)    /* !untyped data */
+	SAMPLEFORMAT_COMPLEXINT        = SampleFormat(5)    /* !complex signed int */
+	SAMPLEFORMAT_COMPLEXIEEEFP     = SampleFormat(6)    /* !complex ieee floating */
+	TIFFTAG_SMINSAMPLEVALUE        = TIFFTAG(340)       /* !variable MinSampleValue */
+	TIFFTAG_SMAXSAMPLEVALUE        = TIFFTAG(341)       /* !variable MaxSampleValue */
+	TIFFTAG_CLIPPATH               = TIFFTAG(343)       /* %ClipPath [Adobe TIFF technote 2] */
+	TIFFTAG_XCLIPPATHUNITS         = TIFFTAG(344)       /* %XClipPathUnits [Adobe TIFF technote 2] */
+	TIFFTAG_YCLIPPATHUNITS         = TIFFTAG(345)       /* %YClipPathUnits [Adobe TIFF technote 2] */
+	TIFFTAG_INDEXED                = TIFFTAG(346)       /* %Indexed [Adobe TIFF Technote 3] */
+	TIFFTAG_JPEGTABLES             = TIFFTAG(347)       /* %JPEG table stream */
+	TIFFTAG_OPIPROXY               = TIFFTAG(351)       /* %OPI Proxy [Adobe TIFF technote] */
 	/* Tags 400-435 are from the TIFF/FX spec */
 	TIFFTAG_GLOBALPARAMETERSIFD = TIFFTAG(400)    /* ! */
 	TIFFTAG_PROFILETYPE         = TIFFTAG(401)    /* ! */
@@ -0,0 +1,571 @@
+package libtiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CopyDirectoryOptions are the options of CopyDirectory.
+type CopyDirectoryOptions struct {
+	// SetTags is called after the tags have been copied and before the
+	// image data is written, to change tags of the copy, like
+	// TIFFTAG_PAGENUMBER.
+	SetTags func(ctx context.Context, dst *File) error
+	// OnDroppedTag is called for every tag that libtiff refuses to write,
+	// like a tag with a type or count that libtiff does not expect. The tag
+	// is left out of the copy.
+	OnDroppedTag func(tag DroppedTag)
+}
+
+// DroppedTag is a tag that CopyDirectory left out of the copy.
+type DroppedTag struct {
+	// Directory is the kind of directory of the tag, like "image" or
+	// "exif", see DirectoryInfo.
+	Directory string
+	Tag       TIFFTAG
+	// Err is the error that libtiff reported for the tag.
+	Err error
+}
+
+// CopyDirectory copies the current directory of f with its SubIFDs and its
+// EXIF and GPS directories to new directories in dst. The compressed strips
+// or tiles are copied with TIFFReadRawStrip and TIFFWriteRawStrip (or the
+// tile variants), so the image data is not decoded and compressed again.
+// The tags are copied as they are stored in the file, single rationals with
+// their exact fraction. Tags that libtiff writes itself, like the strip
+// offsets, are recreated, tags that libtiff refuses to write are left out
+// and reported to OnDroppedTag, as are Interoperability directories and
+// SubIFDs of SubIFDs.
+// Images with old-style JPEG compression can't be copied, and images with
+// more than 8 bits per sample can only be copied between files with the
+// same byte order, unless they are JPEG compressed.
+// f must have been opened with TIFFOpenFileFromReader or
+// TIFFOpenFileFromReadWriteSeeker, dst with TIFFOpenFileFromReadWriteSeeker
+// for writing or appending. The current directory of f is kept.
+func (f *File) CopyDirectory(ctx context.Context, dst *File, options *CopyDirectoryOptions) error {
+	if options == nil {
+		options = &CopyDirectoryOptions{}
+	}
+
+	directory, err := f.currentCopiedDirectory(ctx)
+	if err != nil {
+		return fmt.Errorf("could not copy directory: %w", err)
+	}
+	if err := f.checkCopy(ctx, dst, directory); err != nil {
+		return fmt.Errorf("could not copy directory: %w", err)
+	}
+
+	return f.writeDirectoryCopy(ctx, dst, directory, nil, options.OnDroppedTag, func(ctx context.Context, copied *copiedDirectory) error {
+		if copied == directory && options.SetTags != nil {
+			if err := options.SetTags(ctx, dst); err != nil {
+				return err
+			}
+		}
+		return f.copyImageData(ctx, dst, copied.info.Image)
+	})
+}
+
+// currentCopiedDirectory reads the current directory of f as it is stored.
+func (f *File) currentCopiedDirectory(ctx context.Context) (*copiedDirectory, error) {
+	offset, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		return nil, errors.New("current directory has not been written")
+	}
+
+	var directory *copiedDirectory
+	err = f.withDescriber(func(d *describer) error {
+		if _, err := d.readHeader(); err != nil {
+			return err
+		}
+		info, _, err := d.readDirectory(offset, "image")
+		if err != nil {
+			return err
+		}
+		directory, err = d.readCopiedDirectory(info)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return directory, nil
+}
+
+// writeDirectoryCopy writes directory, which is the current directory of f,
+// to dst with its EXIF and GPS directories and its SubIFDs, without the
+// tags in skip. Tags that libtiff refuses are passed to dropped when it is
+// not nil. writeImage is called for the directory and every SubIFD
+// when its tags have been set, with the SubIFD as the current directory of
+// f, and writes the image data. The current directory of f is kept.
+func (f *File) writeDirectoryCopy(ctx context.Context, dst *File, directory *copiedDirectory, skip map[TIFFTAG]bool, dropped func(tag DroppedTag), writeImage func(ctx context.Context, copied *copiedDirectory) error) error {
+	var err error
+	var exifOffset, gpsOffset uint64
+	if directory.exif != nil {
+		if err := dst.TIFFCreateEXIFDirectory(ctx); err != nil {
+			return err
+		}
+		exifOffset, err = dst.copyCustomDirectory(ctx, directory.exif, exifTagSetGetTypes, dropped)
+		if err != nil {
+			return err
+		}
+	}
+	if directory.gps != nil {
+		if err := dst.TIFFCreateGPSDirectory(ctx); err != nil {
+			return err
+		}
+		gpsOffset, err = dst.copyCustomDirectory(ctx, directory.gps, gpsTagSetGetTypes, dropped)
+		if err != nil {
+			return err
+		}
+	}
+	if directory.exif != nil || directory.gps != nil {
+		if err := dst.TIFFCreateDirectory(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := dst.copyTags(ctx, directory, tagSetGetTypes, skip, dropped); err != nil {
+		return err
+	}
+	if exifOffset != 0 {
+		if err := dst.TIFFSetFieldUint64_t(ctx, TIFFTAG_EXIFIFD, exifOffset); err != nil {
+			return err
+		}
+	}
+	if gpsOffset != 0 {
+		if err := dst.TIFFSetFieldUint64_t(ctx, TIFFTAG_GPSIFD, gpsOffset); err != nil {
+			return err
+		}
+	}
+	if len(directory.subIFDs) > 0 {
+		// libtiff fills in the offsets while the next directories are
+		// written.
+		if err := dst.TIFFSetFieldUint64Array(ctx, TIFFTAG_SUBIFD, make([]uint64, len(directory.subIFDs))); err != nil {
+			return err
+		}
+	}
+
+	if err := writeImage(ctx, directory); err != nil {
+		return err
+	}
+	if err := dst.TIFFWriteDirectory(ctx); err != nil {
+		return err
+	}
+
+	if len(directory.subIFDs) == 0 {
+		return nil
+	}
+
+	// Reading a SubIFD replaces the current directory, it is restored when
+	// the SubIFDs have been written.
+	defer f.TIFFSetSubDirectory(ctx, directory.info.Offset)
+	for _, subIFD := range directory.subIFDs {
+		if err := f.TIFFSetSubDirectory(ctx, subIFD.info.Offset); err != nil {
+			return err
+		}
+		if err := dst.copyTags(ctx, subIFD, tagSetGetTypes, skip, dropped); err != nil {
+			return err
+		}
+		if err := writeImage(ctx, subIFD); err != nil {
+			return err
+		}
+		if err := dst.TIFFWriteDirectory(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copiedDirectory is a directory that is copied by CopyDirectory.
+type copiedDirectory struct {
+	info *DirectoryInfo
+	// rationals are the exact values of the single RATIONAL and SRATIONAL
+	// tags.
+	rationals map[uint16]pendingRational
+	subIFDs   []*copiedDirectory
+	exif      *copiedDirectory
+	gps       *copiedDirectory
+}
+
+// readCopiedDirectory reads the exact rationals of the directory and its sub
+// directories.
+func (d *describer) readCopiedDirectory(info *DirectoryInfo) (*copiedDirectory, error) {
+	directory := &copiedDirectory{
+		info:      info,
+		rationals: map[uint16]pendingRational{},
+	}
+
+	for _, tag := range info.Tags {
+		if tag.Count != 1 || (tag.Type != "RATIONAL" && tag.Type != "SRATIONAL") {
+			continue
+		}
+		entry, err := d.findEntry(info.Offset, tag.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		value, err := d.readAt(entry.valueOffset, 8)
+		if err != nil {
+			return nil, err
+		}
+		if d.order.Uint32(value[4:]) == 0 {
+			continue
+		}
+		directory.rationals[tag.Tag] = pendingRational{
+			dataType:    entry.dataType,
+			numerator:   d.order.Uint32(value),
+			denominator: d.order.Uint32(value[4:]),
+		}
+	}
+
+	var err error
+	if info.Kind == "image" {
+		for i := range info.SubIFDs {
+			subIFD, err := d.readCopiedDirectory(&info.SubIFDs[i])
+			if err != nil {
+				return nil, err
+			}
+			directory.subIFDs = append(directory.subIFDs, subIFD)
+		}
+	}
+	if info.Exif != nil {
+		if directory.exif, err = d.readCopiedDirectory(info.Exif); err != nil {
+			return nil, err
+		}
+	}
+	if info.GPS != nil {
+		if directory.gps, err = d.readCopiedDirectory(info.GPS); err != nil {
+			return nil, err
+		}
+	}
+
+	return directory, nil
+}
+
+// checkCopy returns an error when the raw image data of the directory or
+// one of its SubIFDs can't be copied to dst as it is.
+func (f *File) checkCopy(ctx context.Context, dst *File, directory *copiedDirectory) error {
+	srcBigEndian, err := f.TIFFIsBigEndian(ctx)
+	if err != nil {
+		return err
+	}
+	dstBigEndian, err := dst.TIFFIsBigEndian(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, copied := range append([]*copiedDirectory{directory}, directory.subIFDs...) {
+		image := copied.info.Image
+		if image == nil {
+			continue
+		}
+		if image.Compression == COMPRESSION_OJPEG {
+			return errors.New("old-style JPEG compression is not supported")
+		}
+		if srcBigEndian == dstBigEndian || image.Compression == COMPRESSION_JPEG {
+			continue
+		}
+		for _, bits := range image.BitsPerSample {
+			if bits > 8 {
+				return fmt.Errorf("the %d bit samples depend on the byte order, which differs between the files", bits)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyImageData copies the raw strips or tiles of the current directory of
+// f to dst. Empty strips and tiles are not written.
+func (f *File) copyImageData(ctx context.Context, dst *File, image *ImageInfo) error {
+	if image == nil {
+		return nil
+	}
+
+	for i, byteCount := range image.ByteCounts {
+		if byteCount == 0 {
+			continue
+		}
+
+		if image.Tiled {
+			data, err := f.readRaw(ctx, "TIFFReadRawTile", "tile", uint32(i), int64(byteCount))
+			if err != nil {
+				return err
+			}
+			if err := dst.TIFFWriteRawTile(ctx, uint32(i), data); err != nil {
+				return err
+			}
+		} else {
+			data, err := f.readRaw(ctx, "TIFFReadRawStrip", "strip", uint32(i), int64(byteCount))
+			if err != nil {
+				return err
+			}
+			if err := dst.TIFFWriteRawStrip(ctx, uint32(i), data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyCustomDirectory copies the tags of an EXIF or GPS directory to the
+// custom directory that was created in f, writes it and returns its offset.
+func (f *File) copyCustomDirectory(ctx context.Context, directory *copiedDirectory, setGetTypes map[uint16]setGetType, dropped func(tag DroppedTag)) (uint64, error) {
+	if err := f.copyTags(ctx, directory, setGetTypes, nil, dropped); err != nil {
+		return 0, err
+	}
+	return f.TIFFWriteCustomDirectory(ctx)
+}
+
+// copySkippedTags are the tags of image directories that are not copied,
+// because libtiff writes them itself or CopyDirectory sets them.
+var copySkippedTags = map[TIFFTAG]bool{
+	TIFFTAG_STRIPOFFSETS:    true,
+	TIFFTAG_STRIPBYTECOUNTS: true,
+	TIFFTAG_TILEOFFSETS:     true,
+	TIFFTAG_TILEBYTECOUNTS:  true,
+	TIFFTAG_FREEOFFSETS:     true,
+	TIFFTAG_FREEBYTECOUNTS:  true,
+	TIFFTAG_SUBIFD:          true,
+	TIFFTAG_EXIFIFD:         true,
+	TIFFTAG_GPSIFD:          true,
+	TIFFTAG_JPEGIFOFFSET:    true,
+	TIFFTAG_JPEGIFBYTECOUNT: true,
+}
+
+// copyTags sets the tags of the directory in the current directory of f,
+// except the tags in skip. The tags that libtiff refuses are left out and
+// passed to dropped, other errors are returned.
+func (f *File) copyTags(ctx context.Context, directory *copiedDirectory, setGetTypes map[uint16]setGetType, skip map[TIFFTAG]bool, dropped func(tag DroppedTag)) error {
+	for _, tag := range directory.info.Tags {
+		if skip[TIFFTAG(tag.Tag)] {
+			continue
+		}
+		switch directory.info.Kind {
+		case "image", "subifd":
+			if copySkippedTags[TIFFTAG(tag.Tag)] {
+				continue
+			}
+		case "exif":
+			if TIFFTAG(tag.Tag) == TIFFTAG_INTEROPERABILITYIFD {
+				continue
+			}
+		}
+
+		var rational *pendingRational
+		if value, ok := directory.rationals[tag.Tag]; ok {
+			rational = &value
+		}
+		if err := f.copyTag(ctx, tag, setGetTypes, rational); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// libtiff reports why it refuses a tag, like a type or count
+			// it does not expect. Reading the error clears it.
+			refusal := f.GetError()
+			if refusal == nil {
+				return fmt.Errorf("could not copy tag %d: %w", tag.Tag, err)
+			}
+			if dropped != nil {
+				dropped(DroppedTag{Directory: directory.info.Kind, Tag: TIFFTAG(tag.Tag), Err: refusal})
+			}
+		}
+	}
+
+	return nil
+}
+
+// anonymousSetGetTypes are the TIFF_SETGET types libtiff gives the tags it
+// does not know, by TIFF data type.
+var anonymousSetGetTypes = map[TIFFDataType]setGetType{
+	TIFF_BYTE:      setGetC32Uint8,
+	TIFF_ASCII:     setGetC32ASCII,
+	TIFF_SHORT:     setGetC32Uint16,
+	TIFF_LONG:      setGetC32Uint32,
+	TIFF_RATIONAL:  setGetC32Float,
+	TIFF_SBYTE:     setGetC32Sint8,
+	TIFF_UNDEFINED: setGetC32Uint8,
+	TIFF_SSHORT:    setGetC32Sint16,
+	TIFF_SLONG:     setGetC32Sint32,
+	TIFF_SRATIONAL: setGetC32Float,
+	TIFF_FLOAT:     setGetC32Float,
+	TIFF_DOUBLE:    setGetC32Double,
+	TIFF_IFD:       setGetC32Uint32,
+	TIFF_LONG8:     setGetC32Uint64,
+	TIFF_SLONG8:    setGetC32Sint64,
+	TIFF_IFD8:      setGetC32IFD8,
+}
+
+// copyTag sets a tag that was read from another file. Tags that libtiff
+// does not know are registered first.
+func (f *File) copyTag(ctx context.Context, tag TagInfo, setGetTypes map[uint16]setGetType, rational *pendingRational) error {
+	tiffTag := TIFFTAG(tag.Tag)
+	setGet, ok := setGetTypes[tag.Tag]
+	if !ok {
+		dataType, ok := describeTypeNumber(tag.Type)
+		if !ok {
+			return fmt.Errorf("unknown data type %s", tag.Type)
+		}
+		if err := f.TIFFMergeAnonymousField(ctx, tiffTag, dataType); err != nil {
+			return err
+		}
+		setGet = anonymousSetGetTypes[dataType]
+	}
+
+	uints, floats := tagValueUints(tag.Value), tagValueFloats(tag.Value)
+	switch setGet {
+	case setGetASCII:
+		value, ok := tag.Value.(string)
+		if !ok {
+			return fmt.Errorf("tag %d is not ASCII", tag.Tag)
+		}
+		return f.TIFFSetFieldString(ctx, tiffTag, value)
+	case setGetUint8, setGetSint8, setGetUint16, setGetSint16, setGetUint32, setGetSint32, setGetInt:
+		if len(uints) == 0 {
+			return fmt.Errorf("tag %d has no value", tag.Tag)
+		}
+		// libtiff reads all of these as an int argument.
+		return f.TIFFSetFieldUint32_t(ctx, tiffTag, uint32(uints[0]))
+	case setGetUint64, setGetSint64, setGetIFD8:
+		if len(uints) == 0 {
+			return fmt.Errorf("tag %d has no value", tag.Tag)
+		}
+		return f.TIFFSetFieldUint64_t(ctx, tiffTag, uints[0])
+	case setGetFloat, setGetDouble:
+		if rational != nil {
+			return f.setRational(ctx, tiffTag, floats[0], *rational)
+		}
+		if len(floats) == 0 {
+			return fmt.Errorf("tag %d has no value", tag.Tag)
+		}
+		return f.TIFFSetFieldDouble(ctx, tiffTag, floats[0])
+	case setGetUint16Pair:
+		if len(uints) != 2 {
+			return fmt.Errorf("tag %d does not have 2 values", tag.Tag)
+		}
+		return f.TIFFSetFieldTwoUint16(ctx, tiffTag, uint16(uints[0]), uint16(uints[1]))
+	case setGetOther:
+		// TIFFTAG_COLORMAP and TIFFTAG_TRANSFERFUNCTION, which hold an
+		// array per channel.
+		values := make([]uint16, len(uints))
+		for i, value := range uints {
+			values[i] = uint16(value)
+		}
+		if len(values)%3 != 0 {
+			return f.TIFFSetFieldThreeUint16Arrays(ctx, tiffTag, values, values, values)
+		}
+		n := len(values) / 3
+		return f.TIFFSetFieldThreeUint16Arrays(ctx, tiffTag, values[:n], values[n:2*n], values[2*n:])
+	}
+
+	if setGet < setGetC0ASCII || setGet > setGetC32IFD8 {
+		return fmt.Errorf("tag %d can't be set", tag.Tag)
+	}
+
+	// The array types repeat for C0, C16 and C32, the element type is the
+	// same as that of the matching C0 type.
+	var data []byte
+	count := len(uints)
+	switch setGetC0ASCII + (setGet-setGetC0ASCII)%(setGetC16ASCII-setGetC0ASCII) {
+	case setGetC0ASCII:
+		value, _ := tag.Value.(string)
+		data = append([]byte(value), 0)
+		count = len(data)
+	case setGetC0Uint8, setGetC0Sint8:
+		data = make([]byte, len(uints))
+		for i, value := range uints {
+			data[i] = byte(value)
+		}
+	case setGetC0Uint16, setGetC0Sint16:
+		values := make([]uint16, len(uints))
+		for i, value := range uints {
+			values[i] = uint16(value)
+		}
+		data = encodeUint16Array(values)
+	case setGetC0Uint32, setGetC0Sint32:
+		values := make([]uint32, len(uints))
+		for i, value := range uints {
+			values[i] = uint32(value)
+		}
+		data = encodeUint32Array(values)
+	case setGetC0Float:
+		values := make([]float32, len(floats))
+		for i, value := range floats {
+			values[i] = float32(value)
+		}
+		data = encodeFloatArray(values)
+	case setGetC0Double:
+		data = encodeDoubleArray(floats)
+	default:
+		data = encodeUint64Array(uints)
+	}
+
+	if setGet <= setGetC0IFD8 {
+		return f.setFieldArray(ctx, tiffTag, data)
+	}
+	return f.setFieldCountedArray(ctx, tiffTag, uint32(count), data)
+}
+
+// describeTypeNumber returns the TIFF data type with the name Describe
+// gives it, like "SHORT".
+func describeTypeNumber(name string) (TIFFDataType, bool) {
+	for number, dataType := range describeTypes {
+		if dataType.name == name {
+			return TIFFDataType(number), true
+		}
+	}
+	return 0, false
+}
+
+// tagValueUints returns the value of a tag from Describe as unsigned
+// integers, signed values are converted as two's complement.
+func tagValueUints(value any) []uint64 {
+	var values []uint64
+	switch value := value.(type) {
+	case []uint64:
+		values = value
+	case []int64:
+		for _, v := range value {
+			values = append(values, uint64(v))
+		}
+	case []float64:
+		for _, v := range value {
+			values = append(values, uint64(v))
+		}
+	case []byte:
+		for _, v := range value {
+			values = append(values, uint64(v))
+		}
+	}
+	return values
+}
+
+// tagValueFloats returns the value of a tag from Describe as floats.
+func tagValueFloats(value any) []float64 {
+	var values []float64
+	switch value := value.(type) {
+	case []float64:
+		values = value
+	case []uint64:
+		for _, v := range value {
+			values = append(values, float64(v))
+		}
+	case []int64:
+		for _, v := range value {
+			values = append(values, float64(v))
+		}
+	case []byte:
+		for _, v := range value {
+			values = append(values, float64(v))
+		}
+	}
+	return values
+}
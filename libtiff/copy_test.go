@@ -0,0 +1,285 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CopyDirectory", func() {
+	ctx := context.Background()
+
+	// writeCopyTestFile writes an image per options and returns the file
+	// opened for reading.
+	writeCopyTestFile := func(img image.Image, options ...*libtiff.FromGoImageOptions) *libtiff.File {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		for _, pageOptions := range options {
+			Expect(tiffFile.FromGoImage(ctx, img, pageOptions)).To(Succeed())
+		}
+		return reopenTempTestFile(ctx, tiffFile, tmpFile)
+	}
+
+	// copyAll copies every directory of src to a new file and returns the
+	// new file opened for reading.
+	copyAll := func(src *libtiff.File, options *libtiff.CopyDirectoryOptions) *libtiff.File {
+		dst, tmpFile := openTempTestFile(ctx)
+		count, err := src.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		for i := uint32(0); i < count; i++ {
+			Expect(src.TIFFSetDirectory(ctx, i)).To(Succeed())
+			Expect(src.CopyDirectory(ctx, dst, options)).To(Succeed())
+		}
+		return reopenTempTestFile(ctx, dst, tmpFile)
+	}
+
+	// copiedTags returns the tags of the directory without the tags that
+	// hold offsets, which differ between the files.
+	copiedTags := func(directory libtiff.DirectoryInfo) []libtiff.TagInfo {
+		var tags []libtiff.TagInfo
+		for _, tag := range directory.Tags {
+			switch libtiff.TIFFTAG(tag.Tag) {
+			case libtiff.TIFFTAG_STRIPOFFSETS, libtiff.TIFFTAG_TILEOFFSETS, libtiff.TIFFTAG_SUBIFD, libtiff.TIFFTAG_EXIFIFD, libtiff.TIFFTAG_GPSIFD:
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		return tags
+	}
+
+	// expectSameRawData checks that the strips or tiles of the current
+	// directories are identical.
+	expectSameRawData := func(src, dst *libtiff.File) {
+		tiled, err := src.TIFFIsTiled(ctx)
+		Expect(err).To(BeNil())
+		var count uint32
+		if tiled {
+			count, err = src.TIFFNumberOfTiles(ctx)
+		} else {
+			count, err = src.TIFFNumberOfStrips(ctx)
+		}
+		Expect(err).To(BeNil())
+		Expect(count).To(BeNumerically(">", 0))
+
+		for i := uint32(0); i < count; i++ {
+			var expected, actual []byte
+			if tiled {
+				expected, err = src.TIFFReadRawTile(ctx, i)
+				Expect(err).To(BeNil())
+				actual, err = dst.TIFFReadRawTile(ctx, i)
+			} else {
+				expected, err = src.TIFFReadRawStrip(ctx, i)
+				Expect(err).To(BeNil())
+				actual, err = dst.TIFFReadRawStrip(ctx, i)
+			}
+			Expect(err).To(BeNil())
+			Expect(actual).To(Equal(expected))
+		}
+	}
+
+	It("copies the image data and all tags", func() {
+		altitude := 3.5
+		src := writeCopyTestFile(createTestRGBA(60, 40),
+			&libtiff.FromGoImageOptions{
+				Compression:         libtiff.COMPRESSION_LZW,
+				Predictor:           libtiff.PREDICTOR_HORIZONTAL,
+				XResolutionRational: libtiff.Rational{Numerator: 7200, Denominator: 24},
+				YResolutionRational: libtiff.Rational{Numerator: 1, Denominator: 3},
+				ResolutionUnit:      libtiff.RESUNIT_INCH,
+				Artist:              "artist",
+				RowsPerStrip:        8,
+				Exif:                &libtiff.Exif{FNumber: 2.8, LensModel: "lens"},
+				GPS:                 &libtiff.GPS{Latitude: 52.1, Longitude: 4.3, Altitude: &altitude},
+				ICCProfile:          testICCProfile(560),
+			},
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_JPEG, Quality: 80},
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_ADOBE_DEFLATE, TileWidth: 16, TileHeight: 16},
+			&libtiff.FromGoImageOptions{
+				GeoReference: &libtiff.GeoReference{
+					GeoTransform:  &[6]float64{500000, 0.5, 0, 5800000, 0, -0.5},
+					ProjectedEPSG: 32631,
+					GeoKeys: []libtiff.GeoKey{
+						{ID: libtiff.GTCitationGeoKey, ASCII: "WGS 84 / UTM zone 31N"},
+						{ID: libtiff.GeogSemiMajorAxisGeoKey, Doubles: []float64{6378137}},
+					},
+					NoData: "-9999",
+				},
+			},
+		)
+		dst := copyAll(src, nil)
+
+		srcInfo, err := src.Describe(ctx)
+		Expect(err).To(BeNil())
+		dstInfo, err := dst.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(dstInfo.Directories).To(HaveLen(len(srcInfo.Directories)))
+
+		for i := range srcInfo.Directories {
+			Expect(copiedTags(dstInfo.Directories[i])).To(Equal(copiedTags(srcInfo.Directories[i])))
+
+			Expect(src.TIFFSetDirectory(ctx, uint32(i))).To(Succeed())
+			Expect(dst.TIFFSetDirectory(ctx, uint32(i))).To(Succeed())
+			expectSameRawData(src, dst)
+		}
+
+		first := dstInfo.Directories[0]
+		Expect(first.Exif).NotTo(BeNil())
+		Expect(copiedTags(*first.Exif)).To(Equal(copiedTags(*srcInfo.Directories[0].Exif)))
+		Expect(first.GPS).NotTo(BeNil())
+		Expect(copiedTags(*first.GPS)).To(Equal(copiedTags(*srcInfo.Directories[0].GPS)))
+
+		Expect(dst.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		xResolution, err := dst.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 7200, Denominator: 24}))
+		yResolution, err := dst.GetRational(ctx, libtiff.TIFFTAG_YRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(yResolution).To(Equal(libtiff.Rational{Numerator: 1, Denominator: 3}))
+
+		goImage, imgCleanup, err := dst.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(goImage.(*image.RGBA).Pix).To(Equal(createTestRGBA(60, 40).Pix))
+	})
+
+	It("copies bilevel fax images", func() {
+		src := writeCopyTestFile(createTestGray(64, 32), &libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_CCITTFAX4})
+		dst := copyAll(src, nil)
+
+		compression, err := dst.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_COMPRESSION)
+		Expect(err).To(BeNil())
+		Expect(libtiff.Compression(compression)).To(Equal(libtiff.COMPRESSION_CCITTFAX4))
+		expectSameRawData(src, dst)
+	})
+
+	It("copies SubIFDs", func() {
+		src := writeCopyTestFile(createTestGray(64, 64),
+			&libtiff.FromGoImageOptions{Overviews: &libtiff.Overviews{Factors: []int{2, 4}, SubIFDs: true}},
+			&libtiff.FromGoImageOptions{},
+		)
+		dst := copyAll(src, nil)
+
+		count, err := dst.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(uint32(2)))
+
+		srcInfo, err := src.Describe(ctx)
+		Expect(err).To(BeNil())
+		dstInfo, err := dst.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(dstInfo.Directories[0].SubIFDs).To(HaveLen(2))
+		for i, subIFD := range dstInfo.Directories[0].SubIFDs {
+			Expect(copiedTags(subIFD)).To(Equal(copiedTags(srcInfo.Directories[0].SubIFDs[i])))
+		}
+		Expect(dstInfo.Directories[1].SubIFDs).To(BeEmpty())
+	})
+
+	It("changes tags of the copy", func() {
+		src := writeCopyTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{PageNumber: 4, TotalPages: 9})
+		dst := copyAll(src, &libtiff.CopyDirectoryOptions{
+			SetTags: func(ctx context.Context, dst *libtiff.File) error {
+				return dst.TIFFSetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER, 0, 1)
+			},
+		})
+
+		page, total, err := dst.TIFFGetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER)
+		Expect(err).To(BeNil())
+		Expect([]uint16{page, total}).To(Equal([]uint16{0, 1}))
+	})
+
+	It("reports the tags that libtiff refuses to write", func() {
+		// An uncompressed 4x4 gray image with a Predictor, which libtiff
+		// only knows for the compressions that use it.
+		entries := [][3]uint32{
+			{256, 3, 4}, {257, 3, 4}, {258, 3, 8}, {259, 3, 1}, {262, 3, 1},
+			{273, 4, 0}, {277, 3, 1}, {278, 3, 4}, {279, 4, 16}, {317, 3, 2},
+		}
+		dataOffset := uint32(8 + 2 + len(entries)*12 + 4)
+		file := &bytes.Buffer{}
+		file.WriteString("II")
+		binary.Write(file, binary.LittleEndian, []uint16{42})
+		binary.Write(file, binary.LittleEndian, []uint32{8})
+		binary.Write(file, binary.LittleEndian, uint16(len(entries)))
+		for _, entry := range entries {
+			if entry[0] == 273 {
+				entry[2] = dataOffset
+			}
+			binary.Write(file, binary.LittleEndian, []uint16{uint16(entry[0]), uint16(entry[1])})
+			binary.Write(file, binary.LittleEndian, []uint32{1, entry[2]})
+		}
+		binary.Write(file, binary.LittleEndian, []uint32{0})
+		file.Write(make([]byte, 16))
+
+		src, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", bytes.NewReader(file.Bytes()), uint64(file.Len()), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(src.Close, ctx)
+
+		var dropped []libtiff.DroppedTag
+		dst := copyAll(src, &libtiff.CopyDirectoryOptions{
+			OnDroppedTag: func(tag libtiff.DroppedTag) {
+				dropped = append(dropped, tag)
+			},
+		})
+
+		Expect(dropped).To(HaveLen(1))
+		Expect(dropped[0].Directory).To(Equal("image"))
+		Expect(dropped[0].Tag).To(Equal(libtiff.TIFFTAG_PREDICTOR))
+		Expect(dropped[0].Err).To(MatchError(ContainSubstring("Unknown tag 317")))
+		info, err := dst.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(copiedTags(info.Directories[0])).To(HaveLen(len(entries) - 2))
+		expectSameRawData(src, dst)
+	})
+
+	It("copies the tags of big-endian files", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-copy-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, tmpFile.Name())
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenWrite, ByteOrder: libtiff.ByteOrderBigEndian},
+		})
+		Expect(err).To(BeNil())
+		Expect(tiffFile.FromGoImage(ctx, createTestRGBA(20, 10), &libtiff.FromGoImageOptions{
+			Compression:         libtiff.COMPRESSION_LZW,
+			XResolutionRational: libtiff.Rational{Numerator: 300, Denominator: 7},
+			Exif:                &libtiff.Exif{ExposureTime: 0.01, ISOSpeedRatings: []uint16{200}},
+		})).To(Succeed())
+		src := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		dst := copyAll(src, nil)
+
+		srcInfo, err := src.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(srcInfo.ByteOrder).To(Equal("big-endian"))
+		dstInfo, err := dst.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(dstInfo.ByteOrder).To(Equal("little-endian"))
+		Expect(copiedTags(dstInfo.Directories[0])).To(Equal(copiedTags(srcInfo.Directories[0])))
+		Expect(copiedTags(*dstInfo.Directories[0].Exif)).To(Equal(copiedTags(*srcInfo.Directories[0].Exif)))
+
+		xResolution, err := dst.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 300, Denominator: 7}))
+	})
+
+	It("refuses to copy 16-bit samples to a file with another byte order", func() {
+		src := writeCopyTestFile(createTestGray16(16, 16), &libtiff.FromGoImageOptions{})
+
+		tmpFile, err := os.CreateTemp("", "libtiff-copy-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, tmpFile.Name())
+		defer tmpFile.Close()
+		dst, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenWrite, ByteOrder: libtiff.ByteOrderBigEndian},
+		})
+		Expect(err).To(BeNil())
+		defer dst.Close(ctx)
+
+		Expect(src.CopyDirectory(ctx, dst, nil)).To(MatchError("could not copy directory: the 16 bit samples depend on the byte order, which differs between the files"))
+	})
+})
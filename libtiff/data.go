@@ -2,7 +2,9 @@ package libtiff
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"math"
 	"strings"
 )
 
@@ -86,3 +88,108 @@ func (i *Instance) free(ctx context.Context, pointer uint64) error {
 	}
 	return nil
 }
+
+// newBuffer copies data into newly allocated WASM memory. The caller is
+// responsible for freeing the returned pointer.
+func (i *Instance) newBuffer(ctx context.Context, data []byte) (uint64, error) {
+	size := uint64(len(data))
+	if size == 0 {
+		// Always allocate something so we never pass a null pointer.
+		size = 1
+	}
+
+	pointer, err := i.malloc(ctx, size)
+	if err != nil {
+		return 0, err
+	}
+
+	// Prevent concurrent memory usage.
+	i.internalInstance.CallLock.Lock()
+	defer i.internalInstance.CallLock.Unlock()
+
+	if !i.internalInstance.Module.Memory().Write(uint32(pointer), data) {
+		return 0, errors.New("could not write buffer data")
+	}
+
+	return pointer, nil
+}
+
+func encodeUint16Array(values []uint16) []byte {
+	data := make([]byte, len(values)*2)
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(data[i*2:], v)
+	}
+	return data
+}
+
+func decodeUint16Array(data []byte) []uint16 {
+	values := make([]uint16, len(data)/2)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return values
+}
+
+func encodeUint32Array(values []uint32) []byte {
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
+
+func decodeUint32Array(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return values
+}
+
+func encodeUint64Array(values []uint64) []byte {
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*8:], v)
+	}
+	return data
+}
+
+func decodeUint64Array(data []byte) []uint64 {
+	values := make([]uint64, len(data)/8)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return values
+}
+
+func encodeFloatArray(values []float32) []byte {
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeFloatArray(data []byte) []float32 {
+	values := make([]float32, len(data)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return values
+}
+
+func encodeDoubleArray(values []float64) []byte {
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(v))
+	}
+	return data
+}
+
+func decodeDoubleArray(data []byte) []float64 {
+	values := make([]float64, len(data)/8)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	return values
+}
@@ -0,0 +1,567 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// FileInfo describes the structure and all tags of a TIFF file.
+type FileInfo struct {
+	// ByteOrder is "little-endian" or "big-endian".
+	ByteOrder string `json:"byte_order"`
+	BigTIFF   bool   `json:"bigtiff"`
+	// Directories are the directories of the main IFD chain, in file order.
+	Directories []DirectoryInfo `json:"directories"`
+}
+
+// DirectoryInfo describes a single directory.
+type DirectoryInfo struct {
+	// Kind is "image", "subifd", "exif", "gps" or "interoperability".
+	Kind   string    `json:"kind"`
+	Offset uint64    `json:"offset"`
+	Tags   []TagInfo `json:"tags"`
+	// Image is the image layout, only set for image and SubIFD directories
+	// that have an image width.
+	Image *ImageInfo `json:"image,omitempty"`
+
+	SubIFDs          []DirectoryInfo `json:"sub_ifds,omitempty"`
+	Exif             *DirectoryInfo  `json:"exif,omitempty"`
+	GPS              *DirectoryInfo  `json:"gps,omitempty"`
+	Interoperability *DirectoryInfo  `json:"interoperability,omitempty"`
+}
+
+// TagInfo is a single tag of a directory.
+type TagInfo struct {
+	Tag uint16 `json:"tag"`
+	// Name is the libtiff name of the tag, empty for unknown tags.
+	Name string `json:"name,omitempty"`
+	// Type is the TIFF data type, like "SHORT" or "ASCII".
+	Type  string `json:"type"`
+	Count uint64 `json:"count"`
+	// Value is a string for ASCII, a []byte for BYTE and UNDEFINED, a
+	// []uint64 for unsigned types, a []int64 for signed types and a
+	// []float64 for rational and floating point types.
+	Value any `json:"value"`
+}
+
+// ImageInfo is the image layout of a directory.
+type ImageInfo struct {
+	Width           uint32          `json:"width"`
+	Height          uint32          `json:"height"`
+	BitsPerSample   []uint16        `json:"bits_per_sample"`
+	SamplesPerPixel uint16          `json:"samples_per_pixel"`
+	SampleFormat    SampleFormat    `json:"sample_format"`
+	Photometric     *Photometric    `json:"photometric,omitempty"`
+	Compression     Compression     `json:"compression"`
+	PlanarConfig    uint16          `json:"planar_config"`
+	Orientation     Orientation     `json:"orientation"`
+	XResolution     float64         `json:"x_resolution,omitempty"`
+	YResolution     float64         `json:"y_resolution,omitempty"`
+	ResolutionUnit  *ResolutionUnit `json:"resolution_unit,omitempty"`
+	Tiled           bool            `json:"tiled"`
+	TileWidth       uint32          `json:"tile_width,omitempty"`
+	TileHeight      uint32          `json:"tile_height,omitempty"`
+	RowsPerStrip    uint32          `json:"rows_per_strip,omitempty"`
+	// Offsets and ByteCounts are the strip or tile offsets and sizes.
+	Offsets    []uint64  `json:"offsets"`
+	ByteCounts []uint64  `json:"byte_counts"`
+	Codec      CodecInfo `json:"codec"`
+}
+
+// CodecInfo contains the codec parameters of a directory.
+type CodecInfo struct {
+	Predictor        Predictor `json:"predictor,omitempty"`
+	FillOrder        uint16    `json:"fill_order,omitempty"`
+	Group3Options    *uint32   `json:"group3_options,omitempty"`
+	Group4Options    *uint32   `json:"group4_options,omitempty"`
+	JPEGTablesSize   int       `json:"jpeg_tables_size,omitempty"`
+	YCbCrSubsampling []uint16  `json:"ycbcr_subsampling,omitempty"`
+}
+
+// describeTypes are the names and sizes of the TIFF data types.
+var describeTypes = map[uint16]struct {
+	name string
+	size uint64
+}{
+	1:  {"BYTE", 1},
+	2:  {"ASCII", 1},
+	3:  {"SHORT", 2},
+	4:  {"LONG", 4},
+	5:  {"RATIONAL", 8},
+	6:  {"SBYTE", 1},
+	7:  {"UNDEFINED", 1},
+	8:  {"SSHORT", 2},
+	9:  {"SLONG", 4},
+	10: {"SRATIONAL", 8},
+	11: {"FLOAT", 4},
+	12: {"DOUBLE", 8},
+	13: {"IFD", 4},
+	16: {"LONG8", 8},
+	17: {"SLONG8", 8},
+	18: {"IFD8", 8},
+}
+
+// maxDescribeDirectories limits the number of directories that are read, to
+// guard against loops and corrupt files.
+const maxDescribeDirectories = 65536
+
+// Describe reads the structure of the file and all tags of every directory,
+// including SubIFDs and the EXIF, GPS and Interoperability directories. The
+// result can be passed to json.Marshal.
+// The file is read as it is stored, tags that have been set but not written
+// are not included. The file must have been opened with
+// TIFFOpenFileFromReader or TIFFOpenFileFromReadWriteSeeker.
+func (f *File) Describe(ctx context.Context) (*FileInfo, error) {
+	var info *FileInfo
+	err := f.withDescriber(func(d *describer) error {
+		var err error
+		info, err = d.describe()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not describe file: %w", err)
+	}
+
+	return info, nil
+}
+
+// withDescriber calls fn with a describer that reads the underlying file
+// directly, and restores the position of the file afterwards.
+func (f *File) withDescriber(fn func(d *describer) error) error {
+	if f.readerFile == nil || f.readerFile.ReadWriteSeeker == nil {
+		return errors.New("file was not opened from a reader")
+	}
+
+	reader := f.readerFile.ReadWriteSeeker
+	position, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	// libtiff seeks before every read, but restore the position anyway for
+	// readers that are shared with other code.
+	defer reader.Seek(position, io.SeekStart)
+
+	d, err := newDescriber(reader)
+	if err != nil {
+		return err
+	}
+	return fn(d)
+}
+
+type describer struct {
+	reader io.ReadSeeker
+	// size is the length of the stream, nothing is read beyond it.
+	size    uint64
+	order   binary.ByteOrder
+	bigTIFF bool
+	visited map[uint64]bool
+}
+
+func newDescriber(reader io.ReadSeeker) (*describer, error) {
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &describer{reader: reader, size: uint64(size), visited: map[uint64]bool{}}, nil
+}
+
+// readAt reads size bytes at offset. Reads beyond the end of the stream are
+// refused before the buffer is allocated, so corrupt offsets and counts
+// can't make it allocate more than the file size.
+func (d *describer) readAt(offset, size uint64) ([]byte, error) {
+	if size > d.size || offset > d.size-size {
+		return nil, fmt.Errorf("could not read %d bytes at offset %d: beyond the end of the file of %d bytes", size, offset, d.size)
+	}
+	if _, err := d.reader.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(d.reader, data); err != nil {
+		return nil, fmt.Errorf("could not read %d bytes at offset %d: %w", size, offset, err)
+	}
+	return data, nil
+}
+
+func (d *describer) describe() (*FileInfo, error) {
+	offset, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &FileInfo{
+		ByteOrder: "little-endian",
+		BigTIFF:   d.bigTIFF,
+	}
+	if d.order == binary.BigEndian {
+		info.ByteOrder = "big-endian"
+	}
+
+	info.Directories = []DirectoryInfo{}
+	for offset != 0 {
+		directory, next, err := d.readDirectory(offset, "image")
+		if err != nil {
+			return nil, err
+		}
+		info.Directories = append(info.Directories, *directory)
+		offset = next
+	}
+
+	return info, nil
+}
+
+// readHeader reads the byte order and format of the file and returns the
+// offset of the first directory.
+func (d *describer) readHeader() (uint64, error) {
+	header, err := d.readAt(0, 8)
+	if err != nil {
+		return 0, err
+	}
+
+	switch string(header[:2]) {
+	case "II":
+		d.order = binary.LittleEndian
+	case "MM":
+		d.order = binary.BigEndian
+	default:
+		return 0, errors.New("invalid TIFF byte order")
+	}
+
+	switch d.order.Uint16(header[2:]) {
+	case 42:
+		return uint64(d.order.Uint32(header[4:])), nil
+	case 43:
+		d.bigTIFF = true
+		header, err = d.readAt(8, 8)
+		if err != nil {
+			return 0, err
+		}
+		return d.order.Uint64(header), nil
+	default:
+		return 0, errors.New("invalid TIFF magic number")
+	}
+}
+
+// readDirectory reads the directory at the given offset with its sub
+// directories, and returns the offset of the next directory.
+func (d *describer) readDirectory(offset uint64, kind string) (*DirectoryInfo, uint64, error) {
+	if d.visited[offset] {
+		return nil, 0, fmt.Errorf("directory loop at offset %d", offset)
+	}
+	if len(d.visited) >= maxDescribeDirectories {
+		return nil, 0, errors.New("too many directories")
+	}
+	d.visited[offset] = true
+
+	countSize, entrySize, offsetSize := uint64(2), uint64(12), uint64(4)
+	if d.bigTIFF {
+		countSize, entrySize, offsetSize = 8, 20, 8
+	}
+
+	data, err := d.readAt(offset, countSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := uint64(d.order.Uint16(data))
+	if d.bigTIFF {
+		count = d.order.Uint64(data)
+	}
+	if count > math.MaxUint16 {
+		return nil, 0, fmt.Errorf("invalid directory entry count %d at offset %d", count, offset)
+	}
+
+	data, err = d.readAt(offset+countSize, count*entrySize+offsetSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	directory := &DirectoryInfo{
+		Kind:   kind,
+		Offset: offset,
+		Tags:   []TagInfo{},
+	}
+	for i := uint64(0); i < count; i++ {
+		tag, err := d.readTag(data[i*entrySize:])
+		if err != nil {
+			return nil, 0, err
+		}
+		directory.Tags = append(directory.Tags, *tag)
+	}
+
+	var next uint64
+	if d.bigTIFF {
+		next = d.order.Uint64(data[count*entrySize:])
+	} else {
+		next = uint64(d.order.Uint32(data[count*entrySize:]))
+	}
+
+	if err := d.readSubDirectories(directory); err != nil {
+		return nil, 0, err
+	}
+	if kind == "image" || kind == "subifd" {
+		directory.Image = describeImage(directory.Tags)
+	}
+
+	return directory, next, nil
+}
+
+func (d *describer) readTag(entry []byte) (*TagInfo, error) {
+	tag := &TagInfo{
+		Tag: d.order.Uint16(entry),
+	}
+	typ := d.order.Uint16(entry[2:])
+	var valueField []byte
+	if d.bigTIFF {
+		tag.Count = d.order.Uint64(entry[4:])
+		valueField = entry[12:20]
+	} else {
+		tag.Count = uint64(d.order.Uint32(entry[4:]))
+		valueField = entry[8:12]
+	}
+
+	dataType, ok := describeTypes[typ]
+	if !ok {
+		tag.Type = fmt.Sprintf("%d", typ)
+		return tag, nil
+	}
+	tag.Type = dataType.name
+
+	if tag.Count > d.size/dataType.size {
+		return nil, fmt.Errorf("invalid count %d of tag %d", tag.Count, tag.Tag)
+	}
+	size := dataType.size * tag.Count
+	var value []byte
+	if size <= uint64(len(valueField)) {
+		value = valueField[:size]
+	} else {
+		valueOffset := uint64(d.order.Uint32(valueField))
+		if d.bigTIFF {
+			valueOffset = d.order.Uint64(valueField)
+		}
+		var err error
+		value, err = d.readAt(valueOffset, size)
+		if err != nil {
+			return nil, fmt.Errorf("could not read value of tag %d: %w", tag.Tag, err)
+		}
+	}
+	tag.Value = d.decodeValue(typ, tag.Count, value)
+
+	return tag, nil
+}
+
+func (d *describer) decodeValue(typ uint16, count uint64, value []byte) any {
+	switch typ {
+	case 2:
+		return strings.TrimRight(string(value), "\x00")
+	case 1, 7:
+		return value
+	case 3, 4, 13, 16, 18:
+		values := make([]uint64, count)
+		for i := range values {
+			switch typ {
+			case 3:
+				values[i] = uint64(d.order.Uint16(value[i*2:]))
+			case 4, 13:
+				values[i] = uint64(d.order.Uint32(value[i*4:]))
+			default:
+				values[i] = d.order.Uint64(value[i*8:])
+			}
+		}
+		return values
+	case 6, 8, 9, 17:
+		values := make([]int64, count)
+		for i := range values {
+			switch typ {
+			case 6:
+				values[i] = int64(int8(value[i]))
+			case 8:
+				values[i] = int64(int16(d.order.Uint16(value[i*2:])))
+			case 9:
+				values[i] = int64(int32(d.order.Uint32(value[i*4:])))
+			default:
+				values[i] = int64(d.order.Uint64(value[i*8:]))
+			}
+		}
+		return values
+	default:
+		values := make([]float64, count)
+		for i := range values {
+			switch typ {
+			case 5:
+				numerator, denominator := d.order.Uint32(value[i*8:]), d.order.Uint32(value[i*8+4:])
+				if denominator != 0 {
+					values[i] = float64(numerator) / float64(denominator)
+				}
+			case 10:
+				numerator, denominator := int32(d.order.Uint32(value[i*8:])), int32(d.order.Uint32(value[i*8+4:]))
+				if denominator != 0 {
+					values[i] = float64(numerator) / float64(denominator)
+				}
+			case 11:
+				values[i] = float64(math.Float32frombits(d.order.Uint32(value[i*4:])))
+			case 12:
+				values[i] = math.Float64frombits(d.order.Uint64(value[i*8:]))
+			}
+		}
+		return values
+	}
+}
+
+// readSubDirectories reads the SubIFDs and the EXIF, GPS and
+// Interoperability directories that are referenced by the tags.
+func (d *describer) readSubDirectories(directory *DirectoryInfo) error {
+	names := tagNames
+	switch directory.Kind {
+	case "exif":
+		names = exifTagNames
+	case "gps":
+		names = gpsTagNames
+	case "interoperability":
+		names = nil
+	}
+
+	for i := range directory.Tags {
+		tag := &directory.Tags[i]
+		tag.Name = names[tag.Tag]
+		offsets, _ := tag.Value.([]uint64)
+		if len(offsets) == 0 {
+			continue
+		}
+
+		var kind string
+		switch {
+		case directory.Kind == "image" || directory.Kind == "subifd":
+			switch TIFFTAG(tag.Tag) {
+			case TIFFTAG_SUBIFD:
+				kind = "subifd"
+			case TIFFTAG_EXIFIFD:
+				kind = "exif"
+			case TIFFTAG_GPSIFD:
+				kind = "gps"
+			}
+		case directory.Kind == "exif" && TIFFTAG(tag.Tag) == TIFFTAG_INTEROPERABILITYIFD:
+			kind = "interoperability"
+		}
+		if kind == "" {
+			continue
+		}
+
+		for _, offset := range offsets {
+			if offset == 0 {
+				continue
+			}
+			subDirectory, _, err := d.readDirectory(offset, kind)
+			if err != nil {
+				return err
+			}
+			switch kind {
+			case "subifd":
+				directory.SubIFDs = append(directory.SubIFDs, *subDirectory)
+			case "exif":
+				directory.Exif = subDirectory
+			case "gps":
+				directory.GPS = subDirectory
+			case "interoperability":
+				directory.Interoperability = subDirectory
+			}
+		}
+	}
+	return nil
+}
+
+// describeImage returns the image layout from the tags, with the TIFF
+// defaults for tags that are not set.
+func describeImage(tags []TagInfo) *ImageInfo {
+	uints := map[TIFFTAG][]uint64{}
+	floats := map[TIFFTAG][]float64{}
+	for _, tag := range tags {
+		switch value := tag.Value.(type) {
+		case []uint64:
+			uints[TIFFTAG(tag.Tag)] = value
+		case []float64:
+			floats[TIFFTAG(tag.Tag)] = value
+		case []byte:
+			if TIFFTAG(tag.Tag) == TIFFTAG_JPEGTABLES {
+				uints[TIFFTAG_JPEGTABLES] = []uint64{uint64(len(value))}
+			}
+		}
+	}
+	first := func(tag TIFFTAG, defaultValue uint64) uint64 {
+		if values := uints[tag]; len(values) > 0 {
+			return values[0]
+		}
+		return defaultValue
+	}
+
+	width, ok := uints[TIFFTAG_IMAGEWIDTH]
+	if !ok || len(width) == 0 {
+		return nil
+	}
+
+	image := &ImageInfo{
+		Width:           uint32(width[0]),
+		Height:          uint32(first(TIFFTAG_IMAGELENGTH, 0)),
+		SamplesPerPixel: uint16(first(TIFFTAG_SAMPLESPERPIXEL, 1)),
+		SampleFormat:    SampleFormat(first(TIFFTAG_SAMPLEFORMAT, uint64(SAMPLEFORMAT_UINT))),
+		Compression:     Compression(first(TIFFTAG_COMPRESSION, uint64(COMPRESSION_NONE))),
+		PlanarConfig:    uint16(first(TIFFTAG_PLANARCONFIG, uint64(PLANARCONFIG_CONTIG))),
+		Orientation:     Orientation(first(TIFFTAG_ORIENTATION, uint64(ORIENTATION_TOPLEFT))),
+		Codec: CodecInfo{
+			Predictor:      Predictor(first(TIFFTAG_PREDICTOR, 0)),
+			FillOrder:      uint16(first(TIFFTAG_FILLORDER, 0)),
+			JPEGTablesSize: int(first(TIFFTAG_JPEGTABLES, 0)),
+		},
+	}
+
+	image.BitsPerSample = []uint16{1}
+	if values := uints[TIFFTAG_BITSPERSAMPLE]; len(values) > 0 {
+		image.BitsPerSample = make([]uint16, len(values))
+		for i, value := range values {
+			image.BitsPerSample[i] = uint16(value)
+		}
+	}
+	if values := uints[TIFFTAG_PHOTOMETRIC]; len(values) > 0 {
+		photometric := Photometric(values[0])
+		image.Photometric = &photometric
+	}
+	if values := floats[TIFFTAG_XRESOLUTION]; len(values) > 0 {
+		image.XResolution = values[0]
+	}
+	if values := floats[TIFFTAG_YRESOLUTION]; len(values) > 0 {
+		image.YResolution = values[0]
+	}
+	if values := uints[TIFFTAG_RESOLUTIONUNIT]; len(values) > 0 {
+		unit := ResolutionUnit(values[0])
+		image.ResolutionUnit = &unit
+	}
+
+	if _, ok := uints[TIFFTAG_TILEWIDTH]; ok {
+		image.Tiled = true
+		image.TileWidth = uint32(first(TIFFTAG_TILEWIDTH, 0))
+		image.TileHeight = uint32(first(TIFFTAG_TILELENGTH, 0))
+		image.Offsets = uints[TIFFTAG_TILEOFFSETS]
+		image.ByteCounts = uints[TIFFTAG_TILEBYTECOUNTS]
+	} else {
+		image.RowsPerStrip = uint32(first(TIFFTAG_ROWSPERSTRIP, math.MaxUint32))
+		image.Offsets = uints[TIFFTAG_STRIPOFFSETS]
+		image.ByteCounts = uints[TIFFTAG_STRIPBYTECOUNTS]
+	}
+
+	if values := uints[TIFFTAG_GROUP3OPTIONS]; len(values) > 0 {
+		options := uint32(values[0])
+		image.Codec.Group3Options = &options
+	}
+	if values := uints[TIFFTAG_GROUP4OPTIONS]; len(values) > 0 {
+		options := uint32(values[0])
+		image.Codec.Group4Options = &options
+	}
+	if values := uints[TIFFTAG_YCBCRSUBSAMPLING]; len(values) == 2 {
+		image.Codec.YCbCrSubsampling = []uint16{uint16(values[0]), uint16(values[1])}
+	}
+
+	return image
+}
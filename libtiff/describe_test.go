@@ -0,0 +1,167 @@
+package libtiff_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func findTag(directory libtiff.DirectoryInfo, tag libtiff.TIFFTAG) *libtiff.TagInfo {
+	for i := range directory.Tags {
+		if directory.Tags[i].Tag == uint16(tag) {
+			return &directory.Tags[i]
+		}
+	}
+	return nil
+}
+
+var _ = Describe("Describe", func() {
+	ctx := context.Background()
+
+	It("describes a tiled image with EXIF and GPS directories", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(40, 24), &libtiff.FromGoImageOptions{
+			Compression:    libtiff.COMPRESSION_LZW,
+			Predictor:      libtiff.PREDICTOR_HORIZONTAL,
+			TileWidth:      16,
+			TileHeight:     16,
+			XResolution:    300,
+			YResolution:    300,
+			ResolutionUnit: libtiff.RESUNIT_INCH,
+			Description:    "Invoice",
+			Exif: &libtiff.Exif{
+				LensModel: "Lens",
+			},
+			GPS: &libtiff.GPS{
+				Latitude:  52.5,
+				Longitude: 4.25,
+			},
+		})
+		defer cleanup()
+
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.ByteOrder).To(Equal("little-endian"))
+		Expect(info.BigTIFF).To(BeFalse())
+		Expect(info.Directories).To(HaveLen(1))
+
+		directory := info.Directories[0]
+		Expect(directory.Kind).To(Equal("image"))
+		Expect(findTag(directory, libtiff.TIFFTAG_IMAGEDESCRIPTION)).To(Equal(&libtiff.TagInfo{
+			Tag:   uint16(libtiff.TIFFTAG_IMAGEDESCRIPTION),
+			Name:  "ImageDescription",
+			Type:  "ASCII",
+			Count: 8,
+			Value: "Invoice",
+		}))
+		Expect(findTag(directory, libtiff.TIFFTAG_XRESOLUTION).Value).To(Equal([]float64{300}))
+
+		image := directory.Image
+		Expect(image).ToNot(BeNil())
+		Expect(image.Width).To(Equal(uint32(40)))
+		Expect(image.Height).To(Equal(uint32(24)))
+		Expect(image.BitsPerSample).To(Equal([]uint16{8, 8, 8, 8}))
+		Expect(image.SamplesPerPixel).To(Equal(uint16(4)))
+		Expect(*image.Photometric).To(Equal(libtiff.PHOTOMETRIC_RGB))
+		Expect(image.Compression).To(Equal(libtiff.COMPRESSION_LZW))
+		Expect(*image.ResolutionUnit).To(Equal(libtiff.RESUNIT_INCH))
+		Expect(image.Tiled).To(BeTrue())
+		Expect(image.TileWidth).To(Equal(uint32(16)))
+		Expect(image.TileHeight).To(Equal(uint32(16)))
+		Expect(image.Offsets).To(HaveLen(6))
+		Expect(image.ByteCounts).To(HaveLen(6))
+		Expect(image.Codec.Predictor).To(Equal(libtiff.PREDICTOR_HORIZONTAL))
+
+		Expect(directory.Exif).ToNot(BeNil())
+		Expect(directory.Exif.Kind).To(Equal("exif"))
+		Expect(findTag(*directory.Exif, libtiff.EXIFTAG_LENSMODEL).Name).To(Equal("LensModel"))
+		Expect(directory.Exif.Image).To(BeNil())
+
+		Expect(directory.GPS).ToNot(BeNil())
+		Expect(directory.GPS.Kind).To(Equal("gps"))
+		latitude := findTag(*directory.GPS, libtiff.GPSTAG_LATITUDE)
+		Expect(latitude.Name).To(Equal("Latitude"))
+		Expect(latitude.Value).To(Equal([]float64{52, 30, 0}))
+	})
+
+	It("describes every directory of a multi-page file", func() {
+		file, err := os.Open("../testdata/multipage-sample.tif")
+		Expect(err).To(BeNil())
+		defer file.Close()
+		stat, err := file.Stat()
+		Expect(err).To(BeNil())
+
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "multipage-sample.tif", file, uint64(stat.Size()), nil)
+		Expect(err).To(BeNil())
+		defer tiffFile.Close(ctx)
+
+		count, err := tiffFile.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories).To(HaveLen(int(count)))
+		for _, directory := range info.Directories {
+			Expect(directory.Image.Compression).To(Equal(libtiff.COMPRESSION_JPEG))
+			Expect(directory.Image.Codec.JPEGTablesSize).To(BeNumerically(">", 0))
+			Expect(directory.Image.Codec.YCbCrSubsampling).To(Equal([]uint16{2, 2}))
+		}
+
+		// Reading with libtiff still works after describing.
+		Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		width, _, err := tiffFile.GetDimensions(ctx)
+		Expect(err).To(BeNil())
+		Expect(width).To(Equal(800))
+	})
+
+	It("marshals to JSON with stable names", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(8, 8), nil)
+		defer cleanup()
+
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+
+		data, err := json.Marshal(info)
+		Expect(err).To(BeNil())
+
+		var decoded map[string]any
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("byte_order", "little-endian"))
+		Expect(decoded).To(HaveKeyWithValue("bigtiff", false))
+		directory := decoded["directories"].([]any)[0].(map[string]any)
+		Expect(directory).To(HaveKeyWithValue("kind", "image"))
+		image := directory["image"].(map[string]any)
+		Expect(image).To(HaveKeyWithValue("compression", "COMPRESSION_NONE"))
+		Expect(image).To(HaveKeyWithValue("photometric", "PHOTOMETRIC_RGB"))
+		Expect(image).To(HaveKeyWithValue("orientation", "ORIENTATION_TOPLEFT"))
+
+		var roundTrip libtiff.FileInfo
+		Expect(json.Unmarshal(data, &roundTrip)).To(Succeed())
+		Expect(roundTrip.Directories[0].Image.Compression).To(Equal(libtiff.COMPRESSION_NONE))
+		Expect(*roundTrip.Directories[0].Image.Photometric).To(Equal(libtiff.PHOTOMETRIC_RGB))
+	})
+
+	It("refuses counts that don't fit in the file", func() {
+		// A LONG tag that claims 1GB of values in a file of a few bytes.
+		data := buildTestTIFF([]testIFDEntry{{65000, 4, 1 << 28, []byte{8, 0, 0, 0}}})
+		tiffFile := openTestTIFF(ctx, data)
+		defer tiffFile.Close(ctx)
+
+		_, err := tiffFile.Describe(ctx)
+		Expect(err).To(MatchError("could not describe file: invalid count 268435456 of tag 65000"))
+	})
+
+	It("unmarshals unknown enum values", func() {
+		data, err := json.Marshal(libtiff.Compression(12345))
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal(`"Compression(12345)"`))
+
+		var compression libtiff.Compression
+		Expect(json.Unmarshal(data, &compression)).To(Succeed())
+		Expect(compression).To(Equal(libtiff.Compression(12345)))
+	})
+})
@@ -0,0 +1,122 @@
+package libtiff
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+)
+
+// DocumentWriter writes the pages of a multi-page document in one pass. The
+// pages are numbered as they are added and marked with FILETYPE_PAGE, the
+// total number of pages is written to every page when the writer is closed.
+type DocumentWriter struct {
+	file *File
+	// directories are the indexes of the directories of the pages, which
+	// are not consecutive when pages have overviews.
+	directories []uint32
+	closed      bool
+}
+
+// NewDocumentWriter returns a DocumentWriter for the file, which must have
+// been opened for writing or appending via TIFFOpenFileFromReadWriteSeeker.
+// When appending, the pages that are already in the file are not part of
+// the document and are not renumbered.
+func (f *File) NewDocumentWriter(ctx context.Context) (*DocumentWriter, error) {
+	// The exact resolution of the rewritten pages is read from the file.
+	if f.readerFile == nil || f.readerFile.ReadWriteSeeker == nil {
+		return nil, errors.New("a document requires a file opened with TIFFOpenFileFromReadWriteSeeker")
+	}
+	return &DocumentWriter{
+		file: f,
+	}, nil
+}
+
+// Pages returns the number of pages that were added.
+func (w *DocumentWriter) Pages() int {
+	return len(w.directories)
+}
+
+// AddPage writes img as the next page of the document with FromGoImage.
+// PageNumber, TotalPages and SubfileType of the options are set by the
+// writer, all other options apply to this page only. Overviews are written
+// as FILETYPE_REDUCEDIMAGE directories, SubIFD overviews and COG output are
+// not supported.
+func (w *DocumentWriter) AddPage(ctx context.Context, img image.Image, options *FromGoImageOptions) error {
+	if w.closed {
+		return errors.New("document writer is closed")
+	}
+	pages := len(w.directories)
+	if pages == math.MaxUint16 {
+		return errors.New("a document can have at most 65535 pages")
+	}
+
+	pageOptions := FromGoImageOptions{}
+	if options != nil {
+		pageOptions = *options
+	}
+	// The total is the number of pages so far, it is updated on Close.
+	pageOptions.PageNumber = uint16(pages)
+	pageOptions.TotalPages = uint16(pages + 1)
+	pageOptions.SubfileType = FILETYPE_PAGE
+	if pageOptions.COG {
+		return errors.New("COG output can't be used for the pages of a document")
+	}
+	// After rewriting a directory with SubIFDs, libtiff writes the next
+	// directories as its SubIFDs, so Close could not number the next pages.
+	if pageOptions.Overviews != nil && pageOptions.Overviews.SubIFDs {
+		return errors.New("SubIFD overviews can't be used for the pages of a document")
+	}
+
+	directory, err := w.file.TIFFNumberOfDirectories(ctx)
+	if err != nil {
+		return err
+	}
+	if err := w.file.FromGoImage(ctx, img, &pageOptions); err != nil {
+		return err
+	}
+	w.directories = append(w.directories, directory)
+	return nil
+}
+
+// Close writes the total number of pages to every page. The directories
+// of all pages but the last are rewritten with TIFFRewriteDirectory. It
+// does not close the file.
+func (w *DocumentWriter) Close(ctx context.Context) error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	// The last page already has the right total.
+	pages := len(w.directories)
+	for page := 0; page < pages-1; page++ {
+		if err := w.file.TIFFSetDirectory(ctx, w.directories[page]); err != nil {
+			return err
+		}
+
+		// libtiff reads the resolution as a float, set the exact fraction
+		// from the file again so it isn't rounded when it is rewritten.
+		for _, tag := range []TIFFTAG{TIFFTAG_XRESOLUTION, TIFFTAG_YRESOLUTION} {
+			value, err := w.file.GetRational(ctx, tag)
+			if err != nil {
+				if _, ok := err.(*TagNotDefinedError); ok {
+					continue
+				}
+				return err
+			}
+			if err := w.file.SetRational(ctx, tag, value); err != nil {
+				return err
+			}
+		}
+
+		if err := w.file.TIFFSetFieldTwoUint16(ctx, TIFFTAG_PAGENUMBER, uint16(page), uint16(pages)); err != nil {
+			return err
+		}
+		if err := w.file.TIFFRewriteDirectory(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DocumentWriter", func() {
+	ctx := context.Background()
+
+	// expectPage checks the page tags and size of the given directory.
+	expectPage := func(tiffFile *libtiff.File, directory uint32, page, total uint16, size image.Point) {
+		Expect(tiffFile.TIFFSetDirectory(ctx, directory)).To(Succeed())
+
+		pageNumber, totalPages, err := tiffFile.TIFFGetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER)
+		Expect(err).To(BeNil())
+		Expect(pageNumber).To(Equal(page), "directory %d", directory)
+		Expect(totalPages).To(Equal(total), "directory %d", directory)
+
+		subfileType, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_SUBFILETYPE)
+		Expect(err).To(BeNil())
+		Expect(libtiff.SubfileType(subfileType)).To(Equal(libtiff.FILETYPE_PAGE))
+
+		width, height, err := tiffFile.GetDimensions(ctx)
+		Expect(err).To(BeNil())
+		Expect(image.Pt(width, height)).To(Equal(size))
+	}
+
+	It("numbers the pages of a document", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		document, err := tiffFile.NewDocumentWriter(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(document.AddPage(ctx, createTestRGBA(16, 12), &libtiff.FromGoImageOptions{
+			Compression:         libtiff.COMPRESSION_LZW,
+			XResolutionRational: libtiff.Rational{Numerator: 7200, Denominator: 24},
+			YResolutionRational: libtiff.Rational{Numerator: 1, Denominator: 3},
+			ResolutionUnit:      libtiff.RESUNIT_INCH,
+			Exif:                &libtiff.Exif{FNumber: 4},
+			// Set by the writer.
+			PageNumber: 7,
+			TotalPages: 9,
+		})).To(Succeed())
+		Expect(document.AddPage(ctx, createTestGray(40, 30), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_CCITTFAX4,
+		})).To(Succeed())
+		Expect(document.AddPage(ctx, createTestPaletted(8, 8, 4), nil)).To(Succeed())
+		Expect(document.AddPage(ctx, createTestRGBA(32, 32), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+		})).To(Succeed())
+		Expect(document.Pages()).To(Equal(4))
+		Expect(document.Close(ctx)).To(Succeed())
+		Expect(document.AddPage(ctx, createTestGray(4, 4), nil)).To(MatchError("document writer is closed"))
+
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		directories, err := readTiff.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(directories).To(Equal(uint32(4)))
+
+		expectPage(readTiff, 0, 0, 4, image.Pt(16, 12))
+		expectPage(readTiff, 1, 1, 4, image.Pt(40, 30))
+		expectPage(readTiff, 2, 2, 4, image.Pt(8, 8))
+		expectPage(readTiff, 3, 3, 4, image.Pt(32, 32))
+
+		// The rewritten page keeps its exact resolution, EXIF and image.
+		Expect(readTiff.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		xResolution, err := readTiff.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 7200, Denominator: 24}))
+		yResolution, err := readTiff.GetRational(ctx, libtiff.TIFFTAG_YRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(yResolution).To(Equal(libtiff.Rational{Numerator: 1, Denominator: 3}))
+
+		goImage, imgCleanup, err := readTiff.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		Expect(goImage.At(5, 7)).To(Equal(createTestRGBA(16, 12).At(5, 7)))
+		imgCleanup(ctx)
+
+		offset, err := readTiff.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD)
+		Expect(err).To(BeNil())
+		Expect(readTiff.TIFFReadEXIFDirectory(ctx, offset)).To(Succeed())
+		fNumber, err := readTiff.TIFFGetFieldFloat(ctx, libtiff.EXIFTAG_FNUMBER)
+		Expect(err).To(BeNil())
+		Expect(fNumber).To(Equal(float32(4)))
+	})
+
+	It("numbers pages that have overviews", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		document, err := tiffFile.NewDocumentWriter(ctx)
+		Expect(err).To(BeNil())
+
+		for _, factors := range [][]int{{2, 4}, {2}} {
+			Expect(document.AddPage(ctx, createTestGray(40, 40), &libtiff.FromGoImageOptions{
+				Overviews: &libtiff.Overviews{Factors: factors},
+			})).To(Succeed())
+		}
+		Expect(document.Close(ctx)).To(Succeed())
+
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		directories, err := readTiff.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(directories).To(Equal(uint32(5)))
+
+		expectPage(readTiff, 0, 0, 2, image.Pt(40, 40))
+		expectPage(readTiff, 3, 1, 2, image.Pt(40, 40))
+	})
+
+	It("refuses options it can't number pages with", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+		document, err := tiffFile.NewDocumentWriter(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(document.AddPage(ctx, createTestGray(40, 40), &libtiff.FromGoImageOptions{
+			Overviews: &libtiff.Overviews{Factors: []int{2}, SubIFDs: true},
+		})).To(MatchError("SubIFD overviews can't be used for the pages of a document"))
+		Expect(document.AddPage(ctx, createTestGray(40, 40), &libtiff.FromGoImageOptions{
+			COG: true,
+		})).To(MatchError("COG output can't be used for the pages of a document"))
+		Expect(document.Pages()).To(Equal(0))
+	})
+
+	It("requires a file opened from a stream", func() {
+		tiffFile, err := instance.TIFFOpenFileFromPath(ctx, "/testdata/multipage-sample.tif", nil)
+		Expect(err).To(BeNil())
+		defer tiffFile.Close(ctx)
+
+		_, err = tiffFile.NewDocumentWriter(ctx)
+		Expect(err).To(MatchError("a document requires a file opened with TIFFOpenFileFromReadWriteSeeker"))
+	})
+
+	It("adds a document to an existing file", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.FromGoImage(ctx, createTestRGBA(4, 4), nil)).To(Succeed())
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+
+		appendFile, err := os.OpenFile(tmpFile.Name(), os.O_RDWR, 0)
+		Expect(err).To(BeNil())
+		appendTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", appendFile, 0, &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenAppend},
+		})
+		Expect(err).To(BeNil())
+
+		document, err := appendTiff.NewDocumentWriter(ctx)
+		Expect(err).To(BeNil())
+		for i := 0; i < 3; i++ {
+			Expect(document.AddPage(ctx, createTestGray(10+i, 10), nil)).To(Succeed())
+		}
+		Expect(document.Close(ctx)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+
+		readTiff := reopenTempTestFile(ctx, appendTiff, appendFile)
+		directories, err := readTiff.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(directories).To(Equal(uint32(4)))
+
+		Expect(readTiff.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		_, _, err = readTiff.TIFFGetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER)
+		Expect(err).To(HaveOccurred())
+
+		expectPage(readTiff, 1, 0, 3, image.Pt(10, 10))
+		expectPage(readTiff, 2, 1, 3, image.Pt(11, 10))
+		expectPage(readTiff, 3, 2, 3, image.Pt(12, 10))
+	})
+})
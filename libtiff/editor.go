@@ -0,0 +1,451 @@
+package libtiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// imageDataTags are the tags that describe where and how the image data is
+// stored. Deleting them would make the image unreadable.
+var imageDataTags = map[TIFFTAG]bool{
+	TIFFTAG_IMAGEWIDTH:      true,
+	TIFFTAG_IMAGELENGTH:     true,
+	TIFFTAG_BITSPERSAMPLE:   true,
+	TIFFTAG_COMPRESSION:     true,
+	TIFFTAG_PHOTOMETRIC:     true,
+	TIFFTAG_SAMPLESPERPIXEL: true,
+	TIFFTAG_ROWSPERSTRIP:    true,
+	TIFFTAG_PLANARCONFIG:    true,
+	TIFFTAG_STRIPOFFSETS:    true,
+	TIFFTAG_STRIPBYTECOUNTS: true,
+	TIFFTAG_TILEWIDTH:       true,
+	TIFFTAG_TILELENGTH:      true,
+	TIFFTAG_TILEOFFSETS:     true,
+	TIFFTAG_TILEBYTECOUNTS:  true,
+}
+
+// DirectoryEditor collects the changes to the tags of one directory of a
+// file that is edited with EditFile. The changes are applied after the edit
+// function returns, so File keeps returning the stored values until then.
+type DirectoryEditor struct {
+	file    *File
+	index   int
+	changes []func(ctx context.Context) error
+	deletes map[TIFFTAG]bool
+
+	// exif and gps are the editors of the EXIF and GPS directories, nil
+	// when they are not edited or when this is one of them.
+	exif *DirectoryEditor
+	gps  *DirectoryEditor
+}
+
+// EditFile opens the TIFF file in rws for updating and calls edit for every
+// directory of the main chain. The directories that have changes are
+// rewritten with TIFFRewriteDirectory: the new directory is appended to the
+// file and takes the place of the old one in the chain, the image data is
+// not touched. EXIF and GPS directories are rewritten the same way and
+// linked into the rewritten directory.
+// EditFile returns the indexes of the directories that were changed. When
+// edit returns an error, editing stops and the directories before it keep
+// their changes.
+func (i *Instance) EditFile(ctx context.Context, rws io.ReadWriteSeeker, edit func(dir *DirectoryEditor) error) ([]int, error) {
+	var changed []int
+	for start := 0; start >= 0; {
+		f, err := i.openForEditing(ctx, rws)
+		if err != nil {
+			return changed, err
+		}
+
+		var edited []int
+		edited, start, err = f.editDirectories(ctx, start, edit)
+		changed = append(changed, edited...)
+		if closeErr := f.Close(ctx); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// openForEditing opens the file in rws in update mode.
+func (i *Instance) openForEditing(ctx context.Context, rws io.ReadWriteSeeker) (*File, error) {
+	size, err := rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	// libtiff reads the header from the current position.
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return i.TIFFOpenFileFromReadWriteSeeker(ctx, "edit.tif", rws, uint64(size), &OpenOptions{
+		Mode: &OpenMode{Access: OpenUpdate},
+	})
+}
+
+// editDirectories edits the directories from start on. It returns the
+// indexes of the changed directories and the index to continue from in a
+// newly opened file, or -1 when all directories are done.
+func (f *File) editDirectories(ctx context.Context, start int, edit func(dir *DirectoryEditor) error) ([]int, int, error) {
+	count, err := f.TIFFNumberOfDirectories(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	var changed []int
+	for n := start; n < int(count); n++ {
+		if err := f.TIFFSetDirectory(ctx, uint32(n)); err != nil {
+			return changed, -1, err
+		}
+
+		dir := newDirectoryEditor(f, n)
+		if err := edit(dir); err != nil {
+			return changed, -1, err
+		}
+		if !dir.changed() {
+			continue
+		}
+
+		_, err := f.TIFFGetFieldUint64Array(ctx, TIFFTAG_SUBIFD)
+		hasSubIFDs := err == nil
+
+		if err := dir.apply(ctx); err != nil {
+			return changed, -1, fmt.Errorf("could not edit directory %d: %w", n, err)
+		}
+		changed = append(changed, n)
+
+		// After writing a directory with SubIFDs, libtiff writes the next
+		// directories as its SubIFDs, so the file has to be opened again.
+		if hasSubIFDs && n+1 < int(count) {
+			return changed, n + 1, nil
+		}
+	}
+
+	return changed, -1, nil
+}
+
+func newDirectoryEditor(f *File, index int) *DirectoryEditor {
+	return &DirectoryEditor{file: f, index: index, deletes: map[TIFFTAG]bool{}}
+}
+
+// Index returns the index of the directory in the main chain.
+func (e *DirectoryEditor) Index() int {
+	return e.index
+}
+
+// File returns the file with the directory as the current directory, to
+// read the stored tags.
+func (e *DirectoryEditor) File() *File {
+	return e.file
+}
+
+// Exif returns the editor of the EXIF directory. The directory is created
+// when the file doesn't have one.
+func (e *DirectoryEditor) Exif() *DirectoryEditor {
+	if e.exif == nil {
+		e.exif = newDirectoryEditor(e.file, e.index)
+	}
+	return e.exif
+}
+
+// GPS returns the editor of the GPS directory. The directory is created when
+// the file doesn't have one.
+func (e *DirectoryEditor) GPS() *DirectoryEditor {
+	if e.gps == nil {
+		e.gps = newDirectoryEditor(e.file, e.index)
+	}
+	return e.gps
+}
+
+// Delete removes the tag from the directory. The tags that describe the image
+// data, like TIFFTAG_STRIPOFFSETS, can't be deleted.
+func (e *DirectoryEditor) Delete(tag TIFFTAG) {
+	e.deletes[tag] = true
+}
+
+func (e *DirectoryEditor) set(tag TIFFTAG, apply func(ctx context.Context) error) {
+	delete(e.deletes, tag)
+	e.changes = append(e.changes, apply)
+}
+
+// SetUint16 sets a SHORT tag.
+func (e *DirectoryEditor) SetUint16(tag TIFFTAG, value uint16) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint16_t(ctx, tag, value)
+	})
+}
+
+// SetUint32 sets a LONG tag.
+func (e *DirectoryEditor) SetUint32(tag TIFFTAG, value uint32) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint32_t(ctx, tag, value)
+	})
+}
+
+// SetUint64 sets a LONG8 or IFD8 tag.
+func (e *DirectoryEditor) SetUint64(tag TIFFTAG, value uint64) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint64_t(ctx, tag, value)
+	})
+}
+
+// SetTwoUint16 sets a tag with two SHORT values, like TIFFTAG_PAGENUMBER.
+func (e *DirectoryEditor) SetTwoUint16(tag TIFFTAG, value1, value2 uint16) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldTwoUint16(ctx, tag, value1, value2)
+	})
+}
+
+// SetDouble sets a tag that libtiff stores as float or double.
+func (e *DirectoryEditor) SetDouble(tag TIFFTAG, value float64) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldDouble(ctx, tag, value)
+	})
+}
+
+// SetRational sets a RATIONAL tag to the exact fraction.
+func (e *DirectoryEditor) SetRational(tag TIFFTAG, value Rational) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.SetRational(ctx, tag, value)
+	})
+}
+
+// SetSRational sets an SRATIONAL tag to the exact fraction.
+func (e *DirectoryEditor) SetSRational(tag TIFFTAG, value SRational) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.SetSRational(ctx, tag, value)
+	})
+}
+
+// SetString sets an ASCII tag.
+func (e *DirectoryEditor) SetString(tag TIFFTAG, value string) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldString(ctx, tag, value)
+	})
+}
+
+// SetByteArray sets a counted BYTE or UNDEFINED tag, like
+// TIFFTAG_XMLPACKET.
+func (e *DirectoryEditor) SetByteArray(tag TIFFTAG, value []byte) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldByteArray(ctx, tag, value)
+	})
+}
+
+// SetUint16Array sets a counted SHORT tag.
+func (e *DirectoryEditor) SetUint16Array(tag TIFFTAG, value []uint16) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint16Array(ctx, tag, value)
+	})
+}
+
+// SetUint32Array sets a counted LONG tag.
+func (e *DirectoryEditor) SetUint32Array(tag TIFFTAG, value []uint32) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint32Array(ctx, tag, value)
+	})
+}
+
+// SetUint64Array sets a counted LONG8 tag.
+func (e *DirectoryEditor) SetUint64Array(tag TIFFTAG, value []uint64) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldUint64Array(ctx, tag, value)
+	})
+}
+
+// SetFloatArray sets a counted tag that libtiff stores as float.
+func (e *DirectoryEditor) SetFloatArray(tag TIFFTAG, value []float32) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldFloatArray(ctx, tag, value)
+	})
+}
+
+// SetDoubleArray sets a counted tag that libtiff stores as double, like
+// TIFFTAG_MODELTIEPOINT.
+func (e *DirectoryEditor) SetDoubleArray(tag TIFFTAG, value []float64) {
+	e.set(tag, func(ctx context.Context) error {
+		return e.file.TIFFSetFieldDoubleArray(ctx, tag, value)
+	})
+}
+
+// DefineTag registers a tag that libtiff does not know, so it can be set.
+// Unknown tags that are stored in the file are known already.
+func (e *DirectoryEditor) DefineTag(tag TIFFTAG, fieldType TIFFDataType) {
+	e.changes = append(e.changes, func(ctx context.Context) error {
+		return e.file.TIFFMergeAnonymousField(ctx, tag, fieldType)
+	})
+}
+
+func (e *DirectoryEditor) changed() bool {
+	if e == nil {
+		return false
+	}
+	return len(e.changes) > 0 || len(e.deletes) > 0 || e.exif.changed() || e.gps.changed()
+}
+
+// apply rewrites the directory with the changes.
+func (e *DirectoryEditor) apply(ctx context.Context) error {
+	for tag := range e.deletes {
+		if imageDataTags[tag] {
+			return fmt.Errorf("tag %s describes the image data and can't be deleted", tagNames[uint16(tag)])
+		}
+	}
+
+	// Writing a custom directory discards the current directory, so the EXIF
+	// and GPS directories are written first and the directory is read again.
+	var exifOffset, gpsOffset uint64
+	if e.exif.changed() || e.gps.changed() {
+		storedExif, err := e.subDirectoryOffset(ctx, TIFFTAG_EXIFIFD)
+		if err != nil {
+			return err
+		}
+		storedGPS, err := e.subDirectoryOffset(ctx, TIFFTAG_GPSIFD)
+		if err != nil {
+			return err
+		}
+
+		if e.exif.changed() {
+			exifOffset, err = e.exif.applyCustom(ctx, storedExif, e.file.TIFFReadEXIFDirectory, e.file.TIFFCreateEXIFDirectory)
+			if err != nil {
+				return fmt.Errorf("could not edit EXIF directory: %w", err)
+			}
+		}
+		if e.gps.changed() {
+			gpsOffset, err = e.gps.applyCustom(ctx, storedGPS, e.file.TIFFReadGPSDirectory, e.file.TIFFCreateGPSDirectory)
+			if err != nil {
+				return fmt.Errorf("could not edit GPS directory: %w", err)
+			}
+		}
+
+		if err := e.file.TIFFSetDirectory(ctx, uint32(e.index)); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range e.changes {
+		if err := change(ctx); err != nil {
+			return err
+		}
+	}
+	if exifOffset != 0 {
+		if err := e.file.TIFFSetFieldUint64_t(ctx, TIFFTAG_EXIFIFD, exifOffset); err != nil {
+			return err
+		}
+	}
+	if gpsOffset != 0 {
+		if err := e.file.TIFFSetFieldUint64_t(ctx, TIFFTAG_GPSIFD, gpsOffset); err != nil {
+			return err
+		}
+	}
+
+	offset, err := e.file.rewriteDirectory(ctx, len(e.deletes) > 0)
+	if err != nil {
+		return err
+	}
+
+	// libtiff can't unset all tags, so deleted tags are written and then
+	// removed from the rewritten directory.
+	return e.file.removeDirectoryEntries(offset, e.deletes)
+}
+
+// subDirectoryOffset returns the offset of the sub-IFD the tag points to, or
+// 0 when the directory doesn't have it.
+func (e *DirectoryEditor) subDirectoryOffset(ctx context.Context, tag TIFFTAG) (uint64, error) {
+	offset, err := e.file.TIFFGetFieldUint64_t(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return offset, nil
+}
+
+// applyCustom writes the EXIF or GPS directory at the stored offset with the
+// changes to the end of the file and returns its new offset.
+func (e *DirectoryEditor) applyCustom(ctx context.Context, stored uint64, read func(context.Context, uint64) error, create func(context.Context) error) (uint64, error) {
+	if stored != 0 {
+		if err := read(ctx, stored); err != nil {
+			return 0, err
+		}
+	} else if err := create(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, change := range e.changes {
+		if err := change(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	offset, err := e.file.TIFFWriteCustomDirectory(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.file.removeDirectoryEntries(offset, e.deletes); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// removeDirectoryEntries removes the entries of the tags from the directory
+// at the given offset. The directory shrinks in place, the values of the
+// removed entries stay in the file unreferenced.
+func (f *File) removeDirectoryEntries(offset uint64, tags map[TIFFTAG]bool) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	writer := f.readerFile.ReadWriteSeeker
+	return f.withDescriber(func(d *describer) error {
+		if _, err := d.readHeader(); err != nil {
+			return err
+		}
+
+		count, entrySize, err := d.readEntryCount(offset)
+		if err != nil {
+			return err
+		}
+		nextSize := uint64(4)
+		if d.bigTIFF {
+			nextSize = 8
+		}
+		data, err := d.readAt(offset+d.countSize(), count*entrySize+nextSize)
+		if err != nil {
+			return err
+		}
+
+		// Keep the entries in order, followed by the offset of the next
+		// directory.
+		directory := make([]byte, d.countSize(), d.countSize()+uint64(len(data)))
+		kept := uint64(0)
+		for i := uint64(0); i < count; i++ {
+			entry := data[i*entrySize : (i+1)*entrySize]
+			if tags[TIFFTAG(d.order.Uint16(entry))] {
+				continue
+			}
+			directory = append(directory, entry...)
+			kept++
+		}
+		if kept == count {
+			return nil
+		}
+		directory = append(directory, data[count*entrySize:]...)
+		if d.bigTIFF {
+			d.order.PutUint64(directory, kept)
+		} else {
+			d.order.PutUint16(directory, uint16(kept))
+		}
+
+		if _, err := writer.Seek(int64(offset), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := writer.Write(directory); err != nil {
+			return fmt.Errorf("could not write directory: %w", err)
+		}
+		return nil
+	})
+}
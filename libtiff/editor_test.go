@@ -0,0 +1,220 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EditFile", func() {
+	ctx := context.Background()
+
+	// writeEditTestFile writes an image per options and returns the path of
+	// the file.
+	writeEditTestFile := func(img image.Image, options ...*libtiff.FromGoImageOptions) string {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		for _, pageOptions := range options {
+			Expect(tiffFile.FromGoImage(ctx, img, pageOptions)).To(Succeed())
+		}
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+		return tmpFile.Name()
+	}
+
+	editFile := func(path string, edit func(dir *libtiff.DirectoryEditor) error) ([]int, error) {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		Expect(err).To(BeNil())
+		defer file.Close()
+		return instance.EditFile(ctx, file, edit)
+	}
+
+	openEdited := func(path string) *libtiff.File {
+		file, err := os.Open(path)
+		Expect(err).To(BeNil())
+		DeferCleanup(file.Close)
+		stat, err := file.Stat()
+		Expect(err).To(BeNil())
+
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", file, uint64(stat.Size()), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(tiffFile.Close, ctx)
+		return tiffFile
+	}
+
+	It("sets and deletes tags without touching the image data", func() {
+		img := createTestRGBA(40, 30)
+		path := writeEditTestFile(img,
+			&libtiff.FromGoImageOptions{Software: "scanner", Artist: "first"},
+			&libtiff.FromGoImageOptions{Software: "scanner", Artist: "second"},
+			&libtiff.FromGoImageOptions{Software: "scanner"},
+		)
+		before, err := openEdited(path).Describe(ctx)
+		Expect(err).To(BeNil())
+
+		changed, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			switch dir.Index() {
+			case 0:
+				artist, err := dir.File().TIFFGetFieldConstChar(ctx, libtiff.TIFFTAG_ARTIST)
+				Expect(err).To(BeNil())
+				Expect(artist).To(Equal("first"))
+
+				dir.SetString(libtiff.TIFFTAG_ARTIST, "edited")
+				dir.Delete(libtiff.TIFFTAG_SOFTWARE)
+				dir.SetRational(libtiff.TIFFTAG_XRESOLUTION, libtiff.Rational{Numerator: 7200, Denominator: 24})
+				dir.SetUint16(libtiff.TIFFTAG_RESOLUTIONUNIT, uint16(libtiff.RESUNIT_INCH))
+			case 2:
+				dir.SetTwoUint16(libtiff.TIFFTAG_PAGENUMBER, 2, 3)
+				dir.SetUint16Array(libtiff.TIFFTAG_MINSAMPLEVALUE, []uint16{1, 2, 3})
+			}
+			return nil
+		})
+		Expect(err).To(BeNil())
+		Expect(changed).To(Equal([]int{0, 2}))
+
+		tiffFile := openEdited(path)
+		after, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(after.Directories).To(HaveLen(3))
+		for i := range after.Directories {
+			Expect(after.Directories[i].Image.Offsets).To(Equal(before.Directories[i].Image.Offsets))
+		}
+		Expect(after.Directories[0].Offset).NotTo(Equal(before.Directories[0].Offset))
+		Expect(after.Directories[1].Offset).To(Equal(before.Directories[1].Offset))
+
+		first := after.Directories[0]
+		Expect(findTag(first, libtiff.TIFFTAG_ARTIST).Value).To(Equal("edited"))
+		Expect(findTag(first, libtiff.TIFFTAG_SOFTWARE)).To(BeNil())
+		Expect(findTag(after.Directories[1], libtiff.TIFFTAG_SOFTWARE).Value).To(Equal("scanner"))
+		Expect(findTag(after.Directories[2], libtiff.TIFFTAG_PAGENUMBER).Value).To(Equal([]uint64{2, 3}))
+
+		xResolution, err := tiffFile.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 7200, Denominator: 24}))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(goImage.(*image.RGBA).Pix).To(Equal(img.Pix))
+	})
+
+	It("writes exact rationals to the rewritten directory", func() {
+		path := writeEditTestFile(createTestRGBA(4, 4),
+			&libtiff.FromGoImageOptions{XResolutionRational: libtiff.Rational{Numerator: 100, Denominator: 3}},
+			&libtiff.FromGoImageOptions{XResolutionRational: libtiff.Rational{Numerator: 200, Denominator: 3}},
+		)
+
+		_, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			if dir.Index() == 0 {
+				dir.SetRational(libtiff.TIFFTAG_XRESOLUTION, libtiff.Rational{Numerator: 1000, Denominator: 7})
+			}
+			return nil
+		})
+		Expect(err).To(BeNil())
+
+		tiffFile := openEdited(path)
+		xResolution, err := tiffFile.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 1000, Denominator: 7}))
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		xResolution, err = tiffFile.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 200, Denominator: 3}))
+	})
+
+	It("edits the EXIF and GPS directories", func() {
+		altitude := 12.5
+		path := writeEditTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{
+			Exif: &libtiff.Exif{FNumber: 2.8, LensModel: "lens"},
+			GPS:  &libtiff.GPS{Latitude: 52, Longitude: 4, Altitude: &altitude},
+		})
+
+		changed, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			dir.Exif().SetDouble(libtiff.EXIFTAG_FNUMBER, 4)
+			dir.Exif().Delete(libtiff.EXIFTAG_LENSMODEL)
+			dir.GPS().Delete(libtiff.GPSTAG_ALTITUDE)
+			dir.SetString(libtiff.TIFFTAG_ARTIST, "edited")
+			return nil
+		})
+		Expect(err).To(BeNil())
+		Expect(changed).To(Equal([]int{0}))
+
+		info, err := openEdited(path).Describe(ctx)
+		Expect(err).To(BeNil())
+		directory := info.Directories[0]
+		Expect(findTag(directory, libtiff.TIFFTAG_ARTIST).Value).To(Equal("edited"))
+		Expect(findTag(*directory.Exif, libtiff.EXIFTAG_FNUMBER).Value).To(Equal([]float64{4}))
+		Expect(findTag(*directory.Exif, libtiff.EXIFTAG_LENSMODEL)).To(BeNil())
+		Expect(findTag(*directory.GPS, libtiff.GPSTAG_ALTITUDE)).To(BeNil())
+		Expect(findTag(*directory.GPS, libtiff.GPSTAG_LATITUDE)).NotTo(BeNil())
+	})
+
+	It("creates an EXIF directory", func() {
+		path := writeEditTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{})
+
+		_, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			dir.Exif().SetString(libtiff.EXIFTAG_LENSMODEL, "new lens")
+			return nil
+		})
+		Expect(err).To(BeNil())
+
+		info, err := openEdited(path).Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories[0].Exif).NotTo(BeNil())
+		Expect(findTag(*info.Directories[0].Exif, libtiff.EXIFTAG_LENSMODEL).Value).To(Equal("new lens"))
+	})
+
+	It("keeps the SubIFDs of a directory", func() {
+		path := writeEditTestFile(createTestGray(64, 64),
+			&libtiff.FromGoImageOptions{Overviews: &libtiff.Overviews{Factors: []int{2, 4}, SubIFDs: true}},
+			&libtiff.FromGoImageOptions{},
+		)
+
+		changed, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			dir.SetString(libtiff.TIFFTAG_IMAGEDESCRIPTION, "edited")
+			return nil
+		})
+		Expect(err).To(BeNil())
+		Expect(changed).To(Equal([]int{0, 1}))
+
+		info, err := openEdited(path).Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories).To(HaveLen(2))
+		Expect(info.Directories[0].SubIFDs).To(HaveLen(2))
+		Expect(info.Directories[1].SubIFDs).To(BeEmpty())
+		for _, directory := range info.Directories {
+			Expect(findTag(directory, libtiff.TIFFTAG_IMAGEDESCRIPTION).Value).To(Equal("edited"))
+		}
+	})
+
+	It("doesn't write files without changes", func() {
+		path := writeEditTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{})
+		before, err := os.ReadFile(path)
+		Expect(err).To(BeNil())
+
+		changed, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			return nil
+		})
+		Expect(err).To(BeNil())
+		Expect(changed).To(BeEmpty())
+
+		after, err := os.ReadFile(path)
+		Expect(err).To(BeNil())
+		Expect(after).To(Equal(before))
+	})
+
+	It("refuses to delete the tags of the image data", func() {
+		path := writeEditTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{})
+
+		_, err := editFile(path, func(dir *libtiff.DirectoryEditor) error {
+			dir.Delete(libtiff.TIFFTAG_STRIPOFFSETS)
+			return nil
+		})
+		Expect(err).To(MatchError("could not edit directory 0: tag StripOffsets describes the image data and can't be deleted"))
+	})
+})
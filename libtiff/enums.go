@@ -0,0 +1,369 @@
+package libtiff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Compression is a TIFFTAG_COMPRESSION value.
+type Compression uint16
+
+// Photometric is a TIFFTAG_PHOTOMETRIC value.
+type Photometric uint16
+
+// Orientation is a TIFFTAG_ORIENTATION value.
+type Orientation uint16
+
+// Predictor is a TIFFTAG_PREDICTOR value.
+type Predictor uint16
+
+// ResolutionUnit is a TIFFTAG_RESOLUTIONUNIT value.
+type ResolutionUnit uint16
+
+// SampleFormat is a TIFFTAG_SAMPLEFORMAT value.
+type SampleFormat uint16
+
+// SubfileType is a TIFFTAG_SUBFILETYPE value, a combination of the
+// FILETYPE_* flags.
+type SubfileType uint32
+
+// enumName is the name of an enumerated tag value, the name is the constant
+// name without prefix. Aliases are only used for parsing.
+type enumName[T ~uint16] struct {
+	value   T
+	name    string
+	aliases []string
+}
+
+func enumString[T ~uint16](names []enumName[T], prefix, typeName string, value T) string {
+	for _, name := range names {
+		if name.value == value {
+			return prefix + name.name
+		}
+	}
+	return fmt.Sprintf("%s(%d)", typeName, uint16(value))
+}
+
+// parseEnum matches the constant names first, so that a name with prefix
+// always returns the value of that constant, and the names without prefix
+// and the aliases after that. Matching is case-insensitive.
+func parseEnum[T ~uint16](names []enumName[T], prefix, kind, value string) (T, error) {
+	for _, name := range names {
+		if strings.EqualFold(value, prefix+name.name) {
+			return name.value, nil
+		}
+	}
+	for _, name := range names {
+		if strings.EqualFold(value, name.name) {
+			return name.value, nil
+		}
+		for _, alias := range name.aliases {
+			if strings.EqualFold(value, alias) {
+				return name.value, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown %s: %s", kind, value)
+}
+
+// unmarshalEnum parses the text of MarshalText, which is the constant name
+// or the type name with the number for unknown values.
+func unmarshalEnum[T ~uint16](names []enumName[T], prefix, typeName, kind string, text []byte) (T, error) {
+	var number uint16
+	if _, err := fmt.Sscanf(string(text), typeName+"(%d)", &number); err == nil {
+		return T(number), nil
+	}
+	return parseEnum(names, prefix, kind, string(text))
+}
+
+var compressionNames = []enumName[Compression]{
+	{COMPRESSION_NONE, "NONE", nil},
+	{COMPRESSION_CCITTRLE, "CCITTRLE", nil},
+	{COMPRESSION_CCITTFAX3, "CCITTFAX3", []string{"CCITT_T4", "ccitt3"}},
+	{COMPRESSION_CCITTFAX4, "CCITTFAX4", []string{"CCITT_T6", "ccitt4"}},
+	{COMPRESSION_LZW, "LZW", nil},
+	{COMPRESSION_OJPEG, "OJPEG", nil},
+	{COMPRESSION_JPEG, "JPEG", nil},
+	{COMPRESSION_T85, "T85", nil},
+	{COMPRESSION_T43, "T43", nil},
+	// libtiff writes the Adobe code for Deflate, so "deflate" parses to it.
+	{COMPRESSION_ADOBE_DEFLATE, "ADOBE_DEFLATE", []string{"deflate", "zip"}},
+	{COMPRESSION_NEXT, "NEXT", nil},
+	{COMPRESSION_CCITTRLEW, "CCITTRLEW", nil},
+	{COMPRESSION_PACKBITS, "PACKBITS", nil},
+	{COMPRESSION_THUNDERSCAN, "THUNDERSCAN", nil},
+	{COMPRESSION_IT8CTPAD, "IT8CTPAD", nil},
+	{COMPRESSION_IT8LW, "IT8LW", nil},
+	{COMPRESSION_IT8MP, "IT8MP", nil},
+	{COMPRESSION_IT8BL, "IT8BL", nil},
+	{COMPRESSION_PIXARFILM, "PIXARFILM", nil},
+	{COMPRESSION_PIXARLOG, "PIXARLOG", nil},
+	{COMPRESSION_DEFLATE, "DEFLATE", nil},
+	{COMPRESSION_DCS, "DCS", nil},
+	{COMPRESSION_JBIG, "JBIG", nil},
+	{COMPRESSION_SGILOG, "SGILOG", nil},
+	{COMPRESSION_SGILOG24, "SGILOG24", nil},
+	{COMPRESSION_JP2000, "JP2000", nil},
+	{COMPRESSION_LERC, "LERC", nil},
+	{COMPRESSION_LZMA, "LZMA", nil},
+	{COMPRESSION_ZSTD, "ZSTD", nil},
+	{COMPRESSION_WEBP, "WEBP", nil},
+	{COMPRESSION_JXL, "JXL", nil},
+	{COMPRESSION_JXL_DNG_1_7, "JXL_DNG_1_7", nil},
+}
+
+// String returns the constant name of the compression, like
+// "COMPRESSION_LZW".
+func (c Compression) String() string {
+	return enumString(compressionNames, "COMPRESSION_", "Compression", c)
+}
+
+// ParseCompression parses a compression name, with or without the
+// COMPRESSION_ prefix, like "lzw" or "COMPRESSION_LZW". The short names
+// "deflate", "ccitt3" and "ccitt4" are accepted as well.
+func ParseCompression(value string) (Compression, error) {
+	return parseEnum(compressionNames, "COMPRESSION_", "compression", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Compression) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Compression) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(compressionNames, "COMPRESSION_", "Compression", "compression", text)
+	if err != nil {
+		return err
+	}
+	*c = value
+	return nil
+}
+
+var photometricNames = []enumName[Photometric]{
+	{PHOTOMETRIC_MINISWHITE, "MINISWHITE", nil},
+	{PHOTOMETRIC_MINISBLACK, "MINISBLACK", nil},
+	{PHOTOMETRIC_RGB, "RGB", nil},
+	{PHOTOMETRIC_PALETTE, "PALETTE", nil},
+	{PHOTOMETRIC_MASK, "MASK", nil},
+	{PHOTOMETRIC_SEPARATED, "SEPARATED", []string{"cmyk"}},
+	{PHOTOMETRIC_YCBCR, "YCBCR", nil},
+	{PHOTOMETRIC_CIELAB, "CIELAB", nil},
+	{PHOTOMETRIC_ICCLAB, "ICCLAB", nil},
+	{PHOTOMETRIC_ITULAB, "ITULAB", nil},
+	{PHOTOMETRIC_CFA, "CFA", nil},
+	{PHOTOMETRIC_LOGL, "LOGL", nil},
+	{PHOTOMETRIC_LOGLUV, "LOGLUV", nil},
+}
+
+// String returns the constant name of the photometric interpretation, like
+// "PHOTOMETRIC_RGB".
+func (p Photometric) String() string {
+	return enumString(photometricNames, "PHOTOMETRIC_", "Photometric", p)
+}
+
+// ParsePhotometric parses a photometric interpretation name, with or without
+// the PHOTOMETRIC_ prefix, like "rgb" or "PHOTOMETRIC_RGB".
+func ParsePhotometric(value string) (Photometric, error) {
+	return parseEnum(photometricNames, "PHOTOMETRIC_", "photometric interpretation", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Photometric) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Photometric) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(photometricNames, "PHOTOMETRIC_", "Photometric", "photometric interpretation", text)
+	if err != nil {
+		return err
+	}
+	*p = value
+	return nil
+}
+
+var orientationNames = []enumName[Orientation]{
+	{ORIENTATION_TOPLEFT, "TOPLEFT", nil},
+	{ORIENTATION_TOPRIGHT, "TOPRIGHT", nil},
+	{ORIENTATION_BOTRIGHT, "BOTRIGHT", nil},
+	{ORIENTATION_BOTLEFT, "BOTLEFT", nil},
+	{ORIENTATION_LEFTTOP, "LEFTTOP", nil},
+	{ORIENTATION_RIGHTTOP, "RIGHTTOP", nil},
+	{ORIENTATION_RIGHTBOT, "RIGHTBOT", nil},
+	{ORIENTATION_LEFTBOT, "LEFTBOT", nil},
+}
+
+// String returns the constant name of the orientation, like
+// "ORIENTATION_TOPLEFT".
+func (o Orientation) String() string {
+	return enumString(orientationNames, "ORIENTATION_", "Orientation", o)
+}
+
+// ParseOrientation parses an orientation name, with or without the
+// ORIENTATION_ prefix, like "topleft" or "ORIENTATION_TOPLEFT".
+func ParseOrientation(value string) (Orientation, error) {
+	return parseEnum(orientationNames, "ORIENTATION_", "orientation", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (o Orientation) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *Orientation) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(orientationNames, "ORIENTATION_", "Orientation", "orientation", text)
+	if err != nil {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+var predictorNames = []enumName[Predictor]{
+	{PREDICTOR_NONE, "NONE", nil},
+	{PREDICTOR_HORIZONTAL, "HORIZONTAL", nil},
+	{PREDICTOR_FLOATINGPOINT, "FLOATINGPOINT", nil},
+}
+
+// String returns the constant name of the predictor, like
+// "PREDICTOR_HORIZONTAL".
+func (p Predictor) String() string {
+	return enumString(predictorNames, "PREDICTOR_", "Predictor", p)
+}
+
+// ParsePredictor parses a predictor name, with or without the PREDICTOR_
+// prefix, like "horizontal" or "PREDICTOR_HORIZONTAL".
+func ParsePredictor(value string) (Predictor, error) {
+	return parseEnum(predictorNames, "PREDICTOR_", "predictor", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Predictor) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Predictor) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(predictorNames, "PREDICTOR_", "Predictor", "predictor", text)
+	if err != nil {
+		return err
+	}
+	*p = value
+	return nil
+}
+
+var resolutionUnitNames = []enumName[ResolutionUnit]{
+	{RESUNIT_NONE, "NONE", nil},
+	{RESUNIT_INCH, "INCH", nil},
+	{RESUNIT_CENTIMETER, "CENTIMETER", []string{"cm"}},
+}
+
+// String returns the constant name of the resolution unit, like
+// "RESUNIT_INCH".
+func (r ResolutionUnit) String() string {
+	return enumString(resolutionUnitNames, "RESUNIT_", "ResolutionUnit", r)
+}
+
+// ParseResolutionUnit parses a resolution unit name, with or without the
+// RESUNIT_ prefix, like "inch" or "RESUNIT_INCH".
+func ParseResolutionUnit(value string) (ResolutionUnit, error) {
+	return parseEnum(resolutionUnitNames, "RESUNIT_", "resolution unit", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r ResolutionUnit) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *ResolutionUnit) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(resolutionUnitNames, "RESUNIT_", "ResolutionUnit", "resolution unit", text)
+	if err != nil {
+		return err
+	}
+	*r = value
+	return nil
+}
+
+var sampleFormatNames = []enumName[SampleFormat]{
+	{SAMPLEFORMAT_UINT, "UINT", nil},
+	{SAMPLEFORMAT_INT, "INT", nil},
+	{SAMPLEFORMAT_IEEEFP, "IEEEFP", []string{"float"}},
+	{SAMPLEFORMAT_VOID, "VOID", nil},
+	{SAMPLEFORMAT_COMPLEXINT, "COMPLEXINT", nil},
+	{SAMPLEFORMAT_COMPLEXIEEEFP, "COMPLEXIEEEFP", nil},
+}
+
+// String returns the constant name of the sample format, like
+// "SAMPLEFORMAT_UINT".
+func (s SampleFormat) String() string {
+	return enumString(sampleFormatNames, "SAMPLEFORMAT_", "SampleFormat", s)
+}
+
+// ParseSampleFormat parses a sample format name, with or without the
+// SAMPLEFORMAT_ prefix, like "uint" or "SAMPLEFORMAT_UINT".
+func ParseSampleFormat(value string) (SampleFormat, error) {
+	return parseEnum(sampleFormatNames, "SAMPLEFORMAT_", "sample format", value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s SampleFormat) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *SampleFormat) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(sampleFormatNames, "SAMPLEFORMAT_", "SampleFormat", "sample format", text)
+	if err != nil {
+		return err
+	}
+	*s = value
+	return nil
+}
+
+// GetCompression returns the compression of the current directory.
+// Returns COMPRESSION_NONE when the tag is not set.
+func (f *File) GetCompression(ctx context.Context) (Compression, error) {
+	value, err := f.getUint16WithDefault(ctx, TIFFTAG_COMPRESSION, uint16(COMPRESSION_NONE))
+	return Compression(value), err
+}
+
+// GetPhotometric returns the photometric interpretation of the current
+// directory.
+// Returns a TagNotDefinedError when the tag is not set, since it has no
+// default.
+func (f *File) GetPhotometric(ctx context.Context) (Photometric, error) {
+	value, err := f.TIFFGetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC)
+	return Photometric(value), err
+}
+
+// GetOrientation returns the orientation of the current directory.
+// Returns ORIENTATION_TOPLEFT when the tag is not set.
+func (f *File) GetOrientation(ctx context.Context) (Orientation, error) {
+	value, err := f.getUint16WithDefault(ctx, TIFFTAG_ORIENTATION, uint16(ORIENTATION_TOPLEFT))
+	return Orientation(value), err
+}
+
+// GetPredictor returns the predictor of the current directory.
+// Returns PREDICTOR_NONE when the tag is not set.
+func (f *File) GetPredictor(ctx context.Context) (Predictor, error) {
+	value, err := f.getUint16WithDefault(ctx, TIFFTAG_PREDICTOR, uint16(PREDICTOR_NONE))
+	return Predictor(value), err
+}
+
+// GetResolutionUnit returns the resolution unit of the current directory.
+// Returns RESUNIT_INCH when the tag is not set.
+func (f *File) GetResolutionUnit(ctx context.Context) (ResolutionUnit, error) {
+	value, err := f.getUint16WithDefault(ctx, TIFFTAG_RESOLUTIONUNIT, uint16(RESUNIT_INCH))
+	return ResolutionUnit(value), err
+}
+
+// GetSampleFormat returns the sample format of the current directory.
+// Returns SAMPLEFORMAT_UINT when the tag is not set.
+func (f *File) GetSampleFormat(ctx context.Context) (SampleFormat, error) {
+	value, err := f.getUint16WithDefault(ctx, TIFFTAG_SAMPLEFORMAT, uint16(SAMPLEFORMAT_UINT))
+	return SampleFormat(value), err
+}
@@ -0,0 +1,135 @@
+package libtiff_test
+
+import (
+	"context"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("enums", func() {
+	ctx := context.Background()
+
+	It("returns the constant names", func() {
+		Expect(libtiff.COMPRESSION_LZW.String()).To(Equal("COMPRESSION_LZW"))
+		Expect(libtiff.COMPRESSION_CCITT_T4.String()).To(Equal("COMPRESSION_CCITTFAX3"))
+		Expect(libtiff.PHOTOMETRIC_YCBCR.String()).To(Equal("PHOTOMETRIC_YCBCR"))
+		Expect(libtiff.ORIENTATION_LEFTBOT.String()).To(Equal("ORIENTATION_LEFTBOT"))
+		Expect(libtiff.PREDICTOR_HORIZONTAL.String()).To(Equal("PREDICTOR_HORIZONTAL"))
+		Expect(libtiff.RESUNIT_CENTIMETER.String()).To(Equal("RESUNIT_CENTIMETER"))
+		Expect(libtiff.SAMPLEFORMAT_IEEEFP.String()).To(Equal("SAMPLEFORMAT_IEEEFP"))
+	})
+
+	It("formats unknown values as numbers", func() {
+		Expect(libtiff.Compression(12345).String()).To(Equal("Compression(12345)"))
+		Expect(libtiff.Orientation(0).String()).To(Equal("Orientation(0)"))
+	})
+
+	It("parses names with and without prefix", func() {
+		compression, err := libtiff.ParseCompression("lzw")
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_LZW))
+
+		compression, err = libtiff.ParseCompression("COMPRESSION_PACKBITS")
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_PACKBITS))
+
+		compression, err = libtiff.ParseCompression("ccitt4")
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_CCITTFAX4))
+
+		orientation, err := libtiff.ParseOrientation("RightTop")
+		Expect(err).To(BeNil())
+		Expect(orientation).To(Equal(libtiff.ORIENTATION_RIGHTTOP))
+
+		photometric, err := libtiff.ParsePhotometric("minisblack")
+		Expect(err).To(BeNil())
+		Expect(photometric).To(Equal(libtiff.PHOTOMETRIC_MINISBLACK))
+
+		sampleFormat, err := libtiff.ParseSampleFormat("SAMPLEFORMAT_INT")
+		Expect(err).To(BeNil())
+		Expect(sampleFormat).To(Equal(libtiff.SAMPLEFORMAT_INT))
+	})
+
+	It("parses deflate as the Adobe code unless the legacy constant is named", func() {
+		compression, err := libtiff.ParseCompression("deflate")
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_ADOBE_DEFLATE))
+
+		compression, err = libtiff.ParseCompression("COMPRESSION_DEFLATE")
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_DEFLATE))
+	})
+
+	It("round trips every name", func() {
+		for _, compression := range []libtiff.Compression{libtiff.COMPRESSION_NONE, libtiff.COMPRESSION_JPEG, libtiff.COMPRESSION_DEFLATE, libtiff.COMPRESSION_ADOBE_DEFLATE, libtiff.COMPRESSION_JXL_DNG_1_7} {
+			parsed, err := libtiff.ParseCompression(compression.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(compression))
+		}
+		for _, unit := range []libtiff.ResolutionUnit{libtiff.RESUNIT_NONE, libtiff.RESUNIT_INCH, libtiff.RESUNIT_CENTIMETER} {
+			parsed, err := libtiff.ParseResolutionUnit(unit.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(unit))
+		}
+	})
+
+	It("returns an error for unknown names", func() {
+		_, err := libtiff.ParseCompression("rar")
+		Expect(err).To(MatchError("unknown compression: rar"))
+
+		_, err = libtiff.ParsePredictor("vertical")
+		Expect(err).To(MatchError("unknown predictor: vertical"))
+	})
+
+	It("reads the typed values of a directory", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Compression:    libtiff.COMPRESSION_LZW,
+			Predictor:      libtiff.PREDICTOR_HORIZONTAL,
+			Orientation:    libtiff.ORIENTATION_BOTRIGHT,
+			XResolution:    300,
+			YResolution:    300,
+			ResolutionUnit: libtiff.RESUNIT_CENTIMETER,
+		})
+		defer cleanup()
+
+		compression, err := tiffFile.GetCompression(ctx)
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_LZW))
+
+		photometric, err := tiffFile.GetPhotometric(ctx)
+		Expect(err).To(BeNil())
+		Expect(photometric).To(Equal(libtiff.PHOTOMETRIC_RGB))
+
+		orientation, err := tiffFile.GetOrientation(ctx)
+		Expect(err).To(BeNil())
+		Expect(orientation).To(Equal(libtiff.ORIENTATION_BOTRIGHT))
+
+		predictor, err := tiffFile.GetPredictor(ctx)
+		Expect(err).To(BeNil())
+		Expect(predictor).To(Equal(libtiff.PREDICTOR_HORIZONTAL))
+
+		unit, err := tiffFile.GetResolutionUnit(ctx)
+		Expect(err).To(BeNil())
+		Expect(unit).To(Equal(libtiff.RESUNIT_CENTIMETER))
+
+		sampleFormat, err := tiffFile.GetSampleFormat(ctx)
+		Expect(err).To(BeNil())
+		Expect(sampleFormat).To(Equal(libtiff.SAMPLEFORMAT_UINT))
+	})
+
+	It("returns the defaults for tags that are not set", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), nil)
+		defer cleanup()
+
+		predictor, err := tiffFile.GetPredictor(ctx)
+		Expect(err).To(BeNil())
+		Expect(predictor).To(Equal(libtiff.PREDICTOR_NONE))
+
+		unit, err := tiffFile.GetResolutionUnit(ctx)
+		Expect(err).To(BeNil())
+		Expect(unit).To(Equal(libtiff.RESUNIT_INCH))
+	})
+})
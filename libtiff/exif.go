@@ -0,0 +1,351 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// Exif holds the EXIF metadata that FromGoImage writes to an EXIF sub-IFD.
+// Fields with a zero value are not written. EXIFTAG_EXIFVERSION is always
+// written.
+type Exif struct {
+	// DateTimeOriginal sets EXIFTAG_DATETIMEORIGINAL in "YYYY:MM:DD HH:MM:SS" format.
+	DateTimeOriginal string
+	// DateTimeDigitized sets EXIFTAG_DATETIMEDIGITIZED in "YYYY:MM:DD HH:MM:SS" format.
+	DateTimeDigitized string
+	// ExposureTime sets EXIFTAG_EXPOSURETIME in seconds.
+	ExposureTime float64
+	// FNumber sets EXIFTAG_FNUMBER.
+	FNumber float64
+	// ExposureProgram sets EXIFTAG_EXPOSUREPROGRAM.
+	ExposureProgram uint16
+	// ISOSpeedRatings sets EXIFTAG_ISOSPEEDRATINGS.
+	ISOSpeedRatings []uint16
+	// ExposureBiasValue sets EXIFTAG_EXPOSUREBIASVALUE in APEX units.
+	ExposureBiasValue float64
+	// MeteringMode sets EXIFTAG_METERINGMODE.
+	MeteringMode uint16
+	// Flash sets EXIFTAG_FLASH.
+	Flash uint16
+	// FocalLength sets EXIFTAG_FOCALLENGTH in millimeters.
+	FocalLength float64
+	// FocalLengthIn35mmFilm sets EXIFTAG_FOCALLENGTHIN35MMFILM in millimeters.
+	FocalLengthIn35mmFilm uint16
+	// LensMake sets EXIFTAG_LENSMAKE.
+	LensMake string
+	// LensModel sets EXIFTAG_LENSMODEL.
+	LensModel string
+	// BodySerialNumber sets EXIFTAG_BODYSERIALNUMBER.
+	BodySerialNumber string
+	// UserComment sets EXIFTAG_USERCOMMENT. It is stored with the ASCII
+	// character code when possible, and as UNICODE otherwise.
+	UserComment string
+	// ColorSpace sets EXIFTAG_COLORSPACE, 1 for sRGB and 0xFFFF for uncalibrated.
+	ColorSpace uint16
+	// PixelXDimension sets EXIFTAG_PIXELXDIMENSION.
+	PixelXDimension uint32
+	// PixelYDimension sets EXIFTAG_PIXELYDIMENSION.
+	PixelYDimension uint32
+}
+
+// GPS holds the location that FromGoImage writes to a GPS sub-IFD.
+// GPSTAG_VERSIONID, the latitude and the longitude are always written.
+type GPS struct {
+	// Latitude in decimal degrees, negative values are south of the equator.
+	Latitude float64
+	// Longitude in decimal degrees, negative values are west of Greenwich.
+	Longitude float64
+	// Altitude in meters, negative values are below sea level. If nil, the
+	// altitude is not written.
+	Altitude *float64
+	// Timestamp sets GPSTAG_TIMESTAMP and GPSTAG_DATESTAMP, converted to UTC.
+	// If zero, the tags are not written.
+	Timestamp time.Time
+}
+
+// exifVersion is the EXIF version written to EXIFTAG_EXIFVERSION.
+var exifVersion = []byte("0232")
+
+// gpsVersion is the GPS version written to GPSTAG_VERSIONID.
+var gpsVersion = []byte{2, 3, 0, 0}
+
+// writeMetadataDirectories writes the EXIF and GPS sub-IFDs, starts a new
+// main directory and links the sub-IFDs into it. Writing a custom directory
+// discards the current directory, so this must be called before any tags of
+// the main directory are set.
+func (f *File) writeMetadataDirectories(ctx context.Context, exif *Exif, gps *GPS) error {
+	var exifOffset, gpsOffset uint64
+
+	if exif != nil {
+		if err := f.TIFFCreateEXIFDirectory(ctx); err != nil {
+			return err
+		}
+		if err := f.setExifFields(ctx, exif); err != nil {
+			return err
+		}
+
+		offset, err := f.TIFFWriteCustomDirectory(ctx)
+		if err != nil {
+			return err
+		}
+		exifOffset = offset
+	}
+
+	if gps != nil {
+		if err := f.TIFFCreateGPSDirectory(ctx); err != nil {
+			return err
+		}
+		if err := f.setGPSFields(ctx, gps); err != nil {
+			return err
+		}
+
+		offset, err := f.TIFFWriteCustomDirectory(ctx)
+		if err != nil {
+			return err
+		}
+		gpsOffset = offset
+	}
+
+	if err := f.TIFFCreateDirectory(ctx); err != nil {
+		return err
+	}
+
+	if exifOffset != 0 {
+		if err := f.TIFFSetFieldUint64_t(ctx, TIFFTAG_EXIFIFD, exifOffset); err != nil {
+			return err
+		}
+	}
+	if gpsOffset != 0 {
+		if err := f.TIFFSetFieldUint64_t(ctx, TIFFTAG_GPSIFD, gpsOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *File) setExifFields(ctx context.Context, exif *Exif) error {
+	if err := f.TIFFSetFieldFixedByteArray(ctx, EXIFTAG_EXIFVERSION, exifVersion); err != nil {
+		return err
+	}
+
+	stringTags := []struct {
+		value string
+		tag   TIFFTAG
+	}{
+		{exif.DateTimeOriginal, EXIFTAG_DATETIMEORIGINAL},
+		{exif.DateTimeDigitized, EXIFTAG_DATETIMEDIGITIZED},
+		{exif.LensMake, EXIFTAG_LENSMAKE},
+		{exif.LensModel, EXIFTAG_LENSMODEL},
+		{exif.BodySerialNumber, EXIFTAG_BODYSERIALNUMBER},
+	}
+	for _, t := range stringTags {
+		if t.value != "" {
+			if err := f.TIFFSetFieldString(ctx, t.tag, t.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	rationalTags := []struct {
+		value float64
+		tag   TIFFTAG
+	}{
+		{exif.ExposureTime, EXIFTAG_EXPOSURETIME},
+		{exif.FNumber, EXIFTAG_FNUMBER},
+		{exif.ExposureBiasValue, EXIFTAG_EXPOSUREBIASVALUE},
+		{exif.FocalLength, EXIFTAG_FOCALLENGTH},
+	}
+	for _, t := range rationalTags {
+		if t.value != 0 {
+			if err := f.TIFFSetFieldFloat(ctx, t.tag, float32(t.value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	shortTags := []struct {
+		value uint16
+		tag   TIFFTAG
+	}{
+		{exif.ExposureProgram, EXIFTAG_EXPOSUREPROGRAM},
+		{exif.MeteringMode, EXIFTAG_METERINGMODE},
+		{exif.Flash, EXIFTAG_FLASH},
+		{exif.FocalLengthIn35mmFilm, EXIFTAG_FOCALLENGTHIN35MMFILM},
+		{exif.ColorSpace, EXIFTAG_COLORSPACE},
+	}
+	for _, t := range shortTags {
+		if t.value != 0 {
+			if err := f.TIFFSetFieldUint16_t(ctx, t.tag, t.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if exif.PixelXDimension != 0 {
+		if err := f.TIFFSetFieldUint32_t(ctx, EXIFTAG_PIXELXDIMENSION, exif.PixelXDimension); err != nil {
+			return err
+		}
+	}
+	if exif.PixelYDimension != 0 {
+		if err := f.TIFFSetFieldUint32_t(ctx, EXIFTAG_PIXELYDIMENSION, exif.PixelYDimension); err != nil {
+			return err
+		}
+	}
+
+	if len(exif.ISOSpeedRatings) > 0 {
+		if err := f.TIFFSetFieldUint16Array(ctx, EXIFTAG_ISOSPEEDRATINGS, exif.ISOSpeedRatings); err != nil {
+			return err
+		}
+	}
+
+	if exif.UserComment != "" {
+		bigEndian, err := f.TIFFIsBigEndian(ctx)
+		if err != nil {
+			return err
+		}
+		if err := f.TIFFSetFieldByteArray(ctx, EXIFTAG_USERCOMMENT, encodeUserComment(exif.UserComment, bigEndian)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *File) setGPSFields(ctx context.Context, gps *GPS) error {
+	if err := f.TIFFSetFieldFixedByteArray(ctx, GPSTAG_VERSIONID, gpsVersion); err != nil {
+		return err
+	}
+
+	latitudeRef := "N"
+	if gps.Latitude < 0 {
+		latitudeRef = "S"
+	}
+	if err := f.TIFFSetFieldString(ctx, GPSTAG_LATITUDEREF, latitudeRef); err != nil {
+		return err
+	}
+	if err := f.TIFFSetFieldFixedDoubleArray(ctx, GPSTAG_LATITUDE, degreesToDMS(gps.Latitude)); err != nil {
+		return err
+	}
+
+	longitudeRef := "E"
+	if gps.Longitude < 0 {
+		longitudeRef = "W"
+	}
+	if err := f.TIFFSetFieldString(ctx, GPSTAG_LONGITUDEREF, longitudeRef); err != nil {
+		return err
+	}
+	if err := f.TIFFSetFieldFixedDoubleArray(ctx, GPSTAG_LONGITUDE, degreesToDMS(gps.Longitude)); err != nil {
+		return err
+	}
+
+	if gps.Altitude != nil {
+		altitudeRef := uint16(0)
+		if *gps.Altitude < 0 {
+			altitudeRef = 1
+		}
+		if err := f.TIFFSetFieldUint16_t(ctx, GPSTAG_ALTITUDEREF, altitudeRef); err != nil {
+			return err
+		}
+		if err := f.TIFFSetFieldDouble(ctx, GPSTAG_ALTITUDE, math.Abs(*gps.Altitude)); err != nil {
+			return err
+		}
+	}
+
+	if !gps.Timestamp.IsZero() {
+		utc := gps.Timestamp.UTC()
+		seconds := float64(utc.Second()) + float64(utc.Nanosecond())/1e9
+		if err := f.TIFFSetFieldFixedDoubleArray(ctx, GPSTAG_TIMESTAMP, []float64{float64(utc.Hour()), float64(utc.Minute()), seconds}); err != nil {
+			return err
+		}
+		if err := f.TIFFSetFieldString(ctx, GPSTAG_DATESTAMP, utc.Format("2006:01:02")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// degreesToDMS converts decimal degrees to the degrees, minutes and seconds
+// triplet used by the GPS tags. The sign is dropped, it is stored in the
+// reference tag.
+func degreesToDMS(value float64) []float64 {
+	value = math.Abs(value)
+	degrees := math.Floor(value)
+	minutes := math.Floor((value - degrees) * 60)
+	seconds := (value - degrees - minutes/60) * 3600
+	return []float64{degrees, minutes, seconds}
+}
+
+// dmsToDegrees converts a degrees, minutes and seconds triplet to decimal
+// degrees.
+func dmsToDegrees(dms []float64) float64 {
+	var value float64
+	if len(dms) > 0 {
+		value += dms[0]
+	}
+	if len(dms) > 1 {
+		value += dms[1] / 60
+	}
+	if len(dms) > 2 {
+		value += dms[2] / 3600
+	}
+	return value
+}
+
+// encodeUserComment prefixes the comment with the 8-byte EXIF character code.
+func encodeUserComment(comment string, bigEndian bool) []byte {
+	isASCII := true
+	for i := 0; i < len(comment); i++ {
+		if comment[i] >= 0x80 {
+			isASCII = false
+			break
+		}
+	}
+
+	if isASCII {
+		return append([]byte("ASCII\x00\x00\x00"), comment...)
+	}
+
+	var byteOrder binary.AppendByteOrder = binary.LittleEndian
+	if bigEndian {
+		byteOrder = binary.BigEndian
+	}
+
+	data := []byte("UNICODE\x00")
+	for _, c := range utf16.Encode([]rune(comment)) {
+		data = byteOrder.AppendUint16(data, c)
+	}
+	return data
+}
+
+// decodeUserComment strips the 8-byte EXIF character code from a comment.
+func decodeUserComment(data []byte, byteOrder binary.ByteOrder) string {
+	if len(data) < 8 {
+		return ""
+	}
+
+	code := string(data[:8])
+	data = data[8:]
+	switch code {
+	case "UNICODE\x00":
+		chars := make([]uint16, len(data)/2)
+		for i := range chars {
+			chars[i] = byteOrder.Uint16(data[i*2:])
+		}
+		return trimNull(string(utf16.Decode(chars)))
+	default:
+		return trimNull(string(data))
+	}
+}
+
+// trimNull strips trailing null and space padding.
+func trimNull(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == 0 || s[len(s)-1] == ' ') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,405 @@
+package libtiff
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ExifFromJPEG reads the EXIF APP1 segment of a JPEG stream and converts it
+// into the Exif and GPS options of FromGoImageOptions. Both return values are
+// nil when the JPEG does not contain the corresponding metadata.
+func ExifFromJPEG(r io.Reader) (*Exif, *GPS, error) {
+	payload, err := readJPEGExifSegment(bufio.NewReader(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload == nil {
+		return nil, nil, nil
+	}
+
+	return parseExifPayload(payload)
+}
+
+// readJPEGExifSegment returns the TIFF structure inside the EXIF APP1
+// segment, or nil if there is no such segment before the image data.
+func readJPEGExifSegment(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return nil, err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, errors.New("not a JPEG stream")
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker prefix 0x%02X", b)
+		}
+
+		// Markers can be preceded by any amount of fill bytes.
+		marker := byte(0xFF)
+		for marker == 0xFF {
+			if marker, err = r.ReadByte(); err != nil {
+				return nil, err
+			}
+		}
+
+		// Markers without a segment.
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			continue
+		}
+
+		// Start of scan or end of image, metadata always comes before these.
+		if marker == 0xDA || marker == 0xD9 {
+			return nil, nil
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, err
+		}
+		segmentLength := int(binary.BigEndian.Uint16(length[:]))
+		if segmentLength < 2 {
+			return nil, errors.New("invalid JPEG segment length")
+		}
+
+		segment := make([]byte, segmentLength-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, err
+		}
+
+		if marker == 0xE1 && len(segment) >= 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return segment[6:], nil
+		}
+	}
+}
+
+// parseExifPayload parses the TIFF structure of an EXIF segment.
+func parseExifPayload(data []byte) (*Exif, *GPS, error) {
+	order, ifd0Offset, err := parseTIFFHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ifd0, err := readIFD(data, order, ifd0Offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var exif *Exif
+	var gps *GPS
+
+	if entry, ok := ifd0[uint16(TIFFTAG_EXIFIFD)]; ok {
+		if offset, ok := entry.uint(); ok {
+			entries, err := readIFD(data, order, uint32(offset))
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not read EXIF IFD: %w", err)
+			}
+			exif = exifFromEntries(entries, order)
+		}
+	}
+
+	if entry, ok := ifd0[uint16(TIFFTAG_GPSIFD)]; ok {
+		if offset, ok := entry.uint(); ok {
+			entries, err := readIFD(data, order, uint32(offset))
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not read GPS IFD: %w", err)
+			}
+			gps = gpsFromEntries(entries)
+		}
+	}
+
+	return exif, gps, nil
+}
+
+func exifFromEntries(entries map[uint16]ifdEntry, order binary.ByteOrder) *Exif {
+	exif := &Exif{}
+
+	stringTags := []struct {
+		value *string
+		tag   TIFFTAG
+	}{
+		{&exif.DateTimeOriginal, EXIFTAG_DATETIMEORIGINAL},
+		{&exif.DateTimeDigitized, EXIFTAG_DATETIMEDIGITIZED},
+		{&exif.LensMake, EXIFTAG_LENSMAKE},
+		{&exif.LensModel, EXIFTAG_LENSMODEL},
+		{&exif.BodySerialNumber, EXIFTAG_BODYSERIALNUMBER},
+	}
+	for _, t := range stringTags {
+		if entry, ok := entries[uint16(t.tag)]; ok {
+			*t.value = entry.string()
+		}
+	}
+
+	rationalTags := []struct {
+		value *float64
+		tag   TIFFTAG
+	}{
+		{&exif.ExposureTime, EXIFTAG_EXPOSURETIME},
+		{&exif.FNumber, EXIFTAG_FNUMBER},
+		{&exif.ExposureBiasValue, EXIFTAG_EXPOSUREBIASVALUE},
+		{&exif.FocalLength, EXIFTAG_FOCALLENGTH},
+	}
+	for _, t := range rationalTags {
+		if entry, ok := entries[uint16(t.tag)]; ok {
+			if values := entry.floats(); len(values) > 0 {
+				*t.value = values[0]
+			}
+		}
+	}
+
+	shortTags := []struct {
+		value *uint16
+		tag   TIFFTAG
+	}{
+		{&exif.ExposureProgram, EXIFTAG_EXPOSUREPROGRAM},
+		{&exif.MeteringMode, EXIFTAG_METERINGMODE},
+		{&exif.Flash, EXIFTAG_FLASH},
+		{&exif.FocalLengthIn35mmFilm, EXIFTAG_FOCALLENGTHIN35MMFILM},
+		{&exif.ColorSpace, EXIFTAG_COLORSPACE},
+	}
+	for _, t := range shortTags {
+		if entry, ok := entries[uint16(t.tag)]; ok {
+			if value, ok := entry.uint(); ok {
+				*t.value = uint16(value)
+			}
+		}
+	}
+
+	if entry, ok := entries[uint16(EXIFTAG_PIXELXDIMENSION)]; ok {
+		if value, ok := entry.uint(); ok {
+			exif.PixelXDimension = uint32(value)
+		}
+	}
+	if entry, ok := entries[uint16(EXIFTAG_PIXELYDIMENSION)]; ok {
+		if value, ok := entry.uint(); ok {
+			exif.PixelYDimension = uint32(value)
+		}
+	}
+
+	if entry, ok := entries[uint16(EXIFTAG_ISOSPEEDRATINGS)]; ok {
+		for _, value := range entry.uints() {
+			exif.ISOSpeedRatings = append(exif.ISOSpeedRatings, uint16(value))
+		}
+	}
+
+	if entry, ok := entries[uint16(EXIFTAG_USERCOMMENT)]; ok {
+		exif.UserComment = decodeUserComment(entry.value, order)
+	}
+
+	return exif
+}
+
+func gpsFromEntries(entries map[uint16]ifdEntry) *GPS {
+	latitude, hasLatitude := entries[uint16(GPSTAG_LATITUDE)]
+	longitude, hasLongitude := entries[uint16(GPSTAG_LONGITUDE)]
+	if !hasLatitude || !hasLongitude {
+		return nil
+	}
+
+	gps := &GPS{
+		Latitude:  dmsToDegrees(latitude.floats()),
+		Longitude: dmsToDegrees(longitude.floats()),
+	}
+	if entry, ok := entries[uint16(GPSTAG_LATITUDEREF)]; ok && entry.string() == "S" {
+		gps.Latitude = -gps.Latitude
+	}
+	if entry, ok := entries[uint16(GPSTAG_LONGITUDEREF)]; ok && entry.string() == "W" {
+		gps.Longitude = -gps.Longitude
+	}
+
+	if entry, ok := entries[uint16(GPSTAG_ALTITUDE)]; ok {
+		if values := entry.floats(); len(values) > 0 {
+			altitude := values[0]
+			if ref, ok := entries[uint16(GPSTAG_ALTITUDEREF)]; ok {
+				if value, ok := ref.uint(); ok && value == 1 {
+					altitude = -altitude
+				}
+			}
+			gps.Altitude = &altitude
+		}
+	}
+
+	timeEntry, hasTime := entries[uint16(GPSTAG_TIMESTAMP)]
+	dateEntry, hasDate := entries[uint16(GPSTAG_DATESTAMP)]
+	if hasTime && hasDate {
+		date, err := time.Parse("2006:01:02", dateEntry.string())
+		values := timeEntry.floats()
+		if err == nil && len(values) == 3 {
+			seconds, fraction := math.Modf(values[2])
+			gps.Timestamp = time.Date(date.Year(), date.Month(), date.Day(),
+				int(values[0]), int(values[1]), int(seconds), int(math.Round(fraction*1e9)), time.UTC)
+		}
+	}
+
+	return gps
+}
+
+// parseTIFFHeader returns the byte order and the offset of the first IFD.
+// BigTIFF is not supported, EXIF data is always a classic TIFF structure.
+func parseTIFFHeader(data []byte) (binary.ByteOrder, uint32, error) {
+	if len(data) < 8 {
+		return nil, 0, errors.New("TIFF header is too short")
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, errors.New("invalid TIFF byte order")
+	}
+
+	if order.Uint16(data[2:]) != 42 {
+		return nil, 0, errors.New("invalid TIFF magic number")
+	}
+
+	return order, order.Uint32(data[4:]), nil
+}
+
+// ifdEntry is a single tag of an IFD, with the value bytes resolved.
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+	order binary.ByteOrder
+}
+
+// ifdTypeSizes holds the size in bytes of a single value per TIFF data type.
+var ifdTypeSizes = map[uint16]uint32{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	6:  1, // SBYTE
+	7:  1, // UNDEFINED
+	8:  2, // SSHORT
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+	11: 4, // FLOAT
+	12: 8, // DOUBLE
+	13: 4, // IFD
+}
+
+// readIFD reads the entries of the IFD at the given offset. Entries with an
+// unknown type or a value outside of the data are skipped.
+func readIFD(data []byte, order binary.ByteOrder, offset uint32) (map[uint16]ifdEntry, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, errors.New("IFD offset is out of bounds")
+	}
+
+	count := uint64(order.Uint16(data[offset:]))
+	if uint64(offset)+2+count*12 > uint64(len(data)) {
+		return nil, errors.New("IFD entries are out of bounds")
+	}
+
+	entries := map[uint16]ifdEntry{}
+	for i := uint64(0); i < count; i++ {
+		raw := data[uint64(offset)+2+i*12:]
+		tag := order.Uint16(raw)
+		typ := order.Uint16(raw[2:])
+		valueCount := order.Uint32(raw[4:])
+
+		typeSize, ok := ifdTypeSizes[typ]
+		if !ok {
+			continue
+		}
+
+		size := uint64(typeSize) * uint64(valueCount)
+		var value []byte
+		if size <= 4 {
+			value = raw[8 : 8+size]
+		} else {
+			valueOffset := uint64(order.Uint32(raw[8:]))
+			if valueOffset+size > uint64(len(data)) {
+				continue
+			}
+			value = data[valueOffset : valueOffset+size]
+		}
+
+		entries[tag] = ifdEntry{
+			typ:   typ,
+			count: valueCount,
+			value: value,
+			order: order,
+		}
+	}
+
+	return entries, nil
+}
+
+// string returns an ASCII value without its null terminator.
+func (e ifdEntry) string() string {
+	return trimNull(string(e.value))
+}
+
+// uints returns the values of an unsigned integer entry.
+func (e ifdEntry) uints() []uint64 {
+	values := make([]uint64, 0, e.count)
+	for i := uint32(0); i < e.count; i++ {
+		switch e.typ {
+		case 1, 7:
+			values = append(values, uint64(e.value[i]))
+		case 3:
+			values = append(values, uint64(e.order.Uint16(e.value[i*2:])))
+		case 4, 13:
+			values = append(values, uint64(e.order.Uint32(e.value[i*4:])))
+		default:
+			return nil
+		}
+	}
+	return values
+}
+
+// uint returns the first value of an unsigned integer entry.
+func (e ifdEntry) uint() (uint64, bool) {
+	values := e.uints()
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// floats returns the values of a rational or floating point entry.
+func (e ifdEntry) floats() []float64 {
+	values := make([]float64, 0, e.count)
+	for i := uint32(0); i < e.count; i++ {
+		switch e.typ {
+		case 5:
+			numerator := e.order.Uint32(e.value[i*8:])
+			denominator := e.order.Uint32(e.value[i*8+4:])
+			if denominator == 0 {
+				values = append(values, 0)
+				continue
+			}
+			values = append(values, float64(numerator)/float64(denominator))
+		case 10:
+			numerator := int32(e.order.Uint32(e.value[i*8:]))
+			denominator := int32(e.order.Uint32(e.value[i*8+4:]))
+			if denominator == 0 {
+				values = append(values, 0)
+				continue
+			}
+			values = append(values, float64(numerator)/float64(denominator))
+		case 11:
+			values = append(values, float64(math.Float32frombits(e.order.Uint32(e.value[i*4:]))))
+		case 12:
+			values = append(values, math.Float64frombits(e.order.Uint64(e.value[i*8:])))
+		default:
+			return nil
+		}
+	}
+	return values
+}
@@ -0,0 +1,382 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/jpeg"
+	"os"
+	"time"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type testIFDEntry struct {
+	tag   libtiff.TIFFTAG
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// buildExifPayload builds the TIFF structure of an EXIF APP1 segment with an
+// IFD0 that links to the given EXIF and GPS IFDs.
+func buildExifPayload(order binary.ByteOrder, exifEntries, gpsEntries []testIFDEntry) []byte {
+	ifdSize := func(entries int) uint32 { return uint32(2 + entries*12 + 4) }
+	ifd0Offset := uint32(8)
+	exifOffset := ifd0Offset + ifdSize(2)
+	gpsOffset := exifOffset + ifdSize(len(exifEntries))
+	dataOffset := gpsOffset + ifdSize(len(gpsEntries))
+
+	buf := make([]byte, dataOffset)
+	if order == binary.BigEndian {
+		copy(buf, "MM")
+	} else {
+		copy(buf, "II")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], ifd0Offset)
+
+	writeIFD := func(offset uint32, entries []testIFDEntry) {
+		order.PutUint16(buf[offset:], uint16(len(entries)))
+		for i, entry := range entries {
+			raw := buf[offset+2+uint32(i)*12:]
+			order.PutUint16(raw, uint16(entry.tag))
+			order.PutUint16(raw[2:], entry.typ)
+			order.PutUint32(raw[4:], entry.count)
+			if len(entry.value) <= 4 {
+				copy(raw[8:12], entry.value)
+			} else {
+				order.PutUint32(raw[8:], uint32(len(buf)))
+				buf = append(buf, entry.value...)
+			}
+		}
+	}
+
+	long := func(v uint32) []byte {
+		data := make([]byte, 4)
+		order.PutUint32(data, v)
+		return data
+	}
+	writeIFD(ifd0Offset, []testIFDEntry{
+		{libtiff.TIFFTAG_EXIFIFD, 4, 1, long(exifOffset)},
+		{libtiff.TIFFTAG_GPSIFD, 4, 1, long(gpsOffset)},
+	})
+	writeIFD(exifOffset, exifEntries)
+	writeIFD(gpsOffset, gpsEntries)
+
+	return buf
+}
+
+// insertExifSegment inserts an EXIF APP1 segment directly after the SOI marker.
+func insertExifSegment(jpegData []byte, payload []byte) []byte {
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+8))
+	segment = append(segment, "Exif\x00\x00"...)
+	segment = append(segment, payload...)
+
+	result := append([]byte{}, jpegData[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegData[2:]...)
+}
+
+func encodeTestJPEG() []byte {
+	buf := &bytes.Buffer{}
+	Expect(jpeg.Encode(buf, createTestRGBA(16, 16), nil)).To(Succeed())
+	return buf.Bytes()
+}
+
+func readGPSDirectory(ctx context.Context, tiffFile *libtiff.File) {
+	offset, err := tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_GPSIFD)
+	Expect(err).To(BeNil())
+	Expect(offset).To(BeNumerically(">", 0))
+	Expect(tiffFile.TIFFReadGPSDirectory(ctx, offset)).To(Succeed())
+}
+
+var _ = Describe("FromGoImage EXIF and GPS", func() {
+	ctx := context.Background()
+
+	It("writes an EXIF sub-IFD and links it from the main IFD", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Exif: &libtiff.Exif{
+				DateTimeOriginal: "2024:05:06 07:08:09",
+				ExposureTime:     1.0 / 250,
+				FNumber:          2.8,
+				ISOSpeedRatings:  []uint16{400},
+				FocalLength:      35,
+				LensModel:        "Test Lens 35mm",
+				UserComment:      "hello",
+				ColorSpace:       1,
+			},
+		})
+		defer cleanup()
+
+		width, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGEWIDTH)
+		Expect(err).To(BeNil())
+		Expect(width).To(Equal(uint32(16)))
+
+		_, err = tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_GPSIFD)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_GPSIFD,
+		}))
+
+		offset, err := tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD)
+		Expect(err).To(BeNil())
+		Expect(tiffFile.TIFFReadEXIFDirectory(ctx, offset)).To(Succeed())
+
+		version, err := tiffFile.TIFFGetFieldFixedByteArray(ctx, libtiff.EXIFTAG_EXIFVERSION, 4)
+		Expect(err).To(BeNil())
+		Expect(string(version)).To(Equal("0232"))
+
+		dateTime, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.EXIFTAG_DATETIMEORIGINAL)
+		Expect(err).To(BeNil())
+		Expect(dateTime).To(Equal("2024:05:06 07:08:09"))
+
+		exposureTime, err := tiffFile.TIFFGetFieldFloat(ctx, libtiff.EXIFTAG_EXPOSURETIME)
+		Expect(err).To(BeNil())
+		Expect(exposureTime).To(BeNumerically("~", 1.0/250, 1e-6))
+
+		fNumber, err := tiffFile.TIFFGetFieldFloat(ctx, libtiff.EXIFTAG_FNUMBER)
+		Expect(err).To(BeNil())
+		Expect(fNumber).To(BeNumerically("~", 2.8, 1e-5))
+
+		iso, err := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.EXIFTAG_ISOSPEEDRATINGS)
+		Expect(err).To(BeNil())
+		Expect(iso).To(Equal([]uint16{400}))
+
+		lensModel, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.EXIFTAG_LENSMODEL)
+		Expect(err).To(BeNil())
+		Expect(lensModel).To(Equal("Test Lens 35mm"))
+
+		userComment, err := tiffFile.TIFFGetFieldByteArray(ctx, libtiff.EXIFTAG_USERCOMMENT)
+		Expect(err).To(BeNil())
+		Expect(string(userComment)).To(Equal("ASCII\x00\x00\x00hello"))
+
+		colorSpace, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.EXIFTAG_COLORSPACE)
+		Expect(err).To(BeNil())
+		Expect(colorSpace).To(Equal(uint16(1)))
+
+		_, err = tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.EXIFTAG_FLASH)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.EXIFTAG_FLASH,
+		}))
+	})
+
+	It("writes a GPS sub-IFD and links it from the main IFD", func() {
+		altitude := -12.5
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			GPS: &libtiff.GPS{
+				Latitude:  -33.8568,
+				Longitude: 151.2153,
+				Altitude:  &altitude,
+				Timestamp: time.Date(2024, 5, 6, 9, 8, 7, 0, time.FixedZone("CEST", 2*60*60)),
+			},
+		})
+		defer cleanup()
+
+		_, err := tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_EXIFIFD,
+		}))
+
+		readGPSDirectory(ctx, tiffFile)
+
+		version, err := tiffFile.TIFFGetFieldFixedByteArray(ctx, libtiff.GPSTAG_VERSIONID, 4)
+		Expect(err).To(BeNil())
+		Expect(version).To(Equal([]byte{2, 3, 0, 0}))
+
+		latitudeRef, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.GPSTAG_LATITUDEREF)
+		Expect(err).To(BeNil())
+		Expect(latitudeRef).To(Equal("S"))
+
+		latitude, err := tiffFile.TIFFGetFieldFixedDoubleArray(ctx, libtiff.GPSTAG_LATITUDE, 3)
+		Expect(err).To(BeNil())
+		Expect(latitude[0]).To(Equal(33.0))
+		Expect(latitude[1]).To(Equal(51.0))
+		Expect(latitude[2]).To(BeNumerically("~", 24.48, 1e-6))
+
+		longitudeRef, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.GPSTAG_LONGITUDEREF)
+		Expect(err).To(BeNil())
+		Expect(longitudeRef).To(Equal("E"))
+
+		longitude, err := tiffFile.TIFFGetFieldFixedDoubleArray(ctx, libtiff.GPSTAG_LONGITUDE, 3)
+		Expect(err).To(BeNil())
+		Expect(longitude[0] + longitude[1]/60 + longitude[2]/3600).To(BeNumerically("~", 151.2153, 1e-9))
+
+		altitudeRef, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.GPSTAG_ALTITUDEREF)
+		Expect(err).To(BeNil())
+		Expect(altitudeRef).To(Equal(uint16(1)))
+
+		readAltitude, err := tiffFile.TIFFGetFieldDouble(ctx, libtiff.GPSTAG_ALTITUDE)
+		Expect(err).To(BeNil())
+		Expect(readAltitude).To(BeNumerically("~", 12.5, 1e-9))
+
+		timestamp, err := tiffFile.TIFFGetFieldFixedDoubleArray(ctx, libtiff.GPSTAG_TIMESTAMP, 3)
+		Expect(err).To(BeNil())
+		Expect(timestamp).To(Equal([]float64{7, 8, 7}))
+
+		dateStamp, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.GPSTAG_DATESTAMP)
+		Expect(err).To(BeNil())
+		Expect(dateStamp).To(Equal("2024:05:06"))
+	})
+
+	It("keeps the image data intact", func() {
+		img := createTestRGBA(16, 16)
+		tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+			Exif: &libtiff.Exif{FNumber: 4},
+			GPS:  &libtiff.GPS{Latitude: 52.37, Longitude: 4.89},
+		})
+		defer cleanup()
+
+		goImg, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		Expect(goImg.Bounds()).To(Equal(img.Bounds()))
+		Expect(goImg.At(5, 9)).To(Equal(img.At(5, 9)))
+	})
+
+	It("links separate sub-IFDs for every page", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-test-*.tif")
+		Expect(err).To(BeNil())
+		defer os.Remove(tmpFile.Name())
+
+		fileMode := "w"
+		writeTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			FileMode: &fileMode,
+		})
+		Expect(err).To(BeNil())
+
+		for _, latitude := range []float64{10, 20} {
+			Expect(writeTiff.FromGoImage(ctx, createTestRGBA(8, 8), &libtiff.FromGoImageOptions{
+				GPS: &libtiff.GPS{Latitude: latitude, Longitude: 1},
+			})).To(Succeed())
+		}
+		writeTiff.Close(ctx)
+		tmpFile.Close()
+
+		readFile, err := os.Open(tmpFile.Name())
+		Expect(err).To(BeNil())
+		defer readFile.Close()
+		stat, err := readFile.Stat()
+		Expect(err).To(BeNil())
+
+		readTiff, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", readFile, uint64(stat.Size()), nil)
+		Expect(err).To(BeNil())
+		defer readTiff.Close(ctx)
+
+		pages, err := readTiff.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(pages).To(Equal(uint32(2)))
+
+		for page, expected := range []float64{10, 20} {
+			Expect(readTiff.TIFFSetDirectory(ctx, uint32(page))).To(Succeed())
+			readGPSDirectory(ctx, readTiff)
+
+			latitude, err := readTiff.TIFFGetFieldFixedDoubleArray(ctx, libtiff.GPSTAG_LATITUDE, 3)
+			Expect(err).To(BeNil())
+			Expect(latitude[0]).To(Equal(expected))
+		}
+	})
+})
+
+var _ = Describe("ExifFromJPEG", func() {
+	ctx := context.Background()
+
+	rational := func(order binary.ByteOrder, values ...uint32) []byte {
+		data := make([]byte, len(values)*4)
+		for i, v := range values {
+			order.PutUint32(data[i*4:], v)
+		}
+		return data
+	}
+
+	buildTestJPEG := func(order binary.ByteOrder) []byte {
+		short := func(v uint16) []byte {
+			data := make([]byte, 2)
+			order.PutUint16(data, v)
+			return data
+		}
+		payload := buildExifPayload(order, []testIFDEntry{
+			{libtiff.EXIFTAG_EXPOSURETIME, 5, 1, rational(order, 1, 125)},
+			{libtiff.EXIFTAG_FNUMBER, 5, 1, rational(order, 56, 10)},
+			{libtiff.EXIFTAG_ISOSPEEDRATINGS, 3, 2, append(short(100), short(200)...)},
+			{libtiff.EXIFTAG_DATETIMEORIGINAL, 2, 20, []byte("2023:01:02 03:04:05\x00")},
+			{libtiff.EXIFTAG_EXPOSUREBIASVALUE, 10, 1, rational(order, uint32(0xFFFFFFFF), 3)},
+			{libtiff.EXIFTAG_FLASH, 3, 1, short(16)},
+			{libtiff.EXIFTAG_USERCOMMENT, 7, 12, []byte("ASCII\x00\x00\x00test")},
+		}, []testIFDEntry{
+			{libtiff.GPSTAG_LATITUDEREF, 2, 2, []byte("S\x00")},
+			{libtiff.GPSTAG_LATITUDE, 5, 3, rational(order, 33, 1, 51, 1, 2448, 100)},
+			{libtiff.GPSTAG_LONGITUDEREF, 2, 2, []byte("W\x00")},
+			{libtiff.GPSTAG_LONGITUDE, 5, 3, rational(order, 70, 1, 30, 1, 0, 1)},
+			{libtiff.GPSTAG_ALTITUDEREF, 1, 1, []byte{0}},
+			{libtiff.GPSTAG_ALTITUDE, 5, 1, rational(order, 1005, 10)},
+			{libtiff.GPSTAG_TIMESTAMP, 5, 3, rational(order, 13, 1, 14, 1, 15, 1)},
+			{libtiff.GPSTAG_DATESTAMP, 2, 11, []byte("2023:01:02\x00")},
+		})
+		return insertExifSegment(encodeTestJPEG(), payload)
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		It("parses EXIF and GPS metadata in "+order.String()+" byte order", func() {
+			exif, gps, err := libtiff.ExifFromJPEG(bytes.NewReader(buildTestJPEG(order)))
+			Expect(err).To(BeNil())
+
+			Expect(exif).To(Not(BeNil()))
+			Expect(exif.ExposureTime).To(Equal(1.0 / 125))
+			Expect(exif.FNumber).To(Equal(5.6))
+			Expect(exif.ISOSpeedRatings).To(Equal([]uint16{100, 200}))
+			Expect(exif.DateTimeOriginal).To(Equal("2023:01:02 03:04:05"))
+			Expect(exif.ExposureBiasValue).To(Equal(-1.0 / 3))
+			Expect(exif.Flash).To(Equal(uint16(16)))
+			Expect(exif.UserComment).To(Equal("test"))
+
+			Expect(gps).To(Not(BeNil()))
+			Expect(gps.Latitude).To(BeNumerically("~", -33.8568, 1e-9))
+			Expect(gps.Longitude).To(Equal(-70.5))
+			Expect(gps.Altitude).To(Not(BeNil()))
+			Expect(*gps.Altitude).To(Equal(100.5))
+			Expect(gps.Timestamp).To(Equal(time.Date(2023, 1, 2, 13, 14, 15, 0, time.UTC)))
+		})
+	}
+
+	It("returns nil when the JPEG has no EXIF segment", func() {
+		exif, gps, err := libtiff.ExifFromJPEG(bytes.NewReader(encodeTestJPEG()))
+		Expect(err).To(BeNil())
+		Expect(exif).To(BeNil())
+		Expect(gps).To(BeNil())
+	})
+
+	It("returns an error for data that is not a JPEG", func() {
+		_, _, err := libtiff.ExifFromJPEG(bytes.NewReader([]byte("not a jpeg")))
+		Expect(err).To(MatchError("not a JPEG stream"))
+	})
+
+	It("carries the metadata over into a TIFF file", func() {
+		exif, gps, err := libtiff.ExifFromJPEG(bytes.NewReader(buildTestJPEG(binary.LittleEndian)))
+		Expect(err).To(BeNil())
+
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Exif: exif,
+			GPS:  gps,
+		})
+		defer cleanup()
+
+		readGPSDirectory(ctx, tiffFile)
+		longitude, err := tiffFile.TIFFGetFieldFixedDoubleArray(ctx, libtiff.GPSTAG_LONGITUDE, 3)
+		Expect(err).To(BeNil())
+		Expect(longitude[0] + longitude[1]/60 + longitude[2]/3600).To(BeNumerically("~", 70.5, 1e-9))
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 0)).To(Succeed())
+		offset, err := tiffFile.TIFFGetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD)
+		Expect(err).To(BeNil())
+		Expect(tiffFile.TIFFReadEXIFDirectory(ctx, offset)).To(Succeed())
+
+		bias, err := tiffFile.TIFFGetFieldFloat(ctx, libtiff.EXIFTAG_EXPOSUREBIASVALUE)
+		Expect(err).To(BeNil())
+		Expect(bias).To(BeNumerically("~", -1.0/3, 1e-6))
+	})
+})
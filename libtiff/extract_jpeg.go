@@ -0,0 +1,279 @@
+package libtiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/klippa-app/go-libtiff/internal/icc"
+)
+
+// JPEGNotExtractableError is returned by ExtractJPEG when the JPEG data of
+// the page can't be copied into a standalone JPEG file, callers can fall
+// back to decoding the page instead.
+type JPEGNotExtractableError struct {
+	Reason string
+}
+
+func (e JPEGNotExtractableError) Error() string {
+	return fmt.Sprintf("could not extract JPEG: %s", e.Reason)
+}
+
+func (e *JPEGNotExtractableError) Is(err error) bool {
+	if _, ok := err.(*JPEGNotExtractableError); ok {
+		return true
+	}
+	return false
+}
+
+// ExtractJPEG returns the JPEG data of the current directory as a standalone
+// JPEG file, without decoding and encoding it again. The page must use
+// COMPRESSION_JPEG with a single strip or tile that covers the image, or
+// COMPRESSION_OJPEG with a JPEGInterchangeFormat stream. The JPEGTables are
+// merged into the stream, the colorspace is marked with a JFIF or Adobe
+// segment and the ICC profile of the page is embedded when it is a gray or
+// RGB profile that matches the JPEG data.
+// A *JPEGNotExtractableError is returned for any other layout.
+func (f *File) ExtractJPEG(ctx context.Context) ([]byte, error) {
+	offset, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		return nil, errors.New("current directory has not been written")
+	}
+
+	var data []byte
+	err = f.withDescriber(func(d *describer) error {
+		if _, err := d.readHeader(); err != nil {
+			return err
+		}
+		directory, _, err := d.readDirectory(offset, "image")
+		if err != nil {
+			return err
+		}
+		data, err = d.extractJPEG(directory)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (d *describer) extractJPEG(directory *DirectoryInfo) ([]byte, error) {
+	image := directory.Image
+	if image == nil {
+		return nil, &JPEGNotExtractableError{Reason: "the directory has no image"}
+	}
+
+	values := map[TIFFTAG]any{}
+	for _, tag := range directory.Tags {
+		values[TIFFTAG(tag.Tag)] = tag.Value
+	}
+
+	var tables, stream []byte
+	var err error
+	switch image.Compression {
+	case COMPRESSION_JPEG:
+		if stream, err = d.readJPEGStrip(image); err != nil {
+			return nil, err
+		}
+		tables, _ = values[TIFFTAG_JPEGTABLES].([]byte)
+	case COMPRESSION_OJPEG:
+		offsets, _ := values[TIFFTAG_JPEGIFOFFSET].([]uint64)
+		byteCounts, _ := values[TIFFTAG_JPEGIFBYTECOUNT].([]uint64)
+		if len(offsets) != 1 || len(byteCounts) != 1 || byteCounts[0] == 0 {
+			return nil, &JPEGNotExtractableError{Reason: "the old-style JPEG image has no JPEGInterchangeFormat stream"}
+		}
+		if stream, err = d.readAt(offsets[0], byteCounts[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("the image has compression %s", image.Compression)}
+	}
+
+	marker, err := jpegColorspaceMarker(image)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := mergeJPEGStream(tables, stream, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	colorSpace := icc.ColorSpaceRGB
+	if image.SamplesPerPixel == 1 {
+		colorSpace = icc.ColorSpaceGray
+	}
+	if profile, ok := values[TIFFTAG_ICCPROFILE].([]byte); ok && iccProfileMatches(profile, colorSpace) {
+		if data, err = embedICCProfileJPEG(data, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// readJPEGStrip reads the only strip or tile of a COMPRESSION_JPEG image.
+func (d *describer) readJPEGStrip(image *ImageInfo) ([]byte, error) {
+	kind := "strips"
+	if image.Tiled {
+		kind = "tiles"
+	}
+	if len(image.Offsets) != 1 || len(image.ByteCounts) != 1 {
+		return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("the image has %d %s", len(image.Offsets), kind)}
+	}
+	if image.Tiled && (image.TileWidth != image.Width || image.TileHeight != image.Height) {
+		return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("the tile of %dx%d does not match the image of %dx%d", image.TileWidth, image.TileHeight, image.Width, image.Height)}
+	}
+	if image.ByteCounts[0] == 0 {
+		return nil, &JPEGNotExtractableError{Reason: "the image data is empty"}
+	}
+	return d.readAt(image.Offsets[0], image.ByteCounts[0])
+}
+
+// jpegColorspaceMarker returns the segment that tells JPEG decoders how to
+// interpret the components of the image. libtiff doesn't write these
+// markers, so decoders would otherwise have to guess.
+func jpegColorspaceMarker(image *ImageInfo) ([]byte, error) {
+	photometric := PHOTOMETRIC_MINISBLACK
+	if image.Photometric != nil {
+		photometric = *image.Photometric
+	}
+
+	switch {
+	case photometric == PHOTOMETRIC_MINISBLACK && image.SamplesPerPixel == 1,
+		photometric == PHOTOMETRIC_YCBCR && image.SamplesPerPixel == 3:
+		return jfifMarker(image), nil
+	case photometric == PHOTOMETRIC_RGB && image.SamplesPerPixel == 3:
+		// Transform 0 means the components are stored without color
+		// conversion.
+		return []byte{0xFF, 0xEE, 0, 14, 'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 0}, nil
+	default:
+		return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("photometric %s with %d samples is not supported", photometric, image.SamplesPerPixel)}
+	}
+}
+
+// jfifMarker returns a JFIF APP0 segment with the resolution of the image.
+func jfifMarker(image *ImageInfo) []byte {
+	units, x, y := byte(0), uint16(1), uint16(1)
+	if image.ResolutionUnit != nil && (*image.ResolutionUnit == RESUNIT_INCH || *image.ResolutionUnit == RESUNIT_CENTIMETER) {
+		xResolution, yResolution := math.Round(image.XResolution), math.Round(image.YResolution)
+		if xResolution >= 1 && xResolution <= math.MaxUint16 && yResolution >= 1 && yResolution <= math.MaxUint16 {
+			units, x, y = 1, uint16(xResolution), uint16(yResolution)
+			if *image.ResolutionUnit == RESUNIT_CENTIMETER {
+				units = 2
+			}
+		}
+	}
+
+	marker := []byte{0xFF, 0xE0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 2, units, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(marker[12:], x)
+	binary.BigEndian.PutUint16(marker[14:], y)
+	return marker
+}
+
+// mergeJPEGStream builds a standalone JPEG stream from the tables stream and
+// the image stream. Existing JFIF and Adobe segments are replaced by marker.
+func mergeJPEGStream(tables, stream, marker []byte) ([]byte, error) {
+	var tableSegments [][]byte
+	if len(tables) > 0 {
+		segments, rest, err := splitJPEGSegments(tables)
+		if err != nil {
+			return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("invalid JPEGTables: %s", err)}
+		}
+		if len(rest) > 0 {
+			return nil, &JPEGNotExtractableError{Reason: "the JPEGTables contain image data"}
+		}
+		tableSegments = segments
+	}
+
+	segments, rest, err := splitJPEGSegments(stream)
+	if err != nil {
+		return nil, &JPEGNotExtractableError{Reason: fmt.Sprintf("invalid JPEG data: %s", err)}
+	}
+	if len(rest) == 0 {
+		return nil, &JPEGNotExtractableError{Reason: "the JPEG data has no scan"}
+	}
+
+	result := bytes.NewBuffer(make([]byte, 0, len(tables)+len(stream)+len(marker)))
+	result.Write([]byte{0xFF, 0xD8})
+	result.Write(marker)
+	for _, segment := range append(tableSegments, segments...) {
+		if isColorspaceSegment(segment) {
+			continue
+		}
+		result.Write(segment)
+	}
+	result.Write(rest)
+	return result.Bytes(), nil
+}
+
+// splitJPEGSegments returns the marker segments of a JPEG stream up to the
+// first start of scan, and the rest of the stream from that start of scan.
+// The rest is empty when the stream ends before a scan.
+func splitJPEGSegments(stream []byte) ([][]byte, []byte, error) {
+	if len(stream) < 2 || stream[0] != 0xFF || stream[1] != 0xD8 {
+		return nil, nil, errors.New("not a JPEG stream")
+	}
+
+	var segments [][]byte
+	position := 2
+	for position < len(stream) {
+		if stream[position] != 0xFF {
+			return nil, nil, fmt.Errorf("invalid JPEG marker prefix 0x%02X", stream[position])
+		}
+
+		// Markers can be preceded by any amount of fill bytes.
+		start := position
+		for position < len(stream) && stream[position] == 0xFF {
+			position++
+		}
+		if position == len(stream) {
+			break
+		}
+		marker := stream[position]
+		position++
+
+		switch {
+		case marker == 0xDA:
+			return segments, stream[position-2:], nil
+		case marker == 0xD9:
+			return segments, nil, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// Markers without a segment.
+			continue
+		}
+
+		if position+2 > len(stream) {
+			return nil, nil, errors.New("unexpected end of JPEG stream")
+		}
+		length := int(binary.BigEndian.Uint16(stream[position:]))
+		if length < 2 || position+length > len(stream) {
+			return nil, nil, errors.New("invalid JPEG segment length")
+		}
+		position += length
+		segments = append(segments, stream[start:position])
+	}
+
+	return segments, nil, nil
+}
+
+// isColorspaceSegment reports whether the segment is a JFIF or Adobe segment.
+func isColorspaceSegment(segment []byte) bool {
+	// Skip the fill bytes, the marker and the length.
+	for len(segment) > 0 && segment[0] == 0xFF {
+		segment = segment[1:]
+	}
+	if len(segment) < 3 {
+		return false
+	}
+	marker, payload := segment[0], segment[3:]
+	return (marker == 0xE0 && bytes.HasPrefix(payload, []byte("JFIF\x00"))) ||
+		(marker == 0xEE && bytes.HasPrefix(payload, []byte("Adobe")))
+}
@@ -0,0 +1,172 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExtractJPEG", func() {
+	ctx := context.Background()
+
+	writeAndExtract := func(img image.Image, options *libtiff.FromGoImageOptions) ([]byte, image.Image, error) {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+		tiffFile = reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+		decoded, cleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		DeferCleanup(cleanup, ctx)
+
+		data, err := tiffFile.ExtractJPEG(ctx)
+		return data, decoded, err
+	}
+
+	// expectSameImage compares the pixels of the images, JPEG decoders may
+	// round differently.
+	expectSameImage := func(actual, expected image.Image) {
+		Expect(actual.Bounds()).To(Equal(expected.Bounds()))
+		bounds := expected.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r1, g1, b1, _ := expected.At(x, y).RGBA()
+				r2, g2, b2, _ := actual.At(x, y).RGBA()
+				for i, pair := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}} {
+					difference := int(pair[0]>>8) - int(pair[1]>>8)
+					Expect(difference).To(BeNumerically("~", 0, 4), "channel %d of pixel %d,%d", i, x, y)
+				}
+			}
+		}
+	}
+
+	It("extracts a YCbCr strip with its tables", func() {
+		data, expected, err := writeAndExtract(createTestRGBA(48, 32), &libtiff.FromGoImageOptions{
+			Compression:    libtiff.COMPRESSION_JPEG,
+			Quality:        90,
+			XResolution:    300,
+			YResolution:    300,
+			ResolutionUnit: libtiff.RESUNIT_INCH,
+		})
+		Expect(err).To(BeNil())
+
+		Expect(data[:4]).To(Equal([]byte{0xFF, 0xD8, 0xFF, 0xE0}))
+		Expect(string(data[6:11])).To(Equal("JFIF\x00"))
+		Expect(data[13:18]).To(Equal([]byte{1, 1, 44, 1, 44}))
+		Expect(bytes.Count(data, []byte{0xFF, 0xD8})).To(Equal(1))
+
+		actual, err := jpeg.Decode(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+		Expect(actual).To(BeAssignableToTypeOf(&image.YCbCr{}))
+		expectSameImage(actual, expected)
+	})
+
+	It("extracts a grayscale tile", func() {
+		data, expected, err := writeAndExtract(createTestGray(32, 32), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+			TileWidth:   32,
+			TileHeight:  32,
+		})
+		Expect(err).To(BeNil())
+
+		actual, err := jpeg.Decode(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+		Expect(actual).To(BeAssignableToTypeOf(&image.Gray{}))
+		expectSameImage(actual, expected)
+	})
+
+	It("embeds the ICC profile", func() {
+		profile := testICCProfile(560)
+		data, _, err := writeAndExtract(createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+			ICCProfile:  profile,
+		})
+		Expect(err).To(BeNil())
+		Expect(readJPEGICCProfile(data)).To(Equal(profile))
+
+		_, err = jpeg.Decode(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+
+		// The RGB profile doesn't apply to a grayscale image.
+		tiffFile := writeWithForeignICCProfile(ctx, createTestGray(16, 16), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+		}, profile)
+		data, err = tiffFile.ExtractJPEG(ctx)
+		Expect(err).To(BeNil())
+		Expect(readJPEGICCProfile(data)).To(BeEmpty())
+	})
+
+	It("returns the interchange stream of old-style JPEG images", func() {
+		stream := &bytes.Buffer{}
+		Expect(jpeg.Encode(stream, createTestRGBA(16, 8), nil)).To(Succeed())
+
+		// A little endian TIFF with a single directory followed by the
+		// bits per sample and the JPEG stream.
+		type entry struct {
+			tag, typ uint16
+			value    uint32
+		}
+		entries := []entry{
+			{256, 3, 16}, {257, 3, 8}, {258, 3, 0}, {259, 3, 6}, {262, 3, 6},
+			{273, 4, 0}, {277, 3, 3}, {278, 3, 8}, {279, 4, uint32(stream.Len())},
+			{513, 4, 0}, {514, 4, uint32(stream.Len())},
+		}
+		bitsOffset := uint32(8 + 2 + len(entries)*12 + 4)
+		streamOffset := bitsOffset + 6
+		file := &bytes.Buffer{}
+		file.WriteString("II")
+		binary.Write(file, binary.LittleEndian, []uint16{42})
+		binary.Write(file, binary.LittleEndian, []uint32{8})
+		binary.Write(file, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			count, value := uint32(1), e.value
+			switch e.tag {
+			case 258:
+				count, value = 3, bitsOffset
+			case 273, 513:
+				value = streamOffset
+			}
+			binary.Write(file, binary.LittleEndian, []uint16{e.tag, e.typ})
+			binary.Write(file, binary.LittleEndian, []uint32{count, value})
+		}
+		binary.Write(file, binary.LittleEndian, []uint32{0})
+		binary.Write(file, binary.LittleEndian, []uint16{8, 8, 8})
+		file.Write(stream.Bytes())
+
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", bytes.NewReader(file.Bytes()), uint64(file.Len()), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(tiffFile.Close, ctx)
+
+		data, err := tiffFile.ExtractJPEG(ctx)
+		Expect(err).To(BeNil())
+		expected, err := jpeg.Decode(bytes.NewReader(stream.Bytes()))
+		Expect(err).To(BeNil())
+		actual, err := jpeg.Decode(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal(expected))
+	})
+
+	It("refuses layouts that can't be extracted", func() {
+		_, _, err := writeAndExtract(createTestRGBA(48, 32), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+			TileWidth:   16,
+			TileHeight:  16,
+		})
+		Expect(err).To(MatchError("could not extract JPEG: the image has 6 tiles"))
+		Expect(errors.Is(err, &libtiff.JPEGNotExtractableError{})).To(BeTrue())
+
+		_, _, err = writeAndExtract(createTestGray(16, 16), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+		})
+		Expect(err).To(MatchError("could not extract JPEG: the image has compression " + libtiff.COMPRESSION_LZW.String()))
+		var notExtractable *libtiff.JPEGNotExtractableError
+		Expect(errors.As(err, &notExtractable)).To(BeTrue())
+	})
+})
@@ -16,6 +16,11 @@ type File struct {
 	readerFile *imports.File
 	instance   *Instance
 	closeFunc  func(context.Context) error
+
+	// pendingRationals holds the exact values of SetRational and
+	// SetSRational, they are written when the directory is written and
+	// discarded when another directory becomes current.
+	pendingRationals map[TIFFTAG]pendingRational
 }
 
 func (f *File) GetError() error {
@@ -84,6 +89,22 @@ func (f *File) TIFFCurrentDirectory(ctx context.Context) (uint32, error) {
 	return api.DecodeU32(res[0]), nil
 }
 
+// TIFFCurrentDirOffset returns the file offset of the current directory. It
+// is 0 for a directory that has not been written yet.
+func (f *File) TIFFCurrentDirOffset(ctx context.Context) (uint64, error) {
+	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFCurrentDirOffset", f.pointer)
+	if err != nil {
+		return 0, err
+	}
+
+	err = f.GetError()
+	if err != nil {
+		return 0, err
+	}
+
+	return res[0], nil
+}
+
 // TIFFLastDirectory returns whether the current directory is the last directory.
 func (f *File) TIFFLastDirectory(ctx context.Context) (bool, error) {
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFLastDirectory", f.pointer)
@@ -107,6 +128,8 @@ func (f *File) TIFFLastDirectory(ctx context.Context) (bool, error) {
 
 // TIFFReadDirectory will read the next directory.
 func (f *File) TIFFReadDirectory(ctx context.Context) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFReadDirectory", f.pointer)
 	if err != nil {
 		return err
@@ -126,6 +149,8 @@ func (f *File) TIFFReadDirectory(ctx context.Context) error {
 
 // TIFFSetDirectory will set and read the given directory.
 func (f *File) TIFFSetDirectory(ctx context.Context, n uint32) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFSetDirectory", f.pointer, api.EncodeU32(n))
 	if err != nil {
 		return err
@@ -159,6 +184,8 @@ func (f *File) TIFFNumberOfDirectories(ctx context.Context) (uint32, error) {
 
 // TIFFSetSubDirectory sets the current directory to the sub-IFD at the given byte offset.
 func (f *File) TIFFSetSubDirectory(ctx context.Context, diroff uint64) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFSetSubDirectory", f.pointer, diroff)
 	if err != nil {
 		return err
@@ -198,6 +225,8 @@ func (f *File) TIFFUnlinkDirectory(ctx context.Context, dirn uint32) error {
 // Note: the current libtiff implementation (4.7.1) always returns 0, but we
 // check for non-zero defensively in case future versions add error paths.
 func (f *File) TIFFCreateDirectory(ctx context.Context) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFCreateDirectory", f.pointer)
 	if err != nil {
 		return err
@@ -212,6 +241,8 @@ func (f *File) TIFFCreateDirectory(ctx context.Context) error {
 // TIFFCreateEXIFDirectory creates a new EXIF sub-IFD and makes it current.
 // The C function returns 0 on success, non-zero on error.
 func (f *File) TIFFCreateEXIFDirectory(ctx context.Context) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFCreateEXIFDirectory", f.pointer)
 	if err != nil {
 		return err
@@ -226,6 +257,8 @@ func (f *File) TIFFCreateEXIFDirectory(ctx context.Context) error {
 // TIFFCreateGPSDirectory creates a new GPS sub-IFD and makes it current.
 // The C function returns 0 on success, non-zero on error.
 func (f *File) TIFFCreateGPSDirectory(ctx context.Context) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFCreateGPSDirectory", f.pointer)
 	if err != nil {
 		return err
@@ -262,18 +295,26 @@ func (f *File) TIFFWriteCustomDirectory(ctx context.Context) (uint64, error) {
 
 	// Read the uint64 offset from WASM memory.
 	f.instance.internalInstance.CallLock.Lock()
-	defer f.instance.internalInstance.CallLock.Unlock()
 
 	readValue, success := f.instance.internalInstance.Module.Memory().ReadUint64Le(uint32(offsetPointer))
+	f.instance.internalInstance.CallLock.Unlock()
 	if !success {
 		return 0, errors.New("could not read custom directory offset")
 	}
 
+	err = f.writePendingRationals(readValue)
+	f.pendingRationals = nil
+	if err != nil {
+		return 0, err
+	}
+
 	return readValue, nil
 }
 
 // TIFFReadEXIFDirectory reads the EXIF sub-IFD at the given byte offset.
 func (f *File) TIFFReadEXIFDirectory(ctx context.Context, diroff uint64) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFReadEXIFDirectory", f.pointer, diroff)
 	if err != nil {
 		return err
@@ -292,6 +333,8 @@ func (f *File) TIFFReadEXIFDirectory(ctx context.Context, diroff uint64) error {
 
 // TIFFReadGPSDirectory reads the GPS sub-IFD at the given byte offset.
 func (f *File) TIFFReadGPSDirectory(ctx context.Context, diroff uint64) error {
+	f.pendingRationals = nil
+
 	res, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFReadGPSDirectory", f.pointer, diroff)
 	if err != nil {
 		return err
@@ -397,12 +440,21 @@ type OpenOptions struct {
 	MaxCumulatedMemAlloc *int32
 	WarnHandler          func(module string, message string)
 	WarnAboutUnknownTags *bool
-	FileMode             *string
+	// FileMode is a raw libtiff file mode, like "r" or "w8". Use Mode for a
+	// validated alternative, only one of them can be set.
+	FileMode *string
+	// Mode is the structured file mode, reading by default.
+	Mode *OpenMode
 }
 
 // TIFFOpenFileFromPath opens a file from a path. Be aware that this is limited to the
 // virtual filesystem given to the instance.
 func (i *Instance) TIFFOpenFileFromPath(ctx context.Context, filePath string, options *OpenOptions) (*File, error) {
+	fileMode, err := options.fileMode()
+	if err != nil {
+		return nil, err
+	}
+
 	imports.FileReaders.Mutex.Lock()
 	fileReaderIndex := imports.FileReaders.Counter
 	imports.FileReaders.Counter++
@@ -458,11 +510,6 @@ func (i *Instance) TIFFOpenFileFromPath(ctx context.Context, filePath string, op
 	}
 	defer cStringFilePath.Free(ctx)
 
-	fileMode := "r"
-	if options != nil && options.FileMode != nil && *options.FileMode != "" {
-		fileMode = *options.FileMode
-	}
-
 	cStringFileMode, err := i.newCString(ctx, fileMode)
 	if err != nil {
 		return nil, err
@@ -594,6 +641,11 @@ func (i *Instance) TIFFOpenFileFromReader(ctx context.Context, filename string,
 // fileSize is not absolutely required, but the file might not always be opened
 // correctly if the fileSize is not given.
 func (i *Instance) TIFFOpenFileFromReadWriteSeeker(ctx context.Context, filename string, readWriteSeeker io.ReadWriteSeeker, fileSize uint64, options *OpenOptions) (*File, error) {
+	fileMode, err := options.fileMode()
+	if err != nil {
+		return nil, err
+	}
+
 	imports.FileReaders.Mutex.Lock()
 	fileReaderIndex := imports.FileReaders.Counter
 	imports.FileReaders.Counter++
@@ -652,11 +704,6 @@ func (i *Instance) TIFFOpenFileFromReadWriteSeeker(ctx context.Context, filename
 	}
 	defer cStringFileName.Free(ctx)
 
-	fileMode := "r"
-	if options != nil && options.FileMode != nil && *options.FileMode != "" {
-		fileMode = *options.FileMode
-	}
-
 	cStringFileMode, err := i.newCString(ctx, fileMode)
 	if err != nil {
 		cleanupFileReader(ctx)
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/klippa-app/go-libtiff/internal/icc"
 )
 
 // AlphaMode controls how the alpha channel is stored in the TIFF file.
@@ -21,8 +24,28 @@ const (
 	AlphaUnassociated
 )
 
+// fromGoImageCompressions are the compressions that FromGoImage can write,
+// the other codecs of libtiff can only decode or need special samples.
+var fromGoImageCompressions = []Compression{
+	COMPRESSION_NONE,
+	COMPRESSION_LZW,
+	COMPRESSION_ADOBE_DEFLATE,
+	COMPRESSION_JPEG,
+	COMPRESSION_PACKBITS,
+	COMPRESSION_CCITTFAX3,
+	COMPRESSION_CCITTFAX4,
+}
+
+// FromGoImageCompressions returns the compressions that FromGoImage can
+// write.
+func FromGoImageCompressions() []Compression {
+	return slices.Clone(fromGoImageCompressions)
+}
+
 type FromGoImageOptions struct {
-	Compression TIFFTAG
+	// Compression is one of FromGoImageCompressions, COMPRESSION_NONE when
+	// 0.
+	Compression Compression
 	// Quality sets the compression quality level (1-100). Only used for JPEG
 	// compression. If 0, the default quality (75) is used.
 	Quality int
@@ -39,13 +62,21 @@ type FromGoImageOptions struct {
 	Artist string
 	// Predictor sets TIFFTAG_PREDICTOR. Only meaningful for LZW and Deflate.
 	// If 0, the tag is not set.
-	Predictor TIFFTAG
+	Predictor Predictor
 	// XResolution sets TIFFTAG_XRESOLUTION. If <= 0, the tag is not set.
 	XResolution float32
 	// YResolution sets TIFFTAG_YRESOLUTION. If <= 0, the tag is not set.
 	YResolution float32
+	// XResolutionRational sets TIFFTAG_XRESOLUTION to the exact fraction,
+	// for example as read with GetResolutionRational. If the denominator is
+	// not 0, it is used instead of XResolution. This requires a file opened
+	// with TIFFOpenFileFromReadWriteSeeker.
+	XResolutionRational Rational
+	// YResolutionRational sets TIFFTAG_YRESOLUTION to the exact fraction,
+	// see XResolutionRational.
+	YResolutionRational Rational
 	// ResolutionUnit sets TIFFTAG_RESOLUTIONUNIT. If 0, the tag is not set.
-	ResolutionUnit TIFFTAG
+	ResolutionUnit ResolutionUnit
 	// Description sets TIFFTAG_IMAGEDESCRIPTION. If empty, the tag is not written.
 	Description string
 	// Copyright sets TIFFTAG_COPYRIGHT. If empty, the tag is not written.
@@ -64,7 +95,7 @@ type FromGoImageOptions struct {
 	// Ignored for JPEG compression and tile-based output.
 	RowsPerStrip uint32
 	// Orientation sets TIFFTAG_ORIENTATION. If 0, defaults to ORIENTATION_TOPLEFT.
-	Orientation TIFFTAG
+	Orientation Orientation
 	// TileWidth sets the tile width for tile-based output. Both TileWidth and
 	// TileHeight must be set to enable tiled output. If 0, strip-based output is used.
 	TileWidth uint32
@@ -73,80 +104,416 @@ type FromGoImageOptions struct {
 	TileHeight uint32
 	// BilevelThreshold sets the luminance threshold for CCITT bilevel conversion.
 	// Pixels with luminance >= threshold become white, below become black.
-	// If 0, defaults to 128.
+	// If 0, defaults to 128. With dithering, it is the level between black
+	// and white.
 	BilevelThreshold uint8
+	// Binarization selects how the image is converted to black and white
+	// for CCITT compression. The zero value (BinarizeThreshold) uses only
+	// BilevelThreshold.
+	Binarization Binarization
+	// BinarizationWindow is the size in pixels of the window around every
+	// pixel for BinarizeSauvola and BinarizeNiblack. If 0, 15 is used.
+	// Windows that are about as large as a text line work best.
+	BinarizationWindow int
+	// BinarizationK is the k factor of BinarizeSauvola and BinarizeNiblack.
+	// If 0, 0.2 is used for Sauvola and -0.2 for Niblack.
+	BinarizationK float64
+	// Quantize reduces the colors of the image to a palette, which is
+	// written with PHOTOMETRIC_PALETTE at 4 bits per sample for up to 16
+	// colors and at 8 bits per sample otherwise, the sizes that baseline
+	// palette readers support. Alpha is not kept. Paletted images
+	// that have few enough colors are written as-is. Can't be used with
+	// JPEG or CCITT compression. If nil, the colors are kept.
+	Quantize *Quantize
 	// PageNumber sets TIFFTAG_PAGENUMBER. The first value is the page number
 	// (0-based), the second is the total number of pages. Both must be set
 	// (non-zero TotalPages) for the tag to be written.
 	PageNumber uint16
 	// TotalPages is the total number of pages for TIFFTAG_PAGENUMBER.
 	TotalPages uint16
+	// SubfileType sets TIFFTAG_SUBFILETYPE, like FILETYPE_PAGE for the
+	// pages of a multi-page document. If 0, the tag is not written.
+	SubfileType SubfileType
+	// Exif is written to an EXIF sub-IFD that is linked through
+	// TIFFTAG_EXIFIFD. If nil, no EXIF sub-IFD is written.
+	Exif *Exif
+	// GPS is written to a GPS sub-IFD that is linked through TIFFTAG_GPSIFD.
+	// If nil, no GPS sub-IFD is written.
+	GPS *GPS
+	// GeoReference writes the GeoTIFF tags (GeoKeyDirectory, tiepoint and
+	// pixel scale or transformation) and the GDAL_METADATA and GDAL_NODATA
+	// tags when set. A GeoReference read with File.GeoReference can be
+	// passed as-is. If nil, the image is not georeferenced.
+	GeoReference *GeoReference
+	// ICCProfile is embedded as TIFFTAG_ICCPROFILE when set. The data
+	// colour space of the profile must match the samples that are written:
+	// GRAY for gray and CCITT images, CMYK for *image.CMYK and RGB for all
+	// other images, including palette and JPEG images.
+	ICCProfile []byte
+	// XMP is written as an XMP packet in TIFFTAG_XMLPACKET.
+	// If nil, no XMP packet is written.
+	XMP *XMPMetadata
+	// IPTC is written as IPTC-IIM datasets in TIFFTAG_RICHTIFFIPTC.
+	// If nil, no IPTC metadata is written.
+	IPTC *IPTC
+	// Photoshop is written as Photoshop image resources in
+	// TIFFTAG_PHOTOSHOP. If nil, no Photoshop resources are written.
+	Photoshop *Photoshop
+	// Overviews writes reduced-resolution levels of the image after it. If
+	// nil, no overviews are written, unless COG is set.
+	Overviews *Overviews
+	// COG writes the image as a Cloud Optimized GeoTIFF: tiled, with
+	// overviews, and with all directories before the tile data, which is
+	// ordered from the smallest overview to the full resolution image. This
+	// lets readers fetch tiles with HTTP range requests. If TileWidth and
+	// TileHeight are 0, tiles of 512x512 are used, if Overviews is nil, the
+	// automatic levels are used. The image must be the only image in the
+	// file, which must have been opened via TIFFOpenFileFromReadWriteSeeker.
+	COG bool
 }
 
 // FromGoImage writes a Go image to the open TIFF file.
 // The file must have been opened for writing via TIFFOpenFileFromReadWriteSeeker.
+// The sample layout follows the image type: *image.Gray and *image.Gray16 are
+// written as 8 and 16-bit MINISBLACK, *image.RGBA64 and *image.NRGBA64 as
+// 16-bit RGBA, *image.CMYK as SEPARATED with INKSET_CMYK, *image.Paletted
+// with a ColorMap and all other images as 8-bit RGBA. JPEG only supports
+// 8-bit samples, so with JPEG compression 16-bit images are reduced to 8 bits.
 func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGoImageOptions) error {
+	if options != nil && options.Quantize != nil {
+		var err error
+		img, err = quantizedImage(img, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options != nil && options.COG {
+		return f.writeCOG(ctx, img, options)
+	}
+	if options != nil && options.Overviews != nil {
+		return f.writeGoImageWithOverviews(ctx, img, options)
+	}
+	return f.writeGoImage(ctx, img, options, 0)
+}
+
+// writeGoImage writes img as one directory. When subIFDs is not 0, that
+// many SubIFDs are reserved in the directory, which are filled by the next
+// directories that are written.
+func (f *File) writeGoImage(ctx context.Context, img image.Image, options *FromGoImageOptions, subIFDs int) error {
 	bounds := img.Bounds()
 	width := uint32(bounds.Dx())
 	height := uint32(bounds.Dy())
 
-	compression := COMPRESSION_NONE
+	enc, err := newImageEncoding(img, options)
+	if err != nil {
+		return err
+	}
+	enc.subIFDs = subIFDs
+
+	rowsPerStrip, err := f.setImageTags(ctx, enc, width, height, false, options)
+	if err != nil {
+		return err
+	}
+
+	if !enc.useTiles {
+		strip := uint32(0)
+		return f.writeStrips(ctx, bounds, rowsPerStrip, enc.rowSize(int(width)), &strip, newStripFiller(img, enc, options))
+	}
+
+	tileWidth := options.TileWidth
+	tileHeight := options.TileHeight
+
+	// CCITT bilevel output.
+	if enc.isCCITT {
+		isBlack := newBilevel(img, options)
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, 1, func(tileData []byte, tileX, tileY, tw, th int) {
+			tileBytesPerRow := (tw + 7) / 8
+			for row := 0; row < th && tileY+row < bounds.Max.Y; row++ {
+				imgY := tileY + row
+				for col := 0; col < tw && tileX+col < bounds.Max.X; col++ {
+					if isBlack(tileX+col, imgY) {
+						tileData[row*tileBytesPerRow+col/8] |= 1 << uint(7-col%8) // black = 1 for MINISWHITE
+					}
+				}
+			}
+		})
+	}
+
+	// Paletted output, the indexes are packed into samples of bitsPerSample
+	// bits.
+	if enc.paletted != nil {
+		tileRowSize := (int(tileWidth)*int(enc.bitsPerSample) + 7) / 8
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, int(enc.bitsPerSample), func(tileData []byte, tileX, tileY, tw, th int) {
+			packPaletted(enc.paletted, tileData, tileX, tileY, tw, th, tileRowSize, enc.bitsPerSample)
+		})
+	}
+
+	bytesPerPixel := enc.rowSize(1)
+	isJPEG := enc.isJPEG
+	alphaMode := enc.alphaMode
+
+	// Image types with their own sample layout.
+	if layout := enc.layout; layout != nil {
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel*8, func(tileData []byte, tileX, tileY, tw, th int) {
+			cols := min(tw, bounds.Max.X-tileX)
+			for row := 0; row < th && tileY+row < bounds.Max.Y; row++ {
+				layout.fill(tileData[row*tw*bytesPerPixel:], tileX, tileY+row, cols)
+			}
+		})
+	}
+
+	// Fast path for tiles.
+	if rgbaImg, ok := img.(*image.RGBA); ok && !isJPEG && alphaMode == AlphaAssociated {
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel*8, func(tileData []byte, tileX, tileY, tw, th int) {
+			tileBytesPerRow := tw * bytesPerPixel
+			for row := 0; row < th; row++ {
+				imgY := tileY + row
+				if imgY >= bounds.Max.Y {
+					break
+				}
+				srcY := imgY - bounds.Min.Y
+				dstStart := row * tileBytesPerRow
+				cols := tw
+				if tileX+cols > bounds.Max.X {
+					cols = bounds.Max.X - tileX
+				}
+				srcStart := srcY*rgbaImg.Stride + (tileX-bounds.Min.X)*4
+				copy(tileData[dstStart:dstStart+cols*4], rgbaImg.Pix[srcStart:srcStart+cols*4])
+			}
+		})
+	}
+	if nrgbaImg, ok := img.(*image.NRGBA); ok && !isJPEG && alphaMode == AlphaUnassociated {
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel*8, func(tileData []byte, tileX, tileY, tw, th int) {
+			tileBytesPerRow := tw * bytesPerPixel
+			for row := 0; row < th; row++ {
+				imgY := tileY + row
+				if imgY >= bounds.Max.Y {
+					break
+				}
+				srcY := imgY - bounds.Min.Y
+				dstStart := row * tileBytesPerRow
+				cols := tw
+				if tileX+cols > bounds.Max.X {
+					cols = bounds.Max.X - tileX
+				}
+				srcStart := srcY*nrgbaImg.Stride + (tileX-bounds.Min.X)*4
+				copy(tileData[dstStart:dstStart+cols*4], nrgbaImg.Pix[srcStart:srcStart+cols*4])
+			}
+		})
+	}
+
+	// Generic tile path.
+	if alphaMode == AlphaUnassociated {
+		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel*8, func(tileData []byte, tileX, tileY, tw, th int) {
+			tileBytesPerRow := tw * bytesPerPixel
+			for row := 0; row < th; row++ {
+				imgY := tileY + row
+				if imgY >= bounds.Max.Y {
+					break
+				}
+				for col := 0; col < tw; col++ {
+					imgX := tileX + col
+					if imgX >= bounds.Max.X {
+						break
+					}
+					c := color.NRGBAModel.Convert(img.At(imgX, imgY)).(color.NRGBA)
+					offset := row*tileBytesPerRow + col*bytesPerPixel
+					tileData[offset] = c.R
+					tileData[offset+1] = c.G
+					tileData[offset+2] = c.B
+					if !isJPEG {
+						tileData[offset+3] = c.A
+					}
+				}
+			}
+		})
+	}
+
+	return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel*8, func(tileData []byte, tileX, tileY, tw, th int) {
+		tileBytesPerRow := tw * bytesPerPixel
+		for row := 0; row < th; row++ {
+			imgY := tileY + row
+			if imgY >= bounds.Max.Y {
+				break
+			}
+			for col := 0; col < tw; col++ {
+				imgX := tileX + col
+				if imgX >= bounds.Max.X {
+					break
+				}
+				r, g, b, a := img.At(imgX, imgY).RGBA()
+				offset := row*tileBytesPerRow + col*bytesPerPixel
+				tileData[offset] = uint8(r >> 8)
+				tileData[offset+1] = uint8(g >> 8)
+				tileData[offset+2] = uint8(b >> 8)
+				if !isJPEG {
+					tileData[offset+3] = uint8(a >> 8)
+				}
+			}
+		}
+	})
+}
+
+// imageEncoding is the sample layout and compression an image is written
+// with.
+type imageEncoding struct {
+	compression     Compression
+	useTiles        bool
+	isJPEG          bool
+	isCCITT         bool
+	alphaMode       AlphaMode
+	samplesPerPixel uint16
+	bitsPerSample   uint16
+	// paletted is set for images that are written with a color map.
+	paletted *image.Paletted
+	// layout is set for images with their own sample layout.
+	layout *sampleLayout
+	// subIFDs is the number of SubIFDs that follow the image.
+	subIFDs int
+}
+
+// newImageEncoding returns the encoding of img for the options.
+func newImageEncoding(img image.Image, options *FromGoImageOptions) (*imageEncoding, error) {
+	enc := &imageEncoding{
+		compression:     COMPRESSION_NONE,
+		alphaMode:       AlphaAuto,
+		samplesPerPixel: 4,
+		bitsPerSample:   8,
+	}
 	if options != nil && options.Compression != 0 {
-		compression = options.Compression
+		enc.compression = options.Compression
+	}
+	if !slices.Contains(fromGoImageCompressions, enc.compression) {
+		return nil, fmt.Errorf("compression %s is not supported for writing images", enc.compression)
 	}
 
 	// Validate tile options.
-	useTiles := false
 	if options != nil {
 		if (options.TileWidth > 0) != (options.TileHeight > 0) {
-			return fmt.Errorf("both TileWidth and TileHeight must be set for tile-based output")
+			return nil, fmt.Errorf("both TileWidth and TileHeight must be set for tile-based output")
 		}
-		useTiles = options.TileWidth > 0 && options.TileHeight > 0
+		enc.useTiles = options.TileWidth > 0 && options.TileHeight > 0
 	}
 
-	isCCITT := compression == COMPRESSION_CCITTFAX3 || compression == COMPRESSION_CCITTFAX4
+	enc.isCCITT = enc.compression == COMPRESSION_CCITTFAX3 || enc.compression == COMPRESSION_CCITTFAX4
+	if err := validateBinarization(options); err != nil {
+		return nil, err
+	}
 
 	// Determine alpha mode.
-	alphaMode := AlphaAuto
 	if options != nil {
-		alphaMode = options.AlphaMode
+		enc.alphaMode = options.AlphaMode
 	}
-	if alphaMode == AlphaAuto {
-		if _, ok := img.(*image.NRGBA); ok {
-			alphaMode = AlphaUnassociated
-		} else {
-			alphaMode = AlphaAssociated
+	if enc.alphaMode == AlphaAuto {
+		switch img.(type) {
+		case *image.NRGBA, *image.NRGBA64:
+			enc.alphaMode = AlphaUnassociated
+		default:
+			enc.alphaMode = AlphaAssociated
 		}
 	}
 
-	isJPEG := compression == COMPRESSION_JPEG
-	samplesPerPixel := uint16(4)
-	if isJPEG {
-		samplesPerPixel = 3 // JPEG does not support alpha channel.
+	enc.isJPEG = enc.compression == COMPRESSION_JPEG
+	if enc.isJPEG {
+		enc.samplesPerPixel = 3 // JPEG does not support alpha channel.
 	}
-	if isCCITT {
-		samplesPerPixel = 1
+	if enc.isCCITT {
+		enc.samplesPerPixel = 1
+		enc.bitsPerSample = 1
+	}
+
+	// Paletted images are written as palette indexes with a color map,
+	// unless the compression needs other samples.
+	if paletted, ok := img.(*image.Paletted); ok && !enc.isJPEG && !enc.isCCITT {
+		if len(paletted.Palette) > 256 {
+			return nil, fmt.Errorf("palette has %d colors, at most 256 are supported", len(paletted.Palette))
+		}
+		enc.paletted = paletted
+		enc.samplesPerPixel = 1
+		enc.bitsPerSample = paletteBitsPerSample(len(paletted.Palette))
+		if options != nil && options.Quantize != nil {
+			enc.bitsPerSample = max(enc.bitsPerSample, 4)
+		}
+	}
+
+	// Gray, 16-bit and CMYK images are written in their own sample layout.
+	if !enc.isCCITT && enc.paletted == nil {
+		enc.layout = newSampleLayout(img, enc.alphaMode, enc.isJPEG)
+	}
+	if enc.layout != nil {
+		enc.samplesPerPixel = enc.layout.samplesPerPixel
+		enc.bitsPerSample = enc.layout.bitsPerSample
+	}
+
+	if options != nil && len(options.ICCProfile) > 0 && !iccProfileMatches(options.ICCProfile, enc.colorSpace()) {
+		return nil, fmt.Errorf("ICC profile doesn't describe the %s samples of the image", strings.TrimSpace(string(enc.colorSpace())))
+	}
+
+	return enc, nil
+}
+
+// colorSpace returns the ICC colour space of the samples that are written.
+// Palette colors and the YCbCr samples of JPEG images are RGB.
+func (enc *imageEncoding) colorSpace() icc.ColorSpace {
+	switch {
+	case enc.isCCITT:
+		return icc.ColorSpaceGray
+	case enc.layout != nil && enc.layout.photometric == PHOTOMETRIC_MINISBLACK:
+		return icc.ColorSpaceGray
+	case enc.layout != nil && enc.layout.photometric == PHOTOMETRIC_SEPARATED:
+		return icc.ColorSpaceCMYK
+	default:
+		return icc.ColorSpaceRGB
+	}
+}
+
+// rowSize returns the size in bytes of a row of width pixels.
+func (enc *imageEncoding) rowSize(width int) int {
+	return (width*int(enc.samplesPerPixel)*int(enc.bitsPerSample) + 7) / 8
+}
+
+// bilevelThreshold returns the luminance threshold for CCITT output.
+func bilevelThreshold(options *FromGoImageOptions) uint8 {
+	if options != nil && options.BilevelThreshold != 0 {
+		return options.BilevelThreshold
+	}
+	return 128
+}
+
+// setImageTags sets the tags of the image and its metadata for the options
+// and returns the rows per strip, or 0 for tile-based output. The EXIF and
+// GPS sub-IFDs are written first. JPEG images are written as a single
+// strip, unless multiStripJPEG is set.
+func (f *File) setImageTags(ctx context.Context, enc *imageEncoding, width, height uint32, multiStripJPEG bool, options *FromGoImageOptions) (uint32, error) {
+	compression := enc.compression
+	isJPEG := enc.isJPEG
+	isCCITT := enc.isCCITT
+	layout := enc.layout
+
+	// The EXIF and GPS sub-IFDs are written before the image, so their
+	// offsets can be set in the main directory before it is written.
+	if options != nil && (options.Exif != nil || options.GPS != nil) {
+		if err := f.writeMetadataDirectories(ctx, options.Exif, options.GPS); err != nil {
+			return 0, err
+		}
 	}
 
 	// Set TIFF tags.
 	if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_IMAGEWIDTH, width); err != nil {
-		return err
+		return 0, err
 	}
 	if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_IMAGELENGTH, height); err != nil {
-		return err
+		return 0, err
 	}
-	bitsPerSample := uint16(8)
-	if isCCITT {
-		bitsPerSample = 1
-	}
-	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_BITSPERSAMPLE, bitsPerSample); err != nil {
-		return err
+	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_BITSPERSAMPLE, enc.bitsPerSample); err != nil {
+		return 0, err
 	}
-	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_SAMPLESPERPIXEL, samplesPerPixel); err != nil {
-		return err
+	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_SAMPLESPERPIXEL, enc.samplesPerPixel); err != nil {
+		return 0, err
 	}
 	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_COMPRESSION, uint16(compression)); err != nil {
-		return err
+		return 0, err
 	}
 	if isJPEG {
 		quality := 75
@@ -154,37 +521,56 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 			quality = options.Quality
 		}
 		if err := f.TIFFSetFieldInt(ctx, TIFFTAG_JPEGQUALITY, quality); err != nil {
-			return err
+			return 0, err
+		}
+	}
+	if layout != nil {
+		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(layout.photometric)); err != nil {
+			return 0, err
+		}
+		if layout.photometric == PHOTOMETRIC_SEPARATED {
+			if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_INKSET, uint16(INKSET_CMYK)); err != nil {
+				return 0, err
+			}
 		}
-		// JPEG in TIFF requires YCBCR photometric.
+	} else if isJPEG {
+		// JPEG in TIFF requires YCBCR photometric for RGB data.
 		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(PHOTOMETRIC_YCBCR)); err != nil {
-			return err
+			return 0, err
 		}
 		// Tell libtiff we provide RGB data and it should convert to YCbCr.
 		// Without this, libtiff expects raw subsampled YCbCr data and
 		// miscalculates the scanline size.
 		if err := f.TIFFSetFieldInt(ctx, TIFFTAG_JPEGCOLORMODE, int(JPEGCOLORMODE_RGB)); err != nil {
-			return err
+			return 0, err
 		}
 	} else if isCCITT {
 		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(PHOTOMETRIC_MINISWHITE)); err != nil {
-			return err
+			return 0, err
 		}
 		if compression == COMPRESSION_CCITTFAX3 {
 			if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_GROUP3OPTIONS, uint32(GROUP3OPT_FILLBITS)); err != nil {
-				return err
+				return 0, err
 			}
 		}
+	} else if enc.paletted != nil {
+		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(PHOTOMETRIC_PALETTE)); err != nil {
+			return 0, err
+		}
+		red, green, blue := paletteToColorMap(enc.paletted.Palette, enc.bitsPerSample)
+		if err := f.SetColorMap(ctx, red, green, blue); err != nil {
+			return 0, err
+		}
 	} else {
 		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(PHOTOMETRIC_RGB)); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	// Set predictor if specified.
 	if options != nil && options.Predictor != 0 {
 		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PREDICTOR, uint16(options.Predictor)); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
@@ -194,10 +580,10 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 		orientation = options.Orientation
 	}
 	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_ORIENTATION, uint16(orientation)); err != nil {
-		return err
+		return 0, err
 	}
 	if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PLANARCONFIG, uint16(PLANARCONFIG_CONTIG)); err != nil {
-		return err
+		return 0, err
 	}
 
 	// Set metadata tags.
@@ -206,20 +592,10 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 		software = options.Software
 	}
 	if software == "" {
-		software = "go-libtiff"
-		if ver, err := f.instance.TIFFGetVersion(ctx); err == nil {
-			// TIFFGetVersion returns "LIBTIFF, Version X.Y.Z\n...".
-			if i := strings.Index(ver, "Version "); i != -1 {
-				v := ver[i+len("Version "):]
-				if j := strings.IndexAny(v, "\n\r"); j != -1 {
-					v = v[:j]
-				}
-				software = "go-libtiff/libtiff-" + strings.TrimSpace(v)
-			}
-		}
+		software = f.defaultSoftware(ctx)
 	}
 	if err := f.TIFFSetFieldString(ctx, TIFFTAG_SOFTWARE, software); err != nil {
-		return err
+		return 0, err
 	}
 
 	dateTime := ""
@@ -230,7 +606,7 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 		dateTime = time.Now().Format("2006:01:02 15:04:05")
 	}
 	if err := f.TIFFSetFieldString(ctx, TIFFTAG_DATETIME, dateTime); err != nil {
-		return err
+		return 0, err
 	}
 
 	artist := ""
@@ -239,7 +615,7 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 	}
 	if artist != "" {
 		if err := f.TIFFSetFieldString(ctx, TIFFTAG_ARTIST, artist); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
@@ -260,277 +636,208 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 		for _, t := range optionalStringTags {
 			if t.value != "" {
 				if err := f.TIFFSetFieldString(ctx, t.tag, t.value); err != nil {
-					return err
+					return 0, err
 				}
 			}
 		}
 	}
 
+	// libtiff writes the next directories as the SubIFDs.
+	if enc.subIFDs > 0 {
+		if err := f.TIFFSetFieldUint64Array(ctx, TIFFTAG_SUBIFD, make([]uint64, enc.subIFDs)); err != nil {
+			return 0, err
+		}
+	}
+
+	if options != nil && options.SubfileType != 0 {
+		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_SUBFILETYPE, uint32(options.SubfileType)); err != nil {
+			return 0, err
+		}
+	}
+
 	// Set page number tag.
 	if options != nil && options.TotalPages > 0 {
 		if err := f.TIFFSetFieldTwoUint16(ctx, TIFFTAG_PAGENUMBER, options.PageNumber, options.TotalPages); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	// Set resolution tags.
 	if options != nil {
-		if options.XResolution > 0 {
+		if options.XResolutionRational.Denominator != 0 {
+			if err := f.SetRational(ctx, TIFFTAG_XRESOLUTION, options.XResolutionRational); err != nil {
+				return 0, err
+			}
+		} else if options.XResolution > 0 {
 			if err := f.TIFFSetFieldFloat(ctx, TIFFTAG_XRESOLUTION, options.XResolution); err != nil {
-				return err
+				return 0, err
 			}
 		}
-		if options.YResolution > 0 {
+		if options.YResolutionRational.Denominator != 0 {
+			if err := f.SetRational(ctx, TIFFTAG_YRESOLUTION, options.YResolutionRational); err != nil {
+				return 0, err
+			}
+		} else if options.YResolution > 0 {
 			if err := f.TIFFSetFieldFloat(ctx, TIFFTAG_YRESOLUTION, options.YResolution); err != nil {
-				return err
+				return 0, err
 			}
 		}
 		if options.ResolutionUnit != 0 {
 			if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_RESOLUTIONUNIT, uint16(options.ResolutionUnit)); err != nil {
-				return err
+				return 0, err
 			}
 		}
 	}
 
-	if !isJPEG && !isCCITT {
+	if options != nil && len(options.ICCProfile) > 0 {
+		if err := f.TIFFSetFieldByteArray(ctx, TIFFTAG_ICCPROFILE, options.ICCProfile); err != nil {
+			return 0, err
+		}
+	}
+
+	if options != nil && options.XMP != nil {
+		if err := f.TIFFSetFieldByteArray(ctx, TIFFTAG_XMLPACKET, options.XMP.encode()); err != nil {
+			return 0, err
+		}
+	}
+
+	if options != nil && options.IPTC != nil {
+		if err := f.TIFFSetFieldByteArray(ctx, TIFFTAG_RICHTIFFIPTC, options.IPTC.encode()); err != nil {
+			return 0, err
+		}
+	}
+
+	if options != nil && options.Photoshop != nil {
+		if err := f.TIFFSetFieldByteArray(ctx, TIFFTAG_PHOTOSHOP, options.Photoshop.encode()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Set GeoTIFF tags.
+	if options != nil && options.GeoReference != nil {
+		if err := f.setGeoReference(ctx, options.GeoReference); err != nil {
+			return 0, err
+		}
+	}
+
+	if layout != nil {
+		if layout.extraSample != nil {
+			if err := f.TIFFSetFieldExtraSamples(ctx, []uint16{uint16(*layout.extraSample)}); err != nil {
+				return 0, err
+			}
+		}
+	} else if !isJPEG && !isCCITT && enc.paletted == nil {
 		extraSample := EXTRASAMPLE_ASSOCALPHA
-		if alphaMode == AlphaUnassociated {
+		if enc.alphaMode == AlphaUnassociated {
 			extraSample = EXTRASAMPLE_UNASSALPHA
 		}
 		if err := f.TIFFSetFieldExtraSamples(ctx, []uint16{uint16(extraSample)}); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	// Set up tile or strip layout.
-	if useTiles {
-		tileWidth := options.TileWidth
-		tileHeight := options.TileHeight
-		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH, tileWidth); err != nil {
-			return err
+	if enc.useTiles {
+		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH, options.TileWidth); err != nil {
+			return 0, err
 		}
-		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILELENGTH, tileHeight); err != nil {
-			return err
+		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILELENGTH, options.TileHeight); err != nil {
+			return 0, err
 		}
+		return 0, nil
+	}
+
+	// Get a sensible strip size.
+	var rowsPerStrip uint32
+	if isJPEG && !multiStripJPEG {
+		// JPEG requires writing the entire image as a single strip to avoid
+		// MCU boundary alignment issues with partial last strips.
+		rowsPerStrip = height
+	} else if options != nil && options.RowsPerStrip > 0 {
+		rowsPerStrip = options.RowsPerStrip
 	} else {
-		// Get a sensible strip size.
-		var rowsPerStrip uint32
-		if isJPEG {
-			// JPEG requires writing the entire image as a single strip to avoid
-			// MCU boundary alignment issues with partial last strips.
-			rowsPerStrip = height
-		} else if options != nil && options.RowsPerStrip > 0 {
-			rowsPerStrip = options.RowsPerStrip
-		} else {
-			var err error
-			rowsPerStrip, err = f.TIFFDefaultStripSize(ctx, 0)
-			if err != nil {
-				return err
-			}
-		}
-		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_ROWSPERSTRIP, rowsPerStrip); err != nil {
-			return err
+		var err error
+		rowsPerStrip, err = f.TIFFDefaultStripSize(ctx, 0)
+		if err != nil {
+			return 0, err
 		}
 	}
+	if isJPEG && multiStripJPEG {
+		// Strips must hold whole MCUs, which are 16 rows for subsampled
+		// YCbCr.
+		rowsPerStrip = (rowsPerStrip + 15) / 16 * 16
+	}
+	if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_ROWSPERSTRIP, rowsPerStrip); err != nil {
+		return 0, err
+	}
+	return rowsPerStrip, nil
+}
 
-	// CCITT bilevel output.
-	if isCCITT {
-		threshold := uint8(128)
-		if options != nil && options.BilevelThreshold != 0 {
-			threshold = options.BilevelThreshold
-		}
-		bytesPerRow := (int(width) + 7) / 8
-
-		if useTiles {
-			return f.writeTiles(ctx, bounds, options.TileWidth, options.TileHeight, 0, func(tileData []byte, tileX, tileY, tw, th int) {
-				tileBytesPerRow := (tw + 7) / 8
-				for row := 0; row < th; row++ {
-					imgY := tileY + row
-					for col := 0; col < tw; col++ {
-						imgX := tileX + col
-						var lum uint8
-						if imgX < bounds.Max.X && imgY < bounds.Max.Y {
-							r, g, b, _ := img.At(imgX, imgY).RGBA()
-							lum = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
-						}
-						byteIdx := row*tileBytesPerRow + col/8
-						bitIdx := uint(7 - col%8)
-						if lum < threshold {
-							tileData[byteIdx] |= 1 << bitIdx // black = 1 for MINISWHITE
-						}
-					}
-				}
-			})
-		}
+// newStripFiller returns the function that fills strips with the samples
+// of img in the encoding.
+func newStripFiller(img image.Image, enc *imageEncoding, options *FromGoImageOptions) func(stripData []byte, y, rows int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	bytesPerRow := enc.rowSize(width)
+	isJPEG := enc.isJPEG
+	alphaMode := enc.alphaMode
 
-		strip := uint32(0)
-		rowsPerStrip := uint32(0)
-		if options != nil && options.RowsPerStrip > 0 {
-			rowsPerStrip = options.RowsPerStrip
-		}
-		if rowsPerStrip == 0 {
-			var err error
-			rowsPerStrip, err = f.TIFFDefaultStripSize(ctx, 0)
-			if err != nil {
-				return err
-			}
-		}
-		// For CCITT we already set ROWSPERSTRIP above (in the !useTiles branch),
-		// but we need the value to iterate.
-		return f.writeStrips(ctx, bounds, rowsPerStrip, bytesPerRow, &strip, func(stripData []byte, y, rows int) {
+	// CCITT bilevel output.
+	if enc.isCCITT {
+		isBlack := newBilevel(img, options)
+		return func(stripData []byte, y, rows int) {
 			for row := 0; row < rows; row++ {
 				for x := bounds.Min.X; x < bounds.Max.X; x++ {
-					r, g, b, _ := img.At(x, y+row).RGBA()
-					lum := uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
 					col := x - bounds.Min.X
-					byteIdx := row*bytesPerRow + col/8
-					bitIdx := uint(7 - col%8)
-					if lum < threshold {
-						stripData[byteIdx] |= 1 << bitIdx // black = 1 for MINISWHITE
+					if isBlack(x, y+row) {
+						stripData[row*bytesPerRow+col/8] |= 1 << uint(7-col%8) // black = 1 for MINISWHITE
 					}
 				}
 			}
-		})
-	}
-
-	// Write image data.
-	bytesPerPixel := int(samplesPerPixel)
-	bytesPerRow := int(width) * bytesPerPixel
-
-	if useTiles {
-		tileWidth := options.TileWidth
-		tileHeight := options.TileHeight
-
-		// Fast path for tiles.
-		if rgbaImg, ok := img.(*image.RGBA); ok && !isJPEG && alphaMode == AlphaAssociated {
-			return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel, func(tileData []byte, tileX, tileY, tw, th int) {
-				tileBytesPerRow := tw * bytesPerPixel
-				for row := 0; row < th; row++ {
-					imgY := tileY + row
-					if imgY >= bounds.Max.Y {
-						break
-					}
-					srcY := imgY - bounds.Min.Y
-					dstStart := row * tileBytesPerRow
-					cols := tw
-					if tileX+cols > bounds.Max.X {
-						cols = bounds.Max.X - tileX
-					}
-					srcStart := srcY*rgbaImg.Stride + (tileX-bounds.Min.X)*4
-					copy(tileData[dstStart:dstStart+cols*4], rgbaImg.Pix[srcStart:srcStart+cols*4])
-				}
-			})
-		}
-		if nrgbaImg, ok := img.(*image.NRGBA); ok && !isJPEG && alphaMode == AlphaUnassociated {
-			return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel, func(tileData []byte, tileX, tileY, tw, th int) {
-				tileBytesPerRow := tw * bytesPerPixel
-				for row := 0; row < th; row++ {
-					imgY := tileY + row
-					if imgY >= bounds.Max.Y {
-						break
-					}
-					srcY := imgY - bounds.Min.Y
-					dstStart := row * tileBytesPerRow
-					cols := tw
-					if tileX+cols > bounds.Max.X {
-						cols = bounds.Max.X - tileX
-					}
-					srcStart := srcY*nrgbaImg.Stride + (tileX-bounds.Min.X)*4
-					copy(tileData[dstStart:dstStart+cols*4], nrgbaImg.Pix[srcStart:srcStart+cols*4])
-				}
-			})
 		}
+	}
 
-		// Generic tile path.
-		if alphaMode == AlphaUnassociated {
-			return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel, func(tileData []byte, tileX, tileY, tw, th int) {
-				tileBytesPerRow := tw * bytesPerPixel
-				for row := 0; row < th; row++ {
-					imgY := tileY + row
-					if imgY >= bounds.Max.Y {
-						break
-					}
-					for col := 0; col < tw; col++ {
-						imgX := tileX + col
-						if imgX >= bounds.Max.X {
-							break
-						}
-						c := color.NRGBAModel.Convert(img.At(imgX, imgY)).(color.NRGBA)
-						offset := row*tileBytesPerRow + col*bytesPerPixel
-						tileData[offset] = c.R
-						tileData[offset+1] = c.G
-						tileData[offset+2] = c.B
-						if !isJPEG {
-							tileData[offset+3] = c.A
-						}
-					}
-				}
-			})
+	// Paletted output, the indexes are packed into samples of bitsPerSample
+	// bits.
+	if paletted, ok := img.(*image.Paletted); ok && enc.paletted != nil {
+		return func(stripData []byte, y, rows int) {
+			packPaletted(paletted, stripData, bounds.Min.X, y, width, rows, bytesPerRow, enc.bitsPerSample)
 		}
-
-		return f.writeTiles(ctx, bounds, tileWidth, tileHeight, bytesPerPixel, func(tileData []byte, tileX, tileY, tw, th int) {
-			tileBytesPerRow := tw * bytesPerPixel
-			for row := 0; row < th; row++ {
-				imgY := tileY + row
-				if imgY >= bounds.Max.Y {
-					break
-				}
-				for col := 0; col < tw; col++ {
-					imgX := tileX + col
-					if imgX >= bounds.Max.X {
-						break
-					}
-					r, g, b, a := img.At(imgX, imgY).RGBA()
-					offset := row*tileBytesPerRow + col*bytesPerPixel
-					tileData[offset] = uint8(r >> 8)
-					tileData[offset+1] = uint8(g >> 8)
-					tileData[offset+2] = uint8(b >> 8)
-					if !isJPEG {
-						tileData[offset+3] = uint8(a >> 8)
-					}
-				}
-			}
-		})
 	}
 
-	// Strip-based output.
-	strip := uint32(0)
-	rowsPerStrip := uint32(0)
-	if isJPEG {
-		rowsPerStrip = height
-	} else if options != nil && options.RowsPerStrip > 0 {
-		rowsPerStrip = options.RowsPerStrip
-	} else {
-		var err error
-		rowsPerStrip, err = f.TIFFDefaultStripSize(ctx, 0)
-		if err != nil {
-			return err
+	// Image types with their own sample layout. The layout reads from the
+	// image it was made for, so it is made again for img.
+	if enc.layout != nil {
+		layout := newSampleLayout(img, alphaMode, isJPEG)
+		return func(stripData []byte, y, rows int) {
+			for row := 0; row < rows; row++ {
+				layout.fill(stripData[row*bytesPerRow:], bounds.Min.X, y+row, width)
+			}
 		}
 	}
 
 	// Fast path: direct pixel access for matching image types (non-JPEG only).
 	if rgbaImg, ok := img.(*image.RGBA); ok && !isJPEG && alphaMode == AlphaAssociated {
-		return f.writeStrips(ctx, bounds, rowsPerStrip, bytesPerRow, &strip, func(stripData []byte, y, rows int) {
+		return func(stripData []byte, y, rows int) {
 			for row := 0; row < rows; row++ {
-				srcY := y + row - bounds.Min.Y
-				srcStart := srcY*rgbaImg.Stride + (bounds.Min.X * 4)
+				srcStart := rgbaImg.PixOffset(bounds.Min.X, y+row)
 				copy(stripData[row*bytesPerRow:], rgbaImg.Pix[srcStart:srcStart+bytesPerRow])
 			}
-		})
+		}
 	}
 	if nrgbaImg, ok := img.(*image.NRGBA); ok && !isJPEG && alphaMode == AlphaUnassociated {
-		return f.writeStrips(ctx, bounds, rowsPerStrip, bytesPerRow, &strip, func(stripData []byte, y, rows int) {
+		return func(stripData []byte, y, rows int) {
 			for row := 0; row < rows; row++ {
-				srcY := y + row - bounds.Min.Y
-				srcStart := srcY*nrgbaImg.Stride + (bounds.Min.X * 4)
+				srcStart := nrgbaImg.PixOffset(bounds.Min.X, y+row)
 				copy(stripData[row*bytesPerRow:], nrgbaImg.Pix[srcStart:srcStart+bytesPerRow])
 			}
-		})
+		}
 	}
 
 	// Generic path.
+	bytesPerPixel := enc.rowSize(1)
 	if alphaMode == AlphaUnassociated {
-		return f.writeStrips(ctx, bounds, rowsPerStrip, bytesPerRow, &strip, func(stripData []byte, y, rows int) {
+		return func(stripData []byte, y, rows int) {
 			for row := 0; row < rows; row++ {
 				for x := bounds.Min.X; x < bounds.Max.X; x++ {
 					c := color.NRGBAModel.Convert(img.At(x, y+row)).(color.NRGBA)
@@ -543,10 +850,10 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 					}
 				}
 			}
-		})
+		}
 	}
 
-	return f.writeStrips(ctx, bounds, rowsPerStrip, bytesPerRow, &strip, func(stripData []byte, y, rows int) {
+	return func(stripData []byte, y, rows int) {
 		for row := 0; row < rows; row++ {
 			for x := bounds.Min.X; x < bounds.Max.X; x++ {
 				r, g, b, a := img.At(x, y+row).RGBA()
@@ -559,7 +866,24 @@ func (f *File) FromGoImage(ctx context.Context, img image.Image, options *FromGo
 				}
 			}
 		}
-	})
+	}
+}
+
+// defaultSoftware returns the TIFFTAG_SOFTWARE value written when none is
+// given, like "go-libtiff/libtiff-4.7.1".
+func (f *File) defaultSoftware(ctx context.Context) string {
+	software := "go-libtiff"
+	if ver, err := f.instance.TIFFGetVersion(ctx); err == nil {
+		// TIFFGetVersion returns "LIBTIFF, Version X.Y.Z\n...".
+		if i := strings.Index(ver, "Version "); i != -1 {
+			v := ver[i+len("Version "):]
+			if j := strings.IndexAny(v, "\n\r"); j != -1 {
+				v = v[:j]
+			}
+			software = "go-libtiff/libtiff-" + strings.TrimSpace(v)
+		}
+	}
+	return software
 }
 
 // writeStrips writes image data strip by strip, calling fillStrip to populate each strip's pixel data,
@@ -584,20 +908,14 @@ func (f *File) writeStrips(ctx context.Context, bounds image.Rectangle, rowsPerS
 }
 
 // writeTiles writes image data tile by tile, calling fillTile to populate each tile's pixel data,
-// then writes the TIFF directory. bytesPerPixel is 0 for bilevel (bit-packed) data.
+// then writes the TIFF directory. Rows of less than 8 bits per pixel are bit-packed.
 func (f *File) writeTiles(ctx context.Context, bounds image.Rectangle,
-	tileWidth, tileHeight uint32, bytesPerPixel int,
+	tileWidth, tileHeight uint32, bitsPerPixel int,
 	fillTile func(tileData []byte, tileX, tileY, tw, th int)) error {
 
 	tw := int(tileWidth)
 	th := int(tileHeight)
-	var tileSize int
-	if bytesPerPixel == 0 {
-		// Bilevel: bit-packed.
-		tileSize = ((tw + 7) / 8) * th
-	} else {
-		tileSize = tw * th * bytesPerPixel
-	}
+	tileSize := ((tw*bitsPerPixel + 7) / 8) * th
 
 	tile := uint32(0)
 	for y := bounds.Min.Y; y < bounds.Max.Y; y += th {
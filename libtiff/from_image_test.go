@@ -169,6 +169,17 @@ var _ = Describe("FromGoImage", func() {
 			Expect(err).To(BeNil())
 			Expect(spp).To(Equal(uint16(3)))
 		})
+		It("refuses compressions that can only be decoded", func() {
+			tiffFile, tmpFile := openTempTestFile(ctx)
+			defer tmpFile.Close()
+			defer tiffFile.Close(ctx)
+
+			err := tiffFile.FromGoImage(ctx, createTestRGBA(8, 8), &libtiff.FromGoImageOptions{
+				Compression: libtiff.COMPRESSION_OJPEG,
+			})
+			Expect(err).To(MatchError("compression COMPRESSION_OJPEG is not supported for writing images"))
+			Expect(libtiff.FromGoImageCompressions()).To(ContainElement(libtiff.COMPRESSION_CCITTFAX4))
+		})
 	})
 
 	Context("metadata tags", func() {
@@ -0,0 +1,625 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GeoTIFF tags, these are not known to libtiff itself.
+var (
+	TIFFTAG_MODELPIXELSCALETAG = TIFFTAG(33550) /* GeoTIFF pixel scale */
+	TIFFTAG_GEOKEYDIRECTORYTAG = TIFFTAG(34735) /* GeoTIFF key directory */
+	TIFFTAG_GEODOUBLEPARAMSTAG = TIFFTAG(34736) /* GeoTIFF double parameters */
+	TIFFTAG_GEOASCIIPARAMSTAG  = TIFFTAG(34737) /* GeoTIFF ASCII parameters */
+)
+
+// GeoKeyID identifies a key in the GeoKeyDirectoryTag.
+type GeoKeyID uint16
+
+// The most commonly used GeoKeys, see the GeoTIFF specification for the
+// full list.
+const (
+	GTModelTypeGeoKey       GeoKeyID = 1024
+	GTRasterTypeGeoKey      GeoKeyID = 1025
+	GTCitationGeoKey        GeoKeyID = 1026
+	GeographicTypeGeoKey    GeoKeyID = 2048
+	GeogCitationGeoKey      GeoKeyID = 2049
+	GeogGeodeticDatumGeoKey GeoKeyID = 2050
+	GeogPrimeMeridianGeoKey GeoKeyID = 2051
+	GeogLinearUnitsGeoKey   GeoKeyID = 2052
+	GeogAngularUnitsGeoKey  GeoKeyID = 2054
+	GeogEllipsoidGeoKey     GeoKeyID = 2056
+	GeogSemiMajorAxisGeoKey GeoKeyID = 2057
+	GeogSemiMinorAxisGeoKey GeoKeyID = 2058
+	GeogInvFlatteningGeoKey GeoKeyID = 2059
+	ProjectedCSTypeGeoKey   GeoKeyID = 3072
+	PCSCitationGeoKey       GeoKeyID = 3073
+	ProjectionGeoKey        GeoKeyID = 3074
+	ProjCoordTransGeoKey    GeoKeyID = 3075
+	ProjLinearUnitsGeoKey   GeoKeyID = 3076
+	VerticalCSTypeGeoKey    GeoKeyID = 4096
+	VerticalCitationGeoKey  GeoKeyID = 4097
+	VerticalDatumGeoKey     GeoKeyID = 4098
+	VerticalUnitsGeoKey     GeoKeyID = 4099
+)
+
+// ModelType is the value of GTModelTypeGeoKey.
+type ModelType uint16
+
+const (
+	ModelTypeProjected  ModelType = 1
+	ModelTypeGeographic ModelType = 2
+	ModelTypeGeocentric ModelType = 3
+)
+
+// RasterType is the value of GTRasterTypeGeoKey.
+type RasterType uint16
+
+const (
+	// RasterPixelIsArea means the model coordinates refer to the upper left
+	// corner of a pixel. This is the default when the key is not set.
+	RasterPixelIsArea RasterType = 1
+	// RasterPixelIsPoint means the model coordinates refer to the center of a
+	// pixel.
+	RasterPixelIsPoint RasterType = 2
+)
+
+// EPSGUserDefined is the GeoKey value for a user-defined coordinate system.
+const EPSGUserDefined = 32767
+
+// GeoKey is a single entry of the GeoKeyDirectoryTag with its value resolved.
+// Depending on Location, only one of Shorts, Doubles and ASCII is set.
+type GeoKey struct {
+	ID GeoKeyID
+	// Location is the tag holding the value. It is 0 for values stored
+	// directly in the key, TIFFTAG_GEOKEYDIRECTORYTAG for other SHORT values,
+	// TIFFTAG_GEODOUBLEPARAMSTAG or TIFFTAG_GEOASCIIPARAMSTAG.
+	Location TIFFTAG
+	Count    uint16
+	Shorts   []uint16
+	Doubles  []float64
+	ASCII    string
+}
+
+// GDALMetadataItem is a single item of the GDAL_METADATA XML.
+type GDALMetadataItem struct {
+	Name string
+	// Sample is the 0-based band the item applies to, nil for dataset items.
+	Sample *int
+	Role   string
+	Domain string
+	Value  string
+}
+
+// GeoReference holds the GeoTIFF georeferencing of a directory.
+type GeoReference struct {
+	// GeoTransform maps pixel/line coordinates of the upper left corner of
+	// a pixel to model coordinates, in the same order as GDAL:
+	//   X = GeoTransform[0] + col*GeoTransform[1] + row*GeoTransform[2]
+	//   Y = GeoTransform[3] + col*GeoTransform[4] + row*GeoTransform[5]
+	// For RasterPixelIsPoint files it is shifted by half a pixel, like GDAL
+	// does. It is nil when the directory has neither a transformation nor a
+	// tiepoint with a pixel scale.
+	GeoTransform *[6]float64
+
+	// ModelPixelScale holds the raw ModelPixelScaleTag values.
+	ModelPixelScale []float64
+	// ModelTiepoints holds the raw ModelTiepointTag values, 6 per tiepoint.
+	ModelTiepoints []float64
+	// ModelTransformation holds the raw 4x4 ModelTransformationTag matrix.
+	ModelTransformation []float64
+
+	// KeyDirectoryVersion, KeyRevision and MinorRevision are the header
+	// values of the GeoKeyDirectoryTag.
+	KeyDirectoryVersion uint16
+	KeyRevision         uint16
+	MinorRevision       uint16
+	// GeoKeys holds all keys of the GeoKeyDirectoryTag in file order.
+	GeoKeys []GeoKey
+
+	// ModelType is the value of GTModelTypeGeoKey, 0 if not set.
+	ModelType ModelType
+	// RasterType is the value of GTRasterTypeGeoKey, RasterPixelIsArea if not set.
+	RasterType RasterType
+	// ProjectedEPSG is the value of ProjectedCSTypeGeoKey, 0 if not set.
+	ProjectedEPSG uint16
+	// GeographicEPSG is the value of GeographicTypeGeoKey, 0 if not set.
+	GeographicEPSG uint16
+
+	// NoData is the raw GDAL_NODATA value, empty if not set.
+	NoData string
+	// GDALMetadata is the raw GDAL_METADATA XML, empty if not set.
+	GDALMetadata string
+	// GDALMetadataItems holds the parsed items of GDALMetadata.
+	GDALMetadataItems []GDALMetadataItem
+}
+
+// GeoKey returns the key with the given ID.
+func (g *GeoReference) GeoKey(id GeoKeyID) (*GeoKey, bool) {
+	for i := range g.GeoKeys {
+		if g.GeoKeys[i].ID == id {
+			return &g.GeoKeys[i], true
+		}
+	}
+	return nil, false
+}
+
+// NoDataValue returns NoData as a number. It returns false when NoData is
+// not set or is not a number.
+func (g *GeoReference) NoDataValue() (float64, bool) {
+	return parseNoData(g.NoData)
+}
+
+// parseNoData parses a GDAL_NODATA value.
+func parseNoData(noData string) (float64, bool) {
+	if noData == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(noData), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// formatNoData formats a GDAL_NODATA value the way GDAL does, with "nan"
+// and "inf" for the special values.
+func formatNoData(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// GeoReference reads the GeoTIFF georeferencing of the current directory.
+// It returns a TagNotDefinedError for TIFFTAG_GEOKEYDIRECTORYTAG when the
+// directory has no georeferencing at all.
+func (f *File) GeoReference(ctx context.Context) (*GeoReference, error) {
+	geoReference := &GeoReference{
+		RasterType: RasterPixelIsArea,
+	}
+
+	keyDirectory, err := f.getOptionalUint16Array(ctx, TIFFTAG_GEOKEYDIRECTORYTAG)
+	if err != nil {
+		return nil, err
+	}
+
+	doubleParams, err := f.getOptionalDoubleArray(ctx, TIFFTAG_GEODOUBLEPARAMSTAG)
+	if err != nil {
+		return nil, err
+	}
+
+	asciiParams, err := f.getOptionalString(ctx, TIFFTAG_GEOASCIIPARAMSTAG)
+	if err != nil {
+		return nil, err
+	}
+
+	if geoReference.ModelPixelScale, err = f.getOptionalDoubleArray(ctx, TIFFTAG_MODELPIXELSCALETAG); err != nil {
+		return nil, err
+	}
+	if geoReference.ModelTiepoints, err = f.getOptionalDoubleArray(ctx, TIFFTAG_MODELTIEPOINTTAG); err != nil {
+		return nil, err
+	}
+	if geoReference.ModelTransformation, err = f.getOptionalDoubleArray(ctx, TIFFTAG_MODELTRANSFORMATIONTAG); err != nil {
+		return nil, err
+	}
+
+	if keyDirectory == nil && geoReference.ModelTiepoints == nil && geoReference.ModelTransformation == nil {
+		return nil, &TagNotDefinedError{
+			Tag: TIFFTAG_GEOKEYDIRECTORYTAG,
+		}
+	}
+
+	if keyDirectory != nil {
+		if err := geoReference.parseKeyDirectory(keyDirectory, doubleParams, asciiParams); err != nil {
+			return nil, err
+		}
+	}
+
+	geoReference.GeoTransform = geoReference.computeGeoTransform()
+
+	if geoReference.NoData, err = f.getOptionalString(ctx, TIFFTAG_GDAL_NODATA); err != nil {
+		return nil, err
+	}
+	if geoReference.GDALMetadata, err = f.getOptionalString(ctx, TIFFTAG_GDAL_METADATA); err != nil {
+		return nil, err
+	}
+	if geoReference.GDALMetadata != "" {
+		if geoReference.GDALMetadataItems, err = parseGDALMetadata(geoReference.GDALMetadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return geoReference, nil
+}
+
+func (g *GeoReference) parseKeyDirectory(keyDirectory []uint16, doubleParams []float64, asciiParams string) error {
+	if len(keyDirectory) < 4 {
+		return errors.New("GeoKeyDirectoryTag is too short")
+	}
+
+	g.KeyDirectoryVersion = keyDirectory[0]
+	g.KeyRevision = keyDirectory[1]
+	g.MinorRevision = keyDirectory[2]
+	numberOfKeys := int(keyDirectory[3])
+	if len(keyDirectory) < 4+numberOfKeys*4 {
+		return errors.New("GeoKeyDirectoryTag has less keys than it declares")
+	}
+
+	for i := 0; i < numberOfKeys; i++ {
+		entry := keyDirectory[4+i*4:]
+		key := GeoKey{
+			ID:       GeoKeyID(entry[0]),
+			Location: TIFFTAG(entry[1]),
+			Count:    entry[2],
+		}
+		count := int(entry[2])
+		offset := int(entry[3])
+
+		switch key.Location {
+		case 0:
+			key.Shorts = []uint16{entry[3]}
+		case TIFFTAG_GEOKEYDIRECTORYTAG:
+			if offset+count > len(keyDirectory) {
+				return fmt.Errorf("GeoKey %d points outside of the GeoKeyDirectoryTag", key.ID)
+			}
+			key.Shorts = append([]uint16{}, keyDirectory[offset:offset+count]...)
+		case TIFFTAG_GEODOUBLEPARAMSTAG:
+			if offset+count > len(doubleParams) {
+				return fmt.Errorf("GeoKey %d points outside of the GeoDoubleParamsTag", key.ID)
+			}
+			key.Doubles = append([]float64{}, doubleParams[offset:offset+count]...)
+		case TIFFTAG_GEOASCIIPARAMSTAG:
+			if offset > len(asciiParams) {
+				return fmt.Errorf("GeoKey %d points outside of the GeoAsciiParamsTag", key.ID)
+			}
+			// Some writers count the terminating null, so clamp the end.
+			end := min(offset+count, len(asciiParams))
+			// Values are terminated with a pipe instead of a null.
+			key.ASCII = strings.TrimRight(asciiParams[offset:end], "|\x00")
+		default:
+			// Unknown location, keep the key without a value.
+		}
+
+		g.GeoKeys = append(g.GeoKeys, key)
+
+		if len(key.Shorts) == 1 {
+			switch key.ID {
+			case GTModelTypeGeoKey:
+				g.ModelType = ModelType(key.Shorts[0])
+			case GTRasterTypeGeoKey:
+				g.RasterType = RasterType(key.Shorts[0])
+			case ProjectedCSTypeGeoKey:
+				g.ProjectedEPSG = key.Shorts[0]
+			case GeographicTypeGeoKey:
+				g.GeographicEPSG = key.Shorts[0]
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *GeoReference) computeGeoTransform() *[6]float64 {
+	var geoTransform [6]float64
+
+	if len(g.ModelTransformation) >= 16 {
+		m := g.ModelTransformation
+		geoTransform = [6]float64{m[3], m[0], m[1], m[7], m[4], m[5]}
+	} else if len(g.ModelTiepoints) >= 6 && len(g.ModelPixelScale) >= 2 {
+		tiepoint := g.ModelTiepoints
+		scale := g.ModelPixelScale
+		geoTransform = [6]float64{
+			tiepoint[3] - tiepoint[0]*scale[0], scale[0], 0,
+			tiepoint[4] + tiepoint[1]*scale[1], 0, -scale[1],
+		}
+	} else {
+		return nil
+	}
+
+	if g.RasterType == RasterPixelIsPoint {
+		geoTransform[0] -= geoTransform[1]*0.5 + geoTransform[2]*0.5
+		geoTransform[3] -= geoTransform[4]*0.5 + geoTransform[5]*0.5
+	}
+
+	return &geoTransform
+}
+
+func parseGDALMetadata(data string) ([]GDALMetadataItem, error) {
+	var metadata struct {
+		Items []struct {
+			Name   string `xml:"name,attr"`
+			Sample string `xml:"sample,attr"`
+			Role   string `xml:"role,attr"`
+			Domain string `xml:"domain,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"Item"`
+	}
+	if err := xml.Unmarshal([]byte(data), &metadata); err != nil {
+		return nil, fmt.Errorf("could not parse GDAL metadata: %w", err)
+	}
+
+	items := make([]GDALMetadataItem, 0, len(metadata.Items))
+	for _, item := range metadata.Items {
+		parsed := GDALMetadataItem{
+			Name:   item.Name,
+			Role:   item.Role,
+			Domain: item.Domain,
+			Value:  item.Value,
+		}
+		if item.Sample != "" {
+			sample, err := strconv.Atoi(item.Sample)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GDAL metadata sample %q: %w", item.Sample, err)
+			}
+			parsed.Sample = &sample
+		}
+		items = append(items, parsed)
+	}
+
+	return items, nil
+}
+
+// getOptionalUint16Array reads a counted SHORT tag, returning nil when the
+// tag is not set.
+func (f *File) getOptionalUint16Array(ctx context.Context, tag TIFFTAG) ([]uint16, error) {
+	values, err := f.TIFFGetFieldUint16Array(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return values, nil
+}
+
+// getOptionalDoubleArray reads a counted DOUBLE tag, returning nil when the
+// tag is not set.
+func (f *File) getOptionalDoubleArray(ctx context.Context, tag TIFFTAG) ([]float64, error) {
+	values, err := f.TIFFGetFieldDoubleArray(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return values, nil
+}
+
+// getOptionalString reads a counted ASCII tag, returning an empty string
+// when the tag is not set.
+func (f *File) getOptionalString(ctx context.Context, tag TIFFTAG) (string, error) {
+	value, err := f.TIFFGetFieldByteArray(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\x00"), nil
+}
+
+// geoTIFFFields are the tags that have to be registered before they can be
+// written, libtiff does not know them.
+var geoTIFFFields = []struct {
+	tag       TIFFTAG
+	fieldType TIFFDataType
+}{
+	{TIFFTAG_MODELPIXELSCALETAG, TIFF_DOUBLE},
+	{TIFFTAG_MODELTIEPOINTTAG, TIFF_DOUBLE},
+	{TIFFTAG_MODELTRANSFORMATIONTAG, TIFF_DOUBLE},
+	{TIFFTAG_GEOKEYDIRECTORYTAG, TIFF_SHORT},
+	{TIFFTAG_GEODOUBLEPARAMSTAG, TIFF_DOUBLE},
+	{TIFFTAG_GEOASCIIPARAMSTAG, TIFF_ASCII},
+	{TIFFTAG_GDAL_METADATA, TIFF_ASCII},
+	{TIFFTAG_GDAL_NODATA, TIFF_ASCII},
+}
+
+// setGeoReference sets the GeoTIFF tags of the current directory.
+//
+// When GeoTransform is set it is written as a tiepoint with a pixel scale, or
+// as a ModelTransformationTag when it has rotation terms. Otherwise the raw
+// ModelPixelScale, ModelTiepoints and ModelTransformation are written.
+// The GeoKeys are written together with the keys for ModelType, RasterType,
+// ProjectedEPSG and GeographicEPSG, which take precedence when set.
+// GDALMetadataItems is only used when GDALMetadata is empty.
+func (f *File) setGeoReference(ctx context.Context, geoReference *GeoReference) error {
+	for _, field := range geoTIFFFields {
+		if err := f.TIFFMergeAnonymousField(ctx, field.tag, field.fieldType); err != nil {
+			return err
+		}
+	}
+
+	rasterType := geoReference.RasterType
+	if rasterType == 0 {
+		rasterType = RasterPixelIsArea
+	}
+
+	pixelScale := geoReference.ModelPixelScale
+	tiepoints := geoReference.ModelTiepoints
+	transformation := geoReference.ModelTransformation
+	if geoReference.GeoTransform != nil {
+		geoTransform := *geoReference.GeoTransform
+
+		// The geotransform always refers to the pixel corner.
+		if rasterType == RasterPixelIsPoint {
+			geoTransform[0] += geoTransform[1]*0.5 + geoTransform[2]*0.5
+			geoTransform[3] += geoTransform[4]*0.5 + geoTransform[5]*0.5
+		}
+
+		pixelScale, tiepoints, transformation = nil, nil, nil
+		if geoTransform[2] == 0 && geoTransform[4] == 0 {
+			pixelScale = []float64{geoTransform[1], -geoTransform[5], 0}
+			tiepoints = []float64{0, 0, 0, geoTransform[0], geoTransform[3], 0}
+		} else {
+			transformation = []float64{
+				geoTransform[1], geoTransform[2], 0, geoTransform[0],
+				geoTransform[4], geoTransform[5], 0, geoTransform[3],
+				0, 0, 0, 0,
+				0, 0, 0, 1,
+			}
+		}
+	}
+
+	doubleTags := []struct {
+		values []float64
+		tag    TIFFTAG
+	}{
+		{pixelScale, TIFFTAG_MODELPIXELSCALETAG},
+		{tiepoints, TIFFTAG_MODELTIEPOINTTAG},
+		{transformation, TIFFTAG_MODELTRANSFORMATIONTAG},
+	}
+	for _, t := range doubleTags {
+		if len(t.values) > 0 {
+			if err := f.TIFFSetFieldDoubleArray(ctx, t.tag, t.values); err != nil {
+				return err
+			}
+		}
+	}
+
+	keyDirectory, doubleParams, asciiParams := geoReference.encodeKeyDirectory(rasterType)
+	if err := f.TIFFSetFieldUint16Array(ctx, TIFFTAG_GEOKEYDIRECTORYTAG, keyDirectory); err != nil {
+		return err
+	}
+	if len(doubleParams) > 0 {
+		if err := f.TIFFSetFieldDoubleArray(ctx, TIFFTAG_GEODOUBLEPARAMSTAG, doubleParams); err != nil {
+			return err
+		}
+	}
+
+	gdalMetadata := geoReference.GDALMetadata
+	if gdalMetadata == "" && len(geoReference.GDALMetadataItems) > 0 {
+		gdalMetadata = encodeGDALMetadata(geoReference.GDALMetadataItems)
+	}
+
+	stringTags := []struct {
+		value string
+		tag   TIFFTAG
+	}{
+		{asciiParams, TIFFTAG_GEOASCIIPARAMSTAG},
+		{gdalMetadata, TIFFTAG_GDAL_METADATA},
+		{geoReference.NoData, TIFFTAG_GDAL_NODATA},
+	}
+	for _, t := range stringTags {
+		if t.value != "" {
+			// The count of ASCII tags includes the null terminator.
+			if err := f.TIFFSetFieldByteArray(ctx, t.tag, append([]byte(t.value), 0)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// encodeKeyDirectory builds the GeoKeyDirectoryTag, GeoDoubleParamsTag and
+// GeoAsciiParamsTag values. The location of every key is derived from its
+// value, Location and Count of the keys are ignored.
+func (g *GeoReference) encodeKeyDirectory(rasterType RasterType) ([]uint16, []float64, string) {
+	keys := map[GeoKeyID]GeoKey{}
+	for _, key := range g.GeoKeys {
+		keys[key.ID] = key
+	}
+
+	modelType := g.ModelType
+	if modelType == 0 {
+		if g.ProjectedEPSG != 0 {
+			modelType = ModelTypeProjected
+		} else if g.GeographicEPSG != 0 {
+			modelType = ModelTypeGeographic
+		}
+	}
+
+	shortKeys := []struct {
+		value uint16
+		id    GeoKeyID
+	}{
+		{uint16(modelType), GTModelTypeGeoKey},
+		{uint16(rasterType), GTRasterTypeGeoKey},
+		{g.ProjectedEPSG, ProjectedCSTypeGeoKey},
+		{g.GeographicEPSG, GeographicTypeGeoKey},
+	}
+	for _, k := range shortKeys {
+		if k.value != 0 {
+			keys[k.id] = GeoKey{ID: k.id, Shorts: []uint16{k.value}}
+		}
+	}
+
+	ids := make([]GeoKeyID, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	version, revision, minorRevision := g.KeyDirectoryVersion, g.KeyRevision, g.MinorRevision
+	if version == 0 {
+		version, revision, minorRevision = 1, 1, 0
+	}
+
+	keyDirectory := []uint16{version, revision, minorRevision, uint16(len(ids))}
+	var extraShorts []uint16
+	var doubleParams []float64
+	var asciiParams strings.Builder
+
+	// SHORT values that don't fit in the key are stored after the keys.
+	extraShortsOffset := 4 + len(ids)*4
+	for _, id := range ids {
+		key := keys[id]
+		switch {
+		case key.ASCII != "":
+			// Values are terminated with a pipe, which is part of the count.
+			keyDirectory = append(keyDirectory, uint16(id), uint16(TIFFTAG_GEOASCIIPARAMSTAG), uint16(len(key.ASCII)+1), uint16(asciiParams.Len()))
+			asciiParams.WriteString(key.ASCII)
+			asciiParams.WriteByte('|')
+		case len(key.Doubles) > 0:
+			keyDirectory = append(keyDirectory, uint16(id), uint16(TIFFTAG_GEODOUBLEPARAMSTAG), uint16(len(key.Doubles)), uint16(len(doubleParams)))
+			doubleParams = append(doubleParams, key.Doubles...)
+		case len(key.Shorts) == 1:
+			keyDirectory = append(keyDirectory, uint16(id), 0, 1, key.Shorts[0])
+		default:
+			keyDirectory = append(keyDirectory, uint16(id), uint16(TIFFTAG_GEOKEYDIRECTORYTAG), uint16(len(key.Shorts)), uint16(extraShortsOffset+len(extraShorts)))
+			extraShorts = append(extraShorts, key.Shorts...)
+		}
+	}
+
+	return append(keyDirectory, extraShorts...), doubleParams, asciiParams.String()
+}
+
+func encodeGDALMetadata(items []GDALMetadataItem) string {
+	var metadata strings.Builder
+	metadata.WriteString("<GDALMetadata>\n")
+	for _, item := range items {
+		metadata.WriteString("  <Item name=\"")
+		xml.EscapeText(&metadata, []byte(item.Name))
+		metadata.WriteString("\"")
+		if item.Sample != nil {
+			metadata.WriteString(" sample=\"" + strconv.Itoa(*item.Sample) + "\"")
+		}
+		if item.Role != "" {
+			metadata.WriteString(" role=\"")
+			xml.EscapeText(&metadata, []byte(item.Role))
+			metadata.WriteString("\"")
+		}
+		if item.Domain != "" {
+			metadata.WriteString(" domain=\"")
+			xml.EscapeText(&metadata, []byte(item.Domain))
+			metadata.WriteString("\"")
+		}
+		metadata.WriteString(">")
+		xml.EscapeText(&metadata, []byte(item.Value))
+		metadata.WriteString("</Item>\n")
+	}
+	metadata.WriteString("</GDALMetadata>")
+	return metadata.String()
+}
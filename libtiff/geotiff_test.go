@@ -0,0 +1,322 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// buildTestTIFF builds a little-endian 2x2 8-bit grayscale TIFF with the
+// given extra tags in its only directory.
+func buildTestTIFF(extra []testIFDEntry) []byte {
+	order := binary.LittleEndian
+	short := func(v uint16) []byte { return order.AppendUint16(nil, v) }
+	long := func(v uint32) []byte { return order.AppendUint32(nil, v) }
+
+	// Header, pixel data, then the directory.
+	buf := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	pixelOffset := uint32(len(buf))
+	buf = append(buf, 0, 64, 128, 255)
+
+	entries := append([]testIFDEntry{
+		{libtiff.TIFFTAG_IMAGEWIDTH, 3, 1, short(2)},
+		{libtiff.TIFFTAG_IMAGELENGTH, 3, 1, short(2)},
+		{libtiff.TIFFTAG_BITSPERSAMPLE, 3, 1, short(8)},
+		{libtiff.TIFFTAG_COMPRESSION, 3, 1, short(1)},
+		{libtiff.TIFFTAG_PHOTOMETRIC, 3, 1, short(1)},
+		{libtiff.TIFFTAG_STRIPOFFSETS, 4, 1, long(pixelOffset)},
+		{libtiff.TIFFTAG_SAMPLESPERPIXEL, 3, 1, short(1)},
+		{libtiff.TIFFTAG_ROWSPERSTRIP, 3, 1, short(2)},
+		{libtiff.TIFFTAG_STRIPBYTECOUNTS, 4, 1, long(4)},
+	}, extra...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	// Values that don't fit in the entry go after the directory.
+	ifdOffset := uint32(len(buf))
+	dataOffset := ifdOffset + 2 + uint32(len(entries))*12 + 4
+	order.PutUint32(buf[4:], ifdOffset)
+
+	var data []byte
+	buf = append(buf, short(uint16(len(entries)))...)
+	for _, entry := range entries {
+		buf = append(buf, short(uint16(entry.tag))...)
+		buf = append(buf, short(entry.typ)...)
+		buf = append(buf, long(entry.count)...)
+		if len(entry.value) <= 4 {
+			value := make([]byte, 4)
+			copy(value, entry.value)
+			buf = append(buf, value...)
+		} else {
+			buf = append(buf, long(dataOffset+uint32(len(data)))...)
+			data = append(data, entry.value...)
+			if len(data)%2 == 1 {
+				data = append(data, 0)
+			}
+		}
+	}
+	buf = append(buf, long(0)...)
+
+	return append(buf, data...)
+}
+
+func testShorts(values ...uint16) []byte {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint16(data, v)
+	}
+	return data
+}
+
+func testDoubles(values ...float64) []byte {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return data
+}
+
+func openTestTIFF(ctx context.Context, data []byte) *libtiff.File {
+	tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "geo.tif", bytes.NewReader(data), uint64(len(data)), nil)
+	Expect(err).To(BeNil())
+	return tiffFile
+}
+
+var _ = Describe("GeoReference", func() {
+	ctx := context.Background()
+
+	// A UTM zone 31N (EPSG:32631) orthophoto with 0.5m pixels.
+	keyDirectory := testShorts(
+		1, 1, 0, 5,
+		uint16(libtiff.GTModelTypeGeoKey), 0, 1, uint16(libtiff.ModelTypeProjected),
+		uint16(libtiff.GTRasterTypeGeoKey), 0, 1, uint16(libtiff.RasterPixelIsArea),
+		uint16(libtiff.GTCitationGeoKey), uint16(libtiff.TIFFTAG_GEOASCIIPARAMSTAG), 22, 0,
+		uint16(libtiff.ProjectedCSTypeGeoKey), 0, 1, 32631,
+		uint16(libtiff.GeogSemiMajorAxisGeoKey), uint16(libtiff.TIFFTAG_GEODOUBLEPARAMSTAG), 1, 0,
+	)
+	gdalMetadata := `<GDALMetadata><Item name="AREA">city</Item><Item name="SCALE" sample="0" role="scale">0.01</Item></GDALMetadata>` + "\x00"
+	geoEntries := []testIFDEntry{
+		{libtiff.TIFFTAG_MODELPIXELSCALETAG, 12, 3, testDoubles(0.5, 0.5, 0)},
+		{libtiff.TIFFTAG_MODELTIEPOINTTAG, 12, 6, testDoubles(0, 0, 0, 500000, 5800000, 0)},
+		{libtiff.TIFFTAG_GEOKEYDIRECTORYTAG, 3, uint32(len(keyDirectory) / 2), keyDirectory},
+		{libtiff.TIFFTAG_GEODOUBLEPARAMSTAG, 12, 1, testDoubles(6378137)},
+		{libtiff.TIFFTAG_GEOASCIIPARAMSTAG, 2, 23, []byte("WGS 84 / UTM zone 31N|\x00")},
+		{libtiff.TIFFTAG_GDAL_NODATA, 2, 6, []byte("-9999\x00")},
+		{libtiff.TIFFTAG_GDAL_METADATA, 2, uint32(len(gdalMetadata)), []byte(gdalMetadata)},
+	}
+
+	It("reads the georeferencing of a GeoTIFF", func() {
+		tiffFile := openTestTIFF(ctx, buildTestTIFF(geoEntries))
+		defer tiffFile.Close(ctx)
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{500000, 0.5, 0, 5800000, 0, -0.5}))
+		Expect(geoReference.ModelPixelScale).To(Equal([]float64{0.5, 0.5, 0}))
+		Expect(geoReference.ModelTiepoints).To(Equal([]float64{0, 0, 0, 500000, 5800000, 0}))
+		Expect(geoReference.ModelTransformation).To(BeNil())
+		Expect(geoReference.ModelType).To(Equal(libtiff.ModelTypeProjected))
+		Expect(geoReference.RasterType).To(Equal(libtiff.RasterPixelIsArea))
+		Expect(geoReference.ProjectedEPSG).To(Equal(uint16(32631)))
+		Expect(geoReference.GeographicEPSG).To(Equal(uint16(0)))
+		Expect(geoReference.KeyDirectoryVersion).To(Equal(uint16(1)))
+		Expect(geoReference.GeoKeys).To(HaveLen(5))
+
+		citation, ok := geoReference.GeoKey(libtiff.GTCitationGeoKey)
+		Expect(ok).To(BeTrue())
+		Expect(citation.ASCII).To(Equal("WGS 84 / UTM zone 31N"))
+
+		semiMajorAxis, ok := geoReference.GeoKey(libtiff.GeogSemiMajorAxisGeoKey)
+		Expect(ok).To(BeTrue())
+		Expect(semiMajorAxis.Doubles).To(Equal([]float64{6378137}))
+
+		_, ok = geoReference.GeoKey(libtiff.VerticalCSTypeGeoKey)
+		Expect(ok).To(BeFalse())
+
+		Expect(geoReference.NoData).To(Equal("-9999"))
+		noData, ok := geoReference.NoDataValue()
+		Expect(ok).To(BeTrue())
+		Expect(noData).To(Equal(-9999.0))
+
+		sample := 0
+		Expect(geoReference.GDALMetadataItems).To(Equal([]libtiff.GDALMetadataItem{
+			{Name: "AREA", Value: "city"},
+			{Name: "SCALE", Sample: &sample, Role: "scale", Value: "0.01"},
+		}))
+	})
+
+	It("shifts the geotransform by half a pixel for PixelIsPoint rasters", func() {
+		pointKeyDirectory := testShorts(
+			1, 1, 0, 2,
+			uint16(libtiff.GTRasterTypeGeoKey), 0, 1, uint16(libtiff.RasterPixelIsPoint),
+			uint16(libtiff.GeographicTypeGeoKey), 0, 1, 4326,
+		)
+		tiffFile := openTestTIFF(ctx, buildTestTIFF([]testIFDEntry{
+			{libtiff.TIFFTAG_MODELPIXELSCALETAG, 12, 3, testDoubles(0.25, 0.25, 0)},
+			{libtiff.TIFFTAG_MODELTIEPOINTTAG, 12, 6, testDoubles(0, 0, 0, 4, 52, 0)},
+			{libtiff.TIFFTAG_GEOKEYDIRECTORYTAG, 3, uint32(len(pointKeyDirectory) / 2), pointKeyDirectory},
+		}))
+		defer tiffFile.Close(ctx)
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.RasterType).To(Equal(libtiff.RasterPixelIsPoint))
+		Expect(geoReference.GeographicEPSG).To(Equal(uint16(4326)))
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{3.875, 0.25, 0, 52.125, 0, -0.25}))
+		Expect(geoReference.NoData).To(BeEmpty())
+		Expect(geoReference.GDALMetadataItems).To(BeNil())
+	})
+
+	It("reads a ModelTransformationTag", func() {
+		tiffFile := openTestTIFF(ctx, buildTestTIFF([]testIFDEntry{
+			{libtiff.TIFFTAG_MODELTRANSFORMATIONTAG, 12, 16, testDoubles(
+				2, 0.5, 0, 1000,
+				0.5, -2, 0, 2000,
+				0, 0, 0, 0,
+				0, 0, 0, 1,
+			)},
+		}))
+		defer tiffFile.Close(ctx)
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{1000, 2, 0.5, 2000, 0.5, -2}))
+		Expect(geoReference.GeoKeys).To(BeNil())
+	})
+
+	It("returns an error for a file without georeferencing", func() {
+		tiffFile := openTestTIFF(ctx, buildTestTIFF(nil))
+		defer tiffFile.Close(ctx)
+
+		_, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_GEOKEYDIRECTORYTAG,
+		}))
+	})
+})
+
+var _ = Describe("FromGoImage GeoReference", func() {
+	ctx := context.Background()
+
+	It("writes a georeferenced image from a geotransform", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			GeoReference: &libtiff.GeoReference{
+				GeoTransform:  &[6]float64{500000, 0.5, 0, 5800000, 0, -0.5},
+				ProjectedEPSG: 32631,
+				GeoKeys: []libtiff.GeoKey{
+					{ID: libtiff.GTCitationGeoKey, ASCII: "WGS 84 / UTM zone 31N"},
+					{ID: libtiff.GeogSemiMajorAxisGeoKey, Doubles: []float64{6378137}},
+				},
+				NoData: "-9999",
+			},
+		})
+		defer cleanup()
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{500000, 0.5, 0, 5800000, 0, -0.5}))
+		Expect(geoReference.ModelPixelScale).To(Equal([]float64{0.5, 0.5, 0}))
+		Expect(geoReference.ModelTiepoints).To(Equal([]float64{0, 0, 0, 500000, 5800000, 0}))
+		Expect(geoReference.ModelTransformation).To(BeNil())
+		Expect(geoReference.ModelType).To(Equal(libtiff.ModelTypeProjected))
+		Expect(geoReference.RasterType).To(Equal(libtiff.RasterPixelIsArea))
+		Expect(geoReference.ProjectedEPSG).To(Equal(uint16(32631)))
+		Expect(geoReference.KeyDirectoryVersion).To(Equal(uint16(1)))
+		Expect(geoReference.KeyRevision).To(Equal(uint16(1)))
+		Expect(geoReference.GeoKeys).To(HaveLen(5))
+
+		citation, ok := geoReference.GeoKey(libtiff.GTCitationGeoKey)
+		Expect(ok).To(BeTrue())
+		Expect(citation.ASCII).To(Equal("WGS 84 / UTM zone 31N"))
+
+		semiMajorAxis, ok := geoReference.GeoKey(libtiff.GeogSemiMajorAxisGeoKey)
+		Expect(ok).To(BeTrue())
+		Expect(semiMajorAxis.Doubles).To(Equal([]float64{6378137}))
+
+		noData, ok := geoReference.NoDataValue()
+		Expect(ok).To(BeTrue())
+		Expect(noData).To(Equal(-9999.0))
+	})
+
+	It("writes a ModelTransformationTag for a rotated geotransform", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			GeoReference: &libtiff.GeoReference{
+				GeoTransform:   &[6]float64{1000, 2, 0.5, 2000, 0.5, -2},
+				GeographicEPSG: 4326,
+			},
+		})
+		defer cleanup()
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{1000, 2, 0.5, 2000, 0.5, -2}))
+		Expect(geoReference.ModelTiepoints).To(BeNil())
+		Expect(geoReference.ModelPixelScale).To(BeNil())
+		Expect(geoReference.ModelType).To(Equal(libtiff.ModelTypeGeographic))
+		Expect(geoReference.GeographicEPSG).To(Equal(uint16(4326)))
+	})
+
+	It("keeps the geotransform of a PixelIsPoint tiled image", func() {
+		sample := 0
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(32, 32), &libtiff.FromGoImageOptions{
+			TileWidth:  16,
+			TileHeight: 16,
+			GeoReference: &libtiff.GeoReference{
+				GeoTransform:   &[6]float64{3.875, 0.25, 0, 52.125, 0, -0.25},
+				RasterType:     libtiff.RasterPixelIsPoint,
+				GeographicEPSG: 4326,
+				GDALMetadataItems: []libtiff.GDALMetadataItem{
+					{Name: "AREA", Value: "a & b"},
+					{Name: "SCALE", Sample: &sample, Role: "scale", Value: "0.01"},
+				},
+			},
+		})
+		defer cleanup()
+
+		isTiled, err := tiffFile.TIFFIsTiled(ctx)
+		Expect(err).To(BeNil())
+		Expect(isTiled).To(BeTrue())
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.RasterType).To(Equal(libtiff.RasterPixelIsPoint))
+		Expect(geoReference.ModelTiepoints).To(Equal([]float64{0, 0, 0, 4, 52, 0}))
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{3.875, 0.25, 0, 52.125, 0, -0.25}))
+		Expect(geoReference.NoData).To(BeEmpty())
+		Expect(geoReference.GDALMetadataItems).To(Equal([]libtiff.GDALMetadataItem{
+			{Name: "AREA", Value: "a & b"},
+			{Name: "SCALE", Sample: &sample, Role: "scale", Value: "0.01"},
+		}))
+	})
+
+	It("writes the raw tags and keys of a read GeoReference", func() {
+		source := openTestTIFF(ctx, buildTestTIFF([]testIFDEntry{
+			{libtiff.TIFFTAG_MODELPIXELSCALETAG, 12, 3, testDoubles(0.5, 0.5, 0)},
+			{libtiff.TIFFTAG_MODELTIEPOINTTAG, 12, 6, testDoubles(0, 0, 0, 500000, 5800000, 0)},
+		}))
+		defer source.Close(ctx)
+
+		read, err := source.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		read.GeoTransform = nil
+
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			GeoReference: read,
+		})
+		defer cleanup()
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.ModelPixelScale).To(Equal([]float64{0.5, 0.5, 0}))
+		Expect(geoReference.ModelTiepoints).To(Equal([]float64{0, 0, 0, 500000, 5800000, 0}))
+		Expect(geoReference.GeoTransform).To(Equal(&[6]float64{500000, 0.5, 0, 5800000, 0, -0.5}))
+		Expect(geoReference.RasterType).To(Equal(libtiff.RasterPixelIsArea))
+	})
+})
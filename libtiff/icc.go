@@ -0,0 +1,98 @@
+package libtiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"github.com/klippa-app/go-libtiff/internal/icc"
+)
+
+// ICCProfile returns the embedded ICC profile of the current directory.
+// Returns a TagNotDefinedError when the directory has no ICC profile.
+func (f *File) ICCProfile(ctx context.Context) ([]byte, error) {
+	return f.TIFFGetFieldByteArray(ctx, TIFFTAG_ICCPROFILE)
+}
+
+// iccProfileMatches reports whether the data colour space in the header of
+// the profile is colorSpace. Embedding a profile of another colour space,
+// like the CMYK profile of a CMYK page that is rendered to RGB, would make
+// viewers interpret the colours wrongly.
+func iccProfileMatches(profile []byte, colorSpace icc.ColorSpace) bool {
+	return len(profile) >= 20 && icc.ColorSpace(profile[16:20]) == colorSpace
+}
+
+// embedICCProfilePNG inserts an iCCP chunk with the given profile directly
+// after the IHDR chunk of the PNG data.
+func embedICCProfilePNG(data []byte, profile []byte) ([]byte, error) {
+	// Signature (8) + IHDR length (4), type (4), data (13) and CRC (4).
+	ihdrEnd := 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, errors.New("could not find PNG header")
+	}
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(profile); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	// Profile name, null separator and compression method 0 (deflate).
+	chunkData := append([]byte("ICC profile\x00\x00"), compressed.Bytes()...)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(chunkData)))
+	chunk = append(chunk, "iCCP"...)
+	chunk = append(chunk, chunkData...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	result := make([]byte, 0, len(data)+len(chunk))
+	result = append(result, data[:ihdrEnd]...)
+	result = append(result, chunk...)
+	return append(result, data[ihdrEnd:]...), nil
+}
+
+// iccJPEGMaxChunk is the maximum profile data in one APP2 segment: the
+// segment length field minus its own 2 bytes, the "ICC_PROFILE\0"
+// identifier and the sequence and count bytes.
+const iccJPEGMaxChunk = 0xFFFF - 2 - 12 - 2
+
+// embedICCProfileJPEG inserts the profile as APP2 ICC_PROFILE segments
+// after the SOI marker and any JFIF APP0 segment of the JPEG data.
+func embedICCProfileJPEG(data []byte, profile []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errors.New("not a JPEG stream")
+	}
+
+	chunkCount := (len(profile) + iccJPEGMaxChunk - 1) / iccJPEGMaxChunk
+	if chunkCount > 255 {
+		return nil, errors.New("ICC profile is too large to embed in a JPEG")
+	}
+
+	insertAt := 2
+	if data[2] == 0xFF && data[3] == 0xE0 && len(data) >= 6 {
+		insertAt = 4 + int(binary.BigEndian.Uint16(data[4:6]))
+		if insertAt > len(data) {
+			return nil, errors.New("invalid JPEG APP0 segment")
+		}
+	}
+
+	var segments []byte
+	for i := 0; i < chunkCount; i++ {
+		chunk := profile[i*iccJPEGMaxChunk : min((i+1)*iccJPEGMaxChunk, len(profile))]
+		segments = append(segments, 0xFF, 0xE2)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(2+12+2+len(chunk)))
+		segments = append(segments, "ICC_PROFILE\x00"...)
+		segments = append(segments, byte(i+1), byte(chunkCount))
+		segments = append(segments, chunk...)
+	}
+
+	result := make([]byte, 0, len(data)+len(segments))
+	result = append(result, data[:insertAt]...)
+	result = append(result, segments...)
+	return append(result, data[insertAt:]...), nil
+}
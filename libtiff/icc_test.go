@@ -0,0 +1,213 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testICCProfile returns a fake ICC profile of the given size with a valid
+// looking header.
+func testICCProfile(size int) []byte {
+	profile := make([]byte, size)
+	binary.BigEndian.PutUint32(profile, uint32(size))
+	copy(profile[12:], "mntrRGB XYZ ")
+	copy(profile[36:], "acsp")
+	for i := 128; i < size; i++ {
+		profile[i] = byte(i * 7)
+	}
+	return profile
+}
+
+// writeWithForeignICCProfile writes img and sets the ICC profile afterwards
+// with EditFile, for profiles that FromGoImage refuses because they don't
+// match the image.
+func writeWithForeignICCProfile(ctx context.Context, img image.Image, options *libtiff.FromGoImageOptions, profile []byte) *libtiff.File {
+	tiffFile, tmpFile := openTempTestFile(ctx)
+	Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+	Expect(tiffFile.Close(ctx)).To(Succeed())
+
+	_, err := instance.EditFile(ctx, tmpFile, func(dir *libtiff.DirectoryEditor) error {
+		dir.SetByteArray(libtiff.TIFFTAG_ICCPROFILE, profile)
+		return nil
+	})
+	Expect(err).To(BeNil())
+	Expect(tmpFile.Close()).To(Succeed())
+
+	data, err := os.ReadFile(tmpFile.Name())
+	Expect(err).To(BeNil())
+	readTiff := openTestTIFF(ctx, data)
+	DeferCleanup(readTiff.Close, ctx)
+	return readTiff
+}
+
+// readPNGICCProfile returns the decompressed profile of the iCCP chunk.
+func readPNGICCProfile(data []byte) []byte {
+	for offset := 8; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		if chunkType == "iCCP" {
+			chunkData := data[offset+8 : offset+8+length]
+			nameEnd := bytes.IndexByte(chunkData, 0)
+			Expect(string(chunkData[:nameEnd])).To(Equal("ICC profile"))
+			reader, err := zlib.NewReader(bytes.NewReader(chunkData[nameEnd+2:]))
+			Expect(err).To(BeNil())
+			profile, err := io.ReadAll(reader)
+			Expect(err).To(BeNil())
+			return profile
+		}
+		offset += 12 + length
+	}
+	return nil
+}
+
+// readJPEGICCProfile returns the profile assembled from the APP2 segments.
+func readJPEGICCProfile(data []byte) []byte {
+	var profile []byte
+	for offset := 2; offset+4 <= len(data) && data[offset] == 0xFF; {
+		marker := data[offset+1]
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) {
+			profile = append(profile, segment[14:]...)
+		}
+		offset += 2 + length
+	}
+	return profile
+}
+
+var _ = Describe("ICC profiles", func() {
+	ctx := context.Background()
+
+	It("writes and reads an ICC profile", func() {
+		profile := testICCProfile(560)
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			ICCProfile: profile,
+		})
+		defer cleanup()
+
+		readProfile, err := tiffFile.ICCProfile(ctx)
+		Expect(err).To(BeNil())
+		Expect(readProfile).To(Equal(profile))
+	})
+
+	It("returns an error when there is no ICC profile", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), nil)
+		defer cleanup()
+
+		_, err := tiffFile.ICCProfile(ctx)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_ICCPROFILE,
+		}))
+
+		pngBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatPNG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:   true,
+		})
+		Expect(err).To(BeNil())
+		Expect(readPNGICCProfile(pngBytes)).To(BeNil())
+	})
+
+	It("embeds the ICC profile in a PNG", func() {
+		profile := testICCProfile(560)
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			ICCProfile: profile,
+		})
+		defer cleanup()
+
+		pngBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatPNG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:   true,
+		})
+		Expect(err).To(BeNil())
+		Expect(readPNGICCProfile(pngBytes)).To(Equal(profile))
+
+		img, err := png.Decode(bytes.NewReader(pngBytes))
+		Expect(err).To(BeNil())
+		Expect(img.Bounds().Dx()).To(Equal(16))
+
+		pngBytes, err = tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatPNG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+		})
+		Expect(err).To(BeNil())
+		Expect(readPNGICCProfile(pngBytes)).To(BeNil())
+	})
+
+	It("embeds a large ICC profile in multiple JPEG APP2 segments", func() {
+		profile := testICCProfile(150000)
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			ICCProfile: profile,
+		})
+		defer cleanup()
+
+		jpegBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatJPEG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:   true,
+		})
+		Expect(err).To(BeNil())
+		Expect(readJPEGICCProfile(jpegBytes)).To(Equal(profile))
+
+		img, err := jpeg.Decode(bytes.NewReader(jpegBytes))
+		Expect(err).To(BeNil())
+		Expect(img.Bounds().Dx()).To(Equal(16))
+	})
+
+	It("refuses a profile that doesn't match the image", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		Expect(tiffFile.FromGoImage(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+			ICCProfile: testCMYKLut16Profile(),
+		})).To(MatchError("ICC profile doesn't describe the RGB samples of the image"))
+		Expect(tiffFile.FromGoImage(ctx, createTestCMYK(4, 4), &libtiff.FromGoImageOptions{
+			ICCProfile: testICCProfile(560),
+		})).To(MatchError("ICC profile doesn't describe the CMYK samples of the image"))
+		Expect(tiffFile.FromGoImage(ctx, createTestGray(4, 4), &libtiff.FromGoImageOptions{
+			ICCProfile: []byte("profile"),
+		})).To(MatchError("ICC profile doesn't describe the GRAY samples of the image"))
+
+		Expect(tiffFile.FromGoImage(ctx, createTestCMYK(4, 4), &libtiff.FromGoImageOptions{
+			ICCProfile: testCMYKLut16Profile(),
+		})).To(Succeed())
+	})
+
+	It("doesn't embed a profile of another colour space", func() {
+		tiffFile, cleanup := writeCMYKTestTIFF(ctx, [][4]byte{{0, 255, 255, 0}, {0, 0, 0, 0}}, testCMYKLut16Profile(), libtiff.ORIENTATION_TOPLEFT)
+		defer cleanup()
+
+		pngBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatPNG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:   true,
+		})
+		Expect(err).To(BeNil())
+		Expect(readPNGICCProfile(pngBytes)).To(BeNil())
+
+		jpegBytes, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+			OutputFormat: libtiff.ImageOptionsOutputFormatJPEG,
+			OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			ICCProfile:   true,
+		})
+		Expect(err).To(BeNil())
+		Expect(readJPEGICCProfile(jpegBytes)).To(BeEmpty())
+	})
+})
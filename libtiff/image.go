@@ -9,6 +9,7 @@ import (
 	"image/png"
 	"os"
 
+	"github.com/klippa-app/go-libtiff/internal/icc"
 	"github.com/klippa-app/go-libtiff/internal/image/image_jpeg"
 
 	"github.com/tetratelabs/wazero/api"
@@ -16,8 +17,27 @@ import (
 
 // ToGoImage convert the current directory in the open TIFF file to RGBA, the
 // caller is responsible for closing since the returned cleanup function will
-// free the allocated memory.
+// free the allocated memory. Palette images with 1, 2, 4 or 8 bits per sample
+// are returned as *image.Paletted with the ColorMap as palette.
 func (f *File) ToGoImage(ctx context.Context) (image.Image, func(context.Context) error, error) {
+	bitsPerSample, isPaletted, err := f.palettedBitsPerSample(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isPaletted {
+		img, err := f.readPaletted(ctx, bitsPerSample)
+		if err != nil {
+			return nil, nil, err
+		}
+		return img, func(context.Context) error { return nil }, nil
+	}
+
+	return f.toRGBAGoImage(ctx)
+}
+
+// toRGBAGoImage converts the current directory to RGBA with libtiff, see
+// ToGoImage.
+func (f *File) toRGBAGoImage(ctx context.Context) (*image.RGBA, func(context.Context) error, error) {
 	width, height, err := f.GetDimensions(ctx)
 	if err != nil {
 		return nil, nil, err
@@ -85,12 +105,14 @@ const (
 )
 
 type ImageOptions struct {
-	OutputFormat   ImageOptionsOutputFormat // The format to output the image as
-	OutputTarget   ImageOptionsOutputTarget // Where to output the image
-	OutputQuality  int                      // Only used when OutputFormat RenderToFileOutputFormatJPG. Ranges from 1 to 100 inclusive, higher is better. The default is 95.
-	Progressive    bool                     // Only used when OutputFormat RenderToFileOutputFormatJPG and with build tag libtiff_use_turbojpeg. Will render a progressive jpeg.
-	MaxFileSize    int64                    // The maximum file size, when OutputFormat RenderToFileOutputFormatJPG, it will try to lower the quality it until it fits.
-	TargetFilePath string                   // When OutputTarget is file, the path to write it to.
+	OutputFormat    ImageOptionsOutputFormat // The format to output the image as
+	OutputTarget    ImageOptionsOutputTarget // Where to output the image
+	OutputQuality   int                      // Only used when OutputFormat RenderToFileOutputFormatJPG. Ranges from 1 to 100 inclusive, higher is better. The default is 95.
+	Progressive     bool                     // Only used when OutputFormat RenderToFileOutputFormatJPG and with build tag libtiff_use_turbojpeg. Will render a progressive jpeg.
+	MaxFileSize     int64                    // The maximum file size, when OutputFormat RenderToFileOutputFormatJPG, it will try to lower the quality it until it fits.
+	TargetFilePath  string                   // When OutputTarget is file, the path to write it to.
+	ICCProfile      bool                     // Embed the ICC profile of the directory, if it has an RGB profile, as an iCCP chunk in PNG or APP2 segments in JPEG. Ignored when ColorManagement is set.
+	ColorManagement *ColorManagementOptions  // When set, convert the colours to sRGB using the ICC profile, see ToSRGBGoImage.
 }
 
 // ToImage convert the current directory in the open TIFF file to an image file.
@@ -99,13 +121,36 @@ func (f *File) ToImage(ctx context.Context, options *ImageOptions) ([]byte, erro
 		return nil, errors.New("options cannot be nil")
 	}
 
-	renderedImage, cleanup, err := f.ToGoImage(ctx)
+	var renderedImage image.Image
+	var cleanup func(context.Context) error
+	var err error
+	if options.ColorManagement != nil {
+		renderedImage, cleanup, err = f.ToSRGBGoImage(ctx, options.ColorManagement)
+	} else {
+		renderedImage, cleanup, err = f.ToGoImage(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup(ctx)
 
+	var iccProfile []byte
+	if options.ICCProfile && options.ColorManagement == nil {
+		iccProfile, err = f.ICCProfile(ctx)
+		if err != nil {
+			if _, ok := err.(*TagNotDefinedError); !ok {
+				return nil, err
+			}
+		}
+
+		// The image is always written as RGB.
+		if !iccProfileMatches(iccProfile, icc.ColorSpaceRGB) {
+			iccProfile = nil
+		}
+	}
+
 	var imgBuf bytes.Buffer
+	var imageBytes []byte
 
 	if options.OutputFormat == ImageOptionsOutputFormatJPEG {
 		opt := image_jpeg.Options{
@@ -119,13 +164,22 @@ func (f *File) ToImage(ctx context.Context, options *ImageOptions) ([]byte, erro
 			opt.Options.Quality = options.OutputQuality
 		}
 
+		rgba := image_jpeg.ToRGBA(renderedImage)
 		for {
-			err := image_jpeg.Encode(&imgBuf, renderedImage.(*image.RGBA), opt)
+			err := image_jpeg.Encode(&imgBuf, rgba, opt)
 			if err != nil {
 				return nil, err
 			}
 
-			if options.MaxFileSize == 0 || int64(imgBuf.Len()) < options.MaxFileSize {
+			imageBytes = imgBuf.Bytes()
+			if len(iccProfile) > 0 {
+				imageBytes, err = embedICCProfileJPEG(imageBytes, iccProfile)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if options.MaxFileSize == 0 || int64(len(imageBytes)) < options.MaxFileSize {
 				break
 			}
 
@@ -143,7 +197,15 @@ func (f *File) ToImage(ctx context.Context, options *ImageOptions) ([]byte, erro
 			return nil, err
 		}
 
-		if options.MaxFileSize != 0 && int64(imgBuf.Len()) > options.MaxFileSize {
+		imageBytes = imgBuf.Bytes()
+		if len(iccProfile) > 0 {
+			imageBytes, err = embedICCProfilePNG(imageBytes, iccProfile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if options.MaxFileSize != 0 && int64(len(imageBytes)) > options.MaxFileSize {
 			return nil, errors.New("TIFF image would exceed maximum filesize")
 		}
 	} else {
@@ -151,7 +213,6 @@ func (f *File) ToImage(ctx context.Context, options *ImageOptions) ([]byte, erro
 	}
 
 	if options.OutputTarget == ImageOptionsOutputTargetBytes {
-		imageBytes := imgBuf.Bytes()
 		return imageBytes, nil
 	} else if options.OutputTarget == ImageOptionsOutputTargetFile {
 		var targetFile *os.File
@@ -167,7 +228,7 @@ func (f *File) ToImage(ctx context.Context, options *ImageOptions) ([]byte, erro
 			targetFile = existingFile
 		}
 
-		_, err := targetFile.Write(imgBuf.Bytes())
+		_, err := targetFile.Write(imageBytes)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,257 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"unicode/utf8"
+)
+
+// IPTCDataSet is a raw IPTC-IIM dataset.
+type IPTCDataSet struct {
+	Record  uint8
+	DataSet uint8
+	Value   []byte
+}
+
+// IPTC contains the IPTC-IIM application record (record 2) fields that are
+// commonly used by news archives.
+type IPTC struct {
+	ObjectName                    string   // 2:05
+	Urgency                       string   // 2:10
+	Category                      string   // 2:15
+	SupplementalCategories        []string // 2:20
+	Keywords                      []string // 2:25
+	SpecialInstructions           string   // 2:40
+	DateCreated                   string   // 2:55, CCYYMMDD
+	TimeCreated                   string   // 2:60, HHMMSS±HHMM
+	Bylines                       []string // 2:80
+	BylineTitles                  []string // 2:85
+	City                          string   // 2:90
+	SubLocation                   string   // 2:92
+	ProvinceState                 string   // 2:95
+	CountryCode                   string   // 2:100
+	Country                       string   // 2:101
+	OriginalTransmissionReference string   // 2:103
+	Headline                      string   // 2:105
+	Credit                        string   // 2:110
+	Source                        string   // 2:115
+	CopyrightNotice               string   // 2:116
+	Caption                       string   // 2:120
+	CaptionWriters                []string // 2:122
+
+	// DataSets contains all datasets in the order they were read. When
+	// writing, the datasets that are not represented by the fields above
+	// are written as-is.
+	DataSets []IPTCDataSet
+}
+
+// iptcStringFields and iptcListFields map the application record datasets
+// to the IPTC fields.
+var iptcStringFields = []struct {
+	dataSet uint8
+	field   func(*IPTC) *string
+}{
+	{5, func(i *IPTC) *string { return &i.ObjectName }},
+	{10, func(i *IPTC) *string { return &i.Urgency }},
+	{15, func(i *IPTC) *string { return &i.Category }},
+	{40, func(i *IPTC) *string { return &i.SpecialInstructions }},
+	{55, func(i *IPTC) *string { return &i.DateCreated }},
+	{60, func(i *IPTC) *string { return &i.TimeCreated }},
+	{90, func(i *IPTC) *string { return &i.City }},
+	{92, func(i *IPTC) *string { return &i.SubLocation }},
+	{95, func(i *IPTC) *string { return &i.ProvinceState }},
+	{100, func(i *IPTC) *string { return &i.CountryCode }},
+	{101, func(i *IPTC) *string { return &i.Country }},
+	{103, func(i *IPTC) *string { return &i.OriginalTransmissionReference }},
+	{105, func(i *IPTC) *string { return &i.Headline }},
+	{110, func(i *IPTC) *string { return &i.Credit }},
+	{115, func(i *IPTC) *string { return &i.Source }},
+	{116, func(i *IPTC) *string { return &i.CopyrightNotice }},
+	{120, func(i *IPTC) *string { return &i.Caption }},
+}
+
+var iptcListFields = []struct {
+	dataSet uint8
+	field   func(*IPTC) *[]string
+}{
+	{20, func(i *IPTC) *[]string { return &i.SupplementalCategories }},
+	{25, func(i *IPTC) *[]string { return &i.Keywords }},
+	{80, func(i *IPTC) *[]string { return &i.Bylines }},
+	{85, func(i *IPTC) *[]string { return &i.BylineTitles }},
+	{122, func(i *IPTC) *[]string { return &i.CaptionWriters }},
+}
+
+const (
+	iptcRecordEnvelope    = 1
+	iptcRecordApplication = 2
+
+	iptcDataSetCodedCharacterSet = 90
+	iptcDataSetRecordVersion     = 0
+)
+
+// iptcUTF8 is the coded character set escape sequence for UTF-8.
+const iptcUTF8 = "\x1b%G"
+
+// IPTC returns the IPTC-IIM metadata of the current directory from
+// TIFFTAG_RICHTIFFIPTC.
+// Returns a TagNotDefinedError when the directory has no IPTC metadata.
+func (f *File) IPTC(ctx context.Context) (*IPTC, error) {
+	data, err := f.TIFFGetFieldByteArray(ctx, TIFFTAG_RICHTIFFIPTC)
+	if err != nil {
+		return nil, err
+	}
+	return parseIPTC(data)
+}
+
+// parseIPTC parses IPTC-IIM datasets. Trailing padding is ignored.
+func parseIPTC(data []byte) (*IPTC, error) {
+	iptc := &IPTC{}
+	for offset := 0; offset+5 <= len(data) && data[offset] == 0x1C; {
+		record, dataSet := data[offset+1], data[offset+2]
+		length := int(binary.BigEndian.Uint16(data[offset+3:]))
+		offset += 5
+
+		// Extended datasets store the size of the length in the lower 15 bits.
+		if length&0x8000 != 0 {
+			lengthSize := length & 0x7FFF
+			if lengthSize > 4 || offset+lengthSize > len(data) {
+				return nil, errors.New("invalid IPTC dataset length")
+			}
+			length = 0
+			for _, b := range data[offset : offset+lengthSize] {
+				length = length<<8 | int(b)
+			}
+			offset += lengthSize
+		}
+		if offset+length > len(data) {
+			return nil, errors.New("invalid IPTC dataset length")
+		}
+
+		iptc.DataSets = append(iptc.DataSets, IPTCDataSet{
+			Record:  record,
+			DataSet: dataSet,
+			Value:   data[offset : offset+length],
+		})
+		offset += length
+	}
+
+	for _, dataSet := range iptc.DataSets {
+		if dataSet.Record != iptcRecordApplication {
+			continue
+		}
+		value := decodeIPTCString(dataSet.Value)
+		for _, field := range iptcStringFields {
+			if field.dataSet == dataSet.DataSet {
+				*field.field(iptc) = value
+			}
+		}
+		for _, field := range iptcListFields {
+			if field.dataSet == dataSet.DataSet {
+				list := field.field(iptc)
+				*list = append(*list, value)
+			}
+		}
+	}
+
+	return iptc, nil
+}
+
+// decodeIPTCString decodes a text dataset. Text that is not valid UTF-8 is
+// assumed to be Latin-1, which is what most writers use without a coded
+// character set.
+func decodeIPTCString(value []byte) string {
+	if utf8.Valid(value) {
+		return string(value)
+	}
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// isIPTCField returns whether a dataset is represented by an IPTC field, or
+// is written by encode itself.
+func isIPTCField(record, dataSet uint8) bool {
+	if record == iptcRecordEnvelope {
+		return dataSet == iptcDataSetCodedCharacterSet
+	}
+	if record != iptcRecordApplication {
+		return false
+	}
+	if dataSet == iptcDataSetRecordVersion {
+		return true
+	}
+	for _, field := range iptcStringFields {
+		if field.dataSet == dataSet {
+			return true
+		}
+	}
+	for _, field := range iptcListFields {
+		if field.dataSet == dataSet {
+			return true
+		}
+	}
+	return false
+}
+
+// encode returns the IPTC-IIM datasets of the metadata, text is written as
+// UTF-8. The result is padded to a multiple of 4 bytes, since
+// TIFFTAG_RICHTIFFIPTC is commonly read as LONG.
+func (i *IPTC) encode() []byte {
+	var data []byte
+	appendDataSet := func(record, dataSet uint8, value []byte) {
+		data = append(data, 0x1C, record, dataSet)
+		if len(value) < 0x8000 {
+			data = binary.BigEndian.AppendUint16(data, uint16(len(value)))
+		} else {
+			data = binary.BigEndian.AppendUint16(data, 0x8004)
+			data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+		}
+		data = append(data, value...)
+	}
+
+	// The envelope record comes before the application record.
+	for _, dataSet := range i.DataSets {
+		if dataSet.Record < iptcRecordApplication && !isIPTCField(dataSet.Record, dataSet.DataSet) {
+			appendDataSet(dataSet.Record, dataSet.DataSet, dataSet.Value)
+		}
+	}
+	appendDataSet(iptcRecordEnvelope, iptcDataSetCodedCharacterSet, []byte(iptcUTF8))
+	appendDataSet(iptcRecordApplication, iptcDataSetRecordVersion, []byte{0, 4})
+
+	// Datasets are written in ascending order.
+	type fieldValues struct {
+		dataSet uint8
+		values  []string
+	}
+	var fields []fieldValues
+	for _, field := range iptcStringFields {
+		if value := *field.field(i); value != "" {
+			fields = append(fields, fieldValues{field.dataSet, []string{value}})
+		}
+	}
+	for _, field := range iptcListFields {
+		fields = append(fields, fieldValues{field.dataSet, *field.field(i)})
+	}
+	slices.SortStableFunc(fields, func(a, b fieldValues) int {
+		return int(a.dataSet) - int(b.dataSet)
+	})
+	for _, field := range fields {
+		for _, value := range field.values {
+			appendDataSet(iptcRecordApplication, field.dataSet, []byte(value))
+		}
+	}
+	for _, dataSet := range i.DataSets {
+		if dataSet.Record >= iptcRecordApplication && !isIPTCField(dataSet.Record, dataSet.DataSet) {
+			appendDataSet(dataSet.Record, dataSet.DataSet, dataSet.Value)
+		}
+	}
+
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
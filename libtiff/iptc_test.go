@@ -0,0 +1,109 @@
+package libtiff_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IPTC", func() {
+	ctx := context.Background()
+
+	It("writes and reads the application record fields", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			IPTC: &libtiff.IPTC{
+				ObjectName:      "Invoice",
+				Headline:        "Quarterly invoice",
+				Caption:         "Scanned invoice of Café Zürich",
+				Keywords:        []string{"invoice", "finance"},
+				Bylines:         []string{"Jane Doe", "John Doe"},
+				City:            "Amsterdam",
+				CountryCode:     "NLD",
+				CopyrightNotice: "© 2024 Example",
+				DateCreated:     "20240131",
+			},
+		})
+		defer cleanup()
+
+		iptc, err := tiffFile.IPTC(ctx)
+		Expect(err).To(BeNil())
+		Expect(iptc.ObjectName).To(Equal("Invoice"))
+		Expect(iptc.Headline).To(Equal("Quarterly invoice"))
+		Expect(iptc.Caption).To(Equal("Scanned invoice of Café Zürich"))
+		Expect(iptc.Keywords).To(Equal([]string{"invoice", "finance"}))
+		Expect(iptc.Bylines).To(Equal([]string{"Jane Doe", "John Doe"}))
+		Expect(iptc.City).To(Equal("Amsterdam"))
+		Expect(iptc.CountryCode).To(Equal("NLD"))
+		Expect(iptc.CopyrightNotice).To(Equal("© 2024 Example"))
+		Expect(iptc.DateCreated).To(Equal("20240131"))
+
+		Expect(iptc.DataSets[0]).To(Equal(libtiff.IPTCDataSet{Record: 1, DataSet: 90, Value: []byte("\x1b%G")}))
+		Expect(iptc.DataSets[1]).To(Equal(libtiff.IPTCDataSet{Record: 2, DataSet: 0, Value: []byte{0, 4}}))
+		Expect(iptc.DataSets[2]).To(Equal(libtiff.IPTCDataSet{Record: 2, DataSet: 5, Value: []byte("Invoice")}))
+	})
+
+	It("keeps unknown datasets", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			IPTC: &libtiff.IPTC{
+				Caption: "Caption",
+				DataSets: []libtiff.IPTCDataSet{
+					{Record: 1, DataSet: 20, Value: []byte("file format")},
+					{Record: 2, DataSet: 120, Value: []byte("overwritten")},
+					{Record: 2, DataSet: 200, Value: []byte("custom")},
+				},
+			},
+		})
+		defer cleanup()
+
+		iptc, err := tiffFile.IPTC(ctx)
+		Expect(err).To(BeNil())
+		Expect(iptc.Caption).To(Equal("Caption"))
+		Expect(iptc.DataSets).To(Equal([]libtiff.IPTCDataSet{
+			{Record: 1, DataSet: 20, Value: []byte("file format")},
+			{Record: 1, DataSet: 90, Value: []byte("\x1b%G")},
+			{Record: 2, DataSet: 0, Value: []byte{0, 4}},
+			{Record: 2, DataSet: 120, Value: []byte("Caption")},
+			{Record: 2, DataSet: 200, Value: []byte("custom")},
+		}))
+	})
+
+	It("reads Latin-1 text", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-test-*.tif")
+		Expect(err).To(BeNil())
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+
+		fileMode := "w"
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			FileMode: &fileMode,
+		})
+		Expect(err).To(BeNil())
+		defer tiffFile.Close(ctx)
+
+		err = tiffFile.FromGoImage(ctx, createTestRGBA(16, 16), nil)
+		Expect(err).To(BeNil())
+
+		// A caption without a coded character set, as written by older tools.
+		err = tiffFile.TIFFSetFieldByteArray(ctx, libtiff.TIFFTAG_RICHTIFFIPTC, []byte("\x1c\x02\x78\x00\x04Caf\xe9\x1c\x02\x19\x00\x03abc"))
+		Expect(err).To(BeNil())
+
+		iptc, err := tiffFile.IPTC(ctx)
+		Expect(err).To(BeNil())
+		Expect(iptc.Caption).To(Equal("Café"))
+		Expect(iptc.Keywords).To(Equal([]string{"abc"}))
+	})
+
+	It("returns an error when there is no IPTC metadata", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), nil)
+		defer cleanup()
+
+		_, err := tiffFile.IPTC(ctx)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_RICHTIFFIPTC,
+		}))
+	})
+})
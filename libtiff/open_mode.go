@@ -0,0 +1,184 @@
+package libtiff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OpenAccess selects whether a file is read, written or appended to.
+type OpenAccess int
+
+const (
+	// OpenRead opens an existing file for reading, like file mode "r".
+	OpenRead OpenAccess = iota
+	// OpenWrite creates a new file, like file mode "w".
+	OpenWrite
+	// OpenAppend adds directories to an existing file, like file mode "a".
+	OpenAppend
+	// OpenUpdate opens an existing file for reading and changing its
+	// directories in place, like file mode "r+".
+	OpenUpdate
+)
+
+var openAccessModes = map[OpenAccess]string{
+	OpenRead:   "r",
+	OpenWrite:  "w",
+	OpenAppend: "a",
+	OpenUpdate: "r+",
+}
+
+// String returns the libtiff file mode of the access, like "w".
+func (a OpenAccess) String() string {
+	if mode, ok := openAccessModes[a]; ok {
+		return mode
+	}
+	return fmt.Sprintf("OpenAccess(%d)", int(a))
+}
+
+// ByteOrder selects the byte order of a new file.
+type ByteOrder int
+
+const (
+	// ByteOrderNative writes the native byte order of the wasm module,
+	// which is little-endian.
+	ByteOrderNative ByteOrder = iota
+	// ByteOrderBigEndian writes a big-endian ("MM") file, like file mode "b".
+	ByteOrderBigEndian
+	// ByteOrderLittleEndian writes a little-endian ("II") file, like file
+	// mode "l".
+	ByteOrderLittleEndian
+)
+
+// StripLoading selects when the strip and tile offsets of a directory are
+// loaded.
+type StripLoading int
+
+const (
+	// StripLoadingDefault loads all offsets when a directory is read.
+	StripLoadingDefault StripLoading = iota
+	// StripLoadingDeferred loads all offsets when they are first needed,
+	// like file mode "D".
+	StripLoadingDeferred
+	// StripLoadingOnDemand loads only the offsets of the strips and tiles
+	// that are accessed, like file mode "O". This makes opening files with
+	// many strips or tiles cheap.
+	StripLoadingOnDemand
+)
+
+// OpenMode is a structured alternative to OpenOptions.FileMode.
+type OpenMode struct {
+	// Access selects reading, writing or appending, OpenRead by default.
+	Access OpenAccess
+	// ByteOrder is the byte order of the file, only for OpenWrite.
+	ByteOrder ByteOrder
+	// BigTIFF selects the file format, only for OpenWrite. The format
+	// can't be changed once the file is open, so FromGoImage and the
+	// other writers follow the format chosen here.
+	BigTIFF BigTIFFMode
+	// EstimatedSize is the expected size of the written file in bytes, used
+	// by BigTIFFAuto. EstimateImageSize gives the uncompressed size of an
+	// image written by FromGoImage.
+	EstimatedSize uint64
+	// StripChopping enables or disables splitting large uncompressed strips
+	// into smaller ones when reading. When nil, the libtiff default is used,
+	// which is to chop strips.
+	StripChopping *bool
+	// DeferStripLoading selects when strip and tile offsets are loaded, not
+	// for OpenWrite.
+	DeferStripLoading StripLoading
+}
+
+// validate checks that the fields are known values that apply to the access.
+func (m *OpenMode) validate() error {
+	if _, ok := openAccessModes[m.Access]; !ok {
+		return fmt.Errorf("unknown open access %d", int(m.Access))
+	}
+	if m.ByteOrder < ByteOrderNative || m.ByteOrder > ByteOrderLittleEndian {
+		return fmt.Errorf("unknown byte order %d", int(m.ByteOrder))
+	}
+	if _, ok := bigTIFFModeNames[m.BigTIFF]; !ok {
+		return fmt.Errorf("unknown BigTIFF mode %d", int(m.BigTIFF))
+	}
+	if m.DeferStripLoading < StripLoadingDefault || m.DeferStripLoading > StripLoadingOnDemand {
+		return fmt.Errorf("unknown strip loading %d", int(m.DeferStripLoading))
+	}
+
+	if m.Access != OpenWrite {
+		if m.ByteOrder != ByteOrderNative {
+			return errors.New("ByteOrder can only be set when writing, an existing file keeps its byte order")
+		}
+		if m.BigTIFF != BigTIFFNo {
+			return errors.New("BigTIFF can only be set when writing, an existing file keeps its format")
+		}
+	} else {
+		if m.StripChopping != nil {
+			return errors.New("StripChopping can't be set when writing")
+		}
+		if m.DeferStripLoading != StripLoadingDefault {
+			return errors.New("DeferStripLoading can't be set when writing")
+		}
+	}
+
+	return nil
+}
+
+// String returns the libtiff file mode, like "w8b". It does not validate the
+// mode.
+func (m *OpenMode) String() string {
+	fileMode := m.Access.String()
+
+	switch m.ByteOrder {
+	case ByteOrderBigEndian:
+		fileMode += "b"
+	case ByteOrderLittleEndian:
+		fileMode += "l"
+	}
+
+	switch m.BigTIFF {
+	case BigTIFFYes:
+		fileMode += "8"
+	case BigTIFFAuto:
+		if m.EstimatedSize >= bigTIFFAutoThreshold {
+			fileMode += "8"
+		}
+	}
+
+	if m.StripChopping != nil {
+		if *m.StripChopping {
+			fileMode += "C"
+		} else {
+			fileMode += "c"
+		}
+	}
+
+	switch m.DeferStripLoading {
+	case StripLoadingDeferred:
+		fileMode += "D"
+	case StripLoadingOnDemand:
+		fileMode += "O"
+	}
+
+	return fileMode
+}
+
+// fileMode returns the libtiff file mode for the options, "r" by default.
+func (o *OpenOptions) fileMode() (string, error) {
+	if o == nil {
+		return "r", nil
+	}
+
+	if o.Mode != nil {
+		if o.FileMode != nil {
+			return "", errors.New("FileMode and Mode can't both be set")
+		}
+		if err := o.Mode.validate(); err != nil {
+			return "", fmt.Errorf("invalid open mode: %w", err)
+		}
+		return o.Mode.String(), nil
+	}
+
+	if o.FileMode != nil && *o.FileMode != "" {
+		return *o.FileMode, nil
+	}
+	return "r", nil
+}
@@ -0,0 +1,121 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OpenMode", func() {
+	ctx := context.Background()
+	chop := true
+	noChop := false
+
+	DescribeTable("builds the file mode",
+		func(mode libtiff.OpenMode, expected string) {
+			Expect(mode.String()).To(Equal(expected))
+		},
+		Entry("for reading by default", libtiff.OpenMode{}, "r"),
+		Entry("for writing", libtiff.OpenMode{Access: libtiff.OpenWrite}, "w"),
+		Entry("for appending", libtiff.OpenMode{Access: libtiff.OpenAppend}, "a"),
+		Entry("for updating", libtiff.OpenMode{Access: libtiff.OpenUpdate}, "r+"),
+		Entry("for a big-endian BigTIFF", libtiff.OpenMode{
+			Access:    libtiff.OpenWrite,
+			ByteOrder: libtiff.ByteOrderBigEndian,
+			BigTIFF:   libtiff.BigTIFFYes,
+		}, "wb8"),
+		Entry("for a little-endian file", libtiff.OpenMode{
+			Access:    libtiff.OpenWrite,
+			ByteOrder: libtiff.ByteOrderLittleEndian,
+		}, "wl"),
+		Entry("with strip chopping", libtiff.OpenMode{StripChopping: &chop}, "rC"),
+		Entry("without strip chopping", libtiff.OpenMode{StripChopping: &noChop}, "rc"),
+		Entry("with deferred strip loading", libtiff.OpenMode{
+			Access:            libtiff.OpenAppend,
+			DeferStripLoading: libtiff.StripLoadingDeferred,
+		}, "aD"),
+		Entry("with on-demand strip loading", libtiff.OpenMode{
+			StripChopping:     &noChop,
+			DeferStripLoading: libtiff.StripLoadingOnDemand,
+		}, "rcO"),
+	)
+
+	DescribeTable("rejects invalid modes",
+		func(options *libtiff.OpenOptions, expectedError string) {
+			_, err := instance.TIFFOpenFileFromPath(ctx, "/testdata/multipage-sample.tif", options)
+			Expect(err).To(MatchError(expectedError))
+		},
+		Entry("with an unknown access", &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenAccess(7)},
+		}, "invalid open mode: unknown open access 7"),
+		Entry("with a byte order when reading", &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{ByteOrder: libtiff.ByteOrderBigEndian},
+		}, "invalid open mode: ByteOrder can only be set when writing, an existing file keeps its byte order"),
+		Entry("with BigTIFF when appending", &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenAppend, BigTIFF: libtiff.BigTIFFAuto},
+		}, "invalid open mode: BigTIFF can only be set when writing, an existing file keeps its format"),
+		Entry("with strip chopping when writing", &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenWrite, StripChopping: &chop},
+		}, "invalid open mode: StripChopping can't be set when writing"),
+		Entry("with deferred strip loading when writing", &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenWrite, DeferStripLoading: libtiff.StripLoadingOnDemand},
+		}, "invalid open mode: DeferStripLoading can't be set when writing"),
+		Entry("with both FileMode and Mode", &libtiff.OpenOptions{
+			FileMode: func() *string { mode := "r"; return &mode }(),
+			Mode:     &libtiff.OpenMode{},
+		}, "FileMode and Mode can't both be set"),
+	)
+
+	It("writes a big-endian BigTIFF and reads it on demand", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-open-mode-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, tmpFile.Name())
+
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{
+				Access:    libtiff.OpenWrite,
+				ByteOrder: libtiff.ByteOrderBigEndian,
+				BigTIFF:   libtiff.BigTIFFYes,
+			},
+		})
+		Expect(err).To(BeNil())
+		Expect(tiffFile.FromGoImage(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			RowsPerStrip: 2,
+		})).To(Succeed())
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+
+		readFile, err := os.Open(tmpFile.Name())
+		Expect(err).To(BeNil())
+		defer readFile.Close()
+		stat, err := readFile.Stat()
+		Expect(err).To(BeNil())
+
+		readTiff, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", readFile, uint64(stat.Size()), &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{
+				StripChopping:     &noChop,
+				DeferStripLoading: libtiff.StripLoadingOnDemand,
+			},
+		})
+		Expect(err).To(BeNil())
+		defer readTiff.Close(ctx)
+
+		bigEndian, err := readTiff.TIFFIsBigEndian(ctx)
+		Expect(err).To(BeNil())
+		Expect(bigEndian).To(BeTrue())
+
+		isBigTIFF, err := readTiff.TIFFIsBigTIFF(ctx)
+		Expect(err).To(BeNil())
+		Expect(isBigTIFF).To(BeTrue())
+
+		goImage, imgCleanup, err := readTiff.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(goImage.Bounds()).To(Equal(image.Rect(0, 0, 16, 16)))
+	})
+})
@@ -0,0 +1,303 @@
+package libtiff
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/klippa-app/go-libtiff/internal/image/image_jpeg"
+)
+
+// Resampling selects the filter that overview levels are made with.
+type Resampling int
+
+const (
+	// ResamplingAverage averages the pixels that are reduced to one pixel.
+	ResamplingAverage Resampling = iota
+	// ResamplingNearest takes the pixel nearest to the center of the
+	// reduced pixel, which keeps the exact colors of the image.
+	ResamplingNearest
+	// ResamplingBilinear interpolates between the 4 pixels around the
+	// center of the reduced pixel.
+	ResamplingBilinear
+)
+
+var resamplingNames = map[Resampling]string{
+	ResamplingAverage:  "average",
+	ResamplingNearest:  "nearest",
+	ResamplingBilinear: "bilinear",
+}
+
+// String returns the name of the filter, like "average".
+func (r Resampling) String() string {
+	if name, ok := resamplingNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Resampling(%d)", int(r))
+}
+
+// ParseResampling parses "average", "nearest" or "bilinear",
+// case-insensitively.
+func ParseResampling(value string) (Resampling, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for resampling, name := range resamplingNames {
+		if name == value {
+			return resampling, nil
+		}
+	}
+	return ResamplingAverage, fmt.Errorf("unknown resampling %q", value)
+}
+
+// defaultOverviewMinSize is the size up to which automatic overview levels
+// are made.
+const defaultOverviewMinSize = 256
+
+// Overviews configures the reduced-resolution levels of an image, which let
+// viewers show a zoomed out image without reading the full resolution.
+type Overviews struct {
+	// Factors are the reduction factors of the levels in increasing order,
+	// like 2, 4, 8 and 16. If empty, the factors 2, 4, 8 and so on are
+	// used until the width and height of a level are at most MinSize.
+	Factors []int
+	// MinSize is the size at which the automatic factors stop. If 0, 256
+	// is used.
+	MinSize int
+	// Resampling is the filter the levels are made with. Paletted images
+	// always use ResamplingNearest.
+	Resampling Resampling
+	// SubIFDs writes the levels as SubIFDs of the image, instead of as
+	// FILETYPE_REDUCEDIMAGE directories that follow it. This keeps the
+	// levels out of the page count of multi-page files. Not used for COG
+	// output.
+	SubIFDs bool
+}
+
+// factors returns the reduction factors of the levels for an image of the
+// given size.
+func (o *Overviews) factors(width, height int) ([]int, error) {
+	if _, ok := resamplingNames[o.Resampling]; !ok {
+		return nil, fmt.Errorf("unknown resampling %d", int(o.Resampling))
+	}
+
+	if len(o.Factors) > 0 {
+		previous := 1
+		for _, factor := range o.Factors {
+			if factor <= previous {
+				return nil, fmt.Errorf("overview factors must be increasing and at least 2, got %v", o.Factors)
+			}
+			previous = factor
+		}
+		return o.Factors, nil
+	}
+
+	minSize := o.MinSize
+	if minSize <= 0 {
+		minSize = defaultOverviewMinSize
+	}
+	var factors []int
+	// Keep halving while the previous level is larger than minSize.
+	for factor := 2; max(overviewSize(width, factor/2), overviewSize(height, factor/2)) > minSize; factor *= 2 {
+		factors = append(factors, factor)
+	}
+	return factors, nil
+}
+
+// overviewSize returns the size of a dimension reduced by factor, rounded
+// up so the level covers the whole image.
+func overviewSize(size, factor int) int {
+	return (size + factor - 1) / factor
+}
+
+// overviewOptions returns the options of the levels of an image written
+// with the given options: the same compression and layout, without the
+// metadata of the full resolution image.
+func overviewOptions(options *FromGoImageOptions) *FromGoImageOptions {
+	return &FromGoImageOptions{
+		Compression:        options.Compression,
+		Quality:            options.Quality,
+		AlphaMode:          options.AlphaMode,
+		Software:           options.Software,
+		DateTime:           options.DateTime,
+		Predictor:          options.Predictor,
+		RowsPerStrip:       options.RowsPerStrip,
+		Orientation:        options.Orientation,
+		TileWidth:          options.TileWidth,
+		TileHeight:         options.TileHeight,
+		BilevelThreshold:   options.BilevelThreshold,
+		Binarization:       options.Binarization,
+		BinarizationWindow: options.BinarizationWindow,
+		BinarizationK:      options.BinarizationK,
+		SubfileType:        FILETYPE_REDUCEDIMAGE,
+	}
+}
+
+// writeGoImageWithOverviews writes img followed by its overview levels.
+func (f *File) writeGoImageWithOverviews(ctx context.Context, img image.Image, options *FromGoImageOptions) error {
+	bounds := img.Bounds()
+	factors, err := options.Overviews.factors(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return err
+	}
+
+	subIFDs := 0
+	if options.Overviews.SubIFDs {
+		subIFDs = len(factors)
+	}
+	if err := f.writeGoImage(ctx, img, options, subIFDs); err != nil {
+		return err
+	}
+
+	source := overviewSource(img)
+	levelOptions := overviewOptions(options)
+	for _, factor := range factors {
+		level := resampleImage(source, overviewSize(bounds.Dx(), factor), overviewSize(bounds.Dy(), factor), options.Overviews.Resampling)
+		if err := f.writeGoImage(ctx, level, levelOptions, 0); err != nil {
+			return fmt.Errorf("could not write overview with factor %d: %w", factor, err)
+		}
+	}
+	return nil
+}
+
+// overviewSource returns img when it can be resampled directly, or a copy
+// of it as *image.RGBA.
+func overviewSource(img image.Image) image.Image {
+	switch img.(type) {
+	case *image.Gray, *image.Gray16, *image.RGBA, *image.NRGBA, *image.RGBA64, *image.NRGBA64, *image.CMYK, *image.Paletted:
+		return img
+	}
+	return image_jpeg.ToRGBA(img)
+}
+
+// pixImage is the pixel buffer of an image with samples of 1 or 2 bytes.
+type pixImage struct {
+	pix         []byte
+	stride      int
+	width       int
+	height      int
+	channels    int
+	sampleBytes int
+}
+
+// sample returns the value of channel c of the pixel at x, y.
+func (p *pixImage) sample(x, y, c int) int {
+	offset := y*p.stride + (x*p.channels+c)*p.sampleBytes
+	if p.sampleBytes == 2 {
+		return int(p.pix[offset])<<8 | int(p.pix[offset+1])
+	}
+	return int(p.pix[offset])
+}
+
+func (p *pixImage) setSample(x, y, c, value int) {
+	offset := y*p.stride + (x*p.channels+c)*p.sampleBytes
+	if p.sampleBytes == 2 {
+		p.pix[offset] = uint8(value >> 8)
+		p.pix[offset+1] = uint8(value)
+		return
+	}
+	p.pix[offset] = uint8(value)
+}
+
+// newPixImage returns the pixel buffer of the image, which must be one of
+// the types overviewSource returns.
+func newPixImage(img image.Image) *pixImage {
+	bounds := img.Bounds()
+	p := &pixImage{width: bounds.Dx(), height: bounds.Dy(), channels: 4, sampleBytes: 1}
+	var offset int
+	switch src := img.(type) {
+	case *image.Gray:
+		p.pix, p.stride, offset, p.channels = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), 1
+	case *image.Gray16:
+		p.pix, p.stride, offset, p.channels, p.sampleBytes = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), 1, 2
+	case *image.RGBA:
+		p.pix, p.stride, offset = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y)
+	case *image.NRGBA:
+		p.pix, p.stride, offset = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y)
+	case *image.RGBA64:
+		p.pix, p.stride, offset, p.sampleBytes = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), 2
+	case *image.NRGBA64:
+		p.pix, p.stride, offset, p.sampleBytes = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), 2
+	case *image.CMYK:
+		p.pix, p.stride, offset = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y)
+	case *image.Paletted:
+		p.pix, p.stride, offset, p.channels = src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), 1
+	}
+	p.pix = p.pix[offset:]
+	return p
+}
+
+// resampleImage returns src reduced to width x height, as the same image
+// type. src must be one of the types overviewSource returns.
+func resampleImage(src image.Image, width, height int, resampling Resampling) image.Image {
+	rect := image.Rect(0, 0, width, height)
+	var dst image.Image
+	switch src := src.(type) {
+	case *image.Gray:
+		dst = image.NewGray(rect)
+	case *image.Gray16:
+		dst = image.NewGray16(rect)
+	case *image.RGBA:
+		dst = image.NewRGBA(rect)
+	case *image.NRGBA:
+		dst = image.NewNRGBA(rect)
+	case *image.RGBA64:
+		dst = image.NewRGBA64(rect)
+	case *image.NRGBA64:
+		dst = image.NewNRGBA64(rect)
+	case *image.CMYK:
+		dst = image.NewCMYK(rect)
+	case *image.Paletted:
+		// Palette indexes can't be interpolated.
+		dst = image.NewPaletted(rect, src.Palette)
+		resampling = ResamplingNearest
+	}
+
+	resamplePix(newPixImage(src), newPixImage(dst), resampling)
+	return dst
+}
+
+// resamplePix fills dst with the resampled samples of src.
+func resamplePix(src, dst *pixImage, resampling Resampling) {
+	scaleX := float64(src.width) / float64(dst.width)
+	scaleY := float64(src.height) / float64(dst.height)
+
+	for y := 0; y < dst.height; y++ {
+		for x := 0; x < dst.width; x++ {
+			switch resampling {
+			case ResamplingNearest:
+				srcX := min(int((float64(x)+0.5)*scaleX), src.width-1)
+				srcY := min(int((float64(y)+0.5)*scaleY), src.height-1)
+				for c := 0; c < src.channels; c++ {
+					dst.setSample(x, y, c, src.sample(srcX, srcY, c))
+				}
+
+			case ResamplingBilinear:
+				fx := max((float64(x)+0.5)*scaleX-0.5, 0)
+				fy := max((float64(y)+0.5)*scaleY-0.5, 0)
+				x0, y0 := min(int(fx), src.width-1), min(int(fy), src.height-1)
+				x1, y1 := min(x0+1, src.width-1), min(y0+1, src.height-1)
+				wx, wy := fx-float64(x0), fy-float64(y0)
+				for c := 0; c < src.channels; c++ {
+					top := float64(src.sample(x0, y0, c))*(1-wx) + float64(src.sample(x1, y0, c))*wx
+					bottom := float64(src.sample(x0, y1, c))*(1-wx) + float64(src.sample(x1, y1, c))*wx
+					dst.setSample(x, y, c, int(top*(1-wy)+bottom*wy+0.5))
+				}
+
+			default:
+				x0, x1 := x*src.width/dst.width, max((x+1)*src.width/dst.width, x*src.width/dst.width+1)
+				y0, y1 := y*src.height/dst.height, max((y+1)*src.height/dst.height, y*src.height/dst.height+1)
+				x1, y1 = min(x1, src.width), min(y1, src.height)
+				count := (x1 - x0) * (y1 - y0)
+				for c := 0; c < src.channels; c++ {
+					sum := 0
+					for sy := y0; sy < y1; sy++ {
+						for sx := x0; sx < x1; sx++ {
+							sum += src.sample(sx, sy, c)
+						}
+					}
+					dst.setSample(x, y, c, (sum+count/2)/count)
+				}
+			}
+		}
+	}
+}
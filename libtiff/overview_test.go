@@ -0,0 +1,176 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overviews", func() {
+	ctx := context.Background()
+
+	// writeWithOverviews writes img with the options and reopens the file.
+	writeWithOverviews := func(img image.Image, options *libtiff.FromGoImageOptions) *libtiff.File {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+		return reopenTempTestFile(ctx, tiffFile, tmpFile)
+	}
+
+	// directorySizes returns the size and subfile type of every directory.
+	directorySizes := func(tiffFile *libtiff.File) ([]image.Point, []uint32) {
+		directories, err := tiffFile.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+
+		var sizes []image.Point
+		var subfileTypes []uint32
+		for directory := uint32(0); directory < directories; directory++ {
+			Expect(tiffFile.TIFFSetDirectory(ctx, directory)).To(Succeed())
+			width, height, err := tiffFile.GetDimensions(ctx)
+			Expect(err).To(BeNil())
+			sizes = append(sizes, image.Pt(width, height))
+
+			subfileType, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_SUBFILETYPE)
+			if err != nil {
+				subfileType = 0
+			}
+			subfileTypes = append(subfileTypes, subfileType)
+		}
+		return sizes, subfileTypes
+	}
+
+	It("writes the levels as reduced-resolution directories", func() {
+		img := createTestRGBA(101, 60)
+		tiffFile := writeWithOverviews(img, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Description: "full resolution",
+			Overviews:   &libtiff.Overviews{Factors: []int{2, 4}},
+		})
+
+		sizes, subfileTypes := directorySizes(tiffFile)
+		Expect(sizes).To(Equal([]image.Point{image.Pt(101, 60), image.Pt(51, 30), image.Pt(26, 15)}))
+		Expect(subfileTypes).To(Equal([]uint32{0, uint32(libtiff.FILETYPE_REDUCEDIMAGE), uint32(libtiff.FILETYPE_REDUCEDIMAGE)}))
+
+		// The levels have the compression but not the metadata of the image.
+		Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		compression, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_COMPRESSION)
+		Expect(err).To(BeNil())
+		Expect(libtiff.Compression(compression)).To(Equal(libtiff.COMPRESSION_LZW))
+		_, err = tiffFile.TIFFGetFieldConstChar(ctx, libtiff.TIFFTAG_IMAGEDESCRIPTION)
+		Expect(err).To(HaveOccurred())
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(color.RGBAModel.Convert(goImage.At(10, 5))).To(Equal(color.RGBA{R: 20, G: 11, B: 30, A: 255}))
+	})
+
+	DescribeTable("makes levels until they are at most the minimum size",
+		func(width, height, minSize int, expected []image.Point) {
+			tiffFile := writeWithOverviews(createTestGray(width, height), &libtiff.FromGoImageOptions{
+				Overviews: &libtiff.Overviews{MinSize: minSize},
+			})
+			sizes, _ := directorySizes(tiffFile)
+			Expect(sizes).To(Equal(expected))
+		},
+		Entry("with the default size", 600, 300, 0, []image.Point{image.Pt(600, 300), image.Pt(300, 150), image.Pt(150, 75)}),
+		Entry("with a custom size", 300, 200, 100, []image.Point{image.Pt(300, 200), image.Pt(150, 100), image.Pt(75, 50)}),
+		Entry("for a tall image", 20, 90, 16, []image.Point{image.Pt(20, 90), image.Pt(10, 45), image.Pt(5, 23), image.Pt(3, 12)}),
+		Entry("without levels for a small image", 200, 100, 0, []image.Point{image.Pt(200, 100)}),
+	)
+
+	It("writes the levels as SubIFDs", func() {
+		tiffFile := writeWithOverviews(createTestRGBA(64, 32), &libtiff.FromGoImageOptions{
+			Overviews: &libtiff.Overviews{Factors: []int{2, 8}, SubIFDs: true},
+		})
+
+		directories, err := tiffFile.TIFFNumberOfDirectories(ctx)
+		Expect(err).To(BeNil())
+		Expect(directories).To(Equal(uint32(1)))
+
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories).To(HaveLen(1))
+		subIFDs := info.Directories[0].SubIFDs
+		Expect(subIFDs).To(HaveLen(2))
+		Expect(subIFDs[0].Kind).To(Equal("subifd"))
+		Expect(subIFDs[0].Image.Width).To(Equal(uint32(32)))
+		Expect(subIFDs[0].Image.Height).To(Equal(uint32(16)))
+		Expect(subIFDs[1].Image.Width).To(Equal(uint32(8)))
+		Expect(subIFDs[1].Image.Height).To(Equal(uint32(4)))
+	})
+
+	DescribeTable("resamples the levels",
+		func(resampling libtiff.Resampling, expected []uint8) {
+			tiffFile := writeWithOverviews(createTestGray(4, 4), &libtiff.FromGoImageOptions{
+				Overviews: &libtiff.Overviews{Factors: []int{2}, Resampling: resampling},
+			})
+			Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+			goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+			Expect(err).To(BeNil())
+			defer imgCleanup(ctx)
+
+			var values []uint8
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 2; x++ {
+					values = append(values, color.GrayModel.Convert(goImage.At(x, y)).(color.Gray).Y)
+				}
+			}
+			Expect(values).To(Equal(expected))
+		},
+		Entry("with the average", libtiff.ResamplingAverage, []uint8{5, 19, 11, 25}),
+		Entry("with the nearest pixel", libtiff.ResamplingNearest, []uint8{10, 24, 16, 30}),
+		Entry("with bilinear interpolation", libtiff.ResamplingBilinear, []uint8{5, 19, 11, 25}),
+	)
+
+	It("keeps the palette of paletted images", func() {
+		img := createTestPaletted(16, 16, 4)
+		tiffFile := writeWithOverviews(img, &libtiff.FromGoImageOptions{
+			Overviews: &libtiff.Overviews{Factors: []int{2}},
+		})
+
+		Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		photometric, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_PHOTOMETRIC)
+		Expect(err).To(BeNil())
+		Expect(libtiff.Photometric(photometric)).To(Equal(libtiff.PHOTOMETRIC_PALETTE))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(color.RGBAModel.Convert(goImage.At(2, 3))).To(Equal(color.RGBAModel.Convert(img.At(5, 7))))
+	})
+
+	It("returns an error for invalid overviews", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		err := tiffFile.FromGoImage(ctx, createTestGray(8, 8), &libtiff.FromGoImageOptions{
+			Overviews: &libtiff.Overviews{Factors: []int{2, 2}},
+		})
+		Expect(err).To(MatchError("overview factors must be increasing and at least 2, got [2 2]"))
+
+		err = tiffFile.FromGoImage(ctx, createTestGray(8, 8), &libtiff.FromGoImageOptions{
+			Overviews: &libtiff.Overviews{Resampling: libtiff.Resampling(9)},
+		})
+		Expect(err).To(MatchError("unknown resampling 9"))
+	})
+
+	It("parses resampling names", func() {
+		for _, resampling := range []libtiff.Resampling{libtiff.ResamplingAverage, libtiff.ResamplingNearest, libtiff.ResamplingBilinear} {
+			parsed, err := libtiff.ParseResampling(resampling.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(resampling))
+		}
+		parsed, err := libtiff.ParseResampling(" Bilinear ")
+		Expect(err).To(BeNil())
+		Expect(parsed).To(Equal(libtiff.ResamplingBilinear))
+
+		_, err = libtiff.ParseResampling("lanczos")
+		Expect(err).To(MatchError(`unknown resampling "lanczos"`))
+	})
+})
@@ -0,0 +1,268 @@
+package libtiff
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// GetColorMap returns the red, green and blue arrays of TIFFTAG_COLORMAP of
+// the current directory, each with 1<<BitsPerSample 16-bit values.
+func (f *File) GetColorMap(ctx context.Context) ([]uint16, []uint16, []uint16, error) {
+	bitsPerSample, err := f.getUint16WithDefault(ctx, TIFFTAG_BITSPERSAMPLE, 1)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if bitsPerSample > 16 {
+		return nil, nil, nil, fmt.Errorf("could not read color map with %d bits per sample", bitsPerSample)
+	}
+
+	return f.TIFFGetFieldThreeUint16Arrays(ctx, TIFFTAG_COLORMAP, 1<<bitsPerSample)
+}
+
+// SetColorMap sets TIFFTAG_COLORMAP of the current directory. Every array
+// must have 1<<BitsPerSample values, so TIFFTAG_BITSPERSAMPLE must be set
+// first.
+func (f *File) SetColorMap(ctx context.Context, red, green, blue []uint16) error {
+	bitsPerSample, err := f.getUint16WithDefault(ctx, TIFFTAG_BITSPERSAMPLE, 1)
+	if err != nil {
+		return err
+	}
+	if bitsPerSample > 16 {
+		return fmt.Errorf("could not set color map with %d bits per sample", bitsPerSample)
+	}
+
+	count := 1 << bitsPerSample
+	if len(red) != count || len(green) != count || len(blue) != count {
+		return fmt.Errorf("color map must have %d values per channel for %d bits per sample", count, bitsPerSample)
+	}
+
+	return f.TIFFSetFieldThreeUint16Arrays(ctx, TIFFTAG_COLORMAP, red, green, blue)
+}
+
+// GetPalette returns TIFFTAG_COLORMAP of the current directory as a
+// color.Palette, scaled from 16 to 8 bits.
+func (f *File) GetPalette(ctx context.Context) (color.Palette, error) {
+	red, green, blue, err := f.GetColorMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return colorMapToPalette(red, green, blue), nil
+}
+
+// colorMapToPalette converts a color map to a color.Palette. Some writers
+// store 8-bit values in the color map, like libtiff these are detected by
+// all values being below 256.
+func colorMapToPalette(red, green, blue []uint16) color.Palette {
+	shift := 0
+	for i := range red {
+		if red[i] >= 256 || green[i] >= 256 || blue[i] >= 256 {
+			shift = 8
+			break
+		}
+	}
+
+	palette := make(color.Palette, len(red))
+	for i := range palette {
+		palette[i] = color.RGBA{
+			R: uint8(red[i] >> shift),
+			G: uint8(green[i] >> shift),
+			B: uint8(blue[i] >> shift),
+			A: 255,
+		}
+	}
+	return palette
+}
+
+// paletteToColorMap converts a color.Palette to a color map with
+// 1<<bitsPerSample values per channel, unused entries are black.
+func paletteToColorMap(palette color.Palette, bitsPerSample uint16) ([]uint16, []uint16, []uint16) {
+	count := 1 << bitsPerSample
+	red := make([]uint16, count)
+	green := make([]uint16, count)
+	blue := make([]uint16, count)
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		red[i], green[i], blue[i] = uint16(r), uint16(g), uint16(b)
+	}
+	return red, green, blue
+}
+
+// paletteBitsPerSample returns the smallest sample size that can index a
+// palette of the given size.
+func paletteBitsPerSample(colors int) uint16 {
+	switch {
+	case colors <= 2:
+		return 1
+	case colors <= 4:
+		return 2
+	case colors <= 16:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// palettedBitsPerSample returns the sample size of the current directory
+// when it can be read as an *image.Paletted: a single 1, 2, 4 or 8-bit
+// sample with PHOTOMETRIC_PALETTE.
+func (f *File) palettedBitsPerSample(ctx context.Context) (uint16, bool, error) {
+	photometric, err := f.TIFFGetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if Photometric(photometric) != PHOTOMETRIC_PALETTE {
+		return 0, false, nil
+	}
+
+	samplesPerPixel, err := f.getUint16WithDefault(ctx, TIFFTAG_SAMPLESPERPIXEL, 1)
+	if err != nil {
+		return 0, false, err
+	}
+	bitsPerSample, err := f.getUint16WithDefault(ctx, TIFFTAG_BITSPERSAMPLE, 1)
+	if err != nil {
+		return 0, false, err
+	}
+	if samplesPerPixel != 1 {
+		return 0, false, nil
+	}
+
+	switch bitsPerSample {
+	case 1, 2, 4, 8:
+		return bitsPerSample, true, nil
+	}
+	return 0, false, nil
+}
+
+// readPaletted reads the current directory as an *image.Paletted, flipping
+// the image like TIFFReadRGBAImageOriented.
+func (f *File) readPaletted(ctx context.Context, bitsPerSample uint16) (*image.Paletted, error) {
+	width, height, err := f.GetDimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	palette, err := f.GetPalette(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orientation, err := f.GetOrientation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	bits := int(bitsPerSample)
+
+	// unpackBlock unpacks a block of samples with the given row length to
+	// the image at the given position.
+	unpackBlock := func(data []byte, blockX, blockY, blockWidth, rowLength int) {
+		rowSize := (rowLength*bits + 7) / 8
+		rows := len(data) / rowSize
+		for y := 0; y < rows && blockY+y < height; y++ {
+			row := data[y*rowSize : (y+1)*rowSize]
+			offset := img.PixOffset(blockX, blockY+y)
+			for x := 0; x < blockWidth && blockX+x < width; x++ {
+				bit := x * bits
+				img.Pix[offset+x] = (row[bit/8] >> (8 - bits - bit%8)) & (1<<bits - 1)
+			}
+		}
+	}
+
+	isTiled, err := f.TIFFIsTiled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTiled {
+		tileWidth, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH)
+		if err != nil {
+			return nil, err
+		}
+		tileHeight, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILELENGTH)
+		if err != nil {
+			return nil, err
+		}
+		for y := 0; y < height; y += int(tileHeight) {
+			for x := 0; x < width; x += int(tileWidth) {
+				tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, 0)
+				if err != nil {
+					return nil, err
+				}
+				data, err := f.TIFFReadEncodedTile(ctx, tile)
+				if err != nil {
+					return nil, err
+				}
+				unpackBlock(data, x, y, int(tileWidth), int(tileWidth))
+			}
+		}
+	} else {
+		strips, err := f.TIFFNumberOfStrips(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rowSize := (width*bits + 7) / 8
+		y := 0
+		for strip := uint32(0); strip < strips && y < height; strip++ {
+			data, err := f.TIFFReadEncodedStrip(ctx, strip)
+			if err != nil {
+				return nil, err
+			}
+			unpackBlock(data, 0, y, width, width)
+			y += len(data) / rowSize
+		}
+	}
+
+	// TIFFReadRGBAImageOriented only flips, it does not transpose.
+	switch orientation {
+	case ORIENTATION_TOPRIGHT, ORIENTATION_RIGHTTOP:
+		flipPaletted(img, true, false)
+	case ORIENTATION_BOTRIGHT, ORIENTATION_RIGHTBOT:
+		flipPaletted(img, true, true)
+	case ORIENTATION_BOTLEFT, ORIENTATION_LEFTBOT:
+		flipPaletted(img, false, true)
+	}
+
+	return img, nil
+}
+
+// flipPaletted flips an image in place.
+func flipPaletted(img *image.Paletted, horizontal, vertical bool) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if horizontal {
+		for y := 0; y < height; y++ {
+			row := img.Pix[y*img.Stride : y*img.Stride+width]
+			for left, right := 0, width-1; left < right; left, right = left+1, right-1 {
+				row[left], row[right] = row[right], row[left]
+			}
+		}
+	}
+	if vertical {
+		for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+			topRow := img.Pix[top*img.Stride : top*img.Stride+width]
+			bottomRow := img.Pix[bottom*img.Stride : bottom*img.Stride+width]
+			for x := range topRow {
+				topRow[x], bottomRow[x] = bottomRow[x], topRow[x]
+			}
+		}
+	}
+}
+
+// packPaletted packs the palette indexes of a block of the image into
+// samples of bitsPerSample bits, with rows of rowSize bytes.
+func packPaletted(img *image.Paletted, data []byte, blockX, blockY, blockWidth, rows, rowSize int, bitsPerSample uint16) {
+	bits := int(bitsPerSample)
+	bounds := img.Bounds()
+	for y := 0; y < rows && blockY+y < bounds.Max.Y; y++ {
+		row := data[y*rowSize : (y+1)*rowSize]
+		offset := img.PixOffset(blockX, blockY+y)
+		for x := 0; x < blockWidth && blockX+x < bounds.Max.X; x++ {
+			bit := x * bits
+			row[bit/8] |= (img.Pix[offset+x] & (1<<bits - 1)) << (8 - bits - bit%8)
+		}
+	}
+}
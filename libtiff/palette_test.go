@@ -0,0 +1,212 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func createTestPaletted(width, height, colors int) *image.Paletted {
+	palette := make(color.Palette, colors)
+	for i := range palette {
+		palette[i] = color.RGBA{R: uint8(i * 7), G: uint8(255 - i), B: uint8(i * 13), A: 255}
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetColorIndex(x, y, uint8((x+y*3)%colors))
+		}
+	}
+	return img
+}
+
+var _ = Describe("Paletted images", func() {
+	ctx := context.Background()
+
+	expectPaletted := func(tiffFile *libtiff.File, img *image.Paletted, bitsPerSample uint16) {
+		photometric, err := tiffFile.GetPhotometric(ctx)
+		Expect(err).To(BeNil())
+		Expect(photometric).To(Equal(libtiff.PHOTOMETRIC_PALETTE))
+
+		bps, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE)
+		Expect(err).To(BeNil())
+		Expect(bps).To(Equal(bitsPerSample))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		paletted, ok := goImage.(*image.Paletted)
+		Expect(ok).To(BeTrue())
+		Expect(paletted.Bounds()).To(Equal(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy())))
+		Expect(paletted.Palette).To(HaveLen(1 << bitsPerSample))
+		Expect(paletted.Palette[:len(img.Palette)]).To(Equal(img.Palette))
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				Expect(paletted.ColorIndexAt(x, y)).To(Equal(img.ColorIndexAt(x, y)), "pixel %d,%d", x, y)
+			}
+		}
+	}
+
+	DescribeTable("round trips through FromGoImage",
+		func(colors int, bitsPerSample uint16, options *libtiff.FromGoImageOptions) {
+			img := createTestPaletted(13, 9, colors)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectPaletted(tiffFile, img, bitsPerSample)
+		},
+		Entry("1-bit", 2, uint16(1), nil),
+		Entry("2-bit", 3, uint16(2), nil),
+		Entry("4-bit", 16, uint16(4), nil),
+		Entry("8-bit", 200, uint16(8), nil),
+		Entry("4-bit with LZW and small strips", 11, uint16(4), &libtiff.FromGoImageOptions{
+			Compression:  libtiff.COMPRESSION_LZW,
+			RowsPerStrip: 2,
+		}),
+		Entry("1-bit tiled", 2, uint16(1), &libtiff.FromGoImageOptions{
+			TileWidth:  16,
+			TileHeight: 16,
+		}),
+		Entry("2-bit tiled", 4, uint16(2), &libtiff.FromGoImageOptions{
+			TileWidth:  16,
+			TileHeight: 16,
+		}),
+		Entry("8-bit tiled with Deflate", 256, uint16(8), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			TileWidth:   16,
+			TileHeight:  16,
+		}),
+	)
+
+	It("writes the ColorMap as 16-bit values", func() {
+		img := createTestPaletted(4, 4, 4)
+		tiffFile, cleanup := writeAndReopen(ctx, img, nil)
+		defer cleanup()
+
+		red, green, blue, err := tiffFile.GetColorMap(ctx)
+		Expect(err).To(BeNil())
+		Expect(red).To(Equal([]uint16{0, 7 * 257, 14 * 257, 21 * 257}))
+		Expect(green).To(Equal([]uint16{255 * 257, 254 * 257, 253 * 257, 252 * 257}))
+		Expect(blue).To(Equal([]uint16{0, 13 * 257, 26 * 257, 39 * 257}))
+
+		_, hasExtraSamples := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.TIFFTAG_EXTRASAMPLES)
+		Expect(hasExtraSamples).To(HaveOccurred())
+	})
+
+	It("reads a ColorMap with 8-bit values", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-palette-*.tif")
+		Expect(err).To(BeNil())
+		defer os.Remove(tmpFile.Name())
+
+		fileMode := "w"
+		writeTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			FileMode: &fileMode,
+		})
+		Expect(err).To(BeNil())
+
+		Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGEWIDTH, 2)).To(Succeed())
+		Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_IMAGELENGTH, 1)).To(Succeed())
+		Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE, 1)).To(Succeed())
+		Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_SAMPLESPERPIXEL, 1)).To(Succeed())
+		Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_PHOTOMETRIC, uint16(libtiff.PHOTOMETRIC_PALETTE))).To(Succeed())
+		Expect(writeTiff.SetColorMap(ctx, []uint16{10, 200}, []uint16{20, 100}, []uint16{30, 0})).To(Succeed())
+		Expect(writeTiff.TIFFSetFieldUint32_t(ctx, libtiff.TIFFTAG_ROWSPERSTRIP, 1)).To(Succeed())
+		Expect(writeTiff.TIFFWriteEncodedStrip(ctx, 0, []byte{0x40})).To(Succeed())
+		Expect(writeTiff.TIFFWriteDirectory(ctx)).To(Succeed())
+		writeTiff.Close(ctx)
+		tmpFile.Close()
+
+		readFile, err := os.Open(tmpFile.Name())
+		Expect(err).To(BeNil())
+		defer readFile.Close()
+		stat, err := readFile.Stat()
+		Expect(err).To(BeNil())
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", readFile, uint64(stat.Size()), nil)
+		Expect(err).To(BeNil())
+		defer tiffFile.Close(ctx)
+
+		palette, err := tiffFile.GetPalette(ctx)
+		Expect(err).To(BeNil())
+		Expect(palette).To(Equal(color.Palette{
+			color.RGBA{R: 10, G: 20, B: 30, A: 255},
+			color.RGBA{R: 200, G: 100, B: 0, A: 255},
+		}))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(goImage.(*image.Paletted).Pix).To(Equal([]uint8{0, 1}))
+	})
+
+	It("returns an error for a ColorMap of the wrong size", func() {
+		tmpFile, err := os.CreateTemp("", "libtiff-palette-*.tif")
+		Expect(err).To(BeNil())
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+
+		fileMode := "w"
+		writeTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			FileMode: &fileMode,
+		})
+		Expect(err).To(BeNil())
+		defer writeTiff.Close(ctx)
+
+		Expect(writeTiff.TIFFSetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE, 2)).To(Succeed())
+		err = writeTiff.SetColorMap(ctx, []uint16{0, 1}, []uint16{0, 1}, []uint16{0, 1})
+		Expect(err).To(MatchError("color map must have 4 values per channel for 2 bits per sample"))
+	})
+
+	It("returns an error for a palette with more than 256 colors", func() {
+		img := createTestPaletted(4, 4, 300)
+
+		tmpFile, err := os.CreateTemp("", "libtiff-palette-*.tif")
+		Expect(err).To(BeNil())
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+
+		fileMode := "w"
+		writeTiff, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+			FileMode: &fileMode,
+		})
+		Expect(err).To(BeNil())
+		defer writeTiff.Close(ctx)
+
+		err = writeTiff.FromGoImage(ctx, img, nil)
+		Expect(err).To(MatchError("palette has 300 colors, at most 256 are supported"))
+	})
+
+	It("writes paletted images as RGB with JPEG compression", func() {
+		img := createTestPaletted(16, 16, 4)
+		tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+		})
+		defer cleanup()
+
+		photometric, err := tiffFile.GetPhotometric(ctx)
+		Expect(err).To(BeNil())
+		Expect(photometric).To(Equal(libtiff.PHOTOMETRIC_YCBCR))
+	})
+
+	It("renders palette pages to JPEG and PNG", func() {
+		img := createTestPaletted(16, 16, 4)
+		tiffFile, cleanup := writeAndReopen(ctx, img, nil)
+		defer cleanup()
+
+		for _, format := range []libtiff.ImageOptionsOutputFormat{libtiff.ImageOptionsOutputFormatJPEG, libtiff.ImageOptionsOutputFormatPNG} {
+			data, err := tiffFile.ToImage(ctx, &libtiff.ImageOptions{
+				OutputFormat: format,
+				OutputTarget: libtiff.ImageOptionsOutputTargetBytes,
+			})
+			Expect(err).To(BeNil())
+			Expect(data).ToNot(BeEmpty())
+		}
+	})
+})
@@ -0,0 +1,353 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// Photoshop image resource IDs.
+const (
+	PhotoshopResourceResolutionInfo   = 0x03ED
+	PhotoshopResourceIPTC             = 0x0404
+	PhotoshopResourceFirstPath        = 0x07D0
+	PhotoshopResourceLastPath         = 0x0BB6
+	PhotoshopResourceClippingPathName = 0x0BB7
+)
+
+// PhotoshopResource is a raw Photoshop image resource block.
+type PhotoshopResource struct {
+	ID   uint16
+	Name string
+	Data []byte
+}
+
+// PhotoshopResolutionInfo is the ResolutionInfo resource.
+type PhotoshopResolutionInfo struct {
+	// HorizontalResolution is in pixels per inch.
+	HorizontalResolution float64
+	// HorizontalResolutionUnit is 1 for pixels per inch, 2 for pixels per cm.
+	HorizontalResolutionUnit uint16
+	// WidthUnit is 1 for inches, 2 for cm, 3 for points, 4 for picas and 5
+	// for columns.
+	WidthUnit uint16
+	// VerticalResolution is in pixels per inch.
+	VerticalResolution float64
+	// VerticalResolutionUnit is 1 for pixels per inch, 2 for pixels per cm.
+	VerticalResolutionUnit uint16
+	// HeightUnit is 1 for inches, 2 for cm, 3 for points, 4 for picas and 5
+	// for columns.
+	HeightUnit uint16
+}
+
+// PhotoshopPoint is a point of a path, relative to the image size, so
+// (0, 0) is the top left and (1, 1) the bottom right corner.
+type PhotoshopPoint struct {
+	X float64
+	Y float64
+}
+
+// PhotoshopKnot is a Bezier knot of a path.
+type PhotoshopKnot struct {
+	// Linked means that the control points move together with the anchor.
+	Linked bool
+	// Preceding is the control point for the segment before the anchor.
+	Preceding PhotoshopPoint
+	Anchor    PhotoshopPoint
+	// Leaving is the control point for the segment after the anchor.
+	Leaving PhotoshopPoint
+}
+
+// PhotoshopSubpath is a sequence of knots.
+type PhotoshopSubpath struct {
+	Closed bool
+	Knots  []PhotoshopKnot
+}
+
+// PhotoshopPath is a path resource, like a clipping path.
+type PhotoshopPath struct {
+	// ID is the resource ID, between PhotoshopResourceFirstPath and
+	// PhotoshopResourceLastPath.
+	ID       uint16
+	Name     string
+	Subpaths []PhotoshopSubpath
+}
+
+// Photoshop contains the Photoshop image resources.
+type Photoshop struct {
+	// ResolutionInfo is the ResolutionInfo resource, if present.
+	ResolutionInfo *PhotoshopResolutionInfo
+	// IPTC is the IPTC-IIM resource, if present.
+	IPTC *IPTC
+	// Paths are the path resources.
+	Paths []PhotoshopPath
+	// ClippingPathName is the name of the path that is the clipping path.
+	ClippingPathName string
+
+	// Resources contains all resources in the order they were read. When
+	// writing, the resources that are not represented by the fields above
+	// are written as-is.
+	Resources []PhotoshopResource
+}
+
+// ClippingPath returns the clipping path, if there is one.
+func (p *Photoshop) ClippingPath() (*PhotoshopPath, bool) {
+	if p.ClippingPathName == "" {
+		return nil, false
+	}
+	for i := range p.Paths {
+		if p.Paths[i].Name == p.ClippingPathName {
+			return &p.Paths[i], true
+		}
+	}
+	return nil, false
+}
+
+// Photoshop returns the Photoshop image resources of the current directory
+// from TIFFTAG_PHOTOSHOP.
+// Returns a TagNotDefinedError when the directory has no Photoshop
+// resources.
+func (f *File) Photoshop(ctx context.Context) (*Photoshop, error) {
+	data, err := f.TIFFGetFieldByteArray(ctx, TIFFTAG_PHOTOSHOP)
+	if err != nil {
+		return nil, err
+	}
+	return parsePhotoshop(data)
+}
+
+func isPhotoshopPath(id uint16) bool {
+	return id >= PhotoshopResourceFirstPath && id <= PhotoshopResourceLastPath
+}
+
+// readPascalString reads a Pascal string that is padded to an even size and
+// returns the string and the padded size.
+func readPascalString(data []byte) (string, int, error) {
+	if len(data) < 1 {
+		return "", 0, errors.New("invalid Pascal string")
+	}
+	length := int(data[0])
+	size := (1 + length + 1) &^ 1
+	if len(data) < 1+length {
+		return "", 0, errors.New("invalid Pascal string")
+	}
+	return string(data[1 : 1+length]), size, nil
+}
+
+func appendPascalString(data []byte, value string) []byte {
+	if len(value) > 255 {
+		value = value[:255]
+	}
+	data = append(data, byte(len(value)))
+	data = append(data, value...)
+	if (1+len(value))%2 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func parsePhotoshop(data []byte) (*Photoshop, error) {
+	photoshop := &Photoshop{}
+	for offset := 0; offset+4 <= len(data) && string(data[offset:offset+4]) == "8BIM"; {
+		if offset+6 > len(data) {
+			return nil, errors.New("invalid Photoshop resource")
+		}
+		id := binary.BigEndian.Uint16(data[offset+4:])
+		name, nameSize, err := readPascalString(data[offset+6:])
+		if err != nil {
+			return nil, err
+		}
+		offset += 6 + nameSize
+		if offset+4 > len(data) {
+			return nil, errors.New("invalid Photoshop resource")
+		}
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+		if size < 0 || offset+size > len(data) {
+			return nil, errors.New("invalid Photoshop resource size")
+		}
+
+		photoshop.Resources = append(photoshop.Resources, PhotoshopResource{
+			ID:   id,
+			Name: name,
+			Data: data[offset : offset+size],
+		})
+		offset += (size + 1) &^ 1
+	}
+
+	for _, resource := range photoshop.Resources {
+		switch {
+		case resource.ID == PhotoshopResourceResolutionInfo:
+			if len(resource.Data) < 16 {
+				return nil, errors.New("invalid Photoshop ResolutionInfo resource")
+			}
+			photoshop.ResolutionInfo = &PhotoshopResolutionInfo{
+				HorizontalResolution:     float64(binary.BigEndian.Uint32(resource.Data)) / 65536,
+				HorizontalResolutionUnit: binary.BigEndian.Uint16(resource.Data[4:]),
+				WidthUnit:                binary.BigEndian.Uint16(resource.Data[6:]),
+				VerticalResolution:       float64(binary.BigEndian.Uint32(resource.Data[8:])) / 65536,
+				VerticalResolutionUnit:   binary.BigEndian.Uint16(resource.Data[12:]),
+				HeightUnit:               binary.BigEndian.Uint16(resource.Data[14:]),
+			}
+		case resource.ID == PhotoshopResourceIPTC:
+			iptc, err := parseIPTC(resource.Data)
+			if err != nil {
+				return nil, err
+			}
+			photoshop.IPTC = iptc
+		case resource.ID == PhotoshopResourceClippingPathName:
+			name, _, err := readPascalString(resource.Data)
+			if err != nil {
+				return nil, err
+			}
+			photoshop.ClippingPathName = name
+		case isPhotoshopPath(resource.ID):
+			subpaths, err := parsePhotoshopPath(resource.Data)
+			if err != nil {
+				return nil, err
+			}
+			photoshop.Paths = append(photoshop.Paths, PhotoshopPath{
+				ID:       resource.ID,
+				Name:     resource.Name,
+				Subpaths: subpaths,
+			})
+		}
+	}
+
+	return photoshop, nil
+}
+
+// Path record selectors.
+const (
+	pathClosedSubpathLength = 0
+	pathClosedKnotLinked    = 1
+	pathClosedKnotUnlinked  = 2
+	pathOpenSubpathLength   = 3
+	pathOpenKnotLinked      = 4
+	pathOpenKnotUnlinked    = 5
+	pathFillRule            = 6
+)
+
+// pathRecordSize is the size of every path record.
+const pathRecordSize = 26
+
+// pathFixed converts a signed 8.24 fixed point number.
+func pathFixed(data []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(data))) / (1 << 24)
+}
+
+func appendPathFixed(data []byte, value float64) []byte {
+	return binary.BigEndian.AppendUint32(data, uint32(int32(value*(1<<24))))
+}
+
+func parsePhotoshopPath(data []byte) ([]PhotoshopSubpath, error) {
+	var subpaths []PhotoshopSubpath
+	remaining := 0
+	for offset := 0; offset+pathRecordSize <= len(data); offset += pathRecordSize {
+		record := data[offset : offset+pathRecordSize]
+		switch selector := binary.BigEndian.Uint16(record); selector {
+		case pathClosedSubpathLength, pathOpenSubpathLength:
+			remaining = int(binary.BigEndian.Uint16(record[2:]))
+			subpaths = append(subpaths, PhotoshopSubpath{Closed: selector == pathClosedSubpathLength})
+		case pathClosedKnotLinked, pathClosedKnotUnlinked, pathOpenKnotLinked, pathOpenKnotUnlinked:
+			if len(subpaths) == 0 || remaining == 0 {
+				return nil, errors.New("invalid Photoshop path knot")
+			}
+			remaining--
+			// Points are stored as vertical, horizontal.
+			point := func(at int) PhotoshopPoint {
+				return PhotoshopPoint{Y: pathFixed(record[at:]), X: pathFixed(record[at+4:])}
+			}
+			subpath := &subpaths[len(subpaths)-1]
+			subpath.Knots = append(subpath.Knots, PhotoshopKnot{
+				Linked:    selector == pathClosedKnotLinked || selector == pathOpenKnotLinked,
+				Preceding: point(2),
+				Anchor:    point(10),
+				Leaving:   point(18),
+			})
+		}
+	}
+	return subpaths, nil
+}
+
+func encodePhotoshopPath(subpaths []PhotoshopSubpath) []byte {
+	record := func(selector uint16) []byte {
+		return binary.BigEndian.AppendUint16(make([]byte, 0, pathRecordSize), selector)
+	}
+	pad := func(data []byte) []byte {
+		return append(data, make([]byte, pathRecordSize-len(data))...)
+	}
+
+	data := pad(record(pathFillRule))
+	for _, subpath := range subpaths {
+		selector, linked, unlinked := uint16(pathOpenSubpathLength), uint16(pathOpenKnotLinked), uint16(pathOpenKnotUnlinked)
+		if subpath.Closed {
+			selector, linked, unlinked = pathClosedSubpathLength, pathClosedKnotLinked, pathClosedKnotUnlinked
+		}
+		data = append(data, pad(binary.BigEndian.AppendUint16(record(selector), uint16(len(subpath.Knots))))...)
+		for _, knot := range subpath.Knots {
+			knotRecord := record(unlinked)
+			if knot.Linked {
+				knotRecord = record(linked)
+			}
+			for _, point := range []PhotoshopPoint{knot.Preceding, knot.Anchor, knot.Leaving} {
+				knotRecord = appendPathFixed(knotRecord, point.Y)
+				knotRecord = appendPathFixed(knotRecord, point.X)
+			}
+			data = append(data, knotRecord...)
+		}
+	}
+	return data
+}
+
+// encode returns the Photoshop image resources, sorted by ID.
+func (p *Photoshop) encode() []byte {
+	var resources []PhotoshopResource
+	for _, resource := range p.Resources {
+		id := resource.ID
+		if id == PhotoshopResourceResolutionInfo || id == PhotoshopResourceIPTC || id == PhotoshopResourceClippingPathName || isPhotoshopPath(id) {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	if p.ResolutionInfo != nil {
+		info := p.ResolutionInfo
+		data := binary.BigEndian.AppendUint32(nil, uint32(info.HorizontalResolution*65536))
+		data = binary.BigEndian.AppendUint16(data, info.HorizontalResolutionUnit)
+		data = binary.BigEndian.AppendUint16(data, info.WidthUnit)
+		data = binary.BigEndian.AppendUint32(data, uint32(info.VerticalResolution*65536))
+		data = binary.BigEndian.AppendUint16(data, info.VerticalResolutionUnit)
+		data = binary.BigEndian.AppendUint16(data, info.HeightUnit)
+		resources = append(resources, PhotoshopResource{ID: PhotoshopResourceResolutionInfo, Data: data})
+	}
+	if p.IPTC != nil {
+		resources = append(resources, PhotoshopResource{ID: PhotoshopResourceIPTC, Data: p.IPTC.encode()})
+	}
+	for _, path := range p.Paths {
+		resources = append(resources, PhotoshopResource{ID: path.ID, Name: path.Name, Data: encodePhotoshopPath(path.Subpaths)})
+	}
+	if p.ClippingPathName != "" {
+		// The name is followed by the flatness, 0 is the device default.
+		data := appendPascalString(nil, p.ClippingPathName)
+		data = append(data, 0, 0)
+		resources = append(resources, PhotoshopResource{ID: PhotoshopResourceClippingPathName, Data: data})
+	}
+
+	slices.SortStableFunc(resources, func(a, b PhotoshopResource) int {
+		return int(a.ID) - int(b.ID)
+	})
+
+	var data []byte
+	for _, resource := range resources {
+		data = append(data, "8BIM"...)
+		data = binary.BigEndian.AppendUint16(data, resource.ID)
+		data = appendPascalString(data, resource.Name)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(resource.Data)))
+		data = append(data, resource.Data...)
+		if len(resource.Data)%2 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return data
+}
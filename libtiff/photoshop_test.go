@@ -0,0 +1,118 @@
+package libtiff_test
+
+import (
+	"context"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Photoshop", func() {
+	ctx := context.Background()
+
+	outline := []libtiff.PhotoshopSubpath{
+		{
+			Closed: true,
+			Knots: []libtiff.PhotoshopKnot{
+				{Linked: true, Preceding: libtiff.PhotoshopPoint{X: 0.25, Y: 0.25}, Anchor: libtiff.PhotoshopPoint{X: 0.25, Y: 0.25}, Leaving: libtiff.PhotoshopPoint{X: 0.25, Y: 0.25}},
+				{Linked: true, Preceding: libtiff.PhotoshopPoint{X: 0.75, Y: 0.25}, Anchor: libtiff.PhotoshopPoint{X: 0.75, Y: 0.25}, Leaving: libtiff.PhotoshopPoint{X: 0.75, Y: 0.25}},
+				{Preceding: libtiff.PhotoshopPoint{X: 0.5, Y: 0.625}, Anchor: libtiff.PhotoshopPoint{X: 0.5, Y: 0.75}, Leaving: libtiff.PhotoshopPoint{X: 0.5, Y: 0.875}},
+			},
+		},
+		{
+			Knots: []libtiff.PhotoshopKnot{
+				{Anchor: libtiff.PhotoshopPoint{X: 0, Y: 1}, Leaving: libtiff.PhotoshopPoint{X: 0, Y: 1}},
+				{Anchor: libtiff.PhotoshopPoint{X: 1, Y: 0}, Preceding: libtiff.PhotoshopPoint{X: 1, Y: 0}},
+			},
+		},
+	}
+
+	It("writes and reads resolution info, paths and IPTC", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Photoshop: &libtiff.Photoshop{
+				ResolutionInfo: &libtiff.PhotoshopResolutionInfo{
+					HorizontalResolution:     300,
+					HorizontalResolutionUnit: 1,
+					WidthUnit:                1,
+					VerticalResolution:       150.5,
+					VerticalResolutionUnit:   1,
+					HeightUnit:               2,
+				},
+				IPTC: &libtiff.IPTC{
+					Caption:  "Product photo",
+					Keywords: []string{"product"},
+				},
+				Paths: []libtiff.PhotoshopPath{
+					{ID: 2000, Name: "Outline", Subpaths: outline},
+				},
+				ClippingPathName: "Outline",
+			},
+		})
+		defer cleanup()
+
+		photoshop, err := tiffFile.Photoshop(ctx)
+		Expect(err).To(BeNil())
+		Expect(photoshop.ResolutionInfo).To(Equal(&libtiff.PhotoshopResolutionInfo{
+			HorizontalResolution:     300,
+			HorizontalResolutionUnit: 1,
+			WidthUnit:                1,
+			VerticalResolution:       150.5,
+			VerticalResolutionUnit:   1,
+			HeightUnit:               2,
+		}))
+		Expect(photoshop.IPTC.Caption).To(Equal("Product photo"))
+		Expect(photoshop.IPTC.Keywords).To(Equal([]string{"product"}))
+		Expect(photoshop.Paths).To(Equal([]libtiff.PhotoshopPath{
+			{ID: 2000, Name: "Outline", Subpaths: outline},
+		}))
+		Expect(photoshop.ClippingPathName).To(Equal("Outline"))
+
+		clippingPath, ok := photoshop.ClippingPath()
+		Expect(ok).To(BeTrue())
+		Expect(clippingPath.ID).To(Equal(uint16(2000)))
+
+		ids := []uint16{}
+		for _, resource := range photoshop.Resources {
+			ids = append(ids, resource.ID)
+		}
+		Expect(ids).To(Equal([]uint16{
+			libtiff.PhotoshopResourceResolutionInfo,
+			libtiff.PhotoshopResourceIPTC,
+			2000,
+			libtiff.PhotoshopResourceClippingPathName,
+		}))
+	})
+
+	It("keeps unknown resources", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			Photoshop: &libtiff.Photoshop{
+				Resources: []libtiff.PhotoshopResource{
+					{ID: 0x0425, Data: []byte{1, 2, 3}},
+					{ID: 0x040C, Name: "thumb", Data: []byte{4, 5, 6, 7}},
+					{ID: libtiff.PhotoshopResourceResolutionInfo, Data: make([]byte, 16)},
+				},
+			},
+		})
+		defer cleanup()
+
+		photoshop, err := tiffFile.Photoshop(ctx)
+		Expect(err).To(BeNil())
+		Expect(photoshop.ResolutionInfo).To(BeNil())
+		Expect(photoshop.Resources).To(Equal([]libtiff.PhotoshopResource{
+			{ID: 0x040C, Name: "thumb", Data: []byte{4, 5, 6, 7}},
+			{ID: 0x0425, Data: []byte{1, 2, 3}},
+		}))
+	})
+
+	It("returns an error when there are no Photoshop resources", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), nil)
+		defer cleanup()
+
+		_, err := tiffFile.Photoshop(ctx)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_PHOTOSHOP,
+		}))
+	})
+})
@@ -0,0 +1,401 @@
+package libtiff
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+)
+
+// QuantizeMethod selects how the palette of a quantized image is chosen.
+type QuantizeMethod int
+
+const (
+	// QuantizeMedianCut splits the colors of the image into boxes with the
+	// same number of pixels, like tiffmedian.
+	QuantizeMedianCut QuantizeMethod = iota
+	// QuantizeOctree merges the least used colors of an octree of the
+	// colors of the image.
+	QuantizeOctree
+)
+
+var quantizeMethodNames = map[QuantizeMethod]string{
+	QuantizeMedianCut: "median-cut",
+	QuantizeOctree:    "octree",
+}
+
+// String returns the name of the method, like "median-cut".
+func (m QuantizeMethod) String() string {
+	if name, ok := quantizeMethodNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("QuantizeMethod(%d)", int(m))
+}
+
+// ParseQuantizeMethod parses "median-cut" or "octree", case-insensitively.
+func ParseQuantizeMethod(value string) (QuantizeMethod, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for method, name := range quantizeMethodNames {
+		if name == value {
+			return method, nil
+		}
+	}
+	return QuantizeMedianCut, fmt.Errorf("unknown quantize method %q", value)
+}
+
+// Quantize configures the reduction of an image to a palette.
+type Quantize struct {
+	// Colors is the maximum number of colors of the palette, from 2 to
+	// 256. If 0, 256 is used. Images with at most this many colors keep
+	// their exact colors.
+	Colors int
+	// Method selects how the palette is chosen.
+	Method QuantizeMethod
+	// Dither diffuses the difference between the pixels and their palette
+	// color with Floyd–Steinberg dithering, which avoids banding in
+	// gradients and photos.
+	Dither bool
+}
+
+// colors returns the maximum number of colors of the palette.
+func (q *Quantize) colors() int {
+	if q.Colors == 0 {
+		return 256
+	}
+	return q.Colors
+}
+
+func (q *Quantize) validate() error {
+	if colors := q.colors(); colors < 2 || colors > 256 {
+		return fmt.Errorf("quantize colors must be between 2 and 256, got %d", colors)
+	}
+	if _, ok := quantizeMethodNames[q.Method]; !ok {
+		return fmt.Errorf("unknown quantize method %d", int(q.Method))
+	}
+	return nil
+}
+
+// quantizedImage returns img reduced to a palette for the Quantize option,
+// or img when it is already paletted with few enough colors.
+func quantizedImage(img image.Image, options *FromGoImageOptions) (image.Image, error) {
+	q := options.Quantize
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	switch options.Compression {
+	case COMPRESSION_JPEG, COMPRESSION_CCITTFAX3, COMPRESSION_CCITTFAX4:
+		return nil, errors.New("Quantize can't be used with JPEG or CCITT compression")
+	}
+	if paletted, ok := img.(*image.Paletted); ok && len(paletted.Palette) <= q.colors() {
+		return img, nil
+	}
+	return quantizeImage(img, q), nil
+}
+
+// quantizePixels returns the 8-bit premultiplied colors of img as 0xRRGGBB,
+// alpha is not kept.
+func quantizePixels(img image.Image) []uint32 {
+	bounds := img.Bounds()
+	pixels := make([]uint32, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			pixels = append(pixels, r>>8<<16|g>>8<<8|b>>8)
+		}
+	}
+	return pixels
+}
+
+// quantizeImage returns img reduced to a palette of at most q.Colors.
+func quantizeImage(img image.Image, q *Quantize) *image.Paletted {
+	bounds := img.Bounds()
+	pixels := quantizePixels(img)
+	maxColors := q.colors()
+
+	// Images with few colors, like diagrams, keep their exact colors.
+	var palette []uint32
+	exact := map[uint32]uint8{}
+	for _, pixel := range pixels {
+		if _, ok := exact[pixel]; ok {
+			continue
+		}
+		if len(palette) == maxColors {
+			palette = nil
+			break
+		}
+		exact[pixel] = uint8(len(palette))
+		palette = append(palette, pixel)
+	}
+	if palette != nil {
+		paletted := image.NewPaletted(bounds, toColorPalette(palette))
+		for i, pixel := range pixels {
+			paletted.Pix[i] = exact[pixel]
+		}
+		return paletted
+	}
+
+	histogram := newColorHistogram(pixels)
+	if q.Method == QuantizeOctree {
+		palette = octreePalette(histogram, maxColors)
+	} else {
+		palette = medianCutPalette(histogram, maxColors)
+	}
+
+	paletted := image.NewPaletted(bounds, toColorPalette(palette))
+	nearest := newNearestColor(palette)
+	if !q.Dither {
+		for i, pixel := range pixels {
+			paletted.Pix[i] = nearest(pixel)
+		}
+		return paletted
+	}
+
+	// Floyd–Steinberg dithering, errors holds the error of the current and
+	// the next row for every channel, with room past both edges.
+	width, height := bounds.Dx(), bounds.Dy()
+	errors := [2][]int{make([]int, (width+2)*3), make([]int, (width+2)*3)}
+	for y := 0; y < height; y++ {
+		current, next := errors[0], errors[1]
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var channels [3]int
+			var wanted uint32
+			for c := 0; c < 3; c++ {
+				value := int(pixels[i]>>(16-8*c)&0xff) + current[(x+1)*3+c]/16
+				channels[c] = value
+				wanted |= uint32(min(max(value, 0), 255)) << (16 - 8*c)
+			}
+
+			index := nearest(wanted)
+			paletted.Pix[i] = index
+			for c := 0; c < 3; c++ {
+				quantError := channels[c] - int(palette[index]>>(16-8*c)&0xff)
+				current[(x+2)*3+c] += quantError * 7
+				next[x*3+c] += quantError * 3
+				next[(x+1)*3+c] += quantError * 5
+				next[(x+2)*3+c] += quantError
+			}
+		}
+		clear(current)
+		errors[0], errors[1] = next, current
+	}
+	return paletted
+}
+
+func toColorPalette(colors []uint32) color.Palette {
+	palette := make(color.Palette, len(colors))
+	for i, c := range colors {
+		palette[i] = color.RGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 255}
+	}
+	return palette
+}
+
+// newNearestColor returns the function that finds the palette color that is
+// nearest to a color, which remembers the colors it has seen.
+func newNearestColor(palette []uint32) func(pixel uint32) uint8 {
+	cache := map[uint32]uint8{}
+	return func(pixel uint32) uint8 {
+		if index, ok := cache[pixel]; ok {
+			return index
+		}
+		best, bestDistance := 0, -1
+		for i, c := range palette {
+			distance := 0
+			for shift := 0; shift <= 16; shift += 8 {
+				d := int(pixel>>shift&0xff) - int(c>>shift&0xff)
+				distance += d * d
+			}
+			if bestDistance < 0 || distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		cache[pixel] = uint8(best)
+		return uint8(best)
+	}
+}
+
+// colorBin is the pixels of a color at 5 bits per channel.
+type colorBin struct {
+	// key is the color at 5 bits per channel, 0bRRRRRGGGGGBBBBB.
+	key   int
+	count int
+	// sums are the sums of the 8-bit channels of the pixels.
+	sums [3]uint64
+}
+
+// channel returns the 5-bit value of channel c of the bin.
+func (b *colorBin) channel(c int) int {
+	return b.key >> (10 - 5*c) & 0x1f
+}
+
+// newColorHistogram returns the bins of the colors of the pixels that are
+// used.
+func newColorHistogram(pixels []uint32) []*colorBin {
+	bins := make([]*colorBin, 1<<15)
+	var used []*colorBin
+	for _, pixel := range pixels {
+		key := int(pixel>>19&0x1f)<<10 | int(pixel>>11&0x1f)<<5 | int(pixel>>3&0x1f)
+		bin := bins[key]
+		if bin == nil {
+			bin = &colorBin{key: key}
+			bins[key] = bin
+			used = append(used, bin)
+		}
+		bin.count++
+		bin.sums[0] += uint64(pixel >> 16 & 0xff)
+		bin.sums[1] += uint64(pixel >> 8 & 0xff)
+		bin.sums[2] += uint64(pixel & 0xff)
+	}
+	return used
+}
+
+// averageColor returns the average color of the pixels of the bins.
+func averageColor(bins []*colorBin) uint32 {
+	var count uint64
+	var sums [3]uint64
+	for _, bin := range bins {
+		count += uint64(bin.count)
+		for c := range sums {
+			sums[c] += bin.sums[c]
+		}
+	}
+	var pixel uint32
+	for c, sum := range sums {
+		pixel |= uint32((sum+count/2)/count) << (16 - 8*c)
+	}
+	return pixel
+}
+
+// medianCutPalette splits the bins into at most maxColors boxes, always
+// splitting the box with the largest range of a channel at the median
+// pixel of that channel.
+func medianCutPalette(bins []*colorBin, maxColors int) []uint32 {
+	boxes := [][]*colorBin{bins}
+	for len(boxes) < maxColors {
+		// Find the box and channel with the largest range.
+		bestBox, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				low, high := 31, 0
+				for _, bin := range box {
+					low, high = min(low, bin.channel(c)), max(high, bin.channel(c))
+				}
+				if high-low > bestRange || bestBox < 0 {
+					bestBox, bestChannel, bestRange = i, c, high-low
+				}
+			}
+		}
+		if bestBox < 0 {
+			break
+		}
+
+		box := boxes[bestBox]
+		slices.SortFunc(box, func(a, b *colorBin) int {
+			return a.channel(bestChannel) - b.channel(bestChannel)
+		})
+		total := 0
+		for _, bin := range box {
+			total += bin.count
+		}
+		// Split after the bin that holds the median pixel, keeping at
+		// least one bin on both sides.
+		split, seen := 1, box[0].count
+		for split < len(box)-1 && seen < total/2 {
+			seen += box[split].count
+			split++
+		}
+		boxes[bestBox] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make([]uint32, len(boxes))
+	for i, box := range boxes {
+		palette[i] = averageColor(box)
+	}
+	return palette
+}
+
+// octreeNode is a node of the color octree. The tree is 5 levels deep, the
+// leaves at the bottom are the bins of the histogram.
+type octreeNode struct {
+	children [8]*octreeNode
+	// bins are the bins of a leaf.
+	bins []*colorBin
+	// count is the number of pixels below the node.
+	count int
+	leaf  bool
+}
+
+// octreePalette builds an octree of the bins and merges the nodes with the
+// fewest pixels, deepest level first, until at most maxColors leaves are
+// left.
+func octreePalette(bins []*colorBin, maxColors int) []uint32 {
+	root := &octreeNode{}
+	// levels holds the inner nodes by depth.
+	var levels [5][]*octreeNode
+	levels[0] = []*octreeNode{root}
+	leaves := 0
+	for _, bin := range bins {
+		node := root
+		node.count += bin.count
+		for depth := 0; depth < 5; depth++ {
+			shift := 4 - depth
+			child := (bin.channel(0)>>shift&1)<<2 | (bin.channel(1)>>shift&1)<<1 | bin.channel(2)>>shift&1
+			if node.children[child] == nil {
+				node.children[child] = &octreeNode{leaf: depth == 4}
+				if depth < 4 {
+					levels[depth+1] = append(levels[depth+1], node.children[child])
+				} else {
+					leaves++
+				}
+			}
+			node = node.children[child]
+			node.count += bin.count
+		}
+		node.bins = append(node.bins, bin)
+	}
+
+	for depth := 4; depth >= 0 && leaves > maxColors; depth-- {
+		nodes := levels[depth]
+		slices.SortStableFunc(nodes, func(a, b *octreeNode) int {
+			return a.count - b.count
+		})
+		for _, node := range nodes {
+			if leaves <= maxColors {
+				break
+			}
+			// The children are leaves, as the deeper levels are merged
+			// first.
+			for i, child := range node.children {
+				if child != nil {
+					node.bins = append(node.bins, child.bins...)
+					node.children[i] = nil
+					leaves--
+				}
+			}
+			node.leaf = true
+			leaves++
+		}
+	}
+
+	var palette []uint32
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			palette = append(palette, averageColor(node.bins))
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return palette
+}
@@ -0,0 +1,188 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// createGrayGradient returns an RGBA image that goes from black on the left
+// to white on the right.
+func createGrayGradient(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			level := uint8(x * 255 / (width - 1))
+			img.SetRGBA(x, y, color.RGBA{R: level, G: level, B: level, A: 255})
+		}
+	}
+	return img
+}
+
+var _ = Describe("Quantize", func() {
+	ctx := context.Background()
+
+	// writeQuantized writes img with the quantize options and returns the
+	// bits per sample and the image that was read back.
+	writeQuantized := func(img image.Image, options *libtiff.FromGoImageOptions) (uint16, *image.Paletted) {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+		photometric, err := readTiff.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_PHOTOMETRIC)
+		Expect(err).To(BeNil())
+		Expect(libtiff.Photometric(photometric)).To(Equal(libtiff.PHOTOMETRIC_PALETTE))
+		bitsPerSample, err := readTiff.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE)
+		Expect(err).To(BeNil())
+
+		goImage, imgCleanup, err := readTiff.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		DeferCleanup(imgCleanup, ctx)
+		paletted, ok := goImage.(*image.Paletted)
+		Expect(ok).To(BeTrue())
+		return bitsPerSample, paletted
+	}
+
+	// averageError returns the average difference per channel between the
+	// images.
+	averageError := func(expected, actual image.Image) float64 {
+		bounds := expected.Bounds()
+		total := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r1, g1, b1, _ := expected.At(x, y).RGBA()
+				r2, g2, b2, _ := actual.At(x, y).RGBA()
+				for _, d := range []int{int(r1>>8) - int(r2>>8), int(g1>>8) - int(g2>>8), int(b1>>8) - int(b2>>8)} {
+					total += max(d, -d)
+				}
+			}
+		}
+		return float64(total) / float64(bounds.Dx()*bounds.Dy()*3)
+	}
+
+	It("keeps the exact colors of images with few colors", func() {
+		img := image.NewRGBA(image.Rect(0, 0, 30, 20))
+		colors := []color.RGBA{{255, 255, 255, 255}, {0, 0, 0, 255}, {200, 30, 30, 255}, {30, 30, 200, 255}, {250, 220, 0, 255}}
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 30; x++ {
+				img.SetRGBA(x, y, colors[(x/6+y/5)%len(colors)])
+			}
+		}
+
+		bitsPerSample, paletted := writeQuantized(img, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Quantize:    &libtiff.Quantize{Colors: 16},
+		})
+		Expect(bitsPerSample).To(Equal(uint16(4)))
+		Expect(averageError(img, paletted)).To(Equal(0.0))
+	})
+
+	DescribeTable("reduces truecolor images to a palette",
+		func(quantize *libtiff.Quantize, expectedBits uint16, maxError float64) {
+			img := createTestRGBA(96, 64)
+			bitsPerSample, paletted := writeQuantized(img, &libtiff.FromGoImageOptions{
+				Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+				Quantize:    quantize,
+			})
+			Expect(bitsPerSample).To(Equal(expectedBits))
+
+			used := map[uint8]bool{}
+			for _, index := range paletted.Pix {
+				used[index] = true
+			}
+			Expect(len(used)).To(BeNumerically("<=", max(quantize.Colors, 256)))
+			Expect(len(used)).To(BeNumerically(">", 1))
+			Expect(averageError(img, paletted)).To(BeNumerically("<", maxError))
+		},
+		Entry("with median cut", &libtiff.Quantize{Colors: 16}, uint16(4), 12.0),
+		Entry("with an octree", &libtiff.Quantize{Colors: 16, Method: libtiff.QuantizeOctree}, uint16(4), 16.0),
+		Entry("with 256 colors", &libtiff.Quantize{}, uint16(8), 3.0),
+		Entry("with 256 colors from an octree", &libtiff.Quantize{Method: libtiff.QuantizeOctree}, uint16(8), 4.0),
+	)
+
+	It("dithers gradients", func() {
+		img := createGrayGradient(64, 32)
+
+		// columnError returns the largest difference between the average
+		// level of a band of 4 columns and the gradient, for the middle
+		// columns that are between the two palette colors.
+		columnError := func(paletted *image.Paletted) int {
+			largest := 0
+			for band := 20; band < 44; band += 4 {
+				sum, expected := 0, 0
+				for x := band; x < band+4; x++ {
+					for y := 0; y < 32; y++ {
+						sum += int(color.GrayModel.Convert(paletted.At(x, y)).(color.Gray).Y)
+					}
+					expected += x * 255 / 63
+				}
+				d := sum/32/4 - expected/4
+				largest = max(largest, d, -d)
+			}
+			return largest
+		}
+
+		_, plain := writeQuantized(img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Colors: 2},
+		})
+		Expect(columnError(plain)).To(BeNumerically(">", 40))
+
+		bitsPerSample, dithered := writeQuantized(img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Colors: 2, Dither: true},
+		})
+		Expect(bitsPerSample).To(Equal(uint16(4)))
+		Expect(columnError(dithered)).To(BeNumerically("<", 16))
+	})
+
+	It("keeps paletted images with few enough colors", func() {
+		img := createTestPaletted(16, 16, 8)
+		bitsPerSample, paletted := writeQuantized(img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Colors: 8},
+		})
+		Expect(bitsPerSample).To(Equal(uint16(4)))
+		Expect(paletted.Pix).To(Equal(img.Pix))
+		Expect(paletted.Palette[:8]).To(Equal(img.Palette))
+	})
+
+	It("returns an error for invalid options", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		img := createTestRGBA(8, 8)
+		Expect(tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Colors: 1},
+		})).To(MatchError("quantize colors must be between 2 and 256, got 1"))
+		Expect(tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Colors: 300},
+		})).To(MatchError("quantize colors must be between 2 and 256, got 300"))
+		Expect(tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{Method: libtiff.QuantizeMethod(5)},
+		})).To(MatchError("unknown quantize method 5"))
+		Expect(tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_JPEG,
+			Quantize:    &libtiff.Quantize{},
+		})).To(MatchError("Quantize can't be used with JPEG or CCITT compression"))
+
+		_, err := tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 8, Height: 8, Options: &libtiff.FromGoImageOptions{
+			Quantize: &libtiff.Quantize{},
+		}})
+		Expect(err).To(MatchError("Quantize needs the whole image and is not supported by the strip writer, use a color.Palette color model"))
+	})
+
+	It("parses quantize method names", func() {
+		for _, method := range []libtiff.QuantizeMethod{libtiff.QuantizeMedianCut, libtiff.QuantizeOctree} {
+			parsed, err := libtiff.ParseQuantizeMethod(method.String())
+			Expect(err).To(BeNil())
+			Expect(parsed).To(Equal(method))
+		}
+
+		_, err := libtiff.ParseQuantizeMethod("kmeans")
+		Expect(err).To(MatchError(`unknown quantize method "kmeans"`))
+	})
+})
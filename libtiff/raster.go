@@ -0,0 +1,498 @@
+package libtiff
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// RasterSample is a sample type that can be written with WriteTypedRaster
+// and read with ReadTypedRaster.
+type RasterSample interface {
+	uint8 | int8 | uint16 | int16 | uint32 | int32 | float32 | float64
+}
+
+// Raster holds the samples of a raster. The bands are interleaved, the
+// sample of band b at x, y is Data[(y*Width+x)*Bands+b].
+type Raster[T RasterSample] struct {
+	Data   []T
+	Width  int
+	Height int
+	Bands  int
+	// NoData is the GDAL_NODATA value, nil if not set.
+	NoData *float64
+}
+
+// RasterOptions are the options of WriteRaster and WriteTypedRaster.
+type RasterOptions struct {
+	// Compression sets TIFFTAG_COMPRESSION. If 0, the data is not compressed.
+	Compression Compression
+	// Predictor sets TIFFTAG_PREDICTOR. Only meaningful for LZW and Deflate.
+	// PREDICTOR_FLOATINGPOINT is only supported for float samples.
+	Predictor Predictor
+	// PlanarConfig sets TIFFTAG_PLANARCONFIG. PLANARCONFIG_SEPARATE stores
+	// every band in its own strips or tiles. If 0, the bands are interleaved
+	// (PLANARCONFIG_CONTIG).
+	PlanarConfig TIFFTAG
+	// RowsPerStrip sets the number of rows per strip. If 0, libtiff picks a
+	// default. Ignored when tiles are used.
+	RowsPerStrip uint32
+	// TileWidth and TileHeight enable tiled output when both are set. Both
+	// must be multiples of 16.
+	TileWidth  uint32
+	TileHeight uint32
+	// NoData is written as TIFFTAG_GDAL_NODATA when set, it takes
+	// precedence over GeoReference.NoData.
+	NoData *float64
+	// GeoReference writes the GeoTIFF tags when set, see
+	// FromGoImageOptions.GeoReference.
+	GeoReference *GeoReference
+	// Software sets the TIFFTAG_SOFTWARE tag. If empty, defaults to
+	// "go-libtiff/libtiff-<version>".
+	Software string
+}
+
+// WriteRaster writes 32-bit float samples as a raster to the current
+// directory and writes the directory, see WriteTypedRaster.
+func (f *File) WriteRaster(ctx context.Context, data []float32, width, height, bands int, options *RasterOptions) error {
+	return WriteTypedRaster(ctx, f, data, width, height, bands, options)
+}
+
+// ReadRaster reads the raster of the current directory, which must have
+// 32-bit float samples, see ReadTypedRaster.
+func (f *File) ReadRaster(ctx context.Context) (*Raster[float32], error) {
+	return ReadTypedRaster[float32](ctx, f)
+}
+
+// WriteTypedRaster writes a raster to the current directory of the file and
+// writes the directory. The data holds width*height pixels of bands samples
+// each, with the bands interleaved. BitsPerSample and SampleFormat follow
+// the sample type, the bands are written as a MINISBLACK image with
+// unspecified extra samples, like GDAL does.
+func WriteTypedRaster[T RasterSample](ctx context.Context, f *File, data []T, width, height, bands int, options *RasterOptions) error {
+	if options == nil {
+		options = &RasterOptions{}
+	}
+	if width <= 0 || height <= 0 || bands <= 0 {
+		return fmt.Errorf("invalid raster size %dx%d with %d bands", width, height, bands)
+	}
+	if uint64(width) > math.MaxUint32 || uint64(height) > math.MaxUint32 {
+		return fmt.Errorf("raster size %dx%d is too large", width, height)
+	}
+	if bands > math.MaxUint16 {
+		return fmt.Errorf("raster has %d bands, at most %d are supported", bands, math.MaxUint16)
+	}
+	if len(data) != width*height*bands {
+		return fmt.Errorf("raster data has %d samples, expected %d for %dx%d with %d bands", len(data), width*height*bands, width, height, bands)
+	}
+	if (options.TileWidth > 0) != (options.TileHeight > 0) {
+		return errors.New("both TileWidth and TileHeight must be set for tile-based output")
+	}
+	useTiles := options.TileWidth > 0
+
+	bitsPerSample, sampleFormat := rasterSampleType[T]()
+	if options.Predictor == PREDICTOR_FLOATINGPOINT && sampleFormat != SAMPLEFORMAT_IEEEFP {
+		return fmt.Errorf("the floating point predictor is not supported for %s samples", sampleFormat)
+	}
+
+	planarConfig := PLANARCONFIG_CONTIG
+	if options.PlanarConfig != 0 {
+		planarConfig = options.PlanarConfig
+	}
+	if planarConfig != PLANARCONFIG_CONTIG && planarConfig != PLANARCONFIG_SEPARATE {
+		return fmt.Errorf("invalid planar config %d", planarConfig)
+	}
+
+	compression := COMPRESSION_NONE
+	if options.Compression != 0 {
+		compression = options.Compression
+	}
+
+	uint32Tags := []struct {
+		value uint32
+		tag   TIFFTAG
+	}{
+		{uint32(width), TIFFTAG_IMAGEWIDTH},
+		{uint32(height), TIFFTAG_IMAGELENGTH},
+	}
+	for _, t := range uint32Tags {
+		if err := f.TIFFSetFieldUint32_t(ctx, t.tag, t.value); err != nil {
+			return err
+		}
+	}
+
+	uint16Tags := []struct {
+		value uint16
+		tag   TIFFTAG
+	}{
+		{bitsPerSample, TIFFTAG_BITSPERSAMPLE},
+		{uint16(bands), TIFFTAG_SAMPLESPERPIXEL},
+		{uint16(sampleFormat), TIFFTAG_SAMPLEFORMAT},
+		{uint16(planarConfig), TIFFTAG_PLANARCONFIG},
+		{uint16(PHOTOMETRIC_MINISBLACK), TIFFTAG_PHOTOMETRIC},
+		{uint16(compression), TIFFTAG_COMPRESSION},
+	}
+	for _, t := range uint16Tags {
+		if err := f.TIFFSetFieldUint16_t(ctx, t.tag, t.value); err != nil {
+			return err
+		}
+	}
+
+	if options.Predictor != 0 {
+		if err := f.TIFFSetFieldUint16_t(ctx, TIFFTAG_PREDICTOR, uint16(options.Predictor)); err != nil {
+			return err
+		}
+	}
+
+	if bands > 1 {
+		extraSamples := make([]uint16, bands-1)
+		for i := range extraSamples {
+			extraSamples[i] = uint16(EXTRASAMPLE_UNSPECIFIED)
+		}
+		if err := f.TIFFSetFieldExtraSamples(ctx, extraSamples); err != nil {
+			return err
+		}
+	}
+
+	software := options.Software
+	if software == "" {
+		software = f.defaultSoftware(ctx)
+	}
+	if err := f.TIFFSetFieldString(ctx, TIFFTAG_SOFTWARE, software); err != nil {
+		return err
+	}
+
+	if options.GeoReference != nil {
+		geoReference := *options.GeoReference
+		if options.NoData != nil {
+			geoReference.NoData = formatNoData(*options.NoData)
+		}
+		if err := f.setGeoReference(ctx, &geoReference); err != nil {
+			return err
+		}
+	} else if options.NoData != nil {
+		if err := f.TIFFMergeAnonymousField(ctx, TIFFTAG_GDAL_NODATA, TIFF_ASCII); err != nil {
+			return err
+		}
+		// The count of ASCII tags includes the null terminator.
+		if err := f.TIFFSetFieldByteArray(ctx, TIFFTAG_GDAL_NODATA, append([]byte(formatNoData(*options.NoData)), 0)); err != nil {
+			return err
+		}
+	}
+
+	bytesPerSample := int(bitsPerSample) / 8
+	planes, pixelSamples := 1, bands
+	if planarConfig == PLANARCONFIG_SEPARATE {
+		planes, pixelSamples = bands, 1
+	}
+
+	// encodeRow encodes count pixels of a row of one plane to dst.
+	rowSamples := make([]T, width*pixelSamples)
+	encodeRow := func(dst []byte, x, y, count, plane int) {
+		start := (y*width + x) * bands
+		if planes == 1 {
+			encodeRasterSamples(dst, data[start:start+count*bands])
+			return
+		}
+		for i := 0; i < count; i++ {
+			rowSamples[i] = data[start+i*bands+plane]
+		}
+		encodeRasterSamples(dst, rowSamples[:count])
+	}
+
+	if useTiles {
+		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH, options.TileWidth); err != nil {
+			return err
+		}
+		if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILELENGTH, options.TileHeight); err != nil {
+			return err
+		}
+
+		tileWidth, tileHeight := int(options.TileWidth), int(options.TileHeight)
+		tileRowSize := tileWidth * pixelSamples * bytesPerSample
+		for plane := 0; plane < planes; plane++ {
+			for y := 0; y < height; y += tileHeight {
+				for x := 0; x < width; x += tileWidth {
+					tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, uint16(plane))
+					if err != nil {
+						return err
+					}
+
+					tileData := make([]byte, tileRowSize*tileHeight)
+					cols := min(tileWidth, width-x)
+					for row := 0; row < tileHeight && y+row < height; row++ {
+						encodeRow(tileData[row*tileRowSize:], x, y+row, cols, plane)
+					}
+					if err := f.TIFFWriteEncodedTile(ctx, tile, tileData); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return f.TIFFWriteDirectory(ctx)
+	}
+
+	rowsPerStrip := options.RowsPerStrip
+	if rowsPerStrip == 0 {
+		var err error
+		rowsPerStrip, err = f.TIFFDefaultStripSize(ctx, 0)
+		if err != nil {
+			return err
+		}
+	}
+	if err := f.TIFFSetFieldUint32_t(ctx, TIFFTAG_ROWSPERSTRIP, rowsPerStrip); err != nil {
+		return err
+	}
+
+	rowSize := width * pixelSamples * bytesPerSample
+	for plane := 0; plane < planes; plane++ {
+		for y := 0; y < height; y += int(rowsPerStrip) {
+			strip, err := f.TIFFComputeStrip(ctx, uint32(y), uint16(plane))
+			if err != nil {
+				return err
+			}
+
+			rows := min(int(rowsPerStrip), height-y)
+			stripData := make([]byte, rows*rowSize)
+			for row := 0; row < rows; row++ {
+				encodeRow(stripData[row*rowSize:], 0, y+row, width, plane)
+			}
+			if err := f.TIFFWriteEncodedStrip(ctx, strip, stripData); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.TIFFWriteDirectory(ctx)
+}
+
+// ReadTypedRaster reads the raster of the current directory. The sample
+// type must match BitsPerSample and SampleFormat of the directory, the
+// samples are not converted.
+func ReadTypedRaster[T RasterSample](ctx context.Context, f *File) (*Raster[T], error) {
+	width, height, err := f.GetDimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bands, err := f.getUint16WithDefault(ctx, TIFFTAG_SAMPLESPERPIXEL, 1)
+	if err != nil {
+		return nil, err
+	}
+	bitsPerSample, err := f.getUint16WithDefault(ctx, TIFFTAG_BITSPERSAMPLE, 1)
+	if err != nil {
+		return nil, err
+	}
+	sampleFormat, err := f.GetSampleFormat(ctx)
+	if err != nil {
+		return nil, err
+	}
+	planarConfig, err := f.getUint16WithDefault(ctx, TIFFTAG_PLANARCONFIG, uint16(PLANARCONFIG_CONTIG))
+	if err != nil {
+		return nil, err
+	}
+
+	expectedBitsPerSample, expectedSampleFormat := rasterSampleType[T]()
+	if bitsPerSample != expectedBitsPerSample || sampleFormat != expectedSampleFormat {
+		var zero T
+		return nil, fmt.Errorf("could not read %d-bit %s samples as %T", bitsPerSample, sampleFormat, zero)
+	}
+
+	raster := &Raster[T]{
+		Data:   make([]T, width*height*int(bands)),
+		Width:  width,
+		Height: height,
+		Bands:  int(bands),
+	}
+
+	noData, err := f.getOptionalString(ctx, TIFFTAG_GDAL_NODATA)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := parseNoData(noData); ok {
+		raster.NoData = &value
+	}
+
+	planes, pixelSamples := 1, raster.Bands
+	if TIFFTAG(planarConfig) == PLANARCONFIG_SEPARATE {
+		planes, pixelSamples = raster.Bands, 1
+	}
+
+	// placeBlock copies the samples of a decoded strip or tile with rows of
+	// blockWidth pixels to the raster.
+	placeBlock := func(blockData []byte, blockX, blockY, blockWidth, plane int) {
+		samples := make([]T, len(blockData)/(int(bitsPerSample)/8))
+		decodeRasterSamples(samples, blockData)
+
+		rowSamples := blockWidth * pixelSamples
+		cols := min(blockWidth, width-blockX)
+		for row := 0; (row+1)*rowSamples <= len(samples) && blockY+row < height; row++ {
+			src := samples[row*rowSamples:]
+			dst := raster.Data[((blockY+row)*width+blockX)*raster.Bands:]
+			if planes == 1 {
+				copy(dst[:cols*raster.Bands], src)
+				continue
+			}
+			for i := 0; i < cols; i++ {
+				dst[i*raster.Bands+plane] = src[i]
+			}
+		}
+	}
+
+	isTiled, err := f.TIFFIsTiled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTiled {
+		tileWidth, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH)
+		if err != nil {
+			return nil, err
+		}
+		tileHeight, err := f.TIFFGetFieldUint32_t(ctx, TIFFTAG_TILELENGTH)
+		if err != nil {
+			return nil, err
+		}
+		for plane := 0; plane < planes; plane++ {
+			for y := 0; y < height; y += int(tileHeight) {
+				for x := 0; x < width; x += int(tileWidth) {
+					tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, uint16(plane))
+					if err != nil {
+						return nil, err
+					}
+					tileData, err := f.TIFFReadEncodedTile(ctx, tile)
+					if err != nil {
+						return nil, err
+					}
+					placeBlock(tileData, x, y, int(tileWidth), plane)
+				}
+			}
+		}
+		return raster, nil
+	}
+
+	rowsPerStrip, err := f.getUint32WithDefault(ctx, TIFFTAG_ROWSPERSTRIP, uint32(height))
+	if err != nil {
+		return nil, err
+	}
+	// libtiff refuses a RowsPerStrip of 0, guard against it anyway so the
+	// loop below always advances.
+	if rowsPerStrip == 0 || rowsPerStrip > uint32(height) {
+		rowsPerStrip = uint32(height)
+	}
+	for plane := 0; plane < planes; plane++ {
+		for y := 0; y < height; y += int(rowsPerStrip) {
+			strip, err := f.TIFFComputeStrip(ctx, uint32(y), uint16(plane))
+			if err != nil {
+				return nil, err
+			}
+			stripData, err := f.TIFFReadEncodedStrip(ctx, strip)
+			if err != nil {
+				return nil, err
+			}
+			placeBlock(stripData, 0, y, width, plane)
+		}
+	}
+
+	return raster, nil
+}
+
+// rasterSampleType returns BitsPerSample and SampleFormat of a sample type.
+func rasterSampleType[T RasterSample]() (uint16, SampleFormat) {
+	var zero T
+	switch any(zero).(type) {
+	case uint8:
+		return 8, SAMPLEFORMAT_UINT
+	case int8:
+		return 8, SAMPLEFORMAT_INT
+	case uint16:
+		return 16, SAMPLEFORMAT_UINT
+	case int16:
+		return 16, SAMPLEFORMAT_INT
+	case uint32:
+		return 32, SAMPLEFORMAT_UINT
+	case int32:
+		return 32, SAMPLEFORMAT_INT
+	case float32:
+		return 32, SAMPLEFORMAT_IEEEFP
+	default:
+		return 64, SAMPLEFORMAT_IEEEFP
+	}
+}
+
+// encodeRasterSamples encodes samples to dst in the little-endian byte
+// order of the WASM host, libtiff swaps them when the file has another byte
+// order.
+func encodeRasterSamples[T RasterSample](dst []byte, src []T) {
+	switch samples := any(src).(type) {
+	case []uint8:
+		copy(dst, samples)
+	case []int8:
+		for i, v := range samples {
+			dst[i] = byte(v)
+		}
+	case []uint16:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(dst[i*2:], v)
+		}
+	case []int16:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(dst[i*2:], uint16(v))
+		}
+	case []uint32:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(dst[i*4:], v)
+		}
+	case []int32:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(dst[i*4:], uint32(v))
+		}
+	case []float32:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(dst[i*4:], math.Float32bits(v))
+		}
+	case []float64:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint64(dst[i*8:], math.Float64bits(v))
+		}
+	}
+}
+
+// decodeRasterSamples decodes little-endian samples from src, the
+// counterpart of encodeRasterSamples.
+func decodeRasterSamples[T RasterSample](dst []T, src []byte) {
+	switch samples := any(dst).(type) {
+	case []uint8:
+		copy(samples, src)
+	case []int8:
+		for i := range samples {
+			samples[i] = int8(src[i])
+		}
+	case []uint16:
+		for i := range samples {
+			samples[i] = binary.LittleEndian.Uint16(src[i*2:])
+		}
+	case []int16:
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(src[i*2:]))
+		}
+	case []uint32:
+		for i := range samples {
+			samples[i] = binary.LittleEndian.Uint32(src[i*4:])
+		}
+	case []int32:
+		for i := range samples {
+			samples[i] = int32(binary.LittleEndian.Uint32(src[i*4:]))
+		}
+	case []float32:
+		for i := range samples {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(src[i*4:]))
+		}
+	case []float64:
+		for i := range samples {
+			samples[i] = math.Float64frombits(binary.LittleEndian.Uint64(src[i*8:]))
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package libtiff_test
+
+import (
+	"context"
+	"math"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// createTestElevation returns width*height*bands float samples, with NaN
+// for every 17th sample.
+func createTestElevation(width, height, bands int) []float32 {
+	data := make([]float32, width*height*bands)
+	for i := range data {
+		data[i] = float32(math.Sin(float64(i)/10))*1000 + float32(i%7)*0.125
+		if i%17 == 0 {
+			data[i] = float32(math.NaN())
+		}
+	}
+	return data
+}
+
+// expectSameFloats compares float samples bit by bit, so NaN values can be
+// compared.
+func expectSameFloats(actual, expected []float32) {
+	Expect(actual).To(HaveLen(len(expected)))
+	for i := range expected {
+		Expect(math.Float32bits(actual[i])).To(Equal(math.Float32bits(expected[i])), "sample %d", i)
+	}
+}
+
+var _ = Describe("Raster", func() {
+	ctx := context.Background()
+
+	// writeRaster calls write with a file opened for writing and reopens
+	// the file for reading.
+	writeRaster := func(write func(tiffFile *libtiff.File) error) *libtiff.File {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(write(tiffFile)).To(Succeed())
+		return reopenTempTestFile(ctx, tiffFile, tmpFile)
+	}
+
+	DescribeTable("round trips float32 rasters",
+		func(bands int, options *libtiff.RasterOptions) {
+			data := createTestElevation(37, 21, bands)
+			tiffFile := writeRaster(func(tiffFile *libtiff.File) error {
+				return tiffFile.WriteRaster(ctx, data, 37, 21, bands, options)
+			})
+
+			bitsPerSample, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE)
+			Expect(err).To(BeNil())
+			Expect(bitsPerSample).To(Equal(uint16(32)))
+			sampleFormat, err := tiffFile.GetSampleFormat(ctx)
+			Expect(err).To(BeNil())
+			Expect(sampleFormat).To(Equal(libtiff.SAMPLEFORMAT_IEEEFP))
+
+			raster, err := tiffFile.ReadRaster(ctx)
+			Expect(err).To(BeNil())
+			Expect(raster.Width).To(Equal(37))
+			Expect(raster.Height).To(Equal(21))
+			Expect(raster.Bands).To(Equal(bands))
+			expectSameFloats(raster.Data, data)
+		},
+		Entry("without compression", 1, nil),
+		Entry("with LZW and the floating point predictor", 1, &libtiff.RasterOptions{
+			Compression:  libtiff.COMPRESSION_LZW,
+			Predictor:    libtiff.PREDICTOR_FLOATINGPOINT,
+			RowsPerStrip: 4,
+		}),
+		Entry("with multiple bands", 3, &libtiff.RasterOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			Predictor:   libtiff.PREDICTOR_FLOATINGPOINT,
+		}),
+		Entry("with separate planes", 3, &libtiff.RasterOptions{
+			Compression:  libtiff.COMPRESSION_ADOBE_DEFLATE,
+			PlanarConfig: libtiff.PLANARCONFIG_SEPARATE,
+			RowsPerStrip: 8,
+		}),
+		Entry("with tiles", 2, &libtiff.RasterOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			Predictor:   libtiff.PREDICTOR_FLOATINGPOINT,
+			TileWidth:   16,
+			TileHeight:  16,
+		}),
+		Entry("with tiles and separate planes", 2, &libtiff.RasterOptions{
+			Compression:  libtiff.COMPRESSION_LZW,
+			Predictor:    libtiff.PREDICTOR_FLOATINGPOINT,
+			PlanarConfig: libtiff.PLANARCONFIG_SEPARATE,
+			TileWidth:    16,
+			TileHeight:   32,
+		}),
+	)
+
+	It("writes the tags of a multi-band raster", func() {
+		noData := -9999.0
+		tiffFile := writeRaster(func(tiffFile *libtiff.File) error {
+			return tiffFile.WriteRaster(ctx, createTestElevation(8, 8, 3), 8, 8, 3, &libtiff.RasterOptions{
+				Compression:  libtiff.COMPRESSION_LZW,
+				Predictor:    libtiff.PREDICTOR_FLOATINGPOINT,
+				PlanarConfig: libtiff.PLANARCONFIG_SEPARATE,
+				NoData:       &noData,
+			})
+		})
+
+		predictor, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_PREDICTOR)
+		Expect(err).To(BeNil())
+		Expect(libtiff.Predictor(predictor)).To(Equal(libtiff.PREDICTOR_FLOATINGPOINT))
+
+		planarConfig, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_PLANARCONFIG)
+		Expect(err).To(BeNil())
+		Expect(libtiff.TIFFTAG(planarConfig)).To(Equal(libtiff.PLANARCONFIG_SEPARATE))
+
+		photometric, err := tiffFile.GetPhotometric(ctx)
+		Expect(err).To(BeNil())
+		Expect(photometric).To(Equal(libtiff.PHOTOMETRIC_MINISBLACK))
+
+		extraSamples, err := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.TIFFTAG_EXTRASAMPLES)
+		Expect(err).To(BeNil())
+		Expect(extraSamples).To(Equal([]uint16{uint16(libtiff.EXTRASAMPLE_UNSPECIFIED), uint16(libtiff.EXTRASAMPLE_UNSPECIFIED)}))
+
+		raster, err := tiffFile.ReadRaster(ctx)
+		Expect(err).To(BeNil())
+		Expect(raster.NoData).To(Equal(&noData))
+	})
+
+	It("writes a NaN NoData value like GDAL", func() {
+		noData := math.NaN()
+		tiffFile := writeRaster(func(tiffFile *libtiff.File) error {
+			return tiffFile.WriteRaster(ctx, createTestElevation(4, 4, 1), 4, 4, 1, &libtiff.RasterOptions{
+				NoData: &noData,
+				GeoReference: &libtiff.GeoReference{
+					GeoTransform:   &[6]float64{100, 1, 0, 200, 0, -1},
+					GeographicEPSG: 4326,
+					NoData:         "-1",
+				},
+			})
+		})
+
+		geoReference, err := tiffFile.GeoReference(ctx)
+		Expect(err).To(BeNil())
+		Expect(geoReference.NoData).To(Equal("nan"))
+		Expect(geoReference.GeographicEPSG).To(Equal(uint16(4326)))
+
+		raster, err := tiffFile.ReadRaster(ctx)
+		Expect(err).To(BeNil())
+		Expect(raster.NoData).ToNot(BeNil())
+		Expect(math.IsNaN(*raster.NoData)).To(BeTrue())
+	})
+
+	It("round trips float64 rasters", func() {
+		data := []float64{math.Pi, -math.MaxFloat64, math.SmallestNonzeroFloat64, 0, 1e300, -2.5}
+		tiffFile := writeRaster(func(tiffFile *libtiff.File) error {
+			return libtiff.WriteTypedRaster(ctx, tiffFile, data, 3, 2, 1, &libtiff.RasterOptions{
+				Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+				Predictor:   libtiff.PREDICTOR_FLOATINGPOINT,
+			})
+		})
+
+		bitsPerSample, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE)
+		Expect(err).To(BeNil())
+		Expect(bitsPerSample).To(Equal(uint16(64)))
+
+		raster, err := libtiff.ReadTypedRaster[float64](ctx, tiffFile)
+		Expect(err).To(BeNil())
+		Expect(raster.Data).To(Equal(data))
+		Expect(raster.NoData).To(BeNil())
+	})
+
+	It("round trips integer rasters", func() {
+		data := make([]int16, 20*10*2)
+		for i := range data {
+			data[i] = int16(i*37 - 4000)
+		}
+		tiffFile := writeRaster(func(tiffFile *libtiff.File) error {
+			return libtiff.WriteTypedRaster(ctx, tiffFile, data, 20, 10, 2, &libtiff.RasterOptions{
+				Compression: libtiff.COMPRESSION_LZW,
+				Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+			})
+		})
+
+		sampleFormat, err := tiffFile.GetSampleFormat(ctx)
+		Expect(err).To(BeNil())
+		Expect(sampleFormat).To(Equal(libtiff.SAMPLEFORMAT_INT))
+
+		raster, err := libtiff.ReadTypedRaster[int16](ctx, tiffFile)
+		Expect(err).To(BeNil())
+		Expect(raster.Data).To(Equal(data))
+
+		_, err = libtiff.ReadTypedRaster[uint16](ctx, tiffFile)
+		Expect(err).To(MatchError("could not read 16-bit SAMPLEFORMAT_INT samples as uint16"))
+
+		_, err = tiffFile.ReadRaster(ctx)
+		Expect(err).To(MatchError("could not read 16-bit SAMPLEFORMAT_INT samples as float32"))
+	})
+
+	It("returns an error for invalid input", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		err := tiffFile.WriteRaster(ctx, make([]float32, 5), 2, 2, 1, nil)
+		Expect(err).To(MatchError("raster data has 5 samples, expected 4 for 2x2 with 1 bands"))
+
+		err = libtiff.WriteTypedRaster(ctx, tiffFile, make([]uint8, 65536), 1, 1, 65536, nil)
+		Expect(err).To(MatchError("raster has 65536 bands, at most 65535 are supported"))
+
+		err = libtiff.WriteTypedRaster(ctx, tiffFile, make([]uint8, 4), 2, 2, 1, &libtiff.RasterOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Predictor:   libtiff.PREDICTOR_FLOATINGPOINT,
+		})
+		Expect(err).To(MatchError("the floating point predictor is not supported for SAMPLEFORMAT_UINT samples"))
+
+		err = tiffFile.WriteRaster(ctx, make([]float32, 4), 2, 2, 1, &libtiff.RasterOptions{
+			TileWidth: 16,
+		})
+		Expect(err).To(MatchError("both TileWidth and TileHeight must be set for tile-based output"))
+	})
+})
@@ -0,0 +1,379 @@
+package libtiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Rational is the exact value of a RATIONAL tag.
+type Rational struct {
+	Numerator   uint32
+	Denominator uint32
+}
+
+// Float64 returns the value of the fraction. It is 0 when the denominator
+// is 0.
+func (r Rational) Float64() float64 {
+	if r.Denominator == 0 {
+		return 0
+	}
+	return float64(r.Numerator) / float64(r.Denominator)
+}
+
+func (r Rational) String() string {
+	return fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
+}
+
+// SRational is the exact value of an SRATIONAL tag.
+type SRational struct {
+	Numerator   int32
+	Denominator int32
+}
+
+// Float64 returns the value of the fraction. It is 0 when the denominator
+// is 0.
+func (r SRational) Float64() float64 {
+	if r.Denominator == 0 {
+		return 0
+	}
+	return float64(r.Numerator) / float64(r.Denominator)
+}
+
+func (r SRational) String() string {
+	return fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
+}
+
+type pendingRational struct {
+	dataType               TIFFDataType
+	numerator, denominator uint32
+}
+
+// GetRational returns the exact value of a single-valued RATIONAL tag of the
+// current directory, where libtiff only exposes it as a float. The value is
+// read from the directory entry in the file, so the directory must have been
+// written, unless the value was set with SetRational. The file must have been
+// opened with TIFFOpenFileFromReader or TIFFOpenFileFromReadWriteSeeker.
+func (f *File) GetRational(ctx context.Context, tag TIFFTAG) (Rational, error) {
+	numerator, denominator, err := f.getRational(ctx, tag, TIFF_RATIONAL)
+	if err != nil {
+		return Rational{}, err
+	}
+	return Rational{Numerator: numerator, Denominator: denominator}, nil
+}
+
+// GetSRational returns the exact value of a single-valued SRATIONAL tag of
+// the current directory, see GetRational.
+func (f *File) GetSRational(ctx context.Context, tag TIFFTAG) (SRational, error) {
+	numerator, denominator, err := f.getRational(ctx, tag, TIFF_SRATIONAL)
+	if err != nil {
+		return SRational{}, err
+	}
+	return SRational{Numerator: int32(numerator), Denominator: int32(denominator)}, nil
+}
+
+// SetRational sets a single-valued RATIONAL tag of the current directory.
+// libtiff stores the tag as a float and writes an approximation of it, the
+// exact fraction is written over it when the directory is written with
+// TIFFWriteDirectory, TIFFRewriteDirectory, TIFFCheckpointDirectory or
+// TIFFWriteCustomDirectory. The file must have been opened with
+// TIFFOpenFileFromReadWriteSeeker.
+func (f *File) SetRational(ctx context.Context, tag TIFFTAG, value Rational) error {
+	if value.Denominator == 0 {
+		return errors.New("could not set rational: denominator is 0")
+	}
+	return f.setRational(ctx, tag, value.Float64(), pendingRational{
+		dataType:    TIFF_RATIONAL,
+		numerator:   value.Numerator,
+		denominator: value.Denominator,
+	})
+}
+
+// SetSRational sets a single-valued SRATIONAL tag of the current directory,
+// see SetRational.
+func (f *File) SetSRational(ctx context.Context, tag TIFFTAG, value SRational) error {
+	if value.Denominator == 0 {
+		return errors.New("could not set rational: denominator is 0")
+	}
+	return f.setRational(ctx, tag, value.Float64(), pendingRational{
+		dataType:    TIFF_SRATIONAL,
+		numerator:   uint32(value.Numerator),
+		denominator: uint32(value.Denominator),
+	})
+}
+
+func (f *File) getRational(ctx context.Context, tag TIFFTAG, dataType TIFFDataType) (uint32, uint32, error) {
+	if pending, ok := f.pendingRationals[tag]; ok {
+		if pending.dataType != dataType {
+			return 0, 0, fmt.Errorf("could not read rational: tag %d has type %d", tag, pending.dataType)
+		}
+		return pending.numerator, pending.denominator, nil
+	}
+
+	offset, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset == 0 {
+		return 0, 0, errors.New("could not read rational: current directory has not been written")
+	}
+
+	var numerator, denominator uint32
+	err = f.withDescriber(func(d *describer) error {
+		if _, err := d.readHeader(); err != nil {
+			return err
+		}
+		entry, err := d.findEntry(offset, uint16(tag))
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return &TagNotDefinedError{Tag: tag}
+		}
+		if entry.dataType != dataType || entry.count != 1 {
+			return fmt.Errorf("could not read rational: tag %d has type %d and count %d", tag, entry.dataType, entry.count)
+		}
+
+		value, err := d.readAt(entry.valueOffset, 8)
+		if err != nil {
+			return err
+		}
+		numerator, denominator = d.order.Uint32(value), d.order.Uint32(value[4:])
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return numerator, denominator, nil
+}
+
+func (f *File) setRational(ctx context.Context, tag TIFFTAG, value float64, pending pendingRational) error {
+	if f.readerFile == nil || f.readerFile.ReadWriteSeeker == nil {
+		return errors.New("could not set rational: file was not opened from a ReadWriteSeeker")
+	}
+
+	// libtiff reads both float and double tags as a double argument.
+	if err := f.TIFFSetFieldDouble(ctx, tag, value); err != nil {
+		return err
+	}
+
+	if f.pendingRationals == nil {
+		f.pendingRationals = map[TIFFTAG]pendingRational{}
+	}
+	f.pendingRationals[tag] = pending
+	return nil
+}
+
+// writePendingRationals writes the exact values of SetRational and
+// SetSRational over the values libtiff wrote in the directory at the given
+// offset.
+func (f *File) writePendingRationals(offset uint64) error {
+	if len(f.pendingRationals) == 0 {
+		return nil
+	}
+
+	writer := f.readerFile.ReadWriteSeeker
+	return f.withDescriber(func(d *describer) error {
+		if _, err := d.readHeader(); err != nil {
+			return err
+		}
+
+		for tag, pending := range f.pendingRationals {
+			entry, err := d.findEntry(offset, uint16(tag))
+			if err != nil {
+				return err
+			}
+			if entry == nil || entry.dataType != pending.dataType || entry.count != 1 {
+				return fmt.Errorf("could not write rational: tag %d was not written as expected", tag)
+			}
+
+			value := make([]byte, 8)
+			d.order.PutUint32(value, pending.numerator)
+			d.order.PutUint32(value[4:], pending.denominator)
+			if _, err := writer.Seek(int64(entry.valueOffset), io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := writer.Write(value); err != nil {
+				return fmt.Errorf("could not write rational: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// directoryEntry is the location of a tag value in the file.
+type directoryEntry struct {
+	dataType    TIFFDataType
+	count       uint64
+	valueOffset uint64
+}
+
+// findEntry returns the entry of the tag in the directory at the given
+// offset, or nil when the directory does not contain the tag.
+func (d *describer) findEntry(offset uint64, tag uint16) (*directoryEntry, error) {
+	count, entrySize, err := d.readEntryCount(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.readAt(offset+d.countSize(), count*entrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		entry := data[i*entrySize : (i+1)*entrySize]
+		if d.order.Uint16(entry) != tag {
+			continue
+		}
+
+		result := &directoryEntry{
+			dataType: TIFFDataType(d.order.Uint16(entry[2:])),
+		}
+		valueOffset := offset + d.countSize() + i*entrySize
+		var valueField []byte
+		if d.bigTIFF {
+			result.count = d.order.Uint64(entry[4:])
+			valueOffset += 12
+			valueField = entry[12:]
+		} else {
+			result.count = uint64(d.order.Uint32(entry[4:]))
+			valueOffset += 8
+			valueField = entry[8:]
+		}
+
+		result.valueOffset = valueOffset
+		if dataType, ok := describeTypes[uint16(result.dataType)]; ok && dataType.size*result.count > uint64(len(valueField)) {
+			if d.bigTIFF {
+				result.valueOffset = d.order.Uint64(valueField)
+			} else {
+				result.valueOffset = uint64(d.order.Uint32(valueField))
+			}
+		}
+		return result, nil
+	}
+
+	return nil, nil
+}
+
+// writtenDirectoryOffsets returns the offsets of the directories in the file
+// before a directory is written, to find the written directory with
+// writtenDirectory. It returns nil when locate is false and there are no
+// exact rationals to write.
+func (f *File) writtenDirectoryOffsets(locate bool) (map[uint64]bool, error) {
+	if !locate && len(f.pendingRationals) == 0 {
+		return nil, nil
+	}
+	var offsets map[uint64]bool
+	err := f.withDescriber(func(d *describer) error {
+		first, err := d.readHeader()
+		if err != nil {
+			return err
+		}
+		offsets, err = d.directoryOffsets(first)
+		return err
+	})
+	return offsets, err
+}
+
+// writtenDirectory returns the offset of the directory libtiff wrote, which
+// is the one directory that is not in before. A directory that is written in
+// place keeps its offset previous. Offsets don't tell which directory was
+// written, libtiff can write directories anywhere in the file.
+func (f *File) writtenDirectory(before map[uint64]bool, previous uint64) (uint64, error) {
+	after, err := f.writtenDirectoryOffsets(true)
+	if err != nil {
+		return 0, err
+	}
+	var written []uint64
+	for offset := range after {
+		if !before[offset] {
+			written = append(written, offset)
+		}
+	}
+	switch {
+	case len(written) == 1:
+		return written[0], nil
+	case len(written) == 0 && previous != 0 && after[previous]:
+		return previous, nil
+	default:
+		return 0, fmt.Errorf("could not find the written directory, %d directories were added", len(written))
+	}
+}
+
+// directoryOffsets returns the offsets of the directories in the chain that
+// starts at the given offset and of their SubIFDs.
+func (d *describer) directoryOffsets(offset uint64) (map[uint64]bool, error) {
+	offsets := map[uint64]bool{}
+	for offset != 0 {
+		if offsets[offset] {
+			return nil, fmt.Errorf("directory loop at offset %d", offset)
+		}
+		offsets[offset] = true
+
+		entry, err := d.findEntry(offset, uint16(TIFFTAG_SUBIFD))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			if dataType, ok := describeTypes[uint16(entry.dataType)]; ok {
+				value, err := d.readAt(entry.valueOffset, dataType.size*entry.count)
+				if err != nil {
+					return nil, err
+				}
+				// SubIFDs that have not been written yet are 0.
+				subIFDs, _ := d.decodeValue(uint16(entry.dataType), entry.count, value).([]uint64)
+				for _, subIFD := range subIFDs {
+					if subIFD != 0 {
+						offsets[subIFD] = true
+					}
+				}
+			}
+		}
+
+		count, entrySize, err := d.readEntryCount(offset)
+		if err != nil {
+			return nil, err
+		}
+		nextSize := uint64(4)
+		if d.bigTIFF {
+			nextSize = 8
+		}
+		data, err := d.readAt(offset+d.countSize()+count*entrySize, nextSize)
+		if err != nil {
+			return nil, err
+		}
+		if d.bigTIFF {
+			offset = d.order.Uint64(data)
+		} else {
+			offset = uint64(d.order.Uint32(data))
+		}
+	}
+	return offsets, nil
+}
+
+func (d *describer) countSize() uint64 {
+	if d.bigTIFF {
+		return 8
+	}
+	return 2
+}
+
+// readEntryCount returns the number of entries of the directory at the given
+// offset and the size of an entry.
+func (d *describer) readEntryCount(offset uint64) (uint64, uint64, error) {
+	data, err := d.readAt(offset, d.countSize())
+	if err != nil {
+		return 0, 0, err
+	}
+	if d.bigTIFF {
+		count := d.order.Uint64(data)
+		if count > 0xFFFF {
+			return 0, 0, fmt.Errorf("invalid directory entry count %d at offset %d", count, offset)
+		}
+		return count, 20, nil
+	}
+	return uint64(d.order.Uint16(data)), 12, nil
+}
@@ -0,0 +1,172 @@
+package libtiff_test
+
+import (
+	"context"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rational", func() {
+	ctx := context.Background()
+
+	It("formats and converts fractions", func() {
+		Expect(libtiff.Rational{Numerator: 7200, Denominator: 24}.String()).To(Equal("7200/24"))
+		Expect(libtiff.Rational{Numerator: 7200, Denominator: 24}.Float64()).To(Equal(300.0))
+		Expect(libtiff.Rational{Numerator: 1}.Float64()).To(Equal(0.0))
+		Expect(libtiff.SRational{Numerator: -2, Denominator: 3}.String()).To(Equal("-2/3"))
+		Expect(libtiff.SRational{Numerator: -3, Denominator: 2}.Float64()).To(Equal(-1.5))
+	})
+
+	It("keeps the resolution bit-exact through FromGoImage", func() {
+		x := libtiff.Rational{Numerator: 7200, Denominator: 24}
+		y := libtiff.Rational{Numerator: 1, Denominator: 3}
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+			XResolutionRational: x,
+			YResolutionRational: y,
+			ResolutionUnit:      libtiff.RESUNIT_INCH,
+		})
+		defer cleanup()
+
+		readX, readY, err := tiffFile.GetResolutionRational(ctx)
+		Expect(err).To(BeNil())
+		Expect(readX).To(Equal(x))
+		Expect(readY).To(Equal(y))
+
+		floatX, floatY, err := tiffFile.GetResolution(ctx)
+		Expect(err).To(BeNil())
+		Expect(floatX).To(BeNumerically("~", 300, 0.001))
+		Expect(floatY).To(BeNumerically("~", 1.0/3, 0.001))
+	})
+
+	It("copies the resolution from one file to another", func() {
+		source, cleanup := writeAndReopen(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+			XResolutionRational: libtiff.Rational{Numerator: 1000, Denominator: 3},
+			YResolutionRational: libtiff.Rational{Numerator: 600, Denominator: 7},
+		})
+		defer cleanup()
+
+		x, y, err := source.GetResolutionRational(ctx)
+		Expect(err).To(BeNil())
+
+		copied, copiedCleanup := writeAndReopen(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+			XResolutionRational: x,
+			YResolutionRational: y,
+		})
+		defer copiedCleanup()
+
+		copiedX, copiedY, err := copied.GetResolutionRational(ctx)
+		Expect(err).To(BeNil())
+		Expect(copiedX).To(Equal(libtiff.Rational{Numerator: 1000, Denominator: 3}))
+		Expect(copiedY).To(Equal(libtiff.Rational{Numerator: 600, Denominator: 7}))
+	})
+
+	It("writes exact values to every page", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		for page := uint32(1); page <= 3; page++ {
+			Expect(tiffFile.FromGoImage(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+				XResolutionRational: libtiff.Rational{Numerator: 100 * page, Denominator: 3},
+				YResolutionRational: libtiff.Rational{Numerator: 200 * page, Denominator: 7},
+			})).To(Succeed())
+		}
+
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		for page := uint32(1); page <= 3; page++ {
+			Expect(readTiff.TIFFSetDirectory(ctx, page-1)).To(Succeed())
+			x, y, err := readTiff.GetResolutionRational(ctx)
+			Expect(err).To(BeNil())
+			Expect(x).To(Equal(libtiff.Rational{Numerator: 100 * page, Denominator: 3}))
+			Expect(y).To(Equal(libtiff.Rational{Numerator: 200 * page, Denominator: 7}))
+		}
+	})
+
+	It("writes exact values to a SubIFD and the next page", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		Expect(tiffFile.TIFFSetFieldUint64Array(ctx, libtiff.TIFFTAG_SUBIFD, []uint64{0})).To(Succeed())
+		for _, value := range []uint32{300, 150, 100} {
+			Expect(tiffFile.FromGoImage(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+				XResolutionRational: libtiff.Rational{Numerator: value, Denominator: 7},
+				YResolutionRational: libtiff.Rational{Numerator: value, Denominator: 11},
+			})).To(Succeed())
+		}
+
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		expectResolution := func(value uint32) {
+			x, y, err := readTiff.GetResolutionRational(ctx)
+			Expect(err).To(BeNil())
+			Expect(x).To(Equal(libtiff.Rational{Numerator: value, Denominator: 7}))
+			Expect(y).To(Equal(libtiff.Rational{Numerator: value, Denominator: 11}))
+		}
+
+		expectResolution(300)
+		subIFDs, err := readTiff.TIFFGetFieldUint64Array(ctx, libtiff.TIFFTAG_SUBIFD)
+		Expect(err).To(BeNil())
+		Expect(subIFDs).To(HaveLen(1))
+		Expect(readTiff.TIFFSetSubDirectory(ctx, subIFDs[0])).To(Succeed())
+		expectResolution(150)
+		Expect(readTiff.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		expectResolution(100)
+	})
+
+	It("reads and writes EXIF rationals", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+
+		Expect(tiffFile.TIFFCreateEXIFDirectory(ctx)).To(Succeed())
+		Expect(tiffFile.SetRational(ctx, libtiff.EXIFTAG_EXPOSURETIME, libtiff.Rational{Numerator: 10, Denominator: 2500})).To(Succeed())
+		Expect(tiffFile.SetSRational(ctx, libtiff.EXIFTAG_EXPOSUREBIASVALUE, libtiff.SRational{Numerator: -2, Denominator: 3})).To(Succeed())
+
+		value, err := tiffFile.GetRational(ctx, libtiff.EXIFTAG_EXPOSURETIME)
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(libtiff.Rational{Numerator: 10, Denominator: 2500}))
+
+		exifOffset, err := tiffFile.TIFFWriteCustomDirectory(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(tiffFile.TIFFCreateDirectory(ctx)).To(Succeed())
+		Expect(tiffFile.TIFFSetFieldUint64_t(ctx, libtiff.TIFFTAG_EXIFIFD, exifOffset)).To(Succeed())
+		Expect(tiffFile.FromGoImage(ctx, createTestRGBA(4, 4), nil)).To(Succeed())
+
+		readTiff := reopenTempTestFile(ctx, tiffFile, tmpFile)
+		Expect(readTiff.TIFFReadEXIFDirectory(ctx, exifOffset)).To(Succeed())
+
+		exposureTime, err := readTiff.GetRational(ctx, libtiff.EXIFTAG_EXPOSURETIME)
+		Expect(err).To(BeNil())
+		Expect(exposureTime).To(Equal(libtiff.Rational{Numerator: 10, Denominator: 2500}))
+
+		exposureBias, err := readTiff.GetSRational(ctx, libtiff.EXIFTAG_EXPOSUREBIASVALUE)
+		Expect(err).To(BeNil())
+		Expect(exposureBias).To(Equal(libtiff.SRational{Numerator: -2, Denominator: 3}))
+	})
+
+	It("returns TagNotDefinedError for a missing tag", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(4, 4), nil)
+		defer cleanup()
+
+		_, err := tiffFile.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{Tag: libtiff.TIFFTAG_XRESOLUTION}))
+	})
+
+	It("returns an error for a tag of another type", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(4, 4), &libtiff.FromGoImageOptions{
+			XResolution: 72,
+		})
+		defer cleanup()
+
+		_, err := tiffFile.GetSRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(HaveOccurred())
+
+		_, err = tiffFile.GetRational(ctx, libtiff.TIFFTAG_IMAGEWIDTH)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error for a zero denominator", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		err := tiffFile.SetRational(ctx, libtiff.TIFFTAG_XRESOLUTION, libtiff.Rational{Numerator: 1})
+		Expect(err).To(MatchError("could not set rational: denominator is 0"))
+	})
+})
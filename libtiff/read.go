@@ -3,6 +3,7 @@ package libtiff
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
 )
@@ -221,33 +222,7 @@ func (f *File) TIFFReadRawStrip(ctx context.Context, strip uint32) ([]byte, erro
 		return nil, err
 	}
 
-	bufPointer, err := f.instance.malloc(ctx, uint64(stripSize))
-	if err != nil {
-		return nil, err
-	}
-	defer f.instance.free(ctx, bufPointer)
-
-	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFReadRawStrip", f.pointer, api.EncodeU32(strip), bufPointer, api.EncodeI32(int32(stripSize)))
-	if err != nil {
-		return nil, err
-	}
-
-	bytesRead := api.DecodeI32(results[0])
-	if bytesRead == -1 {
-		return nil, errors.New("error reading raw strip")
-	}
-
-	f.instance.internalInstance.CallLock.Lock()
-	buf, ok := f.instance.internalInstance.Module.Memory().Read(uint32(bufPointer), uint32(bytesRead))
-	if !ok {
-		f.instance.internalInstance.CallLock.Unlock()
-		return nil, errors.New("could not read raw strip data from WASM memory")
-	}
-	data := make([]byte, len(buf))
-	copy(data, buf)
-	f.instance.internalInstance.CallLock.Unlock()
-
-	return data, nil
+	return f.readRaw(ctx, "TIFFReadRawStrip", "strip", strip, stripSize)
 }
 
 // TIFFReadRawTile reads the raw (compressed) data for a tile.
@@ -258,27 +233,33 @@ func (f *File) TIFFReadRawTile(ctx context.Context, tile uint32) ([]byte, error)
 		return nil, err
 	}
 
-	bufPointer, err := f.instance.malloc(ctx, uint64(tileSize))
+	return f.readRaw(ctx, "TIFFReadRawTile", "tile", tile, tileSize)
+}
+
+// readRaw reads at most size bytes of raw data of a strip or tile with the
+// given libtiff function.
+func (f *File) readRaw(ctx context.Context, function, kind string, index uint32, size int64) ([]byte, error) {
+	bufPointer, err := f.instance.malloc(ctx, uint64(size))
 	if err != nil {
 		return nil, err
 	}
 	defer f.instance.free(ctx, bufPointer)
 
-	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFReadRawTile", f.pointer, api.EncodeU32(tile), bufPointer, api.EncodeI32(int32(tileSize)))
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, function, f.pointer, api.EncodeU32(index), bufPointer, api.EncodeI32(int32(size)))
 	if err != nil {
 		return nil, err
 	}
 
 	bytesRead := api.DecodeI32(results[0])
 	if bytesRead == -1 {
-		return nil, errors.New("error reading raw tile")
+		return nil, fmt.Errorf("error reading raw %s", kind)
 	}
 
 	f.instance.internalInstance.CallLock.Lock()
 	buf, ok := f.instance.internalInstance.Module.Memory().Read(uint32(bufPointer), uint32(bytesRead))
 	if !ok {
 		f.instance.internalInstance.CallLock.Unlock()
-		return nil, errors.New("could not read raw tile data from WASM memory")
+		return nil, fmt.Errorf("could not read raw %s data from WASM memory", kind)
 	}
 	data := make([]byte, len(buf))
 	copy(data, buf)
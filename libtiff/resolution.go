@@ -18,3 +18,19 @@ func (f *File) GetResolution(ctx context.Context) (float32, float32, error) {
 
 	return x, y, nil
 }
+
+// GetResolutionRational returns the exact XResolution and YResolution of the
+// current directory as they are stored in the file, see GetRational.
+func (f *File) GetResolutionRational(ctx context.Context) (Rational, Rational, error) {
+	x, err := f.GetRational(ctx, TIFFTAG_XRESOLUTION)
+	if err != nil {
+		return Rational{}, Rational{}, err
+	}
+
+	y, err := f.GetRational(ctx, TIFFTAG_YRESOLUTION)
+	if err != nil {
+		return Rational{}, Rational{}, err
+	}
+
+	return x, y, nil
+}
@@ -0,0 +1,711 @@
+package libtiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FlipDirection selects the axis that Flip mirrors a page along.
+type FlipDirection int
+
+const (
+	// FlipHorizontal mirrors the page left to right.
+	FlipHorizontal FlipDirection = iota
+	// FlipVertical mirrors the page top to bottom.
+	FlipVertical
+)
+
+var flipDirectionNames = map[FlipDirection]string{
+	FlipHorizontal: "horizontal",
+	FlipVertical:   "vertical",
+}
+
+// String returns the name of the direction, like "horizontal".
+func (d FlipDirection) String() string {
+	if name, ok := flipDirectionNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("FlipDirection(%d)", int(d))
+}
+
+// ParseFlipDirection parses "horizontal" or "vertical", case-insensitively.
+func ParseFlipDirection(value string) (FlipDirection, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for direction, name := range flipDirectionNames {
+		if name == value {
+			return direction, nil
+		}
+	}
+	return FlipHorizontal, fmt.Errorf("unknown flip direction %q", value)
+}
+
+// TransformStrategy selects how RotatePage and Flip change a page.
+type TransformStrategy int
+
+const (
+	// TransformOrientation only rewrites TIFFTAG_ORIENTATION of the page.
+	// The image data is not touched, so this is fast and lossless, but
+	// only viewers that honour the orientation show the page transformed.
+	TransformOrientation TransformStrategy = iota
+	// TransformPixels decodes the image data of the page, re-lays the
+	// pixels and encodes them again with the compression and parameters of
+	// the page. This is lossless for uncompressed, LZW, Deflate, PackBits,
+	// CCITT, ZSTD and LZMA data. JPEG data is compressed again, which loses
+	// quality, see TransformOptions.JPEGQuality.
+	TransformPixels
+)
+
+var transformStrategyNames = map[TransformStrategy]string{
+	TransformOrientation: "orientation",
+	TransformPixels:      "pixels",
+}
+
+// String returns the name of the strategy, like "orientation".
+func (s TransformStrategy) String() string {
+	if name, ok := transformStrategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("TransformStrategy(%d)", int(s))
+}
+
+// ParseTransformStrategy parses "orientation" or "pixels",
+// case-insensitively.
+func ParseTransformStrategy(value string) (TransformStrategy, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for strategy, name := range transformStrategyNames {
+		if name == value {
+			return strategy, nil
+		}
+	}
+	return TransformOrientation, fmt.Errorf("unknown transform strategy %q", value)
+}
+
+// TransformOptions are the options of RotatePage and Flip.
+type TransformOptions struct {
+	// Strategy selects how the page is changed, TransformOrientation by
+	// default.
+	Strategy TransformStrategy
+	// JPEGQuality is the quality level (1-100) that JPEG compressed pages
+	// are compressed with again by TransformPixels. Defaults to 90.
+	JPEGQuality int
+}
+
+func (o *TransformOptions) jpegQuality() int {
+	if o.JPEGQuality == 0 {
+		return 90
+	}
+	return o.JPEGQuality
+}
+
+// orientationMatrix maps centered pixel coordinates, with y pointing down,
+// as (u, v) -> (m[0]*u + m[1]*v, m[2]*u + m[3]*v). The matrices of the
+// orientations map the stored image to the displayed image.
+type orientationMatrix [4]int
+
+var orientationMatrices = map[Orientation]orientationMatrix{
+	ORIENTATION_TOPLEFT:  {1, 0, 0, 1},
+	ORIENTATION_TOPRIGHT: {-1, 0, 0, 1},
+	ORIENTATION_BOTRIGHT: {-1, 0, 0, -1},
+	ORIENTATION_BOTLEFT:  {1, 0, 0, -1},
+	ORIENTATION_LEFTTOP:  {0, 1, 1, 0},
+	ORIENTATION_RIGHTTOP: {0, -1, 1, 0},
+	ORIENTATION_RIGHTBOT: {0, -1, -1, 0},
+	ORIENTATION_LEFTBOT:  {0, 1, -1, 0},
+}
+
+func (m orientationMatrix) multiply(n orientationMatrix) orientationMatrix {
+	return orientationMatrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+	}
+}
+
+// inverse returns the inverse of the matrix, which is its transpose.
+func (m orientationMatrix) inverse() orientationMatrix {
+	return orientationMatrix{m[0], m[2], m[1], m[3]}
+}
+
+// transposes returns whether the matrix swaps the width and the height.
+func (m orientationMatrix) transposes() bool {
+	return m[0] == 0
+}
+
+func (m orientationMatrix) orientation() Orientation {
+	for orientation, matrix := range orientationMatrices {
+		if matrix == m {
+			return orientation
+		}
+	}
+	return ORIENTATION_TOPLEFT
+}
+
+// RotatePage rotates the page with the given index of the TIFF file in rws
+// clockwise by degrees, which must be a multiple of 90. Negative degrees
+// rotate counterclockwise. The other pages of the file are not touched.
+// Like EditFile, the changed directory is appended to the file and takes the
+// place of the old one in the chain, so the file grows.
+func (i *Instance) RotatePage(ctx context.Context, rws io.ReadWriteSeeker, page int, degrees int, options *TransformOptions) error {
+	if degrees%90 != 0 {
+		return fmt.Errorf("rotation must be a multiple of 90 degrees, got %d", degrees)
+	}
+
+	var rotation orientationMatrix
+	switch (degrees%360 + 360) % 360 {
+	case 0:
+		rotation = orientationMatrices[ORIENTATION_TOPLEFT]
+	case 90:
+		rotation = orientationMatrices[ORIENTATION_RIGHTTOP]
+	case 180:
+		rotation = orientationMatrices[ORIENTATION_BOTRIGHT]
+	case 270:
+		rotation = orientationMatrices[ORIENTATION_LEFTBOT]
+	}
+	return i.transformPage(ctx, rws, page, rotation, options)
+}
+
+// Flip mirrors the page with the given index of the TIFF file in rws in the
+// given direction. The other pages of the file are not touched. Like
+// EditFile, the changed directory is appended to the file and takes the
+// place of the old one in the chain, so the file grows.
+func (i *Instance) Flip(ctx context.Context, rws io.ReadWriteSeeker, page int, direction FlipDirection, options *TransformOptions) error {
+	switch direction {
+	case FlipHorizontal:
+		return i.transformPage(ctx, rws, page, orientationMatrices[ORIENTATION_TOPRIGHT], options)
+	case FlipVertical:
+		return i.transformPage(ctx, rws, page, orientationMatrices[ORIENTATION_BOTLEFT], options)
+	default:
+		return fmt.Errorf("unknown flip direction %s", direction)
+	}
+}
+
+// transformPage applies the transformation to the displayed image of the
+// page.
+func (i *Instance) transformPage(ctx context.Context, rws io.ReadWriteSeeker, page int, transform orientationMatrix, options *TransformOptions) error {
+	if options == nil {
+		options = &TransformOptions{}
+	}
+
+	switch options.Strategy {
+	case TransformOrientation:
+		return i.transformOrientation(ctx, rws, page, transform)
+	case TransformPixels:
+		return i.transformPixels(ctx, rws, page, transform, options)
+	default:
+		return fmt.Errorf("unknown transform strategy %s", options.Strategy)
+	}
+}
+
+// transformOrientation combines the transformation with the orientation of
+// the page.
+func (i *Instance) transformOrientation(ctx context.Context, rws io.ReadWriteSeeker, page int, transform orientationMatrix) error {
+	pages := 0
+	_, err := i.EditFile(ctx, rws, func(dir *DirectoryEditor) error {
+		pages = dir.Index() + 1
+		if dir.Index() != page || transform == orientationMatrices[ORIENTATION_TOPLEFT] {
+			return nil
+		}
+
+		orientation, err := dir.File().GetOrientation(ctx)
+		if err != nil {
+			return err
+		}
+		matrix, ok := orientationMatrices[orientation]
+		if !ok {
+			return fmt.Errorf("invalid orientation %d", orientation)
+		}
+		dir.SetUint16(TIFFTAG_ORIENTATION, uint16(transform.multiply(matrix).orientation()))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if page < 0 || page >= pages {
+		return fmt.Errorf("page %d is out of range, file has %d pages", page, pages)
+	}
+	return nil
+}
+
+// transformedTags are the tags that transformPixels sets itself.
+var transformedTags = map[TIFFTAG]bool{
+	TIFFTAG_IMAGEWIDTH:   true,
+	TIFFTAG_IMAGELENGTH:  true,
+	TIFFTAG_ROWSPERSTRIP: true,
+	TIFFTAG_XRESOLUTION:  true,
+	TIFFTAG_YRESOLUTION:  true,
+	TIFFTAG_JPEGTABLES:   true,
+}
+
+// transformCompressions are the compressions that transformPixels can
+// decode and encode again, all except JPEG without loss.
+var transformCompressions = map[Compression]bool{
+	COMPRESSION_NONE:          true,
+	COMPRESSION_CCITTRLE:      true,
+	COMPRESSION_CCITTFAX3:     true,
+	COMPRESSION_CCITTFAX4:     true,
+	COMPRESSION_LZW:           true,
+	COMPRESSION_JPEG:          true,
+	COMPRESSION_ADOBE_DEFLATE: true,
+	COMPRESSION_CCITTRLEW:     true,
+	COMPRESSION_PACKBITS:      true,
+	COMPRESSION_DEFLATE:       true,
+	COMPRESSION_LZMA:          true,
+	COMPRESSION_ZSTD:          true,
+}
+
+// transformPixels writes a transformed copy of the page to the end of the
+// file and links it into the chain in place of the page. The orientation of
+// the page is kept, so the stored pixels are transformed by the
+// transformation as seen through the orientation.
+func (i *Instance) transformPixels(ctx context.Context, rws io.ReadWriteSeeker, page int, transform orientationMatrix, options *TransformOptions) error {
+	size, err := rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	// libtiff reads the header from the current position.
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	src, err := i.TIFFOpenFileFromReader(ctx, "transform.tif", rws, uint64(size), nil)
+	if err != nil {
+		return err
+	}
+	defer src.Close(ctx)
+
+	count, err := src.TIFFNumberOfDirectories(ctx)
+	if err != nil {
+		return err
+	}
+	if page < 0 || page >= int(count) {
+		return fmt.Errorf("page %d is out of range, file has %d pages", page, count)
+	}
+	if transform == orientationMatrices[ORIENTATION_TOPLEFT] {
+		return nil
+	}
+
+	if err := src.TIFFSetDirectory(ctx, uint32(page)); err != nil {
+		return err
+	}
+	directory, err := src.currentCopiedDirectory(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkTransform(ctx, i, directory); err != nil {
+		return fmt.Errorf("could not transform page %d: %w", page, err)
+	}
+
+	orientation := orientationMatrices[directory.info.Image.Orientation]
+	stored := orientation.inverse().multiply(transform).multiply(orientation)
+
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dst, err := i.TIFFOpenFileFromReadWriteSeeker(ctx, "transform.tif", rws, uint64(size), &OpenOptions{
+		Mode: &OpenMode{Access: OpenAppend},
+	})
+	if err != nil {
+		return err
+	}
+	err = src.writeDirectoryCopy(ctx, dst, directory, transformedTags, nil, func(ctx context.Context, copied *copiedDirectory) error {
+		return src.writeTransformedImage(ctx, dst, copied, stored, options)
+	})
+	closeErr := dst.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("could not transform page %d: %w", page, err)
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return replaceWithLastDirectory(rws, page)
+}
+
+// checkTransform returns an error when the image data of the directory or
+// one of its SubIFDs can't be decoded and encoded again.
+func checkTransform(ctx context.Context, instance *Instance, directory *copiedDirectory) error {
+	for _, d := range append([]*copiedDirectory{directory}, directory.subIFDs...) {
+		image := d.info.Image
+		if image == nil {
+			return errors.New("directory has no image")
+		}
+		if _, ok := orientationMatrices[image.Orientation]; !ok {
+			return fmt.Errorf("invalid orientation %d", image.Orientation)
+		}
+		if !transformCompressions[image.Compression] {
+			return fmt.Errorf("image data with compression %s can't be encoded again", image.Compression)
+		}
+		if err := checkPlanes(ctx, instance, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPlanes returns an error when readPlanes can't decode the image.
+func checkPlanes(ctx context.Context, instance *Instance, image *ImageInfo) error {
+	if image.Compression == COMPRESSION_OJPEG {
+		return errors.New("old-style JPEG compression is not supported")
+	}
+	configured, err := instance.TIFFIsCODECConfigured(ctx, uint16(image.Compression))
+	if err != nil {
+		return err
+	}
+	if !configured {
+		return fmt.Errorf("compression %s is not configured", image.Compression)
+	}
+	for _, bits := range image.BitsPerSample {
+		if bits != image.BitsPerSample[0] {
+			return errors.New("samples with different bit depths are not supported")
+		}
+	}
+
+	// libtiff only converts subsampled YCbCr data to RGB for JPEG.
+	subsampling := image.Codec.YCbCrSubsampling
+	subsampled := len(subsampling) != 2 || subsampling[0] != 1 || subsampling[1] != 1
+	if isYCbCr(image) && image.Compression != COMPRESSION_JPEG && subsampled {
+		return errors.New("subsampled YCbCr data is only supported with JPEG compression")
+	}
+	return nil
+}
+
+func isYCbCr(image *ImageInfo) bool {
+	return image.Photometric != nil && *image.Photometric == PHOTOMETRIC_YCBCR
+}
+
+// writeTransformedImage sets the tags that change with the transformation
+// in dst and writes the transformed image data of the current directory of
+// f.
+func (f *File) writeTransformedImage(ctx context.Context, dst *File, copied *copiedDirectory, transform orientationMatrix, options *TransformOptions) error {
+	image := copied.info.Image
+	planes, err := f.readPlanes(ctx, image)
+	if err != nil {
+		return err
+	}
+	for n := range planes {
+		planes[n] = planes[n].transform(transform)
+	}
+	width, height := planes[0].width, planes[0].height
+
+	if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_IMAGEWIDTH, uint32(width)); err != nil {
+		return err
+	}
+	if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_IMAGELENGTH, uint32(height)); err != nil {
+		return err
+	}
+	xResolution, yResolution := TIFFTAG_XRESOLUTION, TIFFTAG_YRESOLUTION
+	if transform.transposes() {
+		xResolution, yResolution = yResolution, xResolution
+	}
+	if err := dst.setTransformedResolution(ctx, copied, TIFFTAG_XRESOLUTION, xResolution); err != nil {
+		return err
+	}
+	if err := dst.setTransformedResolution(ctx, copied, TIFFTAG_YRESOLUTION, yResolution); err != nil {
+		return err
+	}
+
+	if image.Compression == COMPRESSION_JPEG {
+		if err := dst.TIFFSetFieldInt(ctx, TIFFTAG_JPEGQUALITY, options.jpegQuality()); err != nil {
+			return err
+		}
+		if isYCbCr(image) {
+			if err := dst.TIFFSetFieldInt(ctx, TIFFTAG_JPEGCOLORMODE, int(JPEGCOLORMODE_RGB)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if image.Tiled {
+		return dst.writeTiledPlanes(ctx, planes, image.TileWidth, image.TileHeight)
+	}
+
+	// A single strip stays a single strip, otherwise the strips keep their
+	// height.
+	rowsPerStrip := image.RowsPerStrip
+	if rowsPerStrip >= image.Height {
+		rowsPerStrip = uint32(height)
+	}
+	if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_ROWSPERSTRIP, rowsPerStrip); err != nil {
+		return err
+	}
+	return dst.writeStripPlanes(ctx, planes, int(rowsPerStrip))
+}
+
+// setTransformedResolution sets the resolution tag to the value of the
+// resolution tag from of the directory, with its exact fraction.
+func (f *File) setTransformedResolution(ctx context.Context, copied *copiedDirectory, tag, from TIFFTAG) error {
+	for _, info := range copied.info.Tags {
+		if TIFFTAG(info.Tag) != from {
+			continue
+		}
+		values := tagValueFloats(info.Value)
+		if len(values) != 1 {
+			return nil
+		}
+		if rational, ok := copied.rationals[uint16(from)]; ok {
+			return f.setRational(ctx, tag, values[0], rational)
+		}
+		return f.TIFFSetFieldDouble(ctx, tag, values[0])
+	}
+	return nil
+}
+
+// pixelPlane holds the decoded pixels of one plane of an image, with rows
+// that start on a byte boundary.
+type pixelPlane struct {
+	data          []byte
+	width, height int
+	pixelBits     int
+	rowSize       int
+}
+
+func newPixelPlane(width, height, pixelBits int) *pixelPlane {
+	rowSize := (width*pixelBits + 7) / 8
+	return &pixelPlane{
+		data:      make([]byte, rowSize*height),
+		width:     width,
+		height:    height,
+		pixelBits: pixelBits,
+		rowSize:   rowSize,
+	}
+}
+
+// transform returns the plane with its pixels transformed.
+func (p *pixelPlane) transform(transform orientationMatrix) *pixelPlane {
+	width, height := p.width, p.height
+	if transform.transposes() {
+		width, height = height, width
+	}
+	result := newPixelPlane(width, height, p.pixelBits)
+
+	// Walk the pixels of the result in doubled centered coordinates and
+	// find their source with the inverse transformation.
+	inverse := transform.inverse()
+	for y := 0; y < height; y++ {
+		u, v := -(width - 1), 2*y-(height-1)
+		sourceX := (inverse[0]*u + inverse[1]*v + p.width - 1) / 2
+		sourceY := (inverse[2]*u + inverse[3]*v + p.height - 1) / 2
+		row := result.data[y*result.rowSize:]
+		for x := 0; x < width; x++ {
+			copyBits(row, x*p.pixelBits, p.data[sourceY*p.rowSize:], sourceX*p.pixelBits, p.pixelBits)
+			sourceX += inverse[0]
+			sourceY += inverse[2]
+		}
+	}
+	return result
+}
+
+// copyBits copies n bits from src, starting at bit srcBit, to dst, starting
+// at bit dstBit. Bits are counted from the most significant bit of a byte.
+func copyBits(dst []byte, dstBit int, src []byte, srcBit int, n int) {
+	if dstBit%8 == 0 && srcBit%8 == 0 && n%8 == 0 {
+		copy(dst[dstBit/8:dstBit/8+n/8], src[srcBit/8:])
+		return
+	}
+	for i := 0; i < n; i++ {
+		s, d := srcBit+i, dstBit+i
+		mask := byte(0x80) >> (d % 8)
+		if src[s/8]&(0x80>>(s%8)) != 0 {
+			dst[d/8] |= mask
+		} else {
+			dst[d/8] &^= mask
+		}
+	}
+}
+
+// readPlanes decodes the image data of the current directory. JPEG
+// compressed YCbCr data is decoded as RGB.
+func (f *File) readPlanes(ctx context.Context, image *ImageInfo) ([]*pixelPlane, error) {
+	if image.Compression == COMPRESSION_JPEG && isYCbCr(image) {
+		// Let libtiff convert the YCbCr data to RGB, so the pixels are not
+		// subsampled.
+		if err := f.TIFFSetFieldInt(ctx, TIFFTAG_JPEGCOLORMODE, int(JPEGCOLORMODE_RGB)); err != nil {
+			return nil, err
+		}
+	}
+
+	width, height := int(image.Width), int(image.Height)
+	bitsPerSample := int(image.BitsPerSample[0])
+	samples := int(image.SamplesPerPixel)
+
+	planes := []*pixelPlane{newPixelPlane(width, height, bitsPerSample*samples)}
+	if image.PlanarConfig == uint16(PLANARCONFIG_SEPARATE) {
+		planes = make([]*pixelPlane, samples)
+		for n := range planes {
+			planes[n] = newPixelPlane(width, height, bitsPerSample)
+		}
+	}
+
+	for n, plane := range planes {
+		if !image.Tiled {
+			rowsPerStrip := int(min(image.RowsPerStrip, image.Height))
+			for row := 0; row < height; row += rowsPerStrip {
+				strip, err := f.TIFFComputeStrip(ctx, uint32(row), uint16(n))
+				if err != nil {
+					return nil, err
+				}
+				data, err := f.TIFFReadEncodedStrip(ctx, strip)
+				if err != nil {
+					return nil, err
+				}
+				copy(plane.data[row*plane.rowSize:], data)
+			}
+			continue
+		}
+
+		tileWidth, tileHeight := int(image.TileWidth), int(image.TileHeight)
+		tileRowSize := (tileWidth*plane.pixelBits + 7) / 8
+		for y := 0; y < height; y += tileHeight {
+			for x := 0; x < width; x += tileWidth {
+				tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, uint16(n))
+				if err != nil {
+					return nil, err
+				}
+				data, err := f.TIFFReadEncodedTile(ctx, tile)
+				if err != nil {
+					return nil, err
+				}
+				columns := min(tileWidth, width-x)
+				for row := 0; row < tileHeight && y+row < height && (row+1)*tileRowSize <= len(data); row++ {
+					copyBits(plane.data[(y+row)*plane.rowSize:], x*plane.pixelBits, data[row*tileRowSize:], 0, columns*plane.pixelBits)
+				}
+			}
+		}
+	}
+
+	return planes, nil
+}
+
+// writeStripPlanes encodes the planes as strips of the given height.
+func (f *File) writeStripPlanes(ctx context.Context, planes []*pixelPlane, rowsPerStrip int) error {
+	for n, plane := range planes {
+		for row := 0; row < plane.height; row += rowsPerStrip {
+			strip, err := f.TIFFComputeStrip(ctx, uint32(row), uint16(n))
+			if err != nil {
+				return err
+			}
+			end := min(row+rowsPerStrip, plane.height)
+			if err := f.TIFFWriteEncodedStrip(ctx, strip, plane.data[row*plane.rowSize:end*plane.rowSize]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTiledPlanes encodes the planes as tiles of the given size.
+func (f *File) writeTiledPlanes(ctx context.Context, planes []*pixelPlane, tileWidth, tileHeight uint32) error {
+	for n, plane := range planes {
+		width, height := int(tileWidth), int(tileHeight)
+		tileRowSize := (width*plane.pixelBits + 7) / 8
+		for y := 0; y < plane.height; y += height {
+			for x := 0; x < plane.width; x += width {
+				data := make([]byte, tileRowSize*height)
+				columns := min(width, plane.width-x)
+				for row := 0; row < height && y+row < plane.height; row++ {
+					copyBits(data[row*tileRowSize:], 0, plane.data[(y+row)*plane.rowSize:], x*plane.pixelBits, columns*plane.pixelBits)
+				}
+
+				tile, err := f.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, uint16(n))
+				if err != nil {
+					return err
+				}
+				if err := f.TIFFWriteEncodedTile(ctx, tile, data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// replaceWithLastDirectory moves the last directory of the main chain to the
+// place of the directory with the given index, which drops out of the
+// chain.
+func replaceWithLastDirectory(rws io.ReadWriteSeeker, index int) error {
+	d, err := newDescriber(rws)
+	if err != nil {
+		return err
+	}
+	offset, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+
+	// pointers are the positions of the offsets of the directories, the
+	// first one is in the header.
+	pointer := uint64(4)
+	if d.bigTIFF {
+		pointer = 8
+	}
+	var offsets, pointers []uint64
+	for offset != 0 {
+		if d.visited[offset] {
+			return fmt.Errorf("directory loop at offset %d", offset)
+		}
+		d.visited[offset] = true
+		offsets = append(offsets, offset)
+		pointers = append(pointers, pointer)
+
+		count, entrySize, err := d.readEntryCount(offset)
+		if err != nil {
+			return err
+		}
+		pointer = offset + d.countSize() + count*entrySize
+		offset, err = d.readOffset(pointer)
+		if err != nil {
+			return err
+		}
+	}
+
+	last := len(offsets) - 1
+	if index >= last {
+		return fmt.Errorf("directory %d is not followed by the new directory", index)
+	}
+
+	if err := d.writeOffset(rws, pointers[index], offsets[last]); err != nil {
+		return err
+	}
+	if index+1 == last {
+		return nil
+	}
+	if err := d.writeOffset(rws, pointer, offsets[index+1]); err != nil {
+		return err
+	}
+	return d.writeOffset(rws, pointers[last], 0)
+}
+
+// readOffset reads the directory offset at the given position.
+func (d *describer) readOffset(position uint64) (uint64, error) {
+	if d.bigTIFF {
+		data, err := d.readAt(position, 8)
+		if err != nil {
+			return 0, err
+		}
+		return d.order.Uint64(data), nil
+	}
+	data, err := d.readAt(position, 4)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(d.order.Uint32(data)), nil
+}
+
+// writeOffset writes the directory offset at the given position.
+func (d *describer) writeOffset(writer io.WriteSeeker, position, offset uint64) error {
+	data := make([]byte, 8)
+	if d.bigTIFF {
+		d.order.PutUint64(data, offset)
+	} else {
+		data = data[:4]
+		d.order.PutUint32(data, uint32(offset))
+	}
+	if _, err := writer.Seek(int64(position), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("could not write directory offset: %w", err)
+	}
+	return nil
+}
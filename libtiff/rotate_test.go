@@ -0,0 +1,317 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sourcePixel returns the pixel of an image of width by height that ends up
+// at x, y after a transformation.
+type sourcePixel func(x, y, width, height int) (int, int)
+
+var (
+	rotated90 sourcePixel = func(x, y, width, height int) (int, int) {
+		return y, height - 1 - x
+	}
+	rotated180 sourcePixel = func(x, y, width, height int) (int, int) {
+		return width - 1 - x, height - 1 - y
+	}
+	rotated270 sourcePixel = func(x, y, width, height int) (int, int) {
+		return width - 1 - y, x
+	}
+	flippedHorizontal sourcePixel = func(x, y, width, height int) (int, int) {
+		return width - 1 - x, y
+	}
+	flippedVertical sourcePixel = func(x, y, width, height int) (int, int) {
+		return x, height - 1 - y
+	}
+)
+
+var _ = Describe("RotatePage and Flip", func() {
+	ctx := context.Background()
+
+	// writeTransformTestFile calls write with a file opened for writing and
+	// returns the path of the file.
+	writeTransformTestFile := func(write func(tiffFile *libtiff.File)) string {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		write(tiffFile)
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+		return tmpFile.Name()
+	}
+
+	writeImages := func(img image.Image, options ...*libtiff.FromGoImageOptions) string {
+		return writeTransformTestFile(func(tiffFile *libtiff.File) {
+			for _, pageOptions := range options {
+				Expect(tiffFile.FromGoImage(ctx, img, pageOptions)).To(Succeed())
+			}
+		})
+	}
+
+	transformFile := func(path string, transform func(file *os.File) error) error {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		Expect(err).To(BeNil())
+		defer file.Close()
+		return transform(file)
+	}
+
+	openTransformed := func(path string) *libtiff.File {
+		file, err := os.Open(path)
+		Expect(err).To(BeNil())
+		DeferCleanup(file.Close)
+		stat, err := file.Stat()
+		Expect(err).To(BeNil())
+
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", file, uint64(stat.Size()), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(tiffFile.Close, ctx)
+		return tiffFile
+	}
+
+	readPage := func(path string, page int) image.Image {
+		tiffFile := openTransformed(path)
+		Expect(tiffFile.TIFFSetDirectory(ctx, uint32(page))).To(Succeed())
+		img, cleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		DeferCleanup(cleanup, ctx)
+		return img
+	}
+
+	// expectTransformed compares every pixel of after with its source pixel
+	// in before, the color channels may differ by tolerance.
+	expectTransformed := func(before, after image.Image, source sourcePixel, tolerance int) {
+		width, height := before.Bounds().Dx(), before.Bounds().Dy()
+		for y := 0; y < after.Bounds().Dy(); y++ {
+			for x := 0; x < after.Bounds().Dx(); x++ {
+				sourceX, sourceY := source(x, y, width, height)
+				r1, g1, b1, a1 := before.At(sourceX, sourceY).RGBA()
+				r2, g2, b2, a2 := after.At(x, y).RGBA()
+				for i, pair := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+					difference := int(pair[0]>>8) - int(pair[1]>>8)
+					Expect(difference).To(BeNumerically("~", 0, tolerance), "channel %d of pixel %d,%d", i, x, y)
+				}
+			}
+		}
+	}
+
+	// otherDirectories returns the directories of the file without the one
+	// with the given index.
+	otherDirectories := func(path string, page int) []libtiff.DirectoryInfo {
+		info, err := openTransformed(path).Describe(ctx)
+		Expect(err).To(BeNil())
+		directories := append([]libtiff.DirectoryInfo{}, info.Directories[:page]...)
+		return append(directories, info.Directories[page+1:]...)
+	}
+
+	pixels := &libtiff.TransformOptions{Strategy: libtiff.TransformPixels}
+
+	It("rotates and flips by changing the orientation", func() {
+		path := writeImages(createTestGray(24, 16), &libtiff.FromGoImageOptions{}, &libtiff.FromGoImageOptions{}, &libtiff.FromGoImageOptions{})
+		before, err := openTransformed(path).Describe(ctx)
+		Expect(err).To(BeNil())
+
+		orientation := func() libtiff.Orientation {
+			tiffFile := openTransformed(path)
+			Expect(tiffFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+			orientation, err := tiffFile.GetOrientation(ctx)
+			Expect(err).To(BeNil())
+			return orientation
+		}
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, 90, nil)
+		})).To(Succeed())
+		Expect(orientation()).To(Equal(libtiff.ORIENTATION_RIGHTTOP))
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, -270, nil)
+		})).To(Succeed())
+		Expect(orientation()).To(Equal(libtiff.ORIENTATION_BOTRIGHT))
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.Flip(ctx, file, 1, libtiff.FlipVertical, nil)
+		})).To(Succeed())
+		Expect(orientation()).To(Equal(libtiff.ORIENTATION_TOPRIGHT))
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.Flip(ctx, file, 1, libtiff.FlipHorizontal, nil)
+		})).To(Succeed())
+		Expect(orientation()).To(Equal(libtiff.ORIENTATION_TOPLEFT))
+
+		after, err := openTransformed(path).Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(after.Directories[1].Image.Offsets).To(Equal(before.Directories[1].Image.Offsets))
+		Expect(otherDirectories(path, 1)).To(Equal([]libtiff.DirectoryInfo{before.Directories[0], before.Directories[2]}))
+	})
+
+	DescribeTable("re-lays the pixels without loss",
+		func(img image.Image, options *libtiff.FromGoImageOptions, transform func(file *os.File) error, source sourcePixel, transposed bool) {
+			path := writeImages(img, &libtiff.FromGoImageOptions{PageName: "first"}, options, &libtiff.FromGoImageOptions{PageName: "last"})
+			expected := readPage(path, 1)
+			others := otherDirectories(path, 1)
+			before, err := openTransformed(path).Describe(ctx)
+			Expect(err).To(BeNil())
+
+			Expect(transformFile(path, transform)).To(Succeed())
+
+			after, err := openTransformed(path).Describe(ctx)
+			Expect(err).To(BeNil())
+			Expect(after.Directories).To(HaveLen(3))
+			Expect(otherDirectories(path, 1)).To(Equal(others))
+
+			page := after.Directories[1].Image
+			Expect(page.Compression).To(Equal(before.Directories[1].Image.Compression))
+			Expect(page.Tiled).To(Equal(before.Directories[1].Image.Tiled))
+			Expect(page.BitsPerSample).To(Equal(before.Directories[1].Image.BitsPerSample))
+			Expect(page.Orientation).To(Equal(libtiff.ORIENTATION_TOPLEFT))
+			expectedWidth, expectedHeight := uint32(expected.Bounds().Dx()), uint32(expected.Bounds().Dy())
+			if transposed {
+				expectedWidth, expectedHeight = expectedHeight, expectedWidth
+			}
+			Expect([]uint32{page.Width, page.Height}).To(Equal([]uint32{expectedWidth, expectedHeight}))
+
+			expectTransformed(expected, readPage(path, 1), source, 0)
+		},
+		Entry("rotating LZW gray by 90 degrees", createTestGray(40, 24), &libtiff.FromGoImageOptions{
+			Compression:  libtiff.COMPRESSION_LZW,
+			Predictor:    libtiff.PREDICTOR_HORIZONTAL,
+			RowsPerStrip: 8,
+		}, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, 90, pixels)
+		}, rotated90, true),
+		Entry("rotating tiled Deflate RGBA by 180 degrees", createTestRGBA(40, 24), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			TileWidth:   16,
+			TileHeight:  16,
+		}, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, 180, pixels)
+		}, rotated180, false),
+		Entry("rotating tiled PackBits RGBA by 270 degrees", createTestRGBA(40, 24), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_PACKBITS,
+			TileWidth:   16,
+			TileHeight:  16,
+		}, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, -90, pixels)
+		}, rotated270, true),
+		Entry("rotating CCITT fax by 90 degrees", createTestGray(45, 30), &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_CCITTFAX4,
+		}, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, 90, pixels)
+		}, rotated90, true),
+		Entry("flipping uncompressed 1-bit palette data horizontally", createTestPaletted(45, 30, 2), &libtiff.FromGoImageOptions{}, func(file *os.File) error {
+			return instance.Flip(ctx, file, 1, libtiff.FlipHorizontal, pixels)
+		}, flippedHorizontal, false),
+		Entry("flipping uncompressed gray vertically", createTestGray(40, 24), &libtiff.FromGoImageOptions{}, func(file *os.File) error {
+			return instance.Flip(ctx, file, 1, libtiff.FlipVertical, pixels)
+		}, flippedVertical, false),
+	)
+
+	It("rotates separate planes", func() {
+		data := createTestElevation(20, 12, 3)
+		path := writeTransformTestFile(func(tiffFile *libtiff.File) {
+			Expect(tiffFile.WriteRaster(ctx, data, 20, 12, 3, &libtiff.RasterOptions{
+				Compression:  libtiff.COMPRESSION_LZW,
+				PlanarConfig: libtiff.PLANARCONFIG_SEPARATE,
+				TileWidth:    16,
+				TileHeight:   16,
+			})).To(Succeed())
+		})
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 0, 90, pixels)
+		})).To(Succeed())
+
+		raster, err := openTransformed(path).ReadRaster(ctx)
+		Expect(err).To(BeNil())
+		Expect([]int{raster.Width, raster.Height}).To(Equal([]int{12, 20}))
+
+		expected := make([]float32, len(data))
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 12; x++ {
+				sourceX, sourceY := rotated90(x, y, 20, 12)
+				copy(expected[(y*12+x)*3:(y*12+x+1)*3], data[(sourceY*20+sourceX)*3:])
+			}
+		}
+		expectSameFloats(raster.Data, expected)
+	})
+
+	It("compresses JPEG pages again", func() {
+		img := createTestRGBA(48, 32)
+		path := writeImages(img, &libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_JPEG, Quality: 95})
+		expected := readPage(path, 0)
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 0, 90, &libtiff.TransformOptions{Strategy: libtiff.TransformPixels, JPEGQuality: 95})
+		})).To(Succeed())
+
+		tiffFile := openTransformed(path)
+		compression, err := tiffFile.GetCompression(ctx)
+		Expect(err).To(BeNil())
+		Expect(compression).To(Equal(libtiff.COMPRESSION_JPEG))
+		expectTransformed(expected, readPage(path, 0), rotated90, 12)
+	})
+
+	It("keeps SubIFDs, EXIF data and the exact resolution", func() {
+		path := writeImages(createTestGray(64, 32), &libtiff.FromGoImageOptions{
+			XResolutionRational: libtiff.Rational{Numerator: 600, Denominator: 1},
+			YResolutionRational: libtiff.Rational{Numerator: 1, Denominator: 3},
+			ResolutionUnit:      libtiff.RESUNIT_INCH,
+			Exif:                &libtiff.Exif{LensModel: "lens"},
+			Overviews:           &libtiff.Overviews{Factors: []int{2}, SubIFDs: true},
+		})
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 0, 270, pixels)
+		})).To(Succeed())
+
+		tiffFile := openTransformed(path)
+		info, err := tiffFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(info.Directories).To(HaveLen(1))
+		Expect(info.Directories[0].Exif).NotTo(BeNil())
+		Expect(info.Directories[0].SubIFDs).To(HaveLen(1))
+		overview := info.Directories[0].SubIFDs[0].Image
+		Expect([]uint32{overview.Width, overview.Height}).To(Equal([]uint32{16, 32}))
+
+		xResolution, err := tiffFile.GetRational(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(libtiff.Rational{Numerator: 1, Denominator: 3}))
+		yResolution, err := tiffFile.GetRational(ctx, libtiff.TIFFTAG_YRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(yResolution).To(Equal(libtiff.Rational{Numerator: 600, Denominator: 1}))
+	})
+
+	It("rejects invalid rotations and pages", func() {
+		path := writeImages(createTestGray(8, 8), &libtiff.FromGoImageOptions{})
+
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 0, 45, nil)
+		})).To(MatchError("rotation must be a multiple of 90 degrees, got 45"))
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.RotatePage(ctx, file, 1, 90, nil)
+		})).To(MatchError("page 1 is out of range, file has 1 pages"))
+		Expect(transformFile(path, func(file *os.File) error {
+			return instance.Flip(ctx, file, -1, libtiff.FlipVertical, pixels)
+		})).To(MatchError("page -1 is out of range, file has 1 pages"))
+	})
+
+	It("parses the flip directions and strategies", func() {
+		direction, err := libtiff.ParseFlipDirection(" Vertical ")
+		Expect(err).To(BeNil())
+		Expect(direction).To(Equal(libtiff.FlipVertical))
+		Expect(direction.String()).To(Equal("vertical"))
+		_, err = libtiff.ParseFlipDirection("diagonal")
+		Expect(err).To(MatchError(`unknown flip direction "diagonal"`))
+
+		strategy, err := libtiff.ParseTransformStrategy("PIXELS")
+		Expect(err).To(BeNil())
+		Expect(strategy).To(Equal(libtiff.TransformPixels))
+		Expect(libtiff.TransformOrientation.String()).To(Equal("orientation"))
+	})
+})
@@ -0,0 +1,136 @@
+package libtiff
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// sampleLayout describes how an image type that is not written as 8-bit
+// RGB(A) is stored in samples.
+type sampleLayout struct {
+	bitsPerSample   uint16
+	samplesPerPixel uint16
+	photometric     Photometric
+	// extraSample is the type of the alpha sample, nil without alpha.
+	extraSample *TIFFTAG
+	// fill writes the samples of count pixels starting at x, y to data.
+	// 16-bit samples are written in the byte order of the host, libtiff
+	// swaps them when the file has another byte order.
+	fill func(data []byte, x, y, count int)
+}
+
+// newSampleLayout returns the sample layout for gray, 16-bit and CMYK
+// images, or nil when the image is written as 8-bit RGB(A). JPEG only
+// supports 8-bit samples, so 16-bit gray is reduced to 8 bits and 16-bit
+// colour is written as 8-bit RGB.
+func newSampleLayout(img image.Image, alphaMode AlphaMode, isJPEG bool) *sampleLayout {
+	switch src := img.(type) {
+	case *image.Gray:
+		return &sampleLayout{
+			bitsPerSample:   8,
+			samplesPerPixel: 1,
+			photometric:     PHOTOMETRIC_MINISBLACK,
+			fill: func(data []byte, x, y, count int) {
+				offset := src.PixOffset(x, y)
+				copy(data, src.Pix[offset:offset+count])
+			},
+		}
+	case *image.Gray16:
+		if isJPEG {
+			return &sampleLayout{
+				bitsPerSample:   8,
+				samplesPerPixel: 1,
+				photometric:     PHOTOMETRIC_MINISBLACK,
+				fill: func(data []byte, x, y, count int) {
+					offset := src.PixOffset(x, y)
+					for i := 0; i < count; i++ {
+						data[i] = src.Pix[offset+i*2]
+					}
+				},
+			}
+		}
+		return &sampleLayout{
+			bitsPerSample:   16,
+			samplesPerPixel: 1,
+			photometric:     PHOTOMETRIC_MINISBLACK,
+			fill: func(data []byte, x, y, count int) {
+				offset := src.PixOffset(x, y)
+				swapUint16Samples(data, src.Pix[offset:offset+count*2])
+			},
+		}
+	case *image.CMYK:
+		return &sampleLayout{
+			bitsPerSample:   8,
+			samplesPerPixel: 4,
+			photometric:     PHOTOMETRIC_SEPARATED,
+			fill: func(data []byte, x, y, count int) {
+				offset := src.PixOffset(x, y)
+				copy(data, src.Pix[offset:offset+count*4])
+			},
+		}
+	case *image.RGBA64, *image.NRGBA64:
+		if isJPEG {
+			return nil
+		}
+		return newRGBA64SampleLayout(img, alphaMode)
+	}
+
+	return nil
+}
+
+// newRGBA64SampleLayout returns the layout for 16-bit RGBA, the pixels are
+// copied directly when the image already has the requested alpha mode.
+func newRGBA64SampleLayout(img image.Image, alphaMode AlphaMode) *sampleLayout {
+	extraSample := EXTRASAMPLE_ASSOCALPHA
+	if alphaMode == AlphaUnassociated {
+		extraSample = EXTRASAMPLE_UNASSALPHA
+	}
+	layout := &sampleLayout{
+		bitsPerSample:   16,
+		samplesPerPixel: 4,
+		photometric:     PHOTOMETRIC_RGB,
+		extraSample:     &extraSample,
+	}
+
+	if rgba64Img, ok := img.(*image.RGBA64); ok && alphaMode == AlphaAssociated {
+		layout.fill = func(data []byte, x, y, count int) {
+			offset := rgba64Img.PixOffset(x, y)
+			swapUint16Samples(data, rgba64Img.Pix[offset:offset+count*8])
+		}
+		return layout
+	}
+	if nrgba64Img, ok := img.(*image.NRGBA64); ok && alphaMode == AlphaUnassociated {
+		layout.fill = func(data []byte, x, y, count int) {
+			offset := nrgba64Img.PixOffset(x, y)
+			swapUint16Samples(data, nrgba64Img.Pix[offset:offset+count*8])
+		}
+		return layout
+	}
+
+	layout.fill = func(data []byte, x, y, count int) {
+		for i := 0; i < count; i++ {
+			var r, g, b, a uint32
+			if alphaMode == AlphaUnassociated {
+				c := color.NRGBA64Model.Convert(img.At(x+i, y)).(color.NRGBA64)
+				r, g, b, a = uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+			} else {
+				r, g, b, a = img.At(x+i, y).RGBA()
+			}
+			pixel := data[i*8 : i*8+8]
+			binary.LittleEndian.PutUint16(pixel, uint16(r))
+			binary.LittleEndian.PutUint16(pixel[2:], uint16(g))
+			binary.LittleEndian.PutUint16(pixel[4:], uint16(b))
+			binary.LittleEndian.PutUint16(pixel[6:], uint16(a))
+		}
+	}
+	return layout
+}
+
+// swapUint16Samples copies the big-endian 16-bit samples of a Go image to
+// data in the little-endian byte order of the WASM host.
+func swapUint16Samples(data []byte, pix []byte) {
+	for i := 0; i+1 < len(pix); i += 2 {
+		data[i], data[i+1] = pix[i+1], pix[i]
+	}
+}
@@ -0,0 +1,315 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func createTestGray(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8((x*7 + y*3) % 256)})
+		}
+	}
+	return img
+}
+
+func createTestGray16(width, height int) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray16(x, y, color.Gray16{Y: uint16((x*1031 + y*257) % 65536)})
+		}
+	}
+	return img
+}
+
+func createTestRGBA64(width, height int) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(x * 1000),
+				G: uint16(y * 1000),
+				B: uint16((x + y) * 500),
+				A: 65535,
+			})
+		}
+	}
+	return img
+}
+
+func createTestNRGBA64(width, height int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(x * 1000),
+				G: uint16(y * 1000),
+				B: uint16((x + y) * 500),
+				A: uint16(30000 + x*100),
+			})
+		}
+	}
+	return img
+}
+
+func createTestCMYK(width, height int) *image.CMYK {
+	img := image.NewCMYK(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetCMYK(x, y, color.CMYK{
+				C: uint8(x * 10),
+				M: uint8(y * 10),
+				Y: uint8((x + y) * 5),
+				K: uint8(x + y),
+			})
+		}
+	}
+	return img
+}
+
+// swapBytes returns the big-endian 16-bit samples of a Go image in the
+// little-endian order libtiff returns them in.
+func swapBytes(pix []byte) []byte {
+	swapped := make([]byte, len(pix))
+	for i := 0; i+1 < len(pix); i += 2 {
+		swapped[i], swapped[i+1] = pix[i+1], pix[i]
+	}
+	return swapped
+}
+
+var _ = Describe("FromGoImage sample layouts", func() {
+	ctx := context.Background()
+
+	// readStrips returns the decoded data of all strips.
+	readStrips := func(tiffFile *libtiff.File) []byte {
+		strips, err := tiffFile.TIFFNumberOfStrips(ctx)
+		Expect(err).To(BeNil())
+
+		var data []byte
+		for strip := uint32(0); strip < strips; strip++ {
+			stripData, err := tiffFile.TIFFReadEncodedStrip(ctx, strip)
+			Expect(err).To(BeNil())
+			data = append(data, stripData...)
+		}
+		return data
+	}
+
+	// readTiles returns the decoded data of all tiles, assembled into rows.
+	readTiles := func(tiffFile *libtiff.File, bytesPerPixel int) []byte {
+		width, height, err := tiffFile.GetDimensions(ctx)
+		Expect(err).To(BeNil())
+		tileWidth, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_TILEWIDTH)
+		Expect(err).To(BeNil())
+		tileHeight, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_TILELENGTH)
+		Expect(err).To(BeNil())
+
+		tileRowSize := int(tileWidth) * bytesPerPixel
+		data := make([]byte, width*height*bytesPerPixel)
+		for y := 0; y < height; y += int(tileHeight) {
+			for x := 0; x < width; x += int(tileWidth) {
+				tile, err := tiffFile.TIFFComputeTile(ctx, uint32(x), uint32(y), 0, 0)
+				Expect(err).To(BeNil())
+				tileData, err := tiffFile.TIFFReadEncodedTile(ctx, tile)
+				Expect(err).To(BeNil())
+
+				cols := min(int(tileWidth), width-x)
+				for row := 0; row < int(tileHeight) && y+row < height; row++ {
+					offset := ((y+row)*width + x) * bytesPerPixel
+					copy(data[offset:offset+cols*bytesPerPixel], tileData[row*tileRowSize:])
+				}
+			}
+		}
+		return data
+	}
+
+	expectLayout := func(tiffFile *libtiff.File, photometric libtiff.Photometric, bitsPerSample, samplesPerPixel uint16) {
+		readPhotometric, err := tiffFile.GetPhotometric(ctx)
+		Expect(err).To(BeNil())
+		Expect(readPhotometric).To(Equal(photometric))
+
+		bps, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_BITSPERSAMPLE)
+		Expect(err).To(BeNil())
+		Expect(bps).To(Equal(bitsPerSample))
+
+		spp, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_SAMPLESPERPIXEL)
+		Expect(err).To(BeNil())
+		Expect(spp).To(Equal(samplesPerPixel))
+	}
+
+	// expectRendered compares the rendered RGBA image with the source image
+	// with the given tolerance per channel.
+	expectRendered := func(tiffFile *libtiff.File, img image.Image, tolerance int) {
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+
+		bounds := img.Bounds()
+		Expect(goImage.Bounds()).To(Equal(bounds))
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				expected := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				actual := color.RGBAModel.Convert(goImage.At(x, y)).(color.RGBA)
+				Expect(int(actual.R)).To(BeNumerically("~", int(expected.R), tolerance), "pixel %d,%d", x, y)
+				Expect(int(actual.G)).To(BeNumerically("~", int(expected.G), tolerance), "pixel %d,%d", x, y)
+				Expect(int(actual.B)).To(BeNumerically("~", int(expected.B), tolerance), "pixel %d,%d", x, y)
+				Expect(int(actual.A)).To(BeNumerically("~", int(expected.A), tolerance), "pixel %d,%d", x, y)
+			}
+		}
+	}
+
+	losslessOptions := []TableEntry{
+		Entry("without compression", &libtiff.FromGoImageOptions{}),
+		Entry("with LZW", &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+		}),
+		Entry("with LZW and the horizontal predictor", &libtiff.FromGoImageOptions{
+			Compression:  libtiff.COMPRESSION_LZW,
+			Predictor:    libtiff.PREDICTOR_HORIZONTAL,
+			RowsPerStrip: 3,
+		}),
+		Entry("with Deflate and the horizontal predictor", &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+		}),
+	}
+
+	DescribeTable("writes *image.Gray as 8-bit MINISBLACK",
+		func(options *libtiff.FromGoImageOptions) {
+			img := createTestGray(21, 10)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectLayout(tiffFile, libtiff.PHOTOMETRIC_MINISBLACK, 8, 1)
+			Expect(readStrips(tiffFile)).To(Equal(img.Pix))
+			expectRendered(tiffFile, img, 0)
+		},
+		losslessOptions,
+	)
+
+	DescribeTable("writes *image.Gray16 as 16-bit MINISBLACK",
+		func(options *libtiff.FromGoImageOptions) {
+			img := createTestGray16(21, 10)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectLayout(tiffFile, libtiff.PHOTOMETRIC_MINISBLACK, 16, 1)
+			Expect(readStrips(tiffFile)).To(Equal(swapBytes(img.Pix)))
+			expectRendered(tiffFile, img, 0)
+		},
+		losslessOptions,
+	)
+
+	DescribeTable("writes *image.RGBA64 as 16-bit RGBA with associated alpha",
+		func(options *libtiff.FromGoImageOptions) {
+			img := createTestRGBA64(21, 10)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectLayout(tiffFile, libtiff.PHOTOMETRIC_RGB, 16, 4)
+			extraSamples, err := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.TIFFTAG_EXTRASAMPLES)
+			Expect(err).To(BeNil())
+			Expect(extraSamples).To(Equal([]uint16{uint16(libtiff.EXTRASAMPLE_ASSOCALPHA)}))
+			Expect(readStrips(tiffFile)).To(Equal(swapBytes(img.Pix)))
+			expectRendered(tiffFile, img, 1)
+		},
+		losslessOptions,
+	)
+
+	DescribeTable("writes *image.NRGBA64 as 16-bit RGBA with unassociated alpha",
+		func(options *libtiff.FromGoImageOptions) {
+			img := createTestNRGBA64(21, 10)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectLayout(tiffFile, libtiff.PHOTOMETRIC_RGB, 16, 4)
+			extraSamples, err := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.TIFFTAG_EXTRASAMPLES)
+			Expect(err).To(BeNil())
+			Expect(extraSamples).To(Equal([]uint16{uint16(libtiff.EXTRASAMPLE_UNASSALPHA)}))
+			Expect(readStrips(tiffFile)).To(Equal(swapBytes(img.Pix)))
+		},
+		losslessOptions,
+	)
+
+	DescribeTable("writes *image.CMYK as SEPARATED with INKSET_CMYK",
+		func(options *libtiff.FromGoImageOptions) {
+			img := createTestCMYK(21, 10)
+			tiffFile, cleanup := writeAndReopen(ctx, img, options)
+			defer cleanup()
+
+			expectLayout(tiffFile, libtiff.PHOTOMETRIC_SEPARATED, 8, 4)
+			inkSet, err := tiffFile.TIFFGetFieldUint16_t(ctx, libtiff.TIFFTAG_INKSET)
+			Expect(err).To(BeNil())
+			Expect(libtiff.TIFFTAG(inkSet)).To(Equal(libtiff.INKSET_CMYK))
+			Expect(readStrips(tiffFile)).To(Equal(img.Pix))
+			expectRendered(tiffFile, img, 1)
+		},
+		losslessOptions,
+	)
+
+	It("converts *image.NRGBA64 to associated alpha when requested", func() {
+		img := createTestNRGBA64(5, 3)
+		tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+			AlphaMode: libtiff.AlphaAssociated,
+		})
+		defer cleanup()
+
+		extraSamples, err := tiffFile.TIFFGetFieldUint16Array(ctx, libtiff.TIFFTAG_EXTRASAMPLES)
+		Expect(err).To(BeNil())
+		Expect(extraSamples).To(Equal([]uint16{uint16(libtiff.EXTRASAMPLE_ASSOCALPHA)}))
+
+		expected := image.NewRGBA64(img.Bounds())
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5; x++ {
+				expected.Set(x, y, img.At(x, y))
+			}
+		}
+		Expect(readStrips(tiffFile)).To(Equal(swapBytes(expected.Pix)))
+	})
+
+	DescribeTable("writes tiles",
+		func(img image.Image, pix []byte, bytesPerPixel int) {
+			tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+				Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+				Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+				TileWidth:   16,
+				TileHeight:  16,
+			})
+			defer cleanup()
+
+			isTiled, err := tiffFile.TIFFIsTiled(ctx)
+			Expect(err).To(BeNil())
+			Expect(isTiled).To(BeTrue())
+			Expect(readTiles(tiffFile, bytesPerPixel)).To(Equal(pix))
+		},
+		Entry("gray", createTestGray(37, 20), createTestGray(37, 20).Pix, 1),
+		Entry("16-bit gray", createTestGray16(37, 20), swapBytes(createTestGray16(37, 20).Pix), 2),
+		Entry("16-bit RGBA", createTestRGBA64(37, 20), swapBytes(createTestRGBA64(37, 20).Pix), 8),
+		Entry("CMYK", createTestCMYK(37, 20), createTestCMYK(37, 20).Pix, 4),
+	)
+
+	DescribeTable("writes 8-bit samples with JPEG compression",
+		func(img image.Image, photometric libtiff.Photometric, samplesPerPixel uint16) {
+			tiffFile, cleanup := writeAndReopen(ctx, img, &libtiff.FromGoImageOptions{
+				Compression: libtiff.COMPRESSION_JPEG,
+				Quality:     95,
+			})
+			defer cleanup()
+
+			expectLayout(tiffFile, photometric, 8, samplesPerPixel)
+			expectRendered(tiffFile, img, 8)
+		},
+		Entry("gray", createTestGray(32, 16), libtiff.PHOTOMETRIC_MINISBLACK, uint16(1)),
+		Entry("16-bit gray", createTestGray16(32, 16), libtiff.PHOTOMETRIC_MINISBLACK, uint16(1)),
+		Entry("16-bit RGBA", createTestRGBA64(32, 16), libtiff.PHOTOMETRIC_YCBCR, uint16(3)),
+		Entry("CMYK", createTestCMYK(32, 16), libtiff.PHOTOMETRIC_SEPARATED, uint16(4)),
+	)
+})
@@ -0,0 +1,201 @@
+package libtiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// StripWriterHeader describes the image written by a StripWriter.
+type StripWriterHeader struct {
+	Width  int
+	Height int
+	// ColorModel selects the layout of the rows given to WriteRows, which
+	// is the Pix layout of the matching Go image: color.RGBAModel,
+	// color.NRGBAModel, color.GrayModel, color.Gray16Model,
+	// color.RGBA64Model, color.NRGBA64Model, color.CMYKModel or a
+	// color.Palette for palette indexes. The image is written like
+	// FromGoImage writes that image type. If nil, color.RGBAModel is used.
+	ColorModel color.Model
+	// Options sets the compression, predictor and tags like for
+	// FromGoImage. Tile-based output, overviews and COG output are not
+	// supported. With JPEG compression, RowsPerStrip is rounded up to a
+	// multiple of 16. With CCITT compression, only BinarizeThreshold and
+	// BinarizeOrdered can be used, the other binarizations need the whole
+	// image.
+	Options *FromGoImageOptions
+}
+
+// StripWriter writes an image strip by strip, so the complete image does
+// not have to be in memory. Only the rows of the strip that is being filled
+// are kept.
+type StripWriter struct {
+	file         *File
+	header       StripWriterHeader
+	enc          *imageEncoding
+	pixelSize    int
+	rowsPerStrip int
+	strip        uint32
+	// y is the number of rows that were written to strips.
+	y int
+	// pending holds the rows of the next strip.
+	pending []byte
+	closed  bool
+}
+
+// NewStripWriter sets the tags of a new image in the file and returns a
+// StripWriter to write its rows with. The file must have been opened for
+// writing via TIFFOpenFileFromReadWriteSeeker. The directory is written
+// when the StripWriter is closed.
+func (f *File) NewStripWriter(ctx context.Context, header StripWriterHeader) (*StripWriter, error) {
+	if header.Width <= 0 || header.Height <= 0 {
+		return nil, fmt.Errorf("invalid image size %dx%d", header.Width, header.Height)
+	}
+	if header.ColorModel == nil {
+		header.ColorModel = color.RGBAModel
+	}
+	if header.Options != nil && (header.Options.TileWidth > 0 || header.Options.TileHeight > 0) {
+		return nil, errors.New("tile-based output is not supported by the strip writer")
+	}
+	if header.Options != nil && (header.Options.Overviews != nil || header.Options.COG) {
+		return nil, errors.New("overviews and COG output need the whole image and are not supported by the strip writer")
+	}
+	if header.Options != nil && header.Options.Quantize != nil {
+		return nil, errors.New("Quantize needs the whole image and is not supported by the strip writer, use a color.Palette color model")
+	}
+
+	prototype, pixelSize, err := newBandImage(header.ColorModel, nil, image.Rectangle{})
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := newImageEncoding(prototype, header.Options)
+	if err != nil {
+		return nil, err
+	}
+	if enc.isCCITT && header.Options != nil && header.Options.Binarization.wholeImage() {
+		return nil, fmt.Errorf("binarization %s needs the whole image and is not supported by the strip writer", header.Options.Binarization)
+	}
+
+	rowsPerStrip, err := f.setImageTags(ctx, enc, uint32(header.Width), uint32(header.Height), true, header.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StripWriter{
+		file:         f,
+		header:       header,
+		enc:          enc,
+		pixelSize:    pixelSize,
+		rowsPerStrip: int(rowsPerStrip),
+	}, nil
+}
+
+// RowSize returns the size in bytes of a row given to WriteRows.
+func (w *StripWriter) RowSize() int {
+	return w.header.Width * w.pixelSize
+}
+
+// WriteRows writes the next rows of the image. rows must hold whole rows of
+// RowSize bytes, in the layout of the header's ColorModel. A strip is
+// compressed and written as soon as it has all its rows.
+func (w *StripWriter) WriteRows(ctx context.Context, rows []byte) error {
+	if w.closed {
+		return errors.New("strip writer is closed")
+	}
+
+	rowSize := w.RowSize()
+	if len(rows)%rowSize != 0 {
+		return fmt.Errorf("rows of %d bytes are not a multiple of the row size %d", len(rows), rowSize)
+	}
+	pendingRows := len(w.pending) / rowSize
+	if w.y+pendingRows+len(rows)/rowSize > w.header.Height {
+		return fmt.Errorf("can't write %d rows, only %d of %d rows are left", len(rows)/rowSize, w.header.Height-w.y-pendingRows, w.header.Height)
+	}
+
+	stripSize := w.rowsPerStrip * rowSize
+	for len(rows) > 0 {
+		// Whole strips are written from rows without copying them.
+		if len(w.pending) == 0 && len(rows) >= stripSize {
+			if err := w.writeStrip(ctx, rows[:stripSize]); err != nil {
+				return err
+			}
+			rows = rows[stripSize:]
+			continue
+		}
+
+		n := min(stripSize-len(w.pending), len(rows))
+		w.pending = append(w.pending, rows[:n]...)
+		rows = rows[n:]
+
+		if len(w.pending) == stripSize || w.y+len(w.pending)/rowSize == w.header.Height {
+			if err := w.writeStrip(ctx, w.pending); err != nil {
+				return err
+			}
+			w.pending = w.pending[:0]
+		}
+	}
+
+	return nil
+}
+
+// writeStrip encodes and writes the rows of the next strip.
+func (w *StripWriter) writeStrip(ctx context.Context, pix []byte) error {
+	rows := len(pix) / w.RowSize()
+	band, _, err := newBandImage(w.header.ColorModel, pix, image.Rect(0, w.y, w.header.Width, w.y+rows))
+	if err != nil {
+		return err
+	}
+
+	stripData := make([]byte, rows*w.enc.rowSize(w.header.Width))
+	newStripFiller(band, w.enc, w.header.Options)(stripData, w.y, rows)
+	if err := w.file.TIFFWriteEncodedStrip(ctx, w.strip, stripData); err != nil {
+		return err
+	}
+
+	w.strip++
+	w.y += rows
+	return nil
+}
+
+// Close writes the directory of the image. It returns an error when not all
+// rows were written.
+func (w *StripWriter) Close(ctx context.Context) error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if rows := w.y + len(w.pending)/w.RowSize(); rows != w.header.Height {
+		return fmt.Errorf("only %d of %d rows were written", rows, w.header.Height)
+	}
+	return w.file.TIFFWriteDirectory(ctx)
+}
+
+// newBandImage returns an image of the color model over pix for the given
+// rectangle, and the size of a pixel in pix.
+func newBandImage(colorModel color.Model, pix []byte, rect image.Rectangle) (image.Image, int, error) {
+	width := rect.Dx()
+	switch colorModel {
+	case color.RGBAModel:
+		return &image.RGBA{Pix: pix, Stride: width * 4, Rect: rect}, 4, nil
+	case color.NRGBAModel:
+		return &image.NRGBA{Pix: pix, Stride: width * 4, Rect: rect}, 4, nil
+	case color.GrayModel:
+		return &image.Gray{Pix: pix, Stride: width, Rect: rect}, 1, nil
+	case color.Gray16Model:
+		return &image.Gray16{Pix: pix, Stride: width * 2, Rect: rect}, 2, nil
+	case color.RGBA64Model:
+		return &image.RGBA64{Pix: pix, Stride: width * 8, Rect: rect}, 8, nil
+	case color.NRGBA64Model:
+		return &image.NRGBA64{Pix: pix, Stride: width * 8, Rect: rect}, 8, nil
+	case color.CMYKModel:
+		return &image.CMYK{Pix: pix, Stride: width * 4, Rect: rect}, 4, nil
+	}
+	if palette, ok := colorModel.(color.Palette); ok {
+		return &image.Paletted{Pix: pix, Stride: width, Rect: rect, Palette: palette}, 1, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported color model %T", colorModel)
+}
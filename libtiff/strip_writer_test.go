@@ -0,0 +1,195 @@
+package libtiff_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StripWriter", func() {
+	ctx := context.Background()
+
+	// readAllStrips returns the number of strips and their decoded data.
+	readAllStrips := func(tiffFile *libtiff.File) (uint32, []byte) {
+		strips, err := tiffFile.TIFFNumberOfStrips(ctx)
+		Expect(err).To(BeNil())
+
+		var data []byte
+		for strip := uint32(0); strip < strips; strip++ {
+			stripData, err := tiffFile.TIFFReadEncodedStrip(ctx, strip)
+			Expect(err).To(BeNil())
+			data = append(data, stripData...)
+		}
+		return strips, data
+	}
+
+	// writeInBands writes pix with a StripWriter in bands of the given
+	// number of rows and reopens the file.
+	writeInBands := func(header libtiff.StripWriterHeader, pix []byte, bandRows int) *libtiff.File {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		writer, err := tiffFile.NewStripWriter(ctx, header)
+		Expect(err).To(BeNil())
+
+		bandSize := bandRows * writer.RowSize()
+		for len(pix) > 0 {
+			n := min(bandSize, len(pix))
+			Expect(writer.WriteRows(ctx, pix[:n])).To(Succeed())
+			pix = pix[n:]
+		}
+		Expect(writer.Close(ctx)).To(Succeed())
+		return reopenTempTestFile(ctx, tiffFile, tmpFile)
+	}
+
+	DescribeTable("writes the same strips as FromGoImage",
+		func(img image.Image, pix []byte, bandRows int, options *libtiff.FromGoImageOptions) {
+			options.DateTime = "2024:01:02 03:04:05"
+			options.RowsPerStrip = 8
+			bounds := img.Bounds()
+
+			streamed := writeInBands(libtiff.StripWriterHeader{
+				Width:      bounds.Dx(),
+				Height:     bounds.Dy(),
+				ColorModel: img.ColorModel(),
+				Options:    options,
+			}, pix, bandRows)
+
+			tiffFile, tmpFile := openTempTestFile(ctx)
+			Expect(tiffFile.FromGoImage(ctx, img, options)).To(Succeed())
+			expected := reopenTempTestFile(ctx, tiffFile, tmpFile)
+
+			streamedStrips, streamedData := readAllStrips(streamed)
+			expectedStrips, expectedData := readAllStrips(expected)
+			Expect(streamedStrips).To(Equal(expectedStrips))
+			Expect(streamedData).To(Equal(expectedData))
+
+			for _, tag := range []libtiff.TIFFTAG{libtiff.TIFFTAG_PHOTOMETRIC, libtiff.TIFFTAG_BITSPERSAMPLE, libtiff.TIFFTAG_SAMPLESPERPIXEL, libtiff.TIFFTAG_COMPRESSION, libtiff.TIFFTAG_PREDICTOR} {
+				streamedValue, streamedErr := streamed.TIFFGetFieldUint16_t(ctx, tag)
+				expectedValue, expectedErr := expected.TIFFGetFieldUint16_t(ctx, tag)
+				Expect(streamedErr == nil).To(Equal(expectedErr == nil), "tag %d", tag)
+				Expect(streamedValue).To(Equal(expectedValue), "tag %d", tag)
+			}
+		},
+		Entry("for RGBA in bands of one row", createTestRGBA(19, 37), createTestRGBA(19, 37).Pix, 1, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+		}),
+		Entry("for NRGBA in bands larger than a strip", createTestNRGBA(19, 37), createTestNRGBA(19, 37).Pix, 13, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+		}),
+		Entry("for gray in bands of a strip", createTestGray(21, 30), createTestGray(21, 30).Pix, 8, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_PACKBITS,
+		}),
+		Entry("for 16-bit gray", createTestGray16(21, 30), createTestGray16(21, 30).Pix, 5, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+		}),
+		Entry("for 16-bit RGBA", createTestRGBA64(11, 17), createTestRGBA64(11, 17).Pix, 3, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+		}),
+		Entry("for CMYK", createTestCMYK(11, 17), createTestCMYK(11, 17).Pix, 4, &libtiff.FromGoImageOptions{}),
+		Entry("for palette indexes", createTestPaletted(13, 20, 16), createTestPaletted(13, 20, 16).Pix, 7, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+		}),
+		Entry("for CCITT group 4", createTestGray(35, 20), createTestGray(35, 20).Pix, 6, &libtiff.FromGoImageOptions{
+			Compression: libtiff.COMPRESSION_CCITTFAX4,
+		}),
+	)
+
+	It("writes JPEG in multiple strips", func() {
+		img := createTestRGBA(40, 70)
+		tiffFile := writeInBands(libtiff.StripWriterHeader{
+			Width:  40,
+			Height: 70,
+			Options: &libtiff.FromGoImageOptions{
+				Compression:  libtiff.COMPRESSION_JPEG,
+				Quality:      95,
+				RowsPerStrip: 10,
+			},
+		}, img.Pix, 9)
+
+		rowsPerStrip, err := tiffFile.TIFFGetFieldUint32_t(ctx, libtiff.TIFFTAG_ROWSPERSTRIP)
+		Expect(err).To(BeNil())
+		Expect(rowsPerStrip).To(Equal(uint32(16)))
+		strips, err := tiffFile.TIFFNumberOfStrips(ctx)
+		Expect(err).To(BeNil())
+		Expect(strips).To(Equal(uint32(5)))
+
+		goImage, imgCleanup, err := tiffFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer imgCleanup(ctx)
+		Expect(goImage.Bounds()).To(Equal(img.Bounds()))
+
+		var totalDiff, count int
+		for y := 0; y < 70; y++ {
+			for x := 0; x < 40; x++ {
+				expected := img.RGBAAt(x, y)
+				actual := color.RGBAModel.Convert(goImage.At(x, y)).(color.RGBA)
+				for _, diff := range []int{int(actual.R) - int(expected.R), int(actual.G) - int(expected.G), int(actual.B) - int(expected.B)} {
+					totalDiff += max(diff, -diff)
+					count++
+				}
+			}
+		}
+		Expect(totalDiff / count).To(BeNumerically("<", 8))
+	})
+
+	It("writes the tags of the options", func() {
+		tiffFile := writeInBands(libtiff.StripWriterHeader{
+			Width:      4,
+			Height:     4,
+			ColorModel: color.GrayModel,
+			Options: &libtiff.FromGoImageOptions{
+				Description:    "plot",
+				XResolution:    300,
+				YResolution:    300,
+				ResolutionUnit: libtiff.RESUNIT_INCH,
+			},
+		}, make([]byte, 16), 4)
+
+		description, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.TIFFTAG_IMAGEDESCRIPTION)
+		Expect(err).To(BeNil())
+		Expect(description).To(Equal("plot"))
+
+		xResolution, err := tiffFile.TIFFGetFieldFloat(ctx, libtiff.TIFFTAG_XRESOLUTION)
+		Expect(err).To(BeNil())
+		Expect(xResolution).To(Equal(float32(300)))
+	})
+
+	It("returns an error for invalid input", func() {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		defer tmpFile.Close()
+		defer tiffFile.Close(ctx)
+
+		_, err := tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 0, Height: 4})
+		Expect(err).To(MatchError("invalid image size 0x4"))
+
+		_, err = tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 4, Height: 4, ColorModel: color.AlphaModel})
+		Expect(err).To(MatchError("unsupported color model *color.modelFunc"))
+
+		_, err = tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 4, Height: 4, Options: &libtiff.FromGoImageOptions{
+			TileWidth:  16,
+			TileHeight: 16,
+		}})
+		Expect(err).To(MatchError("tile-based output is not supported by the strip writer"))
+
+		for _, options := range []*libtiff.FromGoImageOptions{{Overviews: &libtiff.Overviews{}}, {COG: true}} {
+			_, err = tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 4, Height: 4, Options: options})
+			Expect(err).To(MatchError("overviews and COG output need the whole image and are not supported by the strip writer"))
+		}
+
+		writer, err := tiffFile.NewStripWriter(ctx, libtiff.StripWriterHeader{Width: 4, Height: 4, ColorModel: color.GrayModel})
+		Expect(err).To(BeNil())
+		Expect(writer.RowSize()).To(Equal(4))
+
+		Expect(writer.WriteRows(ctx, make([]byte, 6))).To(MatchError("rows of 6 bytes are not a multiple of the row size 4"))
+		Expect(writer.WriteRows(ctx, make([]byte, 8))).To(Succeed())
+		Expect(writer.WriteRows(ctx, make([]byte, 12))).To(MatchError("can't write 3 rows, only 2 of 4 rows are left"))
+		Expect(writer.Close(ctx)).To(MatchError("only 2 of 4 rows were written"))
+		Expect(writer.WriteRows(ctx, make([]byte, 4))).To(MatchError("strip writer is closed"))
+	})
+})
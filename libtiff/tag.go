@@ -277,3 +277,251 @@ func (f *File) TIFFGetFieldConstChar(ctx context.Context, tag TIFFTAG) (string,
 
 	return readValue, nil
 }
+
+// getFieldCountedArray reads a tag that is stored together with its value
+// count (like TIFFTAG_XMLPACKET) and returns a copy of the raw array data.
+func (f *File) getFieldCountedArray(ctx context.Context, tag TIFFTAG, elementSize uint32) ([]byte, error) {
+	countPointer, err := f.instance.malloc(ctx, 4)
+	if err != nil {
+		return nil, err
+	}
+	defer f.instance.free(ctx, countPointer)
+
+	valuePointer, err := f.instance.malloc(ctx, 4)
+	if err != nil {
+		return nil, err
+	}
+	defer f.instance.free(ctx, valuePointer)
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFGetFieldCountedArray", f.pointer, api.EncodeU32(uint32(tag)), countPointer, valuePointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if results[0] == 0 {
+		return nil, &TagNotDefinedError{
+			Tag: tag,
+		}
+	}
+
+	// Prevent concurrent memory usage.
+	f.instance.internalInstance.CallLock.Lock()
+	defer f.instance.internalInstance.CallLock.Unlock()
+
+	count, success := f.instance.internalInstance.Module.Memory().ReadUint32Le(uint32(countPointer))
+	if !success {
+		return nil, errors.New("could not read tag count")
+	}
+
+	readPointer, success := f.instance.internalInstance.Module.Memory().ReadUint32Le(uint32(valuePointer))
+	if !success {
+		return nil, errors.New("could not read tag value")
+	}
+
+	return f.readArray(readPointer, count*elementSize)
+}
+
+// getFieldArray reads a tag with a fixed amount of values (like
+// GPSTAG_LATITUDE) and returns a copy of the raw array data.
+func (f *File) getFieldArray(ctx context.Context, tag TIFFTAG, size uint32) ([]byte, error) {
+	valuePointer, err := f.instance.malloc(ctx, 4)
+	if err != nil {
+		return nil, err
+	}
+	defer f.instance.free(ctx, valuePointer)
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFGetFieldArray", f.pointer, api.EncodeU32(uint32(tag)), valuePointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if results[0] == 0 {
+		return nil, &TagNotDefinedError{
+			Tag: tag,
+		}
+	}
+
+	// Prevent concurrent memory usage.
+	f.instance.internalInstance.CallLock.Lock()
+	defer f.instance.internalInstance.CallLock.Unlock()
+
+	readPointer, success := f.instance.internalInstance.Module.Memory().ReadUint32Le(uint32(valuePointer))
+	if !success {
+		return nil, errors.New("could not read tag value")
+	}
+
+	return f.readArray(readPointer, size)
+}
+
+// readArray copies size bytes at the given pointer out of the WASM memory.
+// The caller must hold the call lock.
+func (f *File) readArray(pointer uint32, size uint32) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+
+	if pointer == 0 {
+		return nil, errors.New("tag value is a null pointer")
+	}
+
+	buf, success := f.instance.internalInstance.Module.Memory().Read(pointer, size)
+	if !success {
+		return nil, errors.New("could not read tag value")
+	}
+
+	data := make([]byte, len(buf))
+	copy(data, buf)
+
+	return data, nil
+}
+
+// TIFFGetFieldByteArray reads a counted BYTE, UNDEFINED or ASCII tag, like
+// TIFFTAG_ICCPROFILE or TIFFTAG_XMLPACKET.
+func (f *File) TIFFGetFieldByteArray(ctx context.Context, tag TIFFTAG) ([]byte, error) {
+	return f.getFieldCountedArray(ctx, tag, 1)
+}
+
+// TIFFGetFieldUint16Array reads a counted SHORT tag.
+func (f *File) TIFFGetFieldUint16Array(ctx context.Context, tag TIFFTAG) ([]uint16, error) {
+	data, err := f.getFieldCountedArray(ctx, tag, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeUint16Array(data), nil
+}
+
+// TIFFGetFieldUint32Array reads a counted LONG tag.
+func (f *File) TIFFGetFieldUint32Array(ctx context.Context, tag TIFFTAG) ([]uint32, error) {
+	data, err := f.getFieldCountedArray(ctx, tag, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeUint32Array(data), nil
+}
+
+// TIFFGetFieldUint64Array reads a counted LONG8 or IFD8 tag, like TIFFTAG_SUBIFD.
+func (f *File) TIFFGetFieldUint64Array(ctx context.Context, tag TIFFTAG) ([]uint64, error) {
+	data, err := f.getFieldCountedArray(ctx, tag, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeUint64Array(data), nil
+}
+
+// TIFFGetFieldFloatArray reads a counted tag that libtiff stores as float.
+func (f *File) TIFFGetFieldFloatArray(ctx context.Context, tag TIFFTAG) ([]float32, error) {
+	data, err := f.getFieldCountedArray(ctx, tag, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFloatArray(data), nil
+}
+
+// TIFFGetFieldDoubleArray reads a counted tag that libtiff stores as double.
+func (f *File) TIFFGetFieldDoubleArray(ctx context.Context, tag TIFFTAG) ([]float64, error) {
+	data, err := f.getFieldCountedArray(ctx, tag, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDoubleArray(data), nil
+}
+
+// TIFFGetFieldFixedByteArray reads a tag with a fixed amount of BYTE or
+// UNDEFINED values, like EXIFTAG_EXIFVERSION.
+func (f *File) TIFFGetFieldFixedByteArray(ctx context.Context, tag TIFFTAG, count int) ([]byte, error) {
+	return f.getFieldArray(ctx, tag, uint32(count))
+}
+
+// TIFFGetFieldFixedFloatArray reads a tag with a fixed amount of values that
+// libtiff stores as float, like EXIFTAG_LENSSPECIFICATION.
+func (f *File) TIFFGetFieldFixedFloatArray(ctx context.Context, tag TIFFTAG, count int) ([]float32, error) {
+	data, err := f.getFieldArray(ctx, tag, uint32(count)*4)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFloatArray(data), nil
+}
+
+// TIFFGetFieldFixedDoubleArray reads a tag with a fixed amount of values that
+// libtiff stores as double, like GPSTAG_LATITUDE.
+func (f *File) TIFFGetFieldFixedDoubleArray(ctx context.Context, tag TIFFTAG, count int) ([]float64, error) {
+	data, err := f.getFieldArray(ctx, tag, uint32(count)*8)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDoubleArray(data), nil
+}
+
+// TIFFGetFieldThreeUint16Arrays reads a tag that is stored as three SHORT
+// arrays of count values each, like TIFFTAG_COLORMAP.
+func (f *File) TIFFGetFieldThreeUint16Arrays(ctx context.Context, tag TIFFTAG, count int) ([]uint16, []uint16, []uint16, error) {
+	valuePointer, err := f.instance.malloc(ctx, 12)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.instance.free(ctx, valuePointer)
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFGetFieldThreeArrays", f.pointer, api.EncodeU32(uint32(tag)), valuePointer, valuePointer+4, valuePointer+8)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if results[0] == 0 {
+		return nil, nil, nil, &TagNotDefinedError{
+			Tag: tag,
+		}
+	}
+
+	// Prevent concurrent memory usage.
+	f.instance.internalInstance.CallLock.Lock()
+	defer f.instance.internalInstance.CallLock.Unlock()
+
+	var values [3][]uint16
+	for i := range values {
+		readPointer, success := f.instance.internalInstance.Module.Memory().ReadUint32Le(uint32(valuePointer) + uint32(i)*4)
+		if !success {
+			return nil, nil, nil, errors.New("could not read tag value")
+		}
+
+		data, err := f.readArray(readPointer, uint32(count)*2)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		values[i] = decodeUint16Array(data)
+	}
+
+	return values[0], values[1], values[2], nil
+}
+
+// getUint16WithDefault reads a SHORT tag, returning defaultValue when the
+// tag is not set.
+func (f *File) getUint16WithDefault(ctx context.Context, tag TIFFTAG, defaultValue uint16) (uint16, error) {
+	value, err := f.TIFFGetFieldUint16_t(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return defaultValue, nil
+		}
+		return 0, err
+	}
+	return value, nil
+}
+
+// getUint32WithDefault reads a LONG tag, returning defaultValue when the
+// tag is not set.
+func (f *File) getUint32WithDefault(ctx context.Context, tag TIFFTAG, defaultValue uint32) (uint32, error) {
+	value, err := f.TIFFGetFieldUint32_t(ctx, tag)
+	if err != nil {
+		if _, ok := err.(*TagNotDefinedError); ok {
+			return defaultValue, nil
+		}
+		return 0, err
+	}
+	return value, nil
+}
@@ -0,0 +1,352 @@
+package libtiff
+
+// setGetType is the way libtiff passes the value of a tag to TIFFSetField
+// and TIFFGetField, the TIFF_SETGET types of libtiff in the same order.
+type setGetType uint8
+
+const (
+	setGetUndefined setGetType = iota
+	setGetASCII
+	setGetUint8
+	setGetSint8
+	setGetUint16
+	setGetSint16
+	setGetUint32
+	setGetSint32
+	setGetUint64
+	setGetSint64
+	setGetFloat
+	setGetDouble
+	setGetIFD8
+	setGetInt
+	setGetUint16Pair
+	setGetC0ASCII
+	setGetC0Uint8
+	setGetC0Sint8
+	setGetC0Uint16
+	setGetC0Sint16
+	setGetC0Uint32
+	setGetC0Sint32
+	setGetC0Uint64
+	setGetC0Sint64
+	setGetC0Float
+	setGetC0Double
+	setGetC0IFD8
+	setGetC16ASCII
+	setGetC16Uint8
+	setGetC16Sint8
+	setGetC16Uint16
+	setGetC16Sint16
+	setGetC16Uint32
+	setGetC16Sint32
+	setGetC16Uint64
+	setGetC16Sint64
+	setGetC16Float
+	setGetC16Double
+	setGetC16IFD8
+	setGetC32ASCII
+	setGetC32Uint8
+	setGetC32Sint8
+	setGetC32Uint16
+	setGetC32Sint16
+	setGetC32Uint32
+	setGetC32Sint32
+	setGetC32Uint64
+	setGetC32Sint64
+	setGetC32Float
+	setGetC32Double
+	setGetC32IFD8
+	setGetOther
+)
+
+// tagSetGetTypes are the TIFF_SETGET types of the image directory tags that
+// libtiff knows, tags that are missing are unknown to libtiff.
+var tagSetGetTypes = map[uint16]setGetType{
+	254:   setGetUint32,
+	255:   setGetUndefined,
+	256:   setGetUint32,
+	257:   setGetUint32,
+	258:   setGetUint16,
+	259:   setGetUint16,
+	262:   setGetUint16,
+	263:   setGetUint16,
+	264:   setGetUint16,
+	265:   setGetUint16,
+	266:   setGetUint16,
+	269:   setGetASCII,
+	270:   setGetASCII,
+	271:   setGetASCII,
+	272:   setGetASCII,
+	273:   setGetUndefined,
+	274:   setGetUint16,
+	277:   setGetUint16,
+	278:   setGetUint32,
+	279:   setGetUndefined,
+	280:   setGetUint16,
+	281:   setGetUint16,
+	282:   setGetFloat,
+	283:   setGetFloat,
+	284:   setGetUint16,
+	285:   setGetASCII,
+	286:   setGetFloat,
+	287:   setGetFloat,
+	288:   setGetUndefined,
+	289:   setGetUndefined,
+	290:   setGetUndefined,
+	291:   setGetUndefined,
+	292:   setGetUint32,
+	293:   setGetUint32,
+	296:   setGetUint16,
+	297:   setGetUint16Pair,
+	300:   setGetUndefined,
+	301:   setGetOther,
+	305:   setGetASCII,
+	306:   setGetASCII,
+	315:   setGetASCII,
+	316:   setGetASCII,
+	317:   setGetUint16,
+	318:   setGetC0Float,
+	319:   setGetC0Float,
+	320:   setGetOther,
+	321:   setGetUint16Pair,
+	322:   setGetUint32,
+	323:   setGetUint32,
+	324:   setGetUndefined,
+	325:   setGetUndefined,
+	326:   setGetUint32,
+	327:   setGetUint16,
+	328:   setGetUint32,
+	330:   setGetC16IFD8,
+	332:   setGetUint16,
+	333:   setGetC16ASCII,
+	334:   setGetUint16,
+	336:   setGetUint16Pair,
+	337:   setGetASCII,
+	338:   setGetC16Uint16,
+	339:   setGetUint16,
+	340:   setGetDouble,
+	341:   setGetDouble,
+	343:   setGetC32Uint8,
+	344:   setGetUint32,
+	345:   setGetUint32,
+	346:   setGetUint16,
+	347:   setGetC32Uint8,
+	400:   setGetIFD8,
+	401:   setGetUint32,
+	402:   setGetUint8,
+	403:   setGetUint32,
+	404:   setGetC0Uint8,
+	405:   setGetUint8,
+	433:   setGetC16Float,
+	434:   setGetC16Uint16,
+	435:   setGetUint32,
+	512:   setGetUint16,
+	513:   setGetUint64,
+	514:   setGetUint64,
+	515:   setGetUint16,
+	519:   setGetC32Uint64,
+	520:   setGetC32Uint64,
+	521:   setGetC32Uint64,
+	529:   setGetC0Float,
+	530:   setGetUint16Pair,
+	531:   setGetUint16,
+	532:   setGetC0Float,
+	559:   setGetC16Uint32,
+	700:   setGetC32Uint8,
+	32995: setGetUint16,
+	32996: setGetUint16,
+	32997: setGetUint32,
+	32998: setGetUint32,
+	33300: setGetUint32,
+	33301: setGetUint32,
+	33302: setGetASCII,
+	33303: setGetASCII,
+	33304: setGetFloat,
+	33305: setGetC0Float,
+	33306: setGetC0Float,
+	33421: setGetC0Uint16,
+	33422: setGetC16Uint8,
+	33432: setGetASCII,
+	33723: setGetC32Uint8,
+	34377: setGetC32Uint8,
+	34665: setGetIFD8,
+	34675: setGetC32Uint8,
+	34732: setGetC0Uint32,
+	34853: setGetIFD8,
+	34908: setGetUint32,
+	34909: setGetASCII,
+	34910: setGetUint32,
+	34911: setGetASCII,
+	37439: setGetDouble,
+	37724: setGetC32Uint8,
+	40965: setGetIFD8,
+	50706: setGetC0Uint8,
+	50707: setGetC0Uint8,
+	50708: setGetASCII,
+	50709: setGetC16Uint8,
+	50710: setGetC16Uint8,
+	50711: setGetUint16,
+	50712: setGetC16Uint16,
+	50713: setGetC0Uint16,
+	50714: setGetC16Float,
+	50715: setGetC16Float,
+	50716: setGetC16Float,
+	50717: setGetC16Uint32,
+	50718: setGetC0Float,
+	50719: setGetC0Float,
+	50720: setGetC0Float,
+	50721: setGetC16Float,
+	50722: setGetC16Float,
+	50723: setGetC16Float,
+	50724: setGetC16Float,
+	50725: setGetC16Float,
+	50726: setGetC16Float,
+	50727: setGetC16Float,
+	50728: setGetC16Float,
+	50729: setGetC0Float,
+	50730: setGetFloat,
+	50731: setGetFloat,
+	50732: setGetFloat,
+	50733: setGetUint32,
+	50734: setGetFloat,
+	50735: setGetASCII,
+	50736: setGetC0Float,
+	50737: setGetFloat,
+	50738: setGetFloat,
+	50739: setGetFloat,
+	50740: setGetC16Uint8,
+	50741: setGetUint16,
+	50778: setGetUint16,
+	50779: setGetUint16,
+	50780: setGetFloat,
+	50781: setGetC0Uint8,
+	50827: setGetC16Uint8,
+	50828: setGetC16Uint8,
+	50829: setGetC0Uint32,
+	50830: setGetC16Uint32,
+	50831: setGetC16Uint8,
+	50832: setGetC16Float,
+	50833: setGetC16Uint8,
+	50834: setGetC16Float,
+}
+
+// exifTagSetGetTypes are the TIFF_SETGET types of the EXIF tags.
+var exifTagSetGetTypes = map[uint16]setGetType{
+	33434: setGetFloat,
+	33437: setGetFloat,
+	34850: setGetUint16,
+	34852: setGetASCII,
+	34855: setGetC16Uint16,
+	34856: setGetC16Uint8,
+	34864: setGetUint16,
+	34865: setGetUint32,
+	34866: setGetUint32,
+	34867: setGetUint32,
+	34868: setGetUint32,
+	34869: setGetUint32,
+	36864: setGetC0Uint8,
+	36867: setGetASCII,
+	36868: setGetASCII,
+	36880: setGetASCII,
+	36881: setGetASCII,
+	36882: setGetASCII,
+	37121: setGetC0Uint8,
+	37122: setGetFloat,
+	37377: setGetFloat,
+	37378: setGetFloat,
+	37379: setGetFloat,
+	37380: setGetFloat,
+	37381: setGetFloat,
+	37382: setGetFloat,
+	37383: setGetUint16,
+	37384: setGetUint16,
+	37385: setGetUint16,
+	37386: setGetFloat,
+	37396: setGetC16Uint16,
+	37500: setGetC16Uint8,
+	37510: setGetC16Uint8,
+	37520: setGetASCII,
+	37521: setGetASCII,
+	37522: setGetASCII,
+	37888: setGetFloat,
+	37889: setGetFloat,
+	37890: setGetFloat,
+	37891: setGetFloat,
+	37892: setGetFloat,
+	37893: setGetFloat,
+	40960: setGetC0Uint8,
+	40961: setGetUint16,
+	40962: setGetUint32,
+	40963: setGetUint32,
+	40964: setGetASCII,
+	41483: setGetFloat,
+	41484: setGetC16Uint8,
+	41486: setGetFloat,
+	41487: setGetFloat,
+	41488: setGetUint16,
+	41492: setGetC0Uint16,
+	41493: setGetFloat,
+	41495: setGetUint16,
+	41728: setGetUint8,
+	41729: setGetUint8,
+	41730: setGetC16Uint8,
+	41985: setGetUint16,
+	41986: setGetUint16,
+	41987: setGetUint16,
+	41988: setGetFloat,
+	41989: setGetUint16,
+	41990: setGetUint16,
+	41991: setGetFloat,
+	41992: setGetUint16,
+	41993: setGetUint16,
+	41994: setGetUint16,
+	41995: setGetC16Uint8,
+	41996: setGetUint16,
+	42016: setGetASCII,
+	42032: setGetASCII,
+	42033: setGetASCII,
+	42034: setGetC0Float,
+	42035: setGetASCII,
+	42036: setGetASCII,
+	42037: setGetASCII,
+	42080: setGetUint16,
+	42081: setGetC0Uint16,
+	42082: setGetC16Uint8,
+	42240: setGetFloat,
+}
+
+// gpsTagSetGetTypes are the TIFF_SETGET types of the GPS tags.
+var gpsTagSetGetTypes = map[uint16]setGetType{
+	0:  setGetC0Uint8,
+	1:  setGetASCII,
+	2:  setGetC0Double,
+	3:  setGetASCII,
+	4:  setGetC0Double,
+	5:  setGetUint8,
+	6:  setGetDouble,
+	7:  setGetC0Double,
+	8:  setGetASCII,
+	9:  setGetASCII,
+	10: setGetASCII,
+	11: setGetDouble,
+	12: setGetASCII,
+	13: setGetDouble,
+	14: setGetASCII,
+	15: setGetDouble,
+	16: setGetASCII,
+	17: setGetDouble,
+	18: setGetASCII,
+	19: setGetASCII,
+	20: setGetC0Double,
+	21: setGetASCII,
+	22: setGetC0Double,
+	23: setGetASCII,
+	24: setGetDouble,
+	25: setGetASCII,
+	26: setGetDouble,
+	27: setGetC16Uint8,
+	28: setGetC16Uint8,
+	29: setGetASCII,
+	30: setGetUint16,
+	31: setGetDouble,
+}
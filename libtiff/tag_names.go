@@ -0,0 +1,302 @@
+package libtiff
+
+// tagNames are the names libtiff uses for the tags of image directories.
+var tagNames = map[uint16]string{
+	254:   "SubfileType",
+	255:   "OldSubfileType",
+	256:   "ImageWidth",
+	257:   "ImageLength",
+	258:   "BitsPerSample",
+	259:   "Compression",
+	262:   "PhotometricInterpretation",
+	263:   "Threshholding",
+	264:   "CellWidth",
+	265:   "CellLength",
+	266:   "FillOrder",
+	269:   "DocumentName",
+	270:   "ImageDescription",
+	271:   "Make",
+	272:   "Model",
+	273:   "StripOffsets",
+	274:   "Orientation",
+	277:   "SamplesPerPixel",
+	278:   "RowsPerStrip",
+	279:   "StripByteCounts",
+	280:   "MinSampleValue",
+	281:   "MaxSampleValue",
+	282:   "XResolution",
+	283:   "YResolution",
+	284:   "PlanarConfiguration",
+	285:   "PageName",
+	286:   "XPosition",
+	287:   "YPosition",
+	288:   "FreeOffsets",
+	289:   "FreeByteCounts",
+	290:   "GrayResponseUnit",
+	291:   "GrayResponseCurve",
+	292:   "Group3Options",
+	293:   "Group4Options",
+	296:   "ResolutionUnit",
+	297:   "PageNumber",
+	300:   "ColorResponseUnit",
+	301:   "TransferFunction",
+	305:   "Software",
+	306:   "DateTime",
+	315:   "Artist",
+	316:   "HostComputer",
+	317:   "Predictor",
+	318:   "WhitePoint",
+	319:   "PrimaryChromaticities",
+	320:   "ColorMap",
+	321:   "HalftoneHints",
+	322:   "TileWidth",
+	323:   "TileLength",
+	324:   "TileOffsets",
+	325:   "TileByteCounts",
+	326:   "BadFaxLines",
+	327:   "CleanFaxData",
+	328:   "ConsecutiveBadFaxLines",
+	330:   "SubIFD",
+	332:   "InkSet",
+	333:   "InkNames",
+	334:   "NumberOfInks",
+	336:   "DotRange",
+	337:   "TargetPrinter",
+	338:   "ExtraSamples",
+	339:   "SampleFormat",
+	340:   "SMinSampleValue",
+	341:   "SMaxSampleValue",
+	343:   "ClipPath",
+	344:   "XClipPathUnits",
+	345:   "YClipPathUnits",
+	346:   "Indexed",
+	347:   "JPEGTables",
+	400:   "GlobalParametersIFD",
+	401:   "ProfileType",
+	402:   "FaxProfile",
+	403:   "CodingMethods",
+	404:   "VersionYear",
+	405:   "ModeNumber",
+	433:   "Decode",
+	434:   "ImageBaseColor",
+	435:   "T82Options",
+	512:   "JpegProc",
+	513:   "JpegInterchangeFormat",
+	514:   "JpegInterchangeFormatLength",
+	515:   "JpegRestartInterval",
+	519:   "JpegQTables",
+	520:   "JpegDcTables",
+	521:   "JpegAcTables",
+	529:   "YCbCrCoefficients",
+	530:   "YCbCrSubsampling",
+	531:   "YCbCrPositioning",
+	532:   "ReferenceBlackWhite",
+	559:   "StripRowCounts",
+	700:   "XMLPacket",
+	32995: "Matteing",
+	32996: "DataType",
+	32997: "ImageDepth",
+	32998: "TileDepth",
+	33300: "ImageFullWidth",
+	33301: "ImageFullLength",
+	33302: "TextureFormat",
+	33303: "TextureWrapModes",
+	33304: "FieldOfViewCotangent",
+	33305: "MatrixWorldToScreen",
+	33306: "MatrixWorldToCamera",
+	33421: "CFARepeatPatternDim",
+	33422: "CFAPattern",
+	33432: "Copyright",
+	33550: "ModelPixelScale",
+	33723: "RichTIFFIPTC",
+	33922: "ModelTiepoint",
+	34264: "ModelTransformation",
+	34377: "Photoshop",
+	34665: "EXIFIFDOffset",
+	34675: "ICCProfile",
+	34732: "ImageLayer",
+	34735: "GeoKeyDirectory",
+	34736: "GeoDoubleParams",
+	34737: "GeoAsciiParams",
+	34853: "GPSIFDOffset",
+	34908: "FaxRecvParams",
+	34909: "FaxSubAddress",
+	34910: "FaxRecvTime",
+	34911: "FaxDcs",
+	37439: "StoNits",
+	37724: "ImageSourceData",
+	40965: "InteroperabilityIFDOffset",
+	42112: "GDAL_METADATA",
+	42113: "GDAL_NODATA",
+	50674: "LERCParameters",
+	50706: "DNGVersion",
+	50707: "DNGBackwardVersion",
+	50708: "UniqueCameraModel",
+	50709: "LocalizedCameraModel",
+	50710: "CFAPlaneColor",
+	50711: "CFALayout",
+	50712: "LinearizationTable",
+	50713: "BlackLevelRepeatDim",
+	50714: "BlackLevel",
+	50715: "BlackLevelDeltaH",
+	50716: "BlackLevelDeltaV",
+	50717: "WhiteLevel",
+	50718: "DefaultScale",
+	50719: "DefaultCropOrigin",
+	50720: "DefaultCropSize",
+	50721: "ColorMatrix1",
+	50722: "ColorMatrix2",
+	50723: "CameraCalibration1",
+	50724: "CameraCalibration2",
+	50725: "ReductionMatrix1",
+	50726: "ReductionMatrix2",
+	50727: "AnalogBalance",
+	50728: "AsShotNeutral",
+	50729: "AsShotWhiteXY",
+	50730: "BaselineExposure",
+	50731: "BaselineNoise",
+	50732: "BaselineSharpness",
+	50733: "BayerGreenSplit",
+	50734: "LinearResponseLimit",
+	50735: "CameraSerialNumber",
+	50736: "LensInfo",
+	50737: "ChromaBlurRadius",
+	50738: "AntiAliasStrength",
+	50739: "ShadowScale",
+	50740: "DNGPrivateData",
+	50741: "MakerNoteSafety",
+	50778: "CalibrationIlluminant1",
+	50779: "CalibrationIlluminant2",
+	50780: "BestQualityScale",
+	50781: "RawDataUniqueID",
+	50827: "OriginalRawFileName",
+	50828: "OriginalRawFileData",
+	50829: "ActiveArea",
+	50830: "MaskedAreas",
+	50831: "AsShotICCProfile",
+	50832: "AsShotPreProfileMatrix",
+	50833: "CurrentICCProfile",
+	50834: "CurrentPreProfileMatrix",
+}
+
+// exifTagNames are the names of the tags of EXIF directories.
+var exifTagNames = map[uint16]string{
+	33434: "ExposureTime",
+	33437: "FNumber",
+	34850: "ExposureProgram",
+	34852: "SpectralSensitivity",
+	34855: "ISOSpeedRatings",
+	34856: "OptoelectricConversionFactor",
+	34864: "SensitivityType",
+	34865: "StandardOutputSensitivity",
+	34866: "RecommendedExposureIndex",
+	34867: "ISOSpeed",
+	34868: "ISOSpeedLatitudeyyy",
+	34869: "ISOSpeedLatitudezzz",
+	36864: "ExifVersion",
+	36867: "DateTimeOriginal",
+	36868: "DateTimeDigitized",
+	36880: "OffsetTime",
+	36881: "OffsetTimeOriginal",
+	36882: "OffsetTimeDigitized",
+	37121: "ComponentsConfiguration",
+	37122: "CompressedBitsPerPixel",
+	37377: "ShutterSpeedValue",
+	37378: "ApertureValue",
+	37379: "BrightnessValue",
+	37380: "ExposureBiasValue",
+	37381: "MaxApertureValue",
+	37382: "SubjectDistance",
+	37383: "MeteringMode",
+	37384: "LightSource",
+	37385: "Flash",
+	37386: "FocalLength",
+	37396: "SubjectArea",
+	37500: "MakerNote",
+	37510: "UserComment",
+	37520: "SubSecTime",
+	37521: "SubSecTimeOriginal",
+	37522: "SubSecTimeDigitized",
+	37888: "Temperature",
+	37889: "Humidity",
+	37890: "Pressure",
+	37891: "WaterDepth",
+	37892: "Acceleration",
+	37893: "CameraElevationAngle",
+	40960: "FlashpixVersion",
+	40961: "ColorSpace",
+	40962: "PixelXDimension",
+	40963: "PixelYDimension",
+	40964: "RelatedSoundFile",
+	40965: "InteroperabilityIFDOffset",
+	41483: "FlashEnergy",
+	41484: "SpatialFrequencyResponse",
+	41486: "FocalPlaneXResolution",
+	41487: "FocalPlaneYResolution",
+	41488: "FocalPlaneResolutionUnit",
+	41492: "SubjectLocation",
+	41493: "ExposureIndex",
+	41495: "SensingMethod",
+	41728: "FileSource",
+	41729: "SceneType",
+	41730: "CFAPattern",
+	41985: "CustomRendered",
+	41986: "ExposureMode",
+	41987: "WhiteBalance",
+	41988: "DigitalZoomRatio",
+	41989: "FocalLengthIn35mmFilm",
+	41990: "SceneCaptureType",
+	41991: "GainControl",
+	41992: "Contrast",
+	41993: "Saturation",
+	41994: "Sharpness",
+	41995: "DeviceSettingDescription",
+	41996: "SubjectDistanceRange",
+	42016: "ImageUniqueID",
+	42032: "CameraOwnerName",
+	42033: "BodySerialNumber",
+	42034: "LensSpecification",
+	42035: "LensMake",
+	42036: "LensModel",
+	42037: "LensSerialNumber",
+	42080: "CompositeImage",
+	42081: "SourceImageNumberOfCompositeImage",
+	42082: "SourceExposureTimesOfCompositeImage",
+	42240: "Gamma",
+}
+
+// gpsTagNames are the names of the tags of GPS directories.
+var gpsTagNames = map[uint16]string{
+	0:  "VersionID",
+	1:  "LatitudeRef",
+	2:  "Latitude",
+	3:  "LongitudeRef",
+	4:  "Longitude",
+	5:  "AltitudeRef",
+	6:  "Altitude",
+	7:  "TimeStamp",
+	8:  "Satellites",
+	9:  "Status",
+	10: "MeasureMode",
+	11: "DOP",
+	12: "SpeedRef",
+	13: "Speed",
+	14: "TrackRef",
+	15: "Track",
+	16: "ImgDirectionRef",
+	17: "ImgDirection",
+	18: "MapDatum",
+	19: "DestLatitudeRef",
+	20: "DestLatitude",
+	21: "DestLongitudeRef",
+	22: "DestLongitude",
+	23: "DestBearingRef",
+	24: "DestBearing",
+	25: "DestDistanceRef",
+	26: "DestDistance",
+	27: "ProcessingMethod",
+	28: "AreaInformation",
+	29: "DateStamp",
+	30: "Differential",
+	31: "HorizontalPositioningError",
+}
@@ -116,3 +116,139 @@ func (f *File) TIFFSetFieldString(ctx context.Context, tag TIFFTAG, val string)
 
 	return nil
 }
+
+// setFieldCountedArray sets a tag that is passed together with its value
+// count. libtiff copies the data, so the buffer is freed directly after.
+func (f *File) setFieldCountedArray(ctx context.Context, tag TIFFTAG, count uint32, data []byte) error {
+	pointer, err := f.instance.newBuffer(ctx, data)
+	if err != nil {
+		return err
+	}
+	defer f.instance.free(ctx, pointer)
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFSetFieldCountedArray", f.pointer, api.EncodeU32(uint32(tag)), api.EncodeU32(count), pointer)
+	if err != nil {
+		return err
+	}
+
+	if results[0] == 0 {
+		return errors.New("could not set tag value")
+	}
+
+	return nil
+}
+
+// setFieldArray sets a tag with a fixed amount of values. libtiff copies the
+// data, so the buffer is freed directly after.
+func (f *File) setFieldArray(ctx context.Context, tag TIFFTAG, data []byte) error {
+	pointer, err := f.instance.newBuffer(ctx, data)
+	if err != nil {
+		return err
+	}
+	defer f.instance.free(ctx, pointer)
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFSetFieldArray", f.pointer, api.EncodeU32(uint32(tag)), pointer)
+	if err != nil {
+		return err
+	}
+
+	if results[0] == 0 {
+		return errors.New("could not set tag value")
+	}
+
+	return nil
+}
+
+// TIFFSetFieldByteArray sets a counted BYTE, UNDEFINED or ASCII tag, like
+// TIFFTAG_ICCPROFILE or TIFFTAG_XMLPACKET.
+func (f *File) TIFFSetFieldByteArray(ctx context.Context, tag TIFFTAG, val []byte) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), val)
+}
+
+// TIFFSetFieldUint16Array sets a counted SHORT tag.
+func (f *File) TIFFSetFieldUint16Array(ctx context.Context, tag TIFFTAG, val []uint16) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), encodeUint16Array(val))
+}
+
+// TIFFSetFieldUint32Array sets a counted LONG tag.
+func (f *File) TIFFSetFieldUint32Array(ctx context.Context, tag TIFFTAG, val []uint32) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), encodeUint32Array(val))
+}
+
+// TIFFSetFieldUint64Array sets a counted LONG8 or IFD8 tag, like TIFFTAG_SUBIFD.
+func (f *File) TIFFSetFieldUint64Array(ctx context.Context, tag TIFFTAG, val []uint64) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), encodeUint64Array(val))
+}
+
+// TIFFSetFieldFloatArray sets a counted tag that libtiff stores as float.
+func (f *File) TIFFSetFieldFloatArray(ctx context.Context, tag TIFFTAG, val []float32) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), encodeFloatArray(val))
+}
+
+// TIFFSetFieldDoubleArray sets a counted tag that libtiff stores as double.
+func (f *File) TIFFSetFieldDoubleArray(ctx context.Context, tag TIFFTAG, val []float64) error {
+	return f.setFieldCountedArray(ctx, tag, uint32(len(val)), encodeDoubleArray(val))
+}
+
+// TIFFSetFieldFixedByteArray sets a tag with a fixed amount of BYTE or
+// UNDEFINED values, like EXIFTAG_EXIFVERSION. The length of val must match
+// the count libtiff expects for the tag.
+func (f *File) TIFFSetFieldFixedByteArray(ctx context.Context, tag TIFFTAG, val []byte) error {
+	return f.setFieldArray(ctx, tag, val)
+}
+
+// TIFFSetFieldFixedFloatArray sets a tag with a fixed amount of values that
+// libtiff stores as float, like EXIFTAG_LENSSPECIFICATION.
+func (f *File) TIFFSetFieldFixedFloatArray(ctx context.Context, tag TIFFTAG, val []float32) error {
+	return f.setFieldArray(ctx, tag, encodeFloatArray(val))
+}
+
+// TIFFSetFieldFixedDoubleArray sets a tag with a fixed amount of values that
+// libtiff stores as double, like GPSTAG_LATITUDE.
+func (f *File) TIFFSetFieldFixedDoubleArray(ctx context.Context, tag TIFFTAG, val []float64) error {
+	return f.setFieldArray(ctx, tag, encodeDoubleArray(val))
+}
+
+// TIFFSetFieldThreeUint16Arrays sets a tag that is passed as three SHORT
+// arrays, like TIFFTAG_COLORMAP. The length of the arrays must match the
+// count libtiff expects for the tag, for TIFFTAG_COLORMAP that is
+// 1<<BitsPerSample, so TIFFTAG_BITSPERSAMPLE must be set first.
+func (f *File) TIFFSetFieldThreeUint16Arrays(ctx context.Context, tag TIFFTAG, val1, val2, val3 []uint16) error {
+	var pointers [3]uint64
+	for i, val := range [][]uint16{val1, val2, val3} {
+		pointer, err := f.instance.newBuffer(ctx, encodeUint16Array(val))
+		if err != nil {
+			return err
+		}
+		defer f.instance.free(ctx, pointer)
+		pointers[i] = pointer
+	}
+
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFSetFieldThreeArrays", f.pointer, api.EncodeU32(uint32(tag)), pointers[0], pointers[1], pointers[2])
+	if err != nil {
+		return err
+	}
+
+	if results[0] == 0 {
+		return errors.New("could not set tag value")
+	}
+
+	return nil
+}
+
+// TIFFMergeAnonymousField registers a tag that libtiff does not know, like
+// the GeoTIFF tags, so it can be written with the counted array setters.
+// The registration only lasts for the current directory, libtiff resets the
+// known fields when a new directory is started.
+func (f *File) TIFFMergeAnonymousField(ctx context.Context, tag TIFFTAG, fieldType TIFFDataType) error {
+	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFMergeAnonymousField", f.pointer, api.EncodeU32(uint32(tag)), api.EncodeU32(uint32(fieldType)))
+	if err != nil {
+		return err
+	}
+
+	if results[0] == 0 {
+		return errors.New("could not register tag")
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package libtiff_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// openTempTestFile creates a temp TIFF file for writing and returns it
+// with its path.
+func openTempTestFile(ctx context.Context) (*libtiff.File, *os.File) {
+	tmpFile, err := os.CreateTemp("", "libtiff-test-*.tif")
+	Expect(err).To(BeNil())
+	DeferCleanup(os.Remove, tmpFile.Name())
+
+	fileMode := "w"
+	tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", tmpFile, 0, &libtiff.OpenOptions{
+		FileMode: &fileMode,
+	})
+	Expect(err).To(BeNil())
+	return tiffFile, tmpFile
+}
+
+// reopenTempTestFile closes the written file and opens it for reading.
+func reopenTempTestFile(ctx context.Context, tiffFile *libtiff.File, tmpFile *os.File) *libtiff.File {
+	Expect(tiffFile.Close(ctx)).To(Succeed())
+	Expect(tmpFile.Close()).To(Succeed())
+
+	readFile, err := os.Open(tmpFile.Name())
+	Expect(err).To(BeNil())
+	DeferCleanup(readFile.Close)
+
+	stat, err := readFile.Stat()
+	Expect(err).To(BeNil())
+
+	readTiff, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", readFile, uint64(stat.Size()), nil)
+	Expect(err).To(BeNil())
+	DeferCleanup(readTiff.Close, ctx)
+	return readTiff
+}
@@ -0,0 +1,364 @@
+package libtiff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klippa-app/go-libtiff/internal/memoryfile"
+)
+
+// TranscodeOptions are the options of Transcode.
+type TranscodeOptions struct {
+	// Compression is the compression of the output. If 0, the image data is
+	// not compressed.
+	Compression Compression
+	// Predictor sets TIFFTAG_PREDICTOR. Only meaningful for LZW, Deflate,
+	// ZSTD and LZMA. If 0, no predictor is used.
+	Predictor Predictor
+	// Quality sets the compression quality level (1-100). Only used for JPEG
+	// compression. If 0, the default quality (75) is used.
+	Quality int
+	// Tiled writes the image data in tiles instead of strips.
+	Tiled bool
+	// TileSize is the width and height of the tiles, a multiple of 16. If 0,
+	// tiles of 256 by 256 pixels are written.
+	TileSize uint32
+	// PerPage is called for every page with its index and image, and returns
+	// the options for the page, or nil to use these options. The PerPage of
+	// the returned options is not used.
+	PerPage func(page int, image *ImageInfo) *TranscodeOptions
+}
+
+func (o *TranscodeOptions) compression() Compression {
+	if o.Compression == 0 {
+		return COMPRESSION_NONE
+	}
+	return o.Compression
+}
+
+func (o *TranscodeOptions) predictor() Predictor {
+	if o.Predictor == 0 {
+		return PREDICTOR_NONE
+	}
+	return o.Predictor
+}
+
+func (o *TranscodeOptions) tileSize() uint32 {
+	if o.TileSize == 0 {
+		return 256
+	}
+	return o.TileSize
+}
+
+// matches returns whether the image data of the image is already stored as
+// the options ask for. The JPEG quality of an image is not known, so JPEG
+// images always match JPEG compression.
+func (o *TranscodeOptions) matches(image *ImageInfo) bool {
+	predictor := image.Codec.Predictor
+	if predictor == 0 {
+		predictor = PREDICTOR_NONE
+	}
+	if image.Compression != o.compression() || predictor != o.predictor() || image.Tiled != o.Tiled {
+		return false
+	}
+	return !o.Tiled || (image.TileWidth == o.tileSize() && image.TileHeight == o.tileSize())
+}
+
+// transcodedTags are the tags that Transcode sets itself.
+var transcodedTags = map[TIFFTAG]bool{
+	TIFFTAG_COMPRESSION:   true,
+	TIFFTAG_PREDICTOR:     true,
+	TIFFTAG_ROWSPERSTRIP:  true,
+	TIFFTAG_TILEWIDTH:     true,
+	TIFFTAG_TILELENGTH:    true,
+	TIFFTAG_JPEGTABLES:    true,
+	TIFFTAG_GROUP3OPTIONS: true,
+	TIFFTAG_GROUP4OPTIONS: true,
+}
+
+// ycbcrTags are the tags of YCbCr images, which are left out when JPEG
+// compressed YCbCr data is written as RGB.
+var ycbcrTags = map[TIFFTAG]bool{
+	TIFFTAG_PHOTOMETRIC:         true,
+	TIFFTAG_YCBCRCOEFFICIENTS:   true,
+	TIFFTAG_YCBCRSUBSAMPLING:    true,
+	TIFFTAG_YCBCRPOSITIONING:    true,
+	TIFFTAG_REFERENCEBLACKWHITE: true,
+}
+
+// Transcode writes all pages of the TIFF file in src to dst with the
+// compression and layout of the options, like tiffcp -c. Every page and its
+// SubIFDs are decoded and encoded again at their native bit depth, all other
+// tags are copied as they are, including the EXIF and GPS directories, the
+// ICC profile and XMP metadata. Pages that already match the options are
+// copied with CopyDirectory, without decoding them. JPEG compressed YCbCr
+// pages that are written with another compression become RGB.
+// The output has the byte order of src and is a BigTIFF when src is one or
+// when the image data doesn't fit in a classic TIFF. When src can't seek,
+// it is read into memory, and when dst can't be read and seeked, the output
+// is built in memory.
+func (i *Instance) Transcode(ctx context.Context, src io.Reader, dst io.Writer, options *TranscodeOptions) error {
+	if options == nil {
+		options = &TranscodeOptions{}
+	}
+
+	reader, ok := src.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return fmt.Errorf("could not read source: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	// libtiff reads the header from the current position.
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	source, err := i.TIFFOpenFileFromReader(ctx, "source.tif", reader, uint64(size), nil)
+	if err != nil {
+		return err
+	}
+	defer source.Close(ctx)
+
+	info, err := source.Describe(ctx)
+	if err != nil {
+		return err
+	}
+	pageOptions := make([]*TranscodeOptions, len(info.Directories))
+	mode := &OpenMode{
+		Access:    OpenWrite,
+		ByteOrder: ByteOrderLittleEndian,
+		BigTIFF:   BigTIFFAuto,
+	}
+	if info.ByteOrder == "big-endian" {
+		mode.ByteOrder = ByteOrderBigEndian
+	}
+	if info.BigTIFF {
+		mode.BigTIFF = BigTIFFYes
+	}
+	for page, directory := range info.Directories {
+		if directory.Image == nil {
+			return fmt.Errorf("page %d has no image", page)
+		}
+		pageOptions[page] = options
+		if options.PerPage != nil {
+			if perPage := options.PerPage(page, directory.Image); perPage != nil {
+				pageOptions[page] = perPage
+			}
+		}
+		mode.EstimatedSize += estimateTranscodedSize(directory.Image, pageOptions[page])
+	}
+
+	output, ok := dst.(io.ReadWriteSeeker)
+	var buffer *memoryfile.File
+	if !ok {
+		buffer = &memoryfile.File{}
+		output = buffer
+	}
+	file, err := i.TIFFOpenFileFromReadWriteSeeker(ctx, "output.tif", output, 0, &OpenOptions{Mode: mode})
+	if err != nil {
+		return err
+	}
+	for page := range info.Directories {
+		if err = source.TIFFSetDirectory(ctx, uint32(page)); err != nil {
+			break
+		}
+		if err = source.transcodeDirectory(ctx, file, pageOptions[page]); err != nil {
+			err = fmt.Errorf("could not transcode page %d: %w", page, err)
+			break
+		}
+	}
+	closeErr := file.Close(ctx)
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if buffer != nil {
+		if _, err := dst.Write(buffer.Bytes()); err != nil {
+			return fmt.Errorf("could not write output: %w", err)
+		}
+	}
+	return nil
+}
+
+// estimateTranscodedSize returns the expected size of the image data of the
+// image in the output: the uncompressed size when it is written without
+// compression, otherwise the size of its compressed data in the source.
+func estimateTranscodedSize(image *ImageInfo, options *TranscodeOptions) uint64 {
+	if options.compression() == COMPRESSION_NONE && image.Compression != COMPRESSION_NONE {
+		bits := uint64(0)
+		for _, sampleBits := range image.BitsPerSample {
+			bits += uint64(sampleBits)
+		}
+		if len(image.BitsPerSample) == 1 {
+			bits *= uint64(image.SamplesPerPixel)
+		}
+		return (uint64(image.Width)*bits + 7) / 8 * uint64(image.Height)
+	}
+	var size uint64
+	for _, byteCount := range image.ByteCounts {
+		size += byteCount
+	}
+	return size
+}
+
+// transcodeDirectory writes the current directory of f to dst with the
+// compression and layout of the options.
+func (f *File) transcodeDirectory(ctx context.Context, dst *File, options *TranscodeOptions) error {
+	directory, err := f.currentCopiedDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	if options.matches(directory.info.Image) {
+		return f.CopyDirectory(ctx, dst, nil)
+	}
+
+	for _, d := range append([]*copiedDirectory{directory}, directory.subIFDs...) {
+		if d.info.Image == nil {
+			return errors.New("directory has no image")
+		}
+		if err := checkPlanes(ctx, f.instance, d.info.Image); err != nil {
+			return err
+		}
+		if err := checkTranscode(ctx, f.instance, d.info.Image, options); err != nil {
+			return err
+		}
+	}
+
+	// JPEG compressed YCbCr data is decoded as RGB, which is kept when it
+	// is written with another compression.
+	skip := transcodedTags
+	if isYCbCr(directory.info.Image) && directory.info.Image.Compression == COMPRESSION_JPEG && options.compression() != COMPRESSION_JPEG {
+		skip = map[TIFFTAG]bool{}
+		for tag := range transcodedTags {
+			skip[tag] = true
+		}
+		for tag := range ycbcrTags {
+			skip[tag] = true
+		}
+	}
+
+	return f.writeDirectoryCopy(ctx, dst, directory, skip, nil, func(ctx context.Context, copied *copiedDirectory) error {
+		return f.writeTranscodedImage(ctx, dst, copied, options)
+	})
+}
+
+// checkTranscode returns an error when the image can't be compressed with
+// the compression of the options.
+func checkTranscode(ctx context.Context, instance *Instance, image *ImageInfo, options *TranscodeOptions) error {
+	compression := options.compression()
+	configured, err := instance.TIFFIsCODECConfigured(ctx, uint16(compression))
+	if err != nil {
+		return err
+	}
+	if !configured {
+		return fmt.Errorf("compression %s is not configured", compression)
+	}
+
+	switch compression {
+	case COMPRESSION_CCITTRLE, COMPRESSION_CCITTFAX3, COMPRESSION_CCITTFAX4, COMPRESSION_CCITTRLEW:
+		if image.SamplesPerPixel != 1 || image.BitsPerSample[0] != 1 {
+			return fmt.Errorf("compression %s needs bilevel images, the image has %d samples of %d bits", compression, image.SamplesPerPixel, image.BitsPerSample[0])
+		}
+	case COMPRESSION_JPEG:
+		if image.BitsPerSample[0] != 8 {
+			return fmt.Errorf("JPEG compression needs 8 bit samples, the image has %d bit samples", image.BitsPerSample[0])
+		}
+	}
+
+	if options.Tiled && options.tileSize()%16 != 0 {
+		return fmt.Errorf("tile size must be a multiple of 16, got %d", options.tileSize())
+	}
+	return nil
+}
+
+// writeTranscodedImage sets the compression and layout tags in dst and
+// writes the image data of the current directory of f.
+func (f *File) writeTranscodedImage(ctx context.Context, dst *File, copied *copiedDirectory, options *TranscodeOptions) error {
+	image := copied.info.Image
+	planes, err := f.readPlanes(ctx, image)
+	if err != nil {
+		return err
+	}
+
+	compression := options.compression()
+	if err := dst.TIFFSetFieldUint16_t(ctx, TIFFTAG_COMPRESSION, uint16(compression)); err != nil {
+		return err
+	}
+
+	photometric := PHOTOMETRIC_MINISBLACK
+	if image.Photometric != nil {
+		photometric = *image.Photometric
+	}
+	// readPlanes decodes JPEG compressed YCbCr data as RGB.
+	if isYCbCr(image) && image.Compression == COMPRESSION_JPEG {
+		photometric = PHOTOMETRIC_RGB
+	}
+	convertRGB := compression == COMPRESSION_JPEG && photometric == PHOTOMETRIC_RGB && len(planes) == 1 && image.SamplesPerPixel == 3
+	if convertRGB {
+		// JPEG in TIFF stores RGB data as YCbCr.
+		photometric = PHOTOMETRIC_YCBCR
+	}
+	if image.Photometric == nil || photometric != *image.Photometric {
+		if err := dst.TIFFSetFieldUint16_t(ctx, TIFFTAG_PHOTOMETRIC, uint16(photometric)); err != nil {
+			return err
+		}
+	}
+
+	switch compression {
+	case COMPRESSION_JPEG:
+		quality := 75
+		if options.Quality > 0 {
+			quality = options.Quality
+		}
+		if err := dst.TIFFSetFieldInt(ctx, TIFFTAG_JPEGQUALITY, quality); err != nil {
+			return err
+		}
+		if convertRGB {
+			// Tell libtiff that the data is RGB and should be converted.
+			if err := dst.TIFFSetFieldInt(ctx, TIFFTAG_JPEGCOLORMODE, int(JPEGCOLORMODE_RGB)); err != nil {
+				return err
+			}
+		}
+	case COMPRESSION_CCITTFAX3:
+		if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_GROUP3OPTIONS, uint32(GROUP3OPT_FILLBITS)); err != nil {
+			return err
+		}
+	}
+	if options.predictor() != PREDICTOR_NONE {
+		if err := dst.TIFFSetFieldUint16_t(ctx, TIFFTAG_PREDICTOR, uint16(options.predictor())); err != nil {
+			return err
+		}
+	}
+
+	if options.Tiled {
+		tileSize := options.tileSize()
+		if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILEWIDTH, tileSize); err != nil {
+			return err
+		}
+		if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_TILELENGTH, tileSize); err != nil {
+			return err
+		}
+		return dst.writeTiledPlanes(ctx, planes, tileSize, tileSize)
+	}
+
+	rowsPerStrip, err := dst.TIFFDefaultStripSize(ctx, 0)
+	if err != nil {
+		return err
+	}
+	if err := dst.TIFFSetFieldUint32_t(ctx, TIFFTAG_ROWSPERSTRIP, rowsPerStrip); err != nil {
+		return err
+	}
+	return dst.writeStripPlanes(ctx, planes, int(rowsPerStrip))
+}
@@ -0,0 +1,226 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"io"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transcode", func() {
+	ctx := context.Background()
+
+	// writeTranscodeTestFile writes an image per options and returns the
+	// file contents.
+	writeTranscodeTestFile := func(img image.Image, options ...*libtiff.FromGoImageOptions) []byte {
+		tiffFile, tmpFile := openTempTestFile(ctx)
+		for _, pageOptions := range options {
+			Expect(tiffFile.FromGoImage(ctx, img, pageOptions)).To(Succeed())
+		}
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+		data, err := os.ReadFile(tmpFile.Name())
+		Expect(err).To(BeNil())
+		return data
+	}
+
+	openTranscoded := func(data []byte) *libtiff.File {
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", bytes.NewReader(data), uint64(len(data)), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(tiffFile.Close, ctx)
+		return tiffFile
+	}
+
+	transcode := func(src []byte, options *libtiff.TranscodeOptions) []byte {
+		dst := &bytes.Buffer{}
+		Expect(instance.Transcode(ctx, bytes.NewReader(src), dst, options)).To(Succeed())
+		return dst.Bytes()
+	}
+
+	readPages := func(data []byte) []image.Image {
+		tiffFile := openTranscoded(data)
+		var pages []image.Image
+		for _, err := range tiffFile.Directories(ctx) {
+			Expect(err).To(BeNil())
+			img, cleanup, err := tiffFile.ToGoImage(ctx)
+			Expect(err).To(BeNil())
+			DeferCleanup(cleanup, ctx)
+			pages = append(pages, img)
+		}
+		return pages
+	}
+
+	// expectSimilar compares the pixels of the images, the color channels
+	// may differ by tolerance.
+	expectSimilar := func(actual, expected image.Image, tolerance int) {
+		Expect(actual.Bounds()).To(Equal(expected.Bounds()))
+		bounds := expected.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r1, g1, b1, a1 := expected.At(x, y).RGBA()
+				r2, g2, b2, a2 := actual.At(x, y).RGBA()
+				for i, pair := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+					difference := int(pair[0]>>8) - int(pair[1]>>8)
+					Expect(difference).To(BeNumerically("~", 0, tolerance), "channel %d of pixel %d,%d", i, x, y)
+				}
+			}
+		}
+	}
+
+	describe := func(data []byte) *libtiff.FileInfo {
+		info, err := openTranscoded(data).Describe(ctx)
+		Expect(err).To(BeNil())
+		return info
+	}
+
+	It("changes the compression and keeps the tags", func() {
+		src := writeTranscodeTestFile(createTestRGBA(60, 40),
+			&libtiff.FromGoImageOptions{
+				Compression: libtiff.COMPRESSION_LZW,
+				Artist:      "artist",
+				Exif:        &libtiff.Exif{LensModel: "lens"},
+				ICCProfile:  testICCProfile(560),
+				XMP:         &libtiff.XMPMetadata{Title: "title"},
+			},
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_PACKBITS},
+		)
+		dst := transcode(src, &libtiff.TranscodeOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+			Predictor:   libtiff.PREDICTOR_HORIZONTAL,
+		})
+
+		srcInfo, dstInfo := describe(src), describe(dst)
+		Expect(dstInfo.Directories).To(HaveLen(2))
+		for _, directory := range dstInfo.Directories {
+			Expect(directory.Image.Compression).To(Equal(libtiff.COMPRESSION_ADOBE_DEFLATE))
+			Expect(directory.Image.Codec.Predictor).To(Equal(libtiff.PREDICTOR_HORIZONTAL))
+		}
+
+		tags := func(directory libtiff.DirectoryInfo) map[uint16]any {
+			values := map[uint16]any{}
+			for _, tag := range directory.Tags {
+				values[tag.Tag] = tag.Value
+			}
+			return values
+		}
+		srcTags, dstTags := tags(srcInfo.Directories[0]), tags(dstInfo.Directories[0])
+		for _, tag := range []libtiff.TIFFTAG{libtiff.TIFFTAG_ARTIST, libtiff.TIFFTAG_ICCPROFILE, libtiff.TIFFTAG_XMLPACKET, libtiff.TIFFTAG_IMAGEWIDTH, libtiff.TIFFTAG_PHOTOMETRIC} {
+			Expect(dstTags[uint16(tag)]).To(Equal(srcTags[uint16(tag)]), "tag %d", tag)
+		}
+		Expect(dstInfo.Directories[0].Exif).NotTo(BeNil())
+
+		expected, actual := readPages(src), readPages(dst)
+		Expect(actual).To(Equal(expected))
+	})
+
+	It("copies pages that already match without decoding them", func() {
+		src := writeTranscodeTestFile(createTestGray(64, 32),
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_LZW, RowsPerStrip: 8},
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_NONE},
+		)
+		dst := transcode(src, &libtiff.TranscodeOptions{Compression: libtiff.COMPRESSION_LZW})
+
+		srcFile, dstFile := openTranscoded(src), openTranscoded(dst)
+		srcInfo, dstInfo := describe(src), describe(dst)
+		Expect(dstInfo.Directories[0].Image.RowsPerStrip).To(Equal(uint32(8)))
+		Expect(dstInfo.Directories[1].Image.Compression).To(Equal(libtiff.COMPRESSION_LZW))
+		for i, byteCount := range srcInfo.Directories[0].Image.ByteCounts {
+			Expect(dstInfo.Directories[0].Image.ByteCounts[i]).To(Equal(byteCount))
+			expected, err := srcFile.TIFFReadRawStrip(ctx, uint32(i))
+			Expect(err).To(BeNil())
+			actual, err := dstFile.TIFFReadRawStrip(ctx, uint32(i))
+			Expect(err).To(BeNil())
+			Expect(actual).To(Equal(expected))
+		}
+	})
+
+	It("writes tiles and keeps the native bit depth", func() {
+		src := writeTranscodeTestFile(createTestGray16(50, 40), &libtiff.FromGoImageOptions{})
+		dst := transcode(src, &libtiff.TranscodeOptions{
+			Compression: libtiff.COMPRESSION_LZW,
+			Tiled:       true,
+			TileSize:    32,
+		})
+
+		image := describe(dst).Directories[0].Image
+		Expect(image.Tiled).To(BeTrue())
+		Expect([]uint32{image.TileWidth, image.TileHeight}).To(Equal([]uint32{32, 32}))
+		Expect(image.BitsPerSample).To(Equal([]uint16{16}))
+
+		expected, err := libtiff.ReadTypedRaster[uint16](ctx, openTranscoded(src))
+		Expect(err).To(BeNil())
+		actual, err := libtiff.ReadTypedRaster[uint16](ctx, openTranscoded(dst))
+		Expect(err).To(BeNil())
+		Expect(actual.Data).To(Equal(expected.Data))
+	})
+
+	It("converts between JPEG and other compressions", func() {
+		src := writeTranscodeTestFile(createTestRGBA(48, 32), &libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_JPEG, Quality: 90})
+		rgb := transcode(src, &libtiff.TranscodeOptions{Compression: libtiff.COMPRESSION_LZW})
+
+		image := describe(rgb).Directories[0].Image
+		Expect(image.Compression).To(Equal(libtiff.COMPRESSION_LZW))
+		Expect(*image.Photometric).To(Equal(libtiff.PHOTOMETRIC_RGB))
+		Expect(image.SamplesPerPixel).To(Equal(uint16(3)))
+		Expect(image.Codec.YCbCrSubsampling).To(BeEmpty())
+		expectSimilar(readPages(rgb)[0], readPages(src)[0], 3)
+
+		jpeg := transcode(rgb, &libtiff.TranscodeOptions{Compression: libtiff.COMPRESSION_JPEG, Quality: 95, Tiled: true, TileSize: 16})
+		image = describe(jpeg).Directories[0].Image
+		Expect(image.Compression).To(Equal(libtiff.COMPRESSION_JPEG))
+		Expect(*image.Photometric).To(Equal(libtiff.PHOTOMETRIC_YCBCR))
+		Expect(image.Tiled).To(BeTrue())
+		expectSimilar(readPages(jpeg)[0], readPages(rgb)[0], 12)
+	})
+
+	It("uses the options of every page", func() {
+		src := writeTranscodeTestFile(createTestGray(64, 32),
+			&libtiff.FromGoImageOptions{Compression: libtiff.COMPRESSION_CCITTFAX4},
+			&libtiff.FromGoImageOptions{},
+		)
+		var images []*libtiff.ImageInfo
+		dst := transcode(src, &libtiff.TranscodeOptions{
+			Compression: libtiff.COMPRESSION_PACKBITS,
+			PerPage: func(page int, image *libtiff.ImageInfo) *libtiff.TranscodeOptions {
+				images = append(images, image)
+				if image.BitsPerSample[0] == 1 {
+					return &libtiff.TranscodeOptions{Compression: libtiff.COMPRESSION_CCITTFAX3}
+				}
+				return nil
+			},
+		})
+
+		Expect(images).To(HaveLen(2))
+		info := describe(dst)
+		Expect(info.Directories[0].Image.Compression).To(Equal(libtiff.COMPRESSION_CCITTFAX3))
+		Expect(info.Directories[1].Image.Compression).To(Equal(libtiff.COMPRESSION_PACKBITS))
+		Expect(readPages(dst)).To(Equal(readPages(src)))
+	})
+
+	It("reads and writes streams that can't seek", func() {
+		src := writeTranscodeTestFile(createTestGray(16, 16), &libtiff.FromGoImageOptions{})
+		dst := &bytes.Buffer{}
+		Expect(instance.Transcode(ctx, io.MultiReader(bytes.NewReader(src)), struct{ io.Writer }{dst}, &libtiff.TranscodeOptions{
+			Compression: libtiff.COMPRESSION_ADOBE_DEFLATE,
+		})).To(Succeed())
+
+		Expect(describe(dst.Bytes()).Directories[0].Image.Compression).To(Equal(libtiff.COMPRESSION_ADOBE_DEFLATE))
+		Expect(readPages(dst.Bytes())).To(Equal(readPages(src)))
+	})
+
+	It("refuses compressions that don't fit the image", func() {
+		src := writeTranscodeTestFile(createTestRGBA(16, 16), &libtiff.FromGoImageOptions{})
+
+		err := instance.Transcode(ctx, bytes.NewReader(src), &bytes.Buffer{}, &libtiff.TranscodeOptions{Compression: libtiff.COMPRESSION_CCITTFAX4})
+		Expect(err).To(MatchError("could not transcode page 0: compression " + libtiff.COMPRESSION_CCITTFAX4.String() + " needs bilevel images, the image has 4 samples of 8 bits"))
+
+		err = instance.Transcode(ctx, bytes.NewReader(src), &bytes.Buffer{}, &libtiff.TranscodeOptions{Tiled: true, TileSize: 20})
+		Expect(err).To(MatchError("could not transcode page 0: tile size must be a multiple of 16, got 20"))
+	})
+})
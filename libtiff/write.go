@@ -45,6 +45,15 @@ func (f *File) TIFFWriteEncodedStrip(ctx context.Context, strip uint32, data []b
 
 // TIFFWriteDirectory writes the current directory to the TIFF file.
 func (f *File) TIFFWriteDirectory(ctx context.Context) error {
+	previous, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return err
+	}
+	before, err := f.writtenDirectoryOffsets(false)
+	if err != nil {
+		return err
+	}
+
 	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFWriteDirectory", f.pointer)
 	if err != nil {
 		return err
@@ -59,7 +68,15 @@ func (f *File) TIFFWriteDirectory(ctx context.Context) error {
 		return errors.New("could not write directory")
 	}
 
-	return nil
+	if before != nil {
+		var offset uint64
+		offset, err = f.writtenDirectory(before, previous)
+		if err == nil {
+			err = f.writePendingRationals(offset)
+		}
+	}
+	f.pendingRationals = nil
+	return err
 }
 
 // TIFFDefaultStripSize returns a sensible default strip size for the given request.
@@ -280,20 +297,54 @@ func (f *File) TIFFCheckpointDirectory(ctx context.Context) error {
 		return errors.New("error checkpointing directory")
 	}
 
-	return nil
+	// The directory stays current, so the exact rationals are kept for the
+	// next time it is written.
+	offset, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return err
+	}
+
+	return f.writePendingRationals(offset)
 }
 
 // TIFFRewriteDirectory rewrites the current directory in place.
 // This can be used to update a directory after it has been written.
 func (f *File) TIFFRewriteDirectory(ctx context.Context) error {
+	_, err := f.rewriteDirectory(ctx, false)
+	return err
+}
+
+// rewriteDirectory rewrites the current directory and returns the offset of
+// the rewritten directory when locate is true or there are exact rationals
+// to write, and 0 otherwise.
+func (f *File) rewriteDirectory(ctx context.Context, locate bool) (uint64, error) {
+	previous, err := f.TIFFCurrentDirOffset(ctx)
+	if err != nil {
+		return 0, err
+	}
+	before, err := f.writtenDirectoryOffsets(locate)
+	if err != nil {
+		return 0, err
+	}
+
 	results, err := f.instance.internalInstance.CallExportedFunction(ctx, "TIFFRewriteDirectory", f.pointer)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if results[0] == 0 {
-		return errors.New("error rewriting directory")
+		return 0, errors.New("error rewriting directory")
 	}
 
-	return nil
+	var offset uint64
+	if before != nil {
+		// The rewritten directory is written anew, but keeps its place in
+		// the chain of directories.
+		offset, err = f.writtenDirectory(before, previous)
+		if err == nil {
+			err = f.writePendingRationals(offset)
+		}
+	}
+	f.pendingRationals = nil
+	return offset, err
 }
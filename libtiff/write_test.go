@@ -316,6 +316,44 @@ var _ = Describe("TIFFSetField", func() {
 			defer cleanup()
 		})
 	})
+
+	Context("TIFFSetFieldByteArray", func() {
+		It("writes and reads back a counted byte tag value", func() {
+			packet := []byte("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"></x:xmpmeta>")
+			readTiff, cleanup := writeMinimalTiff(ctx, func(ctx context.Context, f *libtiff.File) {
+				Expect(f.TIFFSetFieldByteArray(ctx, libtiff.TIFFTAG_XMLPACKET, packet)).To(Succeed())
+			})
+			defer cleanup()
+
+			val, err := readTiff.TIFFGetFieldByteArray(ctx, libtiff.TIFFTAG_XMLPACKET)
+			Expect(err).To(BeNil())
+			Expect(val).To(Equal(packet))
+		})
+
+		It("returns an error when the tag is not set", func() {
+			readTiff, cleanup := writeMinimalTiff(ctx, func(ctx context.Context, f *libtiff.File) {})
+			defer cleanup()
+
+			_, err := readTiff.TIFFGetFieldByteArray(ctx, libtiff.TIFFTAG_XMLPACKET)
+			Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+				Tag: libtiff.TIFFTAG_XMLPACKET,
+			}))
+		})
+	})
+
+	Context("TIFFSetFieldFixedFloatArray", func() {
+		It("writes and reads back a fixed float array tag value", func() {
+			values := []float32{0, 255, 128, 255, 128, 255}
+			readTiff, cleanup := writeMinimalTiff(ctx, func(ctx context.Context, f *libtiff.File) {
+				Expect(f.TIFFSetFieldFixedFloatArray(ctx, libtiff.TIFFTAG_REFERENCEBLACKWHITE, values)).To(Succeed())
+			})
+			defer cleanup()
+
+			val, err := readTiff.TIFFGetFieldFixedFloatArray(ctx, libtiff.TIFFTAG_REFERENCEBLACKWHITE, 6)
+			Expect(err).To(BeNil())
+			Expect(val).To(Equal(values))
+		})
+	})
 })
 
 var _ = Describe("TIFFWriteEncodedStrip", func() {
@@ -0,0 +1,245 @@
+package libtiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Namespaces of commonly used XMP schemas.
+const (
+	XMPNamespaceRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	XMPNamespaceDC        = "http://purl.org/dc/elements/1.1/"
+	XMPNamespaceXMP       = "http://ns.adobe.com/xap/1.0/"
+	XMPNamespaceXMPRights = "http://ns.adobe.com/xap/1.0/rights/"
+	XMPNamespacePhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	XMPNamespaceTIFF      = "http://ns.adobe.com/tiff/1.0/"
+	XMPNamespaceEXIF      = "http://ns.adobe.com/exif/1.0/"
+
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// XMPName is the name of an XMP property.
+type XMPName struct {
+	Namespace string
+	Local     string
+}
+
+// XMP is an XMP packet with its parsed RDF properties.
+type XMP struct {
+	// Packet is the raw XMP packet.
+	Packet []byte
+	// Properties contains the simple properties and the items of the
+	// rdf:Alt, rdf:Bag and rdf:Seq arrays of all rdf:Description elements,
+	// in document order. Structured properties are not included.
+	Properties map[XMPName][]string
+}
+
+// Get returns the first value of a property.
+func (x *XMP) Get(namespace, local string) (string, bool) {
+	values := x.Properties[XMPName{Namespace: namespace, Local: local}]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// XMP returns the XMP packet of the current directory.
+// Returns a TagNotDefinedError when the directory has no XMP packet.
+func (f *File) XMP(ctx context.Context) (*XMP, error) {
+	packet, err := f.TIFFGetFieldByteArray(ctx, TIFFTAG_XMLPACKET)
+	if err != nil {
+		return nil, err
+	}
+
+	properties, err := parseXMPProperties(packet)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse XMP packet: %w", err)
+	}
+
+	return &XMP{
+		Packet:     packet,
+		Properties: properties,
+	}, nil
+}
+
+// xmpNode is an element of the XMP packet.
+type xmpNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmpNode
+	text     string
+}
+
+func (n *xmpNode) attr(namespace, local string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == namespace && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func parseXMPProperties(packet []byte) (map[XMPName][]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimRight(packet, "\x00 \r\n\t")))
+	root := &xmpNode{}
+	stack := []*xmpNode{root}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmpNode{name: t.Name, attrs: t.Attr}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.text += string(t)
+		}
+	}
+
+	properties := map[XMPName][]string{}
+	var walk func(node *xmpNode)
+	walk = func(node *xmpNode) {
+		if node.name.Space == XMPNamespaceRDF && node.name.Local == "Description" {
+			addXMPDescription(properties, node)
+			return
+		}
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return properties, nil
+}
+
+func addXMPDescription(properties map[XMPName][]string, description *xmpNode) {
+	// Simple properties can be written as attributes.
+	for _, attr := range description.attrs {
+		if attr.Name.Space == "" || attr.Name.Space == "xmlns" || attr.Name.Space == XMPNamespaceRDF || attr.Name.Space == xmlNamespace {
+			continue
+		}
+		name := XMPName{Namespace: attr.Name.Space, Local: attr.Name.Local}
+		properties[name] = append(properties[name], attr.Value)
+	}
+
+	for _, property := range description.children {
+		name := XMPName{Namespace: property.name.Space, Local: property.name.Local}
+		if resource, ok := property.attr(XMPNamespaceRDF, "resource"); ok {
+			properties[name] = append(properties[name], resource)
+			continue
+		}
+		if parseType, _ := property.attr(XMPNamespaceRDF, "parseType"); parseType == "Resource" {
+			continue
+		}
+		if len(property.children) == 0 {
+			properties[name] = append(properties[name], property.text)
+			continue
+		}
+
+		array := property.children[0]
+		if array.name.Space != XMPNamespaceRDF || (array.name.Local != "Alt" && array.name.Local != "Bag" && array.name.Local != "Seq") {
+			continue
+		}
+		for _, item := range array.children {
+			if item.name.Space == XMPNamespaceRDF && item.name.Local == "li" && len(item.children) == 0 {
+				properties[name] = append(properties[name], item.text)
+			}
+		}
+	}
+}
+
+// XMPMetadata is written as an XMP packet.
+type XMPMetadata struct {
+	// Title is written as dc:title.
+	Title string
+	// Description is written as dc:description.
+	Description string
+	// Creators are written as dc:creator.
+	Creators []string
+	// Subjects are written as the dc:subject keywords.
+	Subjects []string
+	// Rights is written as dc:rights.
+	Rights string
+	// CreatorTool is written as xmp:CreatorTool.
+	CreatorTool string
+	// Packet is written as-is instead of the fields above when set.
+	Packet []byte
+}
+
+// xmpPacketPadding is the whitespace that is added to the packet, so it can
+// be edited in place.
+const xmpPacketPadding = 2048
+
+// encode returns the XMP packet of the metadata.
+func (m *XMPMetadata) encode() []byte {
+	if len(m.Packet) > 0 {
+		return m.Packet
+	}
+
+	var packet bytes.Buffer
+	escape := func(value string) {
+		xml.EscapeText(&packet, []byte(value))
+	}
+	languageAlternative := func(name, value string) {
+		if value == "" {
+			return
+		}
+		packet.WriteString("   <" + name + ">\n    <rdf:Alt>\n     <rdf:li xml:lang=\"x-default\">")
+		escape(value)
+		packet.WriteString("</rdf:li>\n    </rdf:Alt>\n   </" + name + ">\n")
+	}
+	array := func(name, arrayType string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		packet.WriteString("   <" + name + ">\n    <rdf:" + arrayType + ">\n")
+		for _, value := range values {
+			packet.WriteString("     <rdf:li>")
+			escape(value)
+			packet.WriteString("</rdf:li>\n")
+		}
+		packet.WriteString("    </rdf:" + arrayType + ">\n   </" + name + ">\n")
+	}
+
+	packet.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	packet.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	packet.WriteString(" <rdf:RDF xmlns:rdf=\"" + XMPNamespaceRDF + "\">\n")
+	packet.WriteString("  <rdf:Description rdf:about=\"\"\n")
+	packet.WriteString("    xmlns:dc=\"" + XMPNamespaceDC + "\"\n")
+	packet.WriteString("    xmlns:xmp=\"" + XMPNamespaceXMP + "\"\n")
+	packet.WriteString("    xmlns:xmpRights=\"" + XMPNamespaceXMPRights + "\"")
+	if m.CreatorTool != "" {
+		packet.WriteString("\n    xmp:CreatorTool=\"")
+		escape(m.CreatorTool)
+		packet.WriteString("\"")
+	}
+	if m.Rights != "" {
+		// Adobe tools show the copyright status based on xmpRights:Marked.
+		packet.WriteString("\n    xmpRights:Marked=\"True\"")
+	}
+	packet.WriteString(">\n")
+	languageAlternative("dc:title", m.Title)
+	languageAlternative("dc:description", m.Description)
+	array("dc:creator", "Seq", m.Creators)
+	array("dc:subject", "Bag", m.Subjects)
+	languageAlternative("dc:rights", m.Rights)
+	packet.WriteString("  </rdf:Description>\n")
+	packet.WriteString(" </rdf:RDF>\n")
+	packet.WriteString("</x:xmpmeta>\n")
+	packet.Write(bytes.Repeat([]byte(" "), xmpPacketPadding))
+	packet.WriteString("\n<?xpacket end=\"w\"?>")
+
+	return packet.Bytes()
+}
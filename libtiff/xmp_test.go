@@ -0,0 +1,105 @@
+package libtiff_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XMP", func() {
+	ctx := context.Background()
+
+	It("writes and reads Dublin Core metadata", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			XMP: &libtiff.XMPMetadata{
+				Title:       "Invoice <2024>",
+				Description: "Scanned invoice",
+				Creators:    []string{"Jane Doe", "Scanner & Co"},
+				Subjects:    []string{"invoice", "finance"},
+				Rights:      "© 2024 Example",
+				CreatorTool: "go-libtiff",
+			},
+		})
+		defer cleanup()
+
+		xmp, err := tiffFile.XMP(ctx)
+		Expect(err).To(BeNil())
+		Expect(bytes.HasPrefix(xmp.Packet, []byte("<?xpacket begin="))).To(BeTrue())
+		Expect(bytes.HasSuffix(xmp.Packet, []byte(`<?xpacket end="w"?>`))).To(BeTrue())
+
+		title, ok := xmp.Get(libtiff.XMPNamespaceDC, "title")
+		Expect(ok).To(BeTrue())
+		Expect(title).To(Equal("Invoice <2024>"))
+
+		Expect(xmp.Properties).To(Equal(map[libtiff.XMPName][]string{
+			{Namespace: libtiff.XMPNamespaceDC, Local: "title"}:         {"Invoice <2024>"},
+			{Namespace: libtiff.XMPNamespaceDC, Local: "description"}:   {"Scanned invoice"},
+			{Namespace: libtiff.XMPNamespaceDC, Local: "creator"}:       {"Jane Doe", "Scanner & Co"},
+			{Namespace: libtiff.XMPNamespaceDC, Local: "subject"}:       {"invoice", "finance"},
+			{Namespace: libtiff.XMPNamespaceDC, Local: "rights"}:        {"© 2024 Example"},
+			{Namespace: libtiff.XMPNamespaceXMP, Local: "CreatorTool"}:  {"go-libtiff"},
+			{Namespace: libtiff.XMPNamespaceXMPRights, Local: "Marked"}: {"True"},
+		}))
+	})
+
+	It("writes a raw packet and parses the RDF forms", func() {
+		packet := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:tiff="http://ns.adobe.com/tiff/1.0/" tiff:Make="Scanner">
+   <tiff:Model>Model 1</tiff:Model>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:xmpRights="http://ns.adobe.com/xap/1.0/rights/" xmlns:stRef="http://ns.adobe.com/xap/1.0/sType/ResourceRef#" xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/">
+   <xmpRights:WebStatement rdf:resource="https://example.com/license"/>
+   <xmpMM:DerivedFrom rdf:parseType="Resource">
+    <stRef:documentID>uuid:1</stRef:documentID>
+   </xmpMM:DerivedFrom>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="r"?>` + "\x00"
+
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			XMP: &libtiff.XMPMetadata{
+				Title:  "ignored",
+				Packet: []byte(packet),
+			},
+		})
+		defer cleanup()
+
+		xmp, err := tiffFile.XMP(ctx)
+		Expect(err).To(BeNil())
+		Expect(string(xmp.Packet)).To(Equal(packet))
+		Expect(xmp.Properties).To(Equal(map[libtiff.XMPName][]string{
+			{Namespace: libtiff.XMPNamespaceTIFF, Local: "Make"}:              {"Scanner"},
+			{Namespace: libtiff.XMPNamespaceTIFF, Local: "Model"}:             {"Model 1"},
+			{Namespace: libtiff.XMPNamespaceXMPRights, Local: "WebStatement"}: {"https://example.com/license"},
+		}))
+	})
+
+	It("returns an error for an invalid packet", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), &libtiff.FromGoImageOptions{
+			XMP: &libtiff.XMPMetadata{
+				Packet: []byte("<x:xmpmeta><rdf:RDF>"),
+			},
+		})
+		defer cleanup()
+
+		_, err := tiffFile.XMP(ctx)
+		Expect(err).To(MatchError(ContainSubstring("could not parse XMP packet")))
+	})
+
+	It("returns an error when there is no XMP packet", func() {
+		tiffFile, cleanup := writeAndReopen(ctx, createTestRGBA(16, 16), nil)
+		defer cleanup()
+
+		_, err := tiffFile.XMP(ctx)
+		Expect(err).To(Equal(&libtiff.TagNotDefinedError{
+			Tag: libtiff.TIFFTAG_XMLPACKET,
+		}))
+	})
+})
@@ -1,17 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
 	"log"
+	"math"
 	"os"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
 
 	_ "github.com/klippa-app/go-libtiff/fax2ps"
@@ -62,7 +66,7 @@ func main() {
 	ctx := context.Background()
 
 	availableBinaries := registry.List()
-	availableBinaries = append(availableBinaries, "tiff2img", "img2tiff")
+	availableBinaries = append(availableBinaries, "tiff2img", "img2tiff", "inspect")
 	incorrectStartArgument := func() {
 		log.Fatalf("You should minimally start the program with one of the following arguments: %s", strings.Join(availableBinaries, ", "))
 	}
@@ -89,6 +93,14 @@ func main() {
 		return
 	}
 
+	if os.Args[1] == "inspect" {
+		err := inspect()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	err := registry.Run(ctx, os.Args[1], os.Args[2:]...)
 	if err != nil {
 		if exitErr, ok := err.(*sys.ExitError); ok {
@@ -102,9 +114,10 @@ func main() {
 func tiff2img() error {
 	var (
 		// Used for flags.
-		fileType    string
-		quality     int
-		progressive bool
+		fileType        string
+		quality         int
+		progressive     bool
+		colorManagement bool
 	)
 
 	rootCmd := &cobra.Command{
@@ -158,7 +171,14 @@ func tiff2img() error {
 
 			for i := range file.Directories(ctx) {
 				func() {
-					renderedImage, cleanup, err := file.ToGoImage(ctx)
+					var renderedImage image.Image
+					var cleanup func(context.Context) error
+					var err error
+					if colorManagement {
+						renderedImage, cleanup, err = file.ToSRGBGoImage(ctx, nil)
+					} else {
+						renderedImage, cleanup, err = file.ToGoImage(ctx)
+					}
 					if err != nil {
 						log.Fatal(fmt.Errorf("could not convert tiff image %d to go image: %w", i, err))
 					}
@@ -173,7 +193,8 @@ func tiff2img() error {
 
 					defer outFile.Close()
 					if fileType == "jpeg" {
-						err = image_jpeg.Encode(outFile, renderedImage.(*image.RGBA), image_jpeg.Options{
+						// Palette images are not returned as RGBA.
+						err = image_jpeg.Encode(outFile, image_jpeg.ToRGBA(renderedImage), image_jpeg.Options{
 							Options: &jpeg.Options{
 								Quality: quality,
 							},
@@ -200,6 +221,7 @@ func tiff2img() error {
 	rootCmd.Flags().IntVarP(&quality, "quality", "", 95, "The quality to render the image in, only used for jpeg.")
 	rootCmd.Flags().StringVarP(&fileType, "file-type", "", "jpeg", "The file type to render in, jpeg or png")
 	rootCmd.Flags().BoolVarP(&progressive, "progressive", "", false, "Create progressive images, only used for jpeg.")
+	rootCmd.Flags().BoolVarP(&colorManagement, "color-management", "", false, "Convert the colours to sRGB using the embedded ICC profile.")
 
 	rootCmd.SetOut(os.Stdout)
 	return rootCmd.Execute()
@@ -230,6 +252,17 @@ func img2tiff() error {
 		tileHeight     uint32
 		pageNumber     uint16
 		totalPages     uint16
+		noExif         bool
+		bigTIFF        string
+		overviews      string
+		resampling     string
+		subIFDs        bool
+		cog            bool
+		binarize       string
+		binarizeWindow int
+		quantize       int
+		quantizeMethod string
+		quantizeDither bool
 	)
 
 	rootCmd := &cobra.Command{
@@ -248,78 +281,97 @@ func img2tiff() error {
 			_ = jpeg.Decode
 			_ = png.Decode
 
-			// Map compression string to TIFFTAG.
-			var comp libtiff.TIFFTAG
-			switch strings.ToLower(compression) {
-			case "none":
-				comp = libtiff.COMPRESSION_NONE
-			case "lzw":
-				comp = libtiff.COMPRESSION_LZW
-			case "deflate":
-				comp = libtiff.COMPRESSION_ADOBE_DEFLATE
-			case "jpeg":
-				comp = libtiff.COMPRESSION_JPEG
-			case "packbits":
-				comp = libtiff.COMPRESSION_PACKBITS
-			case "ccitt3":
-				comp = libtiff.COMPRESSION_CCITTFAX3
-			case "ccitt4":
-				comp = libtiff.COMPRESSION_CCITTFAX4
-			default:
-				log.Fatal(fmt.Errorf("unsupported compression: %s (use none, lzw, deflate, jpeg, packbits, ccitt3, or ccitt4)", compression))
-			}
-
-			// Map predictor string.
-			var pred libtiff.TIFFTAG
-			switch strings.ToLower(predictor) {
-			case "none", "":
-				// Don't set predictor.
-			case "horizontal":
-				pred = libtiff.PREDICTOR_HORIZONTAL
-			case "floatingpoint":
-				pred = libtiff.PREDICTOR_FLOATINGPOINT
-			default:
-				log.Fatal(fmt.Errorf("unsupported predictor: %s (use none, horizontal, or floatingpoint)", predictor))
-			}
-
-			// Map resolution unit string.
-			var resUnit libtiff.TIFFTAG
-			switch strings.ToLower(resolutionUnit) {
-			case "", "default":
-				// Don't set resolution unit.
-			case "none":
-				resUnit = libtiff.RESUNIT_NONE
-			case "inch":
-				resUnit = libtiff.RESUNIT_INCH
-			case "centimeter":
-				resUnit = libtiff.RESUNIT_CENTIMETER
-			default:
-				log.Fatal(fmt.Errorf("unsupported resolution unit: %s (use none, inch, or centimeter)", resolutionUnit))
-			}
-
-			// Map orientation string.
-			var orient libtiff.TIFFTAG
-			switch strings.ToLower(orientation) {
-			case "", "default":
-				// Don't set orientation (use default TOPLEFT).
-			case "topleft":
-				orient = libtiff.ORIENTATION_TOPLEFT
-			case "topright":
-				orient = libtiff.ORIENTATION_TOPRIGHT
-			case "botright":
-				orient = libtiff.ORIENTATION_BOTRIGHT
-			case "botleft":
-				orient = libtiff.ORIENTATION_BOTLEFT
-			case "lefttop":
-				orient = libtiff.ORIENTATION_LEFTTOP
-			case "righttop":
-				orient = libtiff.ORIENTATION_RIGHTTOP
-			case "rightbot":
-				orient = libtiff.ORIENTATION_RIGHTBOT
-			case "leftbot":
-				orient = libtiff.ORIENTATION_LEFTBOT
-			default:
-				log.Fatal(fmt.Errorf("unsupported orientation: %s (use topleft, topright, botright, botleft, lefttop, righttop, rightbot, or leftbot)", orientation))
+			comp, err := libtiff.ParseCompression(compression)
+			if err == nil && !slices.Contains(libtiff.FromGoImageCompressions(), comp) {
+				err = fmt.Errorf("compression %s can't be written", comp)
+			}
+			if err != nil {
+				log.Fatal(fmt.Errorf("%w (use %s)", err, compressionNames()))
+			}
+
+			var pred libtiff.Predictor
+			if predictor != "" && !strings.EqualFold(predictor, "none") {
+				pred, err = libtiff.ParsePredictor(predictor)
+				if err != nil {
+					log.Fatal(fmt.Errorf("%w (use none, horizontal, or floatingpoint)", err))
+				}
+			}
+
+			var resUnit libtiff.ResolutionUnit
+			if resolutionUnit != "" && !strings.EqualFold(resolutionUnit, "default") {
+				resUnit, err = libtiff.ParseResolutionUnit(resolutionUnit)
+				if err != nil {
+					log.Fatal(fmt.Errorf("%w (use none, inch, or centimeter)", err))
+				}
+			}
+
+			// Don't set orientation by default (use default TOPLEFT).
+			var orient libtiff.Orientation
+			if orientation != "" && !strings.EqualFold(orientation, "default") {
+				orient, err = libtiff.ParseOrientation(orientation)
+				if err != nil {
+					log.Fatal(fmt.Errorf("%w (use topleft, topright, botright, botleft, lefttop, righttop, rightbot, or leftbot)", err))
+				}
+			}
+
+			bigTIFFMode, err := libtiff.ParseBigTIFFMode(bigTIFF)
+			if err != nil {
+				log.Fatal(fmt.Errorf("%w (use no, yes, or auto)", err))
+			}
+
+			var overviewOptions *libtiff.Overviews
+			if overviews != "" && !strings.EqualFold(overviews, "none") {
+				overviewOptions = &libtiff.Overviews{SubIFDs: subIFDs}
+				overviewOptions.Resampling, err = libtiff.ParseResampling(resampling)
+				if err != nil {
+					log.Fatal(fmt.Errorf("%w (use average, nearest, or bilinear)", err))
+				}
+				if !strings.EqualFold(overviews, "auto") {
+					values := strings.Split(overviews, ",")
+					overviewOptions.Factors = make([]int, len(values))
+					for i, value := range values {
+						overviewOptions.Factors[i], err = strconv.Atoi(strings.TrimSpace(value))
+						if err != nil {
+							log.Fatal(fmt.Errorf("invalid overview factor %q (use auto or factors like 2,4,8)", value))
+						}
+					}
+				}
+			}
+
+			binarization, err := libtiff.ParseBinarization(binarize)
+			if err != nil {
+				log.Fatal(fmt.Errorf("%w (use threshold, floyd-steinberg, atkinson, ordered, otsu, sauvola, or niblack)", err))
+			}
+
+			var quantizeOptions *libtiff.Quantize
+			if quantize > 0 {
+				method, err := libtiff.ParseQuantizeMethod(quantizeMethod)
+				if err != nil {
+					log.Fatal(fmt.Errorf("%w (use median-cut or octree)", err))
+				}
+				quantizeOptions = &libtiff.Quantize{Colors: quantize, Method: method, Dither: quantizeDither}
+			}
+
+			if cog && (append || len(inputs) > 1) {
+				log.Fatal("--cog writes a single image to a new file, it can't be used with --append or multiple inputs")
+			}
+
+			// Estimate the output size from the image headers, so auto can
+			// pick BigTIFF before the file is created.
+			var estimatedSize uint64
+			if bigTIFFMode == libtiff.BigTIFFAuto && !append {
+				for _, input := range inputs {
+					inputFile, err := os.Open(input)
+					if err != nil {
+						log.Fatal(err)
+					}
+					config, _, err := image.DecodeConfig(inputFile)
+					inputFile.Close()
+					if err != nil {
+						log.Fatal(fmt.Errorf("could not decode input image %s: %w", input, err))
+					}
+					estimatedSize += libtiff.EstimateImageSize(config.Width, config.Height, config.ColorModel)
+				}
 			}
 
 			instance, err := libtiff.GetInstance(ctx, &libtiff.Config{
@@ -332,66 +384,113 @@ func img2tiff() error {
 
 			// Open or create the output file.
 			var outputFile *os.File
-			var fileMode string
+			openMode := &libtiff.OpenMode{
+				Access:        libtiff.OpenWrite,
+				BigTIFF:       bigTIFFMode,
+				EstimatedSize: estimatedSize,
+			}
 			if append {
 				outputFile, err = os.OpenFile(output, os.O_RDWR, 0)
 				if err != nil {
 					log.Fatal(fmt.Errorf("could not open existing tiff file for appending: %w", err))
 				}
-				fileMode = "a"
+				openMode = &libtiff.OpenMode{Access: libtiff.OpenAppend}
 			} else {
 				outputFile, err = os.Create(output)
 				if err != nil {
 					log.Fatal(err)
 				}
-				fileMode = "w"
 			}
 			defer outputFile.Close()
 			tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, path.Base(output), outputFile, 0, &libtiff.OpenOptions{
-				FileMode: &fileMode,
+				Mode: openMode,
 			})
 			if err != nil {
 				log.Fatal(fmt.Errorf("could not open tiff file for writing: %w", err))
 			}
 			defer tiffFile.Close(ctx)
 
+			// Number the pages of a new multi-page file, unless the page
+			// numbers are given. The document writer can't number pages
+			// with SubIFD overviews, those are numbered as they are
+			// written.
+			var document *libtiff.DocumentWriter
+			numberPages := !append && totalPages == 0 && len(inputs) > 1
+			if numberPages && overviewOptions != nil && overviewOptions.SubIFDs {
+				if len(inputs) > math.MaxUint16 {
+					log.Fatal("a document can have at most 65535 pages")
+				}
+			} else if numberPages {
+				document, err = tiffFile.NewDocumentWriter(ctx)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
 			for i, input := range inputs {
-				// Open and decode the input image.
-				inputFile, err := os.Open(input)
+				// Read and decode the input image.
+				inputData, err := os.ReadFile(input)
 				if err != nil {
 					log.Fatal(err)
 				}
 
-				img, _, err := image.Decode(inputFile)
-				inputFile.Close()
+				img, format, err := image.Decode(bytes.NewReader(inputData))
 				if err != nil {
 					log.Fatal(fmt.Errorf("could not decode input image %s: %w", input, err))
 				}
 
-				err = tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
-					Compression:    comp,
-					Quality:        quality,
-					Software:       software,
-					DateTime:       dateTime,
-					Artist:         artist,
-					Predictor:      pred,
-					XResolution:    xResolution,
-					YResolution:    yResolution,
-					ResolutionUnit: resUnit,
-					Description:    description,
-					Copyright:      copyright,
-					DocumentName:   documentName,
-					PageName:       pageName,
-					HostComputer:   hostComputer,
-					Make:           make_,
-					Model:          model,
-					RowsPerStrip:   rowsPerStrip,
-					Orientation:    orient,
-					TileWidth:      tileWidth,
-					TileHeight:     tileHeight,
-					PageNumber:     pageNumber,
-					TotalPages:     totalPages,
-				})
+				// Carry over the EXIF metadata of JPEG inputs.
+				var exif *libtiff.Exif
+				var gps *libtiff.GPS
+				if format == "jpeg" && !noExif {
+					exif, gps, err = libtiff.ExifFromJPEG(bytes.NewReader(inputData))
+					if err != nil {
+						log.Printf("Could not read EXIF metadata from %s, skipping it: %v", input, err)
+						exif, gps = nil, nil
+					}
+				}
+
+				options := &libtiff.FromGoImageOptions{
+					Compression:        comp,
+					Quality:            quality,
+					Software:           software,
+					DateTime:           dateTime,
+					Artist:             artist,
+					Predictor:          pred,
+					XResolution:        xResolution,
+					YResolution:        yResolution,
+					ResolutionUnit:     resUnit,
+					Description:        description,
+					Copyright:          copyright,
+					DocumentName:       documentName,
+					PageName:           pageName,
+					HostComputer:       hostComputer,
+					Make:               make_,
+					Model:              model,
+					RowsPerStrip:       rowsPerStrip,
+					Orientation:        orient,
+					TileWidth:          tileWidth,
+					TileHeight:         tileHeight,
+					PageNumber:         pageNumber,
+					TotalPages:         totalPages,
+					Exif:               exif,
+					GPS:                gps,
+					Overviews:          overviewOptions,
+					COG:                cog,
+					Binarization:       binarization,
+					BinarizationWindow: binarizeWindow,
+					Quantize:           quantizeOptions,
+				}
+				if document != nil {
+					err = document.AddPage(ctx, img, options)
+				} else {
+					if numberPages {
+						options.PageNumber = uint16(i)
+						options.TotalPages = uint16(len(inputs))
+						options.SubfileType = libtiff.FILETYPE_PAGE
+					}
+					err = tiffFile.FromGoImage(ctx, img, options)
+				}
 				if err != nil {
 					log.Fatal(fmt.Errorf("could not write image %s to tiff: %w", input, err))
 				}
@@ -399,6 +498,12 @@ func img2tiff() error {
 				log.Printf("Written image %d/%d: %s", i+1, len(inputs), input)
 			}
 
+			if document != nil {
+				if err := document.Close(ctx); err != nil {
+					log.Fatal(fmt.Errorf("could not write page numbers: %w", err))
+				}
+			}
+
 			if append {
 				log.Printf("Appended %d image(s) to TIFF file %s", len(inputs), output)
 			} else {
@@ -407,9 +512,10 @@ func img2tiff() error {
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&compression, "compression", "", "deflate", "Compression type: none, lzw, deflate, jpeg, packbits, ccitt3, or ccitt4")
+	rootCmd.Flags().StringVarP(&compression, "compression", "", "adobe_deflate", "Compression type: "+compressionNames())
 	rootCmd.Flags().IntVarP(&quality, "quality", "", 75, "JPEG compression quality (1-100), only used with --compression jpeg")
 	rootCmd.Flags().BoolVarP(&append, "append", "", false, "Append to an existing TIFF file instead of creating a new one")
+	rootCmd.Flags().StringVarP(&bigTIFF, "bigtiff", "", "auto", "Write a BigTIFF: no, yes, or auto (when the uncompressed images exceed the 4 GiB limit of classic TIFF), ignored with --append")
 	rootCmd.Flags().StringVarP(&software, "software", "", "", "TIFFTAG_SOFTWARE value (default: go-libtiff/libtiff-{version})")
 	rootCmd.Flags().StringVarP(&dateTime, "datetime", "", "", "TIFFTAG_DATETIME value in YYYY:MM:DD HH:MM:SS format (default: current time)")
 	rootCmd.Flags().StringVarP(&artist, "artist", "", "", "TIFFTAG_ARTIST value (omitted if empty)")
@@ -429,7 +535,129 @@ func img2tiff() error {
 	rootCmd.Flags().Uint32VarP(&tileWidth, "tile-width", "", 0, "Tile width (0 = strip-based)")
 	rootCmd.Flags().Uint32VarP(&tileHeight, "tile-height", "", 0, "Tile height (0 = strip-based)")
 	rootCmd.Flags().Uint16VarP(&pageNumber, "page-number", "", 0, "Page number (0-based) for TIFFTAG_PAGENUMBER")
-	rootCmd.Flags().Uint16VarP(&totalPages, "total-pages", "", 0, "Total pages for TIFFTAG_PAGENUMBER (tag is omitted if 0)")
+	rootCmd.Flags().Uint16VarP(&totalPages, "total-pages", "", 0, "Total pages for TIFFTAG_PAGENUMBER (tag is omitted if 0, unless multiple inputs are written to a new file, which are then numbered automatically)")
+	rootCmd.Flags().StringVarP(&overviews, "overviews", "", "", "Write reduced-resolution overviews: auto (halve until at most 256 pixels), or factors like 2,4,8,16")
+	rootCmd.Flags().StringVarP(&resampling, "resampling", "", "average", "Resampling filter of the overviews: average, nearest, or bilinear")
+	rootCmd.Flags().BoolVarP(&subIFDs, "overview-subifds", "", false, "Write the overviews as SubIFDs instead of reduced-resolution directories")
+	rootCmd.Flags().BoolVarP(&cog, "cog", "", false, "Write a Cloud Optimized GeoTIFF: tiled (512x512 unless --tile-width and --tile-height are set), with overviews (auto unless --overviews is set) and the tile data after all directories")
+	rootCmd.Flags().StringVarP(&binarize, "binarize", "", "threshold", "Black and white conversion for ccitt3 and ccitt4: threshold, floyd-steinberg, atkinson, ordered, otsu, sauvola, or niblack")
+	rootCmd.Flags().IntVarP(&binarizeWindow, "binarize-window", "", 0, "Window size in pixels for --binarize sauvola and niblack (0 = 15)")
+	rootCmd.Flags().IntVarP(&quantize, "quantize", "", 0, "Reduce the colors to a palette of this many colors, 2 to 256 (0 = off)")
+	rootCmd.Flags().StringVarP(&quantizeMethod, "quantize-method", "", "median-cut", "Palette selection for --quantize: median-cut or octree")
+	rootCmd.Flags().BoolVarP(&quantizeDither, "quantize-dither", "", false, "Dither the colors with Floyd–Steinberg when using --quantize")
+	rootCmd.Flags().BoolVarP(&noExif, "no-exif", "", false, "Do not carry over EXIF and GPS metadata from JPEG inputs")
+
+	rootCmd.SetOut(os.Stdout)
+	return rootCmd.Execute()
+}
+
+// compressionNames lists the compressions that img2tiff can write, like
+// "none, lzw, or jpeg".
+func compressionNames() string {
+	var names []string
+	for _, compression := range libtiff.FromGoImageCompressions() {
+		names = append(names, strings.ToLower(strings.TrimPrefix(compression.String(), "COMPRESSION_")))
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", or " + names[len(names)-1]
+}
+
+func inspect() error {
+	var (
+		// Used for flags.
+		outputJSON bool
+	)
+
+	rootCmd := &cobra.Command{
+		Use:   "inspect [input]",
+		Short: "A CLI tool to show the structure and tags of a tiff file",
+		Args: func(cmd *cobra.Command, args []string) error {
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			input := args[1]
+
+			openFile, err := os.Open(input)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer openFile.Close()
+
+			stat, err := openFile.Stat()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			instance, err := libtiff.GetInstance(ctx, &libtiff.Config{
+				CompilationCache: compilationCache,
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer instance.Close(ctx)
+
+			file, err := instance.TIFFOpenFileFromReader(ctx, path.Base(input), openFile, uint64(stat.Size()), nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer file.Close(ctx)
+
+			info, err := file.Describe(ctx)
+			if err != nil {
+				log.Fatal(fmt.Errorf("could not describe tiff file: %w", err))
+			}
+
+			if outputJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(info); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Byte order: %s, BigTIFF: %t\n", info.ByteOrder, info.BigTIFF)
+			var printDirectory func(directory libtiff.DirectoryInfo, title string, indent string)
+			printDirectory = func(directory libtiff.DirectoryInfo, title string, indent string) {
+				fmt.Fprintf(out, "%s%s at offset %d:\n", indent, title, directory.Offset)
+				if image := directory.Image; image != nil {
+					fmt.Fprintf(out, "%s  %dx%d, %d samples of %v bits, %s, %s\n", indent, image.Width, image.Height, image.SamplesPerPixel, image.BitsPerSample, image.Compression, image.SampleFormat)
+				}
+				for _, tag := range directory.Tags {
+					name := tag.Name
+					if name == "" {
+						name = "Unknown"
+					}
+					value := fmt.Sprint(tag.Value)
+					if data, ok := tag.Value.([]byte); ok {
+						value = fmt.Sprintf("<%d bytes>", len(data))
+					}
+					if len(value) > 80 {
+						value = value[:77] + "..."
+					}
+					fmt.Fprintf(out, "%s  %s (%d) %s[%d]: %s\n", indent, name, tag.Tag, tag.Type, tag.Count, value)
+				}
+				for i, subIFD := range directory.SubIFDs {
+					printDirectory(subIFD, fmt.Sprintf("SubIFD %d", i), indent+"  ")
+				}
+				if directory.Exif != nil {
+					printDirectory(*directory.Exif, "EXIF directory", indent+"  ")
+				}
+				if directory.GPS != nil {
+					printDirectory(*directory.GPS, "GPS directory", indent+"  ")
+				}
+				if directory.Interoperability != nil {
+					printDirectory(*directory.Interoperability, "Interoperability directory", indent+"  ")
+				}
+			}
+			for i, directory := range info.Directories {
+				printDirectory(directory, fmt.Sprintf("Directory %d", i), "")
+			}
+		},
+	}
+
+	rootCmd.Flags().BoolVarP(&outputJSON, "json", "", false, "Output the structure and tags as JSON.")
 
 	rootCmd.SetOut(os.Stdout)
 	return rootCmd.Execute()
@@ -0,0 +1,321 @@
+// Package pages merges, splits and reorders the pages of TIFF files. The
+// pages are copied with libtiff.File.CopyDirectory, so the compressed image
+// data is copied as it is, without decoding and compressing it again.
+//
+// The output has the byte order of the file of its first page, and is a
+// BigTIFF when one of the sources is a BigTIFF or when the pages don't fit in
+// a classic TIFF. When a page has a TIFFTAG_PAGENUMBER, it is renumbered to
+// its place in the output.
+package pages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klippa-app/go-libtiff/internal/memoryfile"
+	"github.com/klippa-app/go-libtiff/libtiff"
+)
+
+// Count returns the number of pages of src.
+func Count(ctx context.Context, instance *libtiff.Instance, src io.ReadSeeker) (int, error) {
+	source, err := openSource(ctx, instance, src)
+	if err != nil {
+		return 0, err
+	}
+	defer source.file.Close(ctx)
+
+	return source.pages, nil
+}
+
+// Merge writes all pages of the sources to dst, in order.
+func Merge(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, srcs ...io.ReadSeeker) error {
+	sources, err := openSources(ctx, instance, srcs...)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	var pages []page
+	for i, source := range sources {
+		pages = append(pages, source.allPages(i)...)
+	}
+	return writePages(ctx, instance, dst, sources, pages)
+}
+
+// Split writes every page of src to its own file. dst is called with the
+// index of every page and returns the writer of the file of the page.
+func Split(ctx context.Context, instance *libtiff.Instance, src io.ReadSeeker, dst func(page int) (io.WriteSeeker, error)) error {
+	sources, err := openSources(ctx, instance, src)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	for i := 0; i < sources[0].pages; i++ {
+		writer, err := dst(i)
+		if err != nil {
+			return err
+		}
+		if err := writePages(ctx, instance, writer, sources, []page{{source: 0, index: i}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Select writes the pages of src with the given indices to dst, in the order
+// of indices. A page can be selected more than once.
+func Select(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, src io.ReadSeeker, indices []int) error {
+	sources, err := openSources(ctx, instance, src)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	pages := make([]page, len(indices))
+	for i, index := range indices {
+		if err := sources[0].checkIndex(index); err != nil {
+			return err
+		}
+		pages[i] = page{source: 0, index: index}
+	}
+	return writePages(ctx, instance, dst, sources, pages)
+}
+
+// Reorder writes the pages of src to dst in the given order, page i of dst
+// is page order[i] of src. order must contain every page of src once.
+func Reorder(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, src io.ReadSeeker, order []int) error {
+	sources, err := openSources(ctx, instance, src)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	if len(order) != sources[0].pages {
+		return fmt.Errorf("order has %d pages, src has %d pages", len(order), sources[0].pages)
+	}
+	seen := make([]bool, sources[0].pages)
+	pages := make([]page, len(order))
+	for i, index := range order {
+		if err := sources[0].checkIndex(index); err != nil {
+			return err
+		}
+		if seen[index] {
+			return fmt.Errorf("page %d is in the order more than once", index)
+		}
+		seen[index] = true
+		pages[i] = page{source: 0, index: index}
+	}
+	return writePages(ctx, instance, dst, sources, pages)
+}
+
+// Delete writes the pages of src to dst, without the pages with the given
+// indices.
+func Delete(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, src io.ReadSeeker, indices []int) error {
+	sources, err := openSources(ctx, instance, src)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	deleted := map[int]bool{}
+	for _, index := range indices {
+		if err := sources[0].checkIndex(index); err != nil {
+			return err
+		}
+		deleted[index] = true
+	}
+
+	var pages []page
+	for _, page := range sources[0].allPages(0) {
+		if !deleted[page.index] {
+			pages = append(pages, page)
+		}
+	}
+	return writePages(ctx, instance, dst, sources, pages)
+}
+
+// InsertAt writes the pages of src to dst with all pages of insert inserted
+// before page index of src. An index of the number of pages of src appends
+// the pages.
+func InsertAt(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, src io.ReadSeeker, index int, insert io.ReadSeeker) error {
+	sources, err := openSources(ctx, instance, src, insert)
+	if err != nil {
+		return err
+	}
+	defer closeSources(ctx, sources)
+
+	if index < 0 || index > sources[0].pages {
+		return fmt.Errorf("insert index %d is out of range, src has %d pages", index, sources[0].pages)
+	}
+
+	srcPages := sources[0].allPages(0)
+	pages := append([]page{}, srcPages[:index]...)
+	pages = append(pages, sources[1].allPages(1)...)
+	pages = append(pages, srcPages[index:]...)
+	return writePages(ctx, instance, dst, sources, pages)
+}
+
+// page is a page of one of the sources.
+type page struct {
+	source int
+	index  int
+}
+
+// source is an input file.
+type source struct {
+	file      *libtiff.File
+	pages     int
+	size      uint64
+	bigEndian bool
+	bigTIFF   bool
+}
+
+func openSource(ctx context.Context, instance *libtiff.Instance, reader io.ReadSeeker) (*source, error) {
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	file, err := instance.TIFFOpenFileFromReader(ctx, "source.tif", reader, uint64(size), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &source{
+		file: file,
+		size: uint64(size),
+	}
+	pages, err := file.TIFFNumberOfDirectories(ctx)
+	if err == nil {
+		source.pages = int(pages)
+		source.bigEndian, err = file.TIFFIsBigEndian(ctx)
+	}
+	if err == nil {
+		source.bigTIFF, err = file.TIFFIsBigTIFF(ctx)
+	}
+	if err != nil {
+		file.Close(ctx)
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// openSources opens all readers, the files must be closed with
+// closeSources.
+func openSources(ctx context.Context, instance *libtiff.Instance, readers ...io.ReadSeeker) ([]*source, error) {
+	var sources []*source
+	for i, reader := range readers {
+		source, err := openSource(ctx, instance, reader)
+		if err != nil {
+			closeSources(ctx, sources)
+			return nil, fmt.Errorf("could not open source %d: %w", i, err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func closeSources(ctx context.Context, sources []*source) {
+	for _, source := range sources {
+		source.file.Close(ctx)
+	}
+}
+
+func (s *source) allPages(sourceIndex int) []page {
+	pages := make([]page, s.pages)
+	for i := range pages {
+		pages[i] = page{source: sourceIndex, index: i}
+	}
+	return pages
+}
+
+func (s *source) checkIndex(index int) error {
+	if index < 0 || index >= s.pages {
+		return fmt.Errorf("page %d is out of range, src has %d pages", index, s.pages)
+	}
+	return nil
+}
+
+// writePages copies the pages to a new file in dst. libtiff reads back what
+// it writes, so when dst can't be read the file is built in memory.
+func writePages(ctx context.Context, instance *libtiff.Instance, dst io.WriteSeeker, sources []*source, pages []page) error {
+	if len(pages) == 0 {
+		return errors.New("no pages to write")
+	}
+
+	mode := &libtiff.OpenMode{
+		Access:    libtiff.OpenWrite,
+		ByteOrder: libtiff.ByteOrderLittleEndian,
+		BigTIFF:   libtiff.BigTIFFAuto,
+	}
+	if sources[pages[0].source].bigEndian {
+		mode.ByteOrder = libtiff.ByteOrderBigEndian
+	}
+	for _, page := range pages {
+		source := sources[page.source]
+		if source.bigTIFF {
+			mode.BigTIFF = libtiff.BigTIFFYes
+		}
+		mode.EstimatedSize += source.size / uint64(source.pages)
+	}
+
+	output, ok := dst.(io.ReadWriteSeeker)
+	var buffer *memoryfile.File
+	if !ok {
+		buffer = &memoryfile.File{}
+		output = buffer
+	}
+
+	file, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "output.tif", output, 0, &libtiff.OpenOptions{Mode: mode})
+	if err != nil {
+		return err
+	}
+	err = copyPages(ctx, file, sources, pages)
+	closeErr := file.Close(ctx)
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if buffer != nil {
+		if _, err := dst.Write(buffer.Bytes()); err != nil {
+			return fmt.Errorf("could not write output: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyPages copies the pages to file and renumbers the pages that have a
+// page number.
+func copyPages(ctx context.Context, file *libtiff.File, sources []*source, pages []page) error {
+	for i, page := range pages {
+		source := sources[page.source]
+		if err := source.file.TIFFSetDirectory(ctx, uint32(page.index)); err != nil {
+			return fmt.Errorf("could not read page %d of source %d: %w", page.index, page.source, err)
+		}
+
+		_, _, err := source.file.TIFFGetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER)
+		renumber := err == nil
+		err = source.file.CopyDirectory(ctx, file, &libtiff.CopyDirectoryOptions{
+			SetTags: func(ctx context.Context, dst *libtiff.File) error {
+				if !renumber {
+					return nil
+				}
+				return dst.TIFFSetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER, uint16(i), uint16(len(pages)))
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not copy page %d of source %d: %w", page.index, page.source, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package pages_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var instance *libtiff.Instance
+
+var _ = BeforeSuite(func() {
+	var err error
+	instance, err = libtiff.GetInstance(context.Background(), &libtiff.Config{})
+	Expect(err).To(BeNil())
+})
+
+var _ = AfterSuite(func() {
+	Expect(instance.Close(context.Background())).To(Succeed())
+})
+
+func TestPages(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pages Suite")
+}
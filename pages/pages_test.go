@@ -0,0 +1,211 @@
+package pages_test
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"github.com/klippa-app/go-libtiff/libtiff"
+	"github.com/klippa-app/go-libtiff/pages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeOnly hides the Read method of a file, so the output is built in
+// memory.
+type writeOnly struct {
+	io.WriteSeeker
+}
+
+var _ = Describe("pages", func() {
+	ctx := context.Background()
+
+	createFile := func() *os.File {
+		file, err := os.CreateTemp("", "pages-*.tif")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.Remove, file.Name())
+		DeferCleanup(file.Close)
+		return file
+	}
+
+	// writeDocument writes a numbered document with the given page names,
+	// every page has its own size and gray level.
+	writeDocument := func(compression libtiff.Compression, names ...string) *os.File {
+		file := createFile()
+		tiffFile, err := instance.TIFFOpenFileFromReadWriteSeeker(ctx, "test.tif", file, 0, &libtiff.OpenOptions{
+			Mode: &libtiff.OpenMode{Access: libtiff.OpenWrite},
+		})
+		Expect(err).To(BeNil())
+		for i, name := range names {
+			img := image.NewGray(image.Rect(0, 0, 32, 16+i*8))
+			for j := range img.Pix {
+				img.Pix[j] = uint8(40*i + j%7)
+			}
+			Expect(tiffFile.FromGoImage(ctx, img, &libtiff.FromGoImageOptions{
+				Compression: compression,
+				PageName:    name,
+				PageNumber:  uint16(i),
+				TotalPages:  uint16(len(names)),
+				SubfileType: libtiff.FILETYPE_PAGE,
+			})).To(Succeed())
+		}
+		Expect(tiffFile.Close(ctx)).To(Succeed())
+		return file
+	}
+
+	openFile := func(file io.ReadSeeker) *libtiff.File {
+		size, err := file.Seek(0, io.SeekEnd)
+		Expect(err).To(BeNil())
+		_, err = file.Seek(0, io.SeekStart)
+		Expect(err).To(BeNil())
+		tiffFile, err := instance.TIFFOpenFileFromReader(ctx, "test.tif", file, uint64(size), nil)
+		Expect(err).To(BeNil())
+		DeferCleanup(tiffFile.Close, ctx)
+		return tiffFile
+	}
+
+	// pageNames returns the page names and page numbers of all pages.
+	pageNames := func(file io.ReadSeeker) []string {
+		tiffFile := openFile(file)
+		var names []string
+		for n, err := range tiffFile.Directories(ctx) {
+			Expect(err).To(BeNil())
+			name, err := tiffFile.TIFFGetFieldConstChar(ctx, libtiff.TIFFTAG_PAGENAME)
+			Expect(err).To(BeNil())
+			page, total, err := tiffFile.TIFFGetFieldTwoUint16(ctx, libtiff.TIFFTAG_PAGENUMBER)
+			Expect(err).To(BeNil())
+			Expect(int(page)).To(Equal(n))
+			names = append(names, fmt.Sprintf("%s %d/%d", name, page+1, total))
+		}
+		return names
+	}
+
+	It("merges files", func() {
+		first := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1", "a2")
+		second := writeDocument(libtiff.COMPRESSION_ADOBE_DEFLATE, "b0", "b1")
+		dst := createFile()
+
+		Expect(pages.Merge(ctx, instance, dst, first, second)).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a0 1/5", "a1 2/5", "a2 3/5", "b0 4/5", "b1 5/5"}))
+	})
+
+	It("copies the compressed data as it is", func() {
+		src := writeDocument(libtiff.COMPRESSION_JPEG, "a0", "a1")
+		dst := createFile()
+		Expect(pages.Select(ctx, instance, dst, src, []int{1})).To(Succeed())
+
+		srcFile, dstFile := openFile(src), openFile(dst)
+		Expect(srcFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		srcInfo, err := srcFile.Describe(ctx)
+		Expect(err).To(BeNil())
+		Expect(srcInfo.Directories[1].Image.Compression).To(Equal(libtiff.COMPRESSION_JPEG))
+
+		strips, err := srcFile.TIFFNumberOfStrips(ctx)
+		Expect(err).To(BeNil())
+		for i := uint32(0); i < strips; i++ {
+			expected, err := srcFile.TIFFReadRawStrip(ctx, i)
+			Expect(err).To(BeNil())
+			actual, err := dstFile.TIFFReadRawStrip(ctx, i)
+			Expect(err).To(BeNil())
+			Expect(actual).To(Equal(expected))
+		}
+	})
+
+	It("builds the output in memory when it can't be read", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1")
+		dst := createFile()
+
+		Expect(pages.Reorder(ctx, instance, writeOnly{dst}, src, []int{1, 0})).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a1 1/2", "a0 2/2"}))
+	})
+
+	It("splits files", func() {
+		src := writeDocument(libtiff.COMPRESSION_PACKBITS, "a0", "a1", "a2")
+
+		var files []*os.File
+		Expect(pages.Split(ctx, instance, src, func(page int) (io.WriteSeeker, error) {
+			Expect(page).To(Equal(len(files)))
+			files = append(files, createFile())
+			return files[page], nil
+		})).To(Succeed())
+
+		Expect(files).To(HaveLen(3))
+		for i, file := range files {
+			Expect(pageNames(file)).To(Equal([]string{fmt.Sprintf("a%d 1/1", i)}))
+		}
+	})
+
+	It("selects pages", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1", "a2")
+		dst := createFile()
+
+		Expect(pages.Select(ctx, instance, dst, src, []int{2, 0, 2})).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a2 1/3", "a0 2/3", "a2 3/3"}))
+
+		Expect(pages.Select(ctx, instance, createFile(), src, []int{3})).To(MatchError("page 3 is out of range, src has 3 pages"))
+		Expect(pages.Select(ctx, instance, createFile(), src, nil)).To(MatchError("no pages to write"))
+	})
+
+	It("reorders pages", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1", "a2")
+		dst := createFile()
+
+		Expect(pages.Reorder(ctx, instance, dst, src, []int{2, 0, 1})).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a2 1/3", "a0 2/3", "a1 3/3"}))
+
+		Expect(pages.Reorder(ctx, instance, createFile(), src, []int{0, 1})).To(MatchError("order has 2 pages, src has 3 pages"))
+		Expect(pages.Reorder(ctx, instance, createFile(), src, []int{0, 1, 1})).To(MatchError("page 1 is in the order more than once"))
+	})
+
+	It("deletes pages", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1", "a2")
+		dst := createFile()
+
+		Expect(pages.Delete(ctx, instance, dst, src, []int{1})).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a0 1/2", "a2 2/2"}))
+
+		Expect(pages.Delete(ctx, instance, createFile(), src, []int{0, 1, 2})).To(MatchError("no pages to write"))
+	})
+
+	It("inserts pages", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1", "a2")
+		insert := writeDocument(libtiff.COMPRESSION_CCITTFAX4, "b0", "b1")
+		dst := createFile()
+
+		Expect(pages.InsertAt(ctx, instance, dst, src, 1, insert)).To(Succeed())
+		Expect(pageNames(dst)).To(Equal([]string{"a0 1/5", "b0 2/5", "b1 3/5", "a1 4/5", "a2 5/5"}))
+
+		appended := createFile()
+		Expect(pages.InsertAt(ctx, instance, appended, src, 3, insert)).To(Succeed())
+		Expect(pageNames(appended)).To(Equal([]string{"a0 1/5", "a1 2/5", "a2 3/5", "b0 4/5", "b1 5/5"}))
+
+		Expect(pages.InsertAt(ctx, instance, createFile(), src, 4, insert)).To(MatchError("insert index 4 is out of range, src has 3 pages"))
+	})
+
+	It("keeps the pixels of every page", func() {
+		src := writeDocument(libtiff.COMPRESSION_LZW, "a0", "a1")
+		dst := createFile()
+		Expect(pages.Reorder(ctx, instance, dst, src, []int{1, 0})).To(Succeed())
+
+		srcFile, dstFile := openFile(src), openFile(dst)
+		Expect(srcFile.TIFFSetDirectory(ctx, 1)).To(Succeed())
+		expected, expectedCleanup, err := srcFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer expectedCleanup(ctx)
+		actual, actualCleanup, err := dstFile.ToGoImage(ctx)
+		Expect(err).To(BeNil())
+		defer actualCleanup(ctx)
+
+		Expect(actual.Bounds()).To(Equal(image.Rect(0, 0, 32, 24)))
+		Expect(actual).To(Equal(expected))
+	})
+
+	It("counts pages", func() {
+		count, err := pages.Count(ctx, instance, writeDocument(libtiff.COMPRESSION_NONE, "a0", "a1", "a2"))
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(3))
+	})
+})